@@ -0,0 +1,392 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// ErrMalformedBlock is returned when an adaptive block's payload doesn't
+// match its header.
+var ErrMalformedBlock = errors.New("uleb128: malformed adaptive block")
+
+// BlockMode identifies how the values of an adaptive block are encoded.
+type BlockMode uint64
+
+const (
+	// Each value as a ULEB128.
+	BlockPlain BlockMode = iota
+	// Each value's difference from the previous one (starting from 0) as a
+	// zigzag-encoded ULEB128.
+	BlockDelta
+	// Frame of reference: the smallest value as a ULEB128 and a bit width
+	// byte, followed by each value's offset from the smallest, packed
+	// LSB-first at that width.
+	BlockFOR
+	// Runs of equal values, each as the value followed by the run length.
+	BlockRLE
+	// A dictionary of frequent values followed by a code for each value, as
+	// in the dictionary mode of AppendDictionaryBlock.
+	BlockDictionary
+	blockModeCount
+)
+
+var blockModeNames = [blockModeCount]string{"plain", "delta", "FOR", "RLE", "dictionary"}
+
+func (m BlockMode) String() string {
+	if m < blockModeCount {
+		return blockModeNames[m]
+	}
+	return fmt.Sprintf("BlockMode(%d)", uint64(m))
+}
+
+// The number of values that the adaptive writer collects into each block
+// when no block size is given.
+const DefaultAdaptiveBlockSize = 1024
+
+// The maximum number of leading values that are looked at when choosing a
+// block's mode.
+const adaptiveSampleSize = 256
+
+// An adaptive block is encoded as its mode, its value count and the size of
+// its payload in bytes (all as ULEB128), followed by the payload. The size
+// lets a reader fetch the whole payload in one read, and skip blocks in
+// modes it doesn't understand.
+
+// AppendAdaptiveBlock appends values to buffer as an adaptive block,
+// returning the mode that was chosen. The mode is chosen by estimating the
+// size of each mode from a sample of the block's leading values.
+func AppendAdaptiveBlock(buffer []byte, values []uint64) (result []byte, mode BlockMode) {
+	result, _, mode = appendAdaptiveBlock(buffer, nil, values)
+	return
+}
+
+// DecodeAdaptiveBlockFromBytes decodes an adaptive block written by
+// AppendAdaptiveBlock from the start of buffer. The count is validated with
+// CheckLength against maxCount before anything is allocated. A buffer that
+// ends before the end of the block returns ErrTruncated.
+func DecodeAdaptiveBlockFromBytes(buffer []byte, maxCount int) (values []uint64, mode BlockMode, byteCount int, err error) {
+	header := [3]uint64{}
+	for i := range header {
+		var fieldByteCount int
+		if header[i], fieldByteCount, err = decodeUint64FromBytes(buffer[byteCount:]); err != nil {
+			return
+		}
+		byteCount += fieldByteCount
+	}
+	mode = BlockMode(header[0])
+	count, err := CheckLength(header[1], nil, maxCount, 8)
+	if err != nil {
+		return
+	}
+	if header[2] > uint64(len(buffer)-byteCount) {
+		err = ErrTruncated
+		return
+	}
+	payload := buffer[byteCount : byteCount+int(header[2])]
+	byteCount += len(payload)
+	values = make([]uint64, count)
+	if err = decodeBlockPayload(payload, values, mode); err != nil {
+		values = nil
+	}
+	return
+}
+
+// AdaptiveBlockWriter collects values into blocks, and writes each block
+// with AppendAdaptiveBlock (in a single Write call) once it's full.
+type AdaptiveBlockWriter struct {
+	writer    io.Writer
+	blockSize int
+	values    []uint64
+	buffer    []byte
+	payload   []byte
+}
+
+// NewAdaptiveBlockWriter returns a writer that makes blocks of blockSize
+// values. If blockSize is less than 1, DefaultAdaptiveBlockSize is used.
+func NewAdaptiveBlockWriter(writer io.Writer, blockSize int) *AdaptiveBlockWriter {
+	if blockSize < 1 {
+		blockSize = DefaultAdaptiveBlockSize
+	}
+	return &AdaptiveBlockWriter{
+		writer:    writer,
+		blockSize: blockSize,
+		values:    make([]uint64, 0, blockSize),
+	}
+}
+
+// WriteUint64 adds a value to the current block, writing the block out if
+// it's full.
+func (w *AdaptiveBlockWriter) WriteUint64(value uint64) error {
+	w.values = append(w.values, value)
+	if len(w.values) >= w.blockSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes out the current block, even if it isn't full. Flush must be
+// called when done.
+func (w *AdaptiveBlockWriter) Flush() (err error) {
+	if len(w.values) == 0 {
+		return
+	}
+	w.buffer, w.payload, _ = appendAdaptiveBlock(w.buffer[:0], w.payload, w.values)
+	w.values = w.values[:0]
+	_, err = w.writer.Write(w.buffer)
+	return
+}
+
+// AdaptiveBlockReader reads blocks written by an AdaptiveBlockWriter.
+type AdaptiveBlockReader struct {
+	reader   io.Reader
+	maxCount int
+	payload  []byte
+}
+
+// NewAdaptiveBlockReader returns a reader that refuses blocks of more than
+// maxCount values.
+func NewAdaptiveBlockReader(reader io.Reader, maxCount int) *AdaptiveBlockReader {
+	return &AdaptiveBlockReader{
+		reader:   reader,
+		maxCount: maxCount,
+	}
+}
+
+// ReadBlock reads the next block. If the stream ends cleanly before the
+// block, err will be io.EOF, and if it ends partway through, err will be
+// io.ErrUnexpectedEOF. A count over the maximum returns a *LengthError.
+func (r *AdaptiveBlockReader) ReadBlock() (values []uint64, mode BlockMode, err error) {
+	buffer := []byte{0}
+	asUint, _, err := decodeUint64(r.reader, buffer)
+	if err != nil {
+		return
+	}
+	mode = BlockMode(asUint)
+	count, _, err := DecodeLength(r.reader, r.maxCount, 8)
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	payloadSize, _, err := DecodeLength(r.reader, maxBlockPayloadSize(count), 1)
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	if cap(r.payload) < payloadSize {
+		r.payload = make([]byte, payloadSize)
+	}
+	r.payload = r.payload[:payloadSize]
+	if _, err = io.ReadFull(r.reader, r.payload); err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	values = make([]uint64, count)
+	if err = decodeBlockPayload(r.payload, values, mode); err != nil {
+		values = nil
+	}
+	return
+}
+
+// The largest payload any mode can produce for count values (a dictionary
+// block with every value both in the dictionary and escaped).
+func maxBlockPayloadSize(count int) int {
+	const perValue = 2*MaxBufferWriteBytes + 1
+	if count >= maxInt/perValue-1 {
+		return maxInt
+	}
+	return (count + 1) * perValue
+}
+
+func appendAdaptiveBlock(buffer []byte, scratch []byte, values []uint64) (result []byte, payload []byte, mode BlockMode) {
+	mode = chooseBlockMode(values)
+	payload = appendBlockPayload(scratch[:0], values, mode)
+	result = AppendUint64(buffer, uint64(mode))
+	result = AppendUint64(result, uint64(len(values)))
+	result = AppendUint64(result, uint64(len(payload)))
+	result = append(result, payload...)
+	return
+}
+
+// Pick the mode with the smallest estimated size for a sample of the
+// leading values. Ties go to the simpler mode.
+func chooseBlockMode(values []uint64) (mode BlockMode) {
+	sample := values[:minInt(len(values), adaptiveSampleSize)]
+	_, dictionarySize := buildDictionary(sample)
+	sizes := [blockModeCount]int{
+		BlockPlain:      0,
+		BlockDelta:      0,
+		BlockFOR:        forPayloadSize(sample),
+		BlockRLE:        0,
+		BlockDictionary: dictionarySize,
+	}
+	previous := uint64(0)
+	for i, value := range sample {
+		sizes[BlockPlain] += EncodedSizeUint64(value)
+		sizes[BlockDelta] += EncodedSizeUint64(ZigZag64(int64(value - previous)))
+		if i == 0 || value != previous {
+			runLength := 1
+			for i+runLength < len(sample) && sample[i+runLength] == value {
+				runLength++
+			}
+			sizes[BlockRLE] += EncodedSizeUint64(value) + EncodedSizeUint64(uint64(runLength))
+		}
+		previous = value
+	}
+	for candidate := BlockMode(1); candidate < blockModeCount; candidate++ {
+		if sizes[candidate] < sizes[mode] {
+			mode = candidate
+		}
+	}
+	return
+}
+
+func forRange(values []uint64) (minimum uint64, bitWidth uint) {
+	if len(values) == 0 {
+		return
+	}
+	minimum, maximum := values[0], values[0]
+	for _, value := range values {
+		if value < minimum {
+			minimum = value
+		}
+		if value > maximum {
+			maximum = value
+		}
+	}
+	bitWidth = uint(bits.Len64(maximum - minimum))
+	return
+}
+
+func forPayloadSize(values []uint64) int {
+	minimum, bitWidth := forRange(values)
+	return EncodedSizeUint64(minimum) + 1 + (len(values)*int(bitWidth)+7)/8
+}
+
+func appendBlockPayload(buffer []byte, values []uint64, mode BlockMode) []byte {
+	switch mode {
+	case BlockDelta:
+		previous := uint64(0)
+		for _, value := range values {
+			buffer = AppendUint64(buffer, ZigZag64(int64(value-previous)))
+			previous = value
+		}
+	case BlockFOR:
+		minimum, bitWidth := forRange(values)
+		buffer = AppendUint64(buffer, minimum)
+		buffer = append(buffer, byte(bitWidth))
+		offsets := make([]uint64, len(values))
+		for i, value := range values {
+			offsets[i] = value - minimum
+		}
+		buffer = appendPackedBits(buffer, offsets, bitWidth)
+	case BlockRLE:
+		for i := 0; i < len(values); {
+			runLength := 1
+			for i+runLength < len(values) && values[i+runLength] == values[i] {
+				runLength++
+			}
+			buffer = AppendUint64(buffer, values[i])
+			buffer = AppendUint64(buffer, uint64(runLength))
+			i += runLength
+		}
+	case BlockDictionary:
+		dictionary, _ := buildDictionary(values)
+		buffer = appendDictionaryCoded(buffer, values, dictionary)
+	default:
+		for _, value := range values {
+			buffer = AppendUint64(buffer, value)
+		}
+	}
+	return buffer
+}
+
+// Decode payload into values, which must have the length given in the
+// block's header. The payload must be used up exactly.
+func decodeBlockPayload(payload []byte, values []uint64, mode BlockMode) (err error) {
+	byteCount := 0
+	decodeField := func() (value uint64) {
+		var fieldByteCount int
+		value, fieldByteCount, err = decodeUint64FromBytes(payload[byteCount:])
+		byteCount += fieldByteCount
+		return
+	}
+
+	switch mode {
+	case BlockPlain:
+		for i := 0; i < len(values) && err == nil; i++ {
+			values[i] = decodeField()
+		}
+	case BlockDelta:
+		previous := uint64(0)
+		for i := 0; i < len(values) && err == nil; i++ {
+			previous += uint64(UnZigZag64(decodeField()))
+			values[i] = previous
+		}
+	case BlockFOR:
+		minimum := decodeField()
+		if err != nil {
+			break
+		}
+		if byteCount == len(payload) || payload[byteCount] > 64 {
+			return ErrMalformedBlock
+		}
+		bitWidth := uint(payload[byteCount])
+		byteCount++
+		packedSize := (len(values)*int(bitWidth) + 7) / 8
+		if packedSize > len(payload)-byteCount {
+			return ErrMalformedBlock
+		}
+		unpackBits(values[:0], payload[byteCount:], len(values), bitWidth)
+		byteCount += packedSize
+		for i := range values {
+			values[i] += minimum
+		}
+	case BlockRLE:
+		for filled := 0; filled < len(values) && err == nil; {
+			value := decodeField()
+			runLength := decodeField()
+			if err != nil {
+				break
+			}
+			if runLength == 0 || runLength > uint64(len(values)-filled) {
+				return ErrMalformedBlock
+			}
+			for end := filled + int(runLength); filled < end; filled++ {
+				values[filled] = value
+			}
+		}
+	case BlockDictionary:
+		byteCount, err = decodeDictionaryCoded(payload, values)
+		if err == ErrMalformedDictionary {
+			err = ErrMalformedBlock
+		}
+	default:
+		return ErrMalformedBlock
+	}
+	if err == ErrTruncated || (err == nil && byteCount != len(payload)) {
+		err = ErrMalformedBlock
+	}
+	return
+}
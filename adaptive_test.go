@@ -0,0 +1,186 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertAdaptiveBlock(t *testing.T, values []uint64, expectedMode BlockMode) []byte {
+	encoded, mode := AppendAdaptiveBlock(nil, values)
+	if mode != expectedMode {
+		t.Errorf("Expected mode %v but got %v", expectedMode, mode)
+	}
+	decoded, decodedMode, byteCount, err := DecodeAdaptiveBlockFromBytes(encoded, len(values))
+	if err != nil {
+		t.Error(err)
+		return encoded
+	}
+	if byteCount != len(encoded) || decodedMode != mode {
+		t.Errorf("Expected %v bytes in mode %v but got %v in mode %v", len(encoded), mode, byteCount, decodedMode)
+	}
+	if len(values) == 0 && len(decoded) == 0 {
+		return encoded
+	}
+	if describe.D(decoded) != describe.D(values) {
+		t.Errorf("Expected %v but got %v", describe.D(values), describe.D(decoded))
+	}
+	return encoded
+}
+
+func TestAdaptiveBlockModes(t *testing.T) {
+	assertAdaptiveBlock(t, nil, BlockPlain)
+	assertAdaptiveBlock(t, []uint64{1, 2, 3}, BlockPlain)
+
+	ascending := make([]uint64, 500)
+	for i := range ascending {
+		ascending[i] = 1<<40 + uint64(i)*3
+	}
+	assertAdaptiveBlock(t, ascending, BlockDelta)
+
+	clustered := make([]uint64, 500)
+	random := rand.New(rand.NewSource(1))
+	for i := range clustered {
+		clustered[i] = 1<<50 + uint64(random.Intn(200))
+	}
+	assertAdaptiveBlock(t, clustered, BlockFOR)
+
+	runs := make([]uint64, 500)
+	for i := range runs {
+		runs[i] = uint64(i/100) << 40
+	}
+	assertAdaptiveBlock(t, runs, BlockRLE)
+
+	skewed := make([]uint64, 500)
+	for i := range skewed {
+		skewed[i] = []uint64{1 << 45, 1 << 55, 1 << 60}[random.Intn(3)]
+	}
+	assertAdaptiveBlock(t, skewed, BlockDictionary)
+
+	// Decreasing values and wraparound still round-trip in delta mode.
+	descending := make([]uint64, 500)
+	for i := range descending {
+		descending[i] = 0xffffffffffffffff - uint64(i)*7
+	}
+	assertAdaptiveBlock(t, descending, BlockDelta)
+}
+
+func TestAdaptiveBlockEncoding(t *testing.T) {
+	encoded, _ := AppendAdaptiveBlock(nil, []uint64{300, 300, 300, 300, 5, 5, 5, 5})
+	expected := []byte{byte(BlockRLE), 0x08, 0x05, 0xac, 0x02, 0x04, 0x05, 0x04}
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(encoded))
+	}
+}
+
+func TestAdaptiveBlockErrors(t *testing.T) {
+	for _, test := range []struct {
+		encoded []byte
+		err     error
+	}{
+		{[]byte{}, ErrTruncated},
+		{[]byte{0x00, 0x02, 0x03, 0x01}, ErrTruncated},
+		{[]byte{0x00, 0x02, 0x01, 0x01}, ErrMalformedBlock},
+		{[]byte{0x00, 0x01, 0x02, 0x01, 0x01}, ErrMalformedBlock},
+		{[]byte{0x09, 0x01, 0x01, 0x01}, ErrMalformedBlock},
+		{[]byte{byte(BlockRLE), 0x02, 0x02, 0x05, 0x03}, ErrMalformedBlock},
+		{[]byte{byte(BlockRLE), 0x02, 0x02, 0x05, 0x00}, ErrMalformedBlock},
+		{[]byte{byte(BlockFOR), 0x02, 0x02, 0x05, 0x41}, ErrMalformedBlock},
+		{[]byte{byte(BlockFOR), 0x02, 0x03, 0x05, 0x08, 0x01}, ErrMalformedBlock},
+		{[]byte{byte(BlockDictionary), 0x01, 0x03, 0x01, 0x07, 0x02}, ErrMalformedBlock},
+	} {
+		if _, _, _, err := DecodeAdaptiveBlockFromBytes(test.encoded, 10); err != test.err {
+			t.Errorf("Expected %v to give %v but got %v", describe.D(test.encoded), test.err, err)
+		}
+	}
+	if _, _, _, err := DecodeAdaptiveBlockFromBytes([]byte{0x00, 0x20, 0x00}, 10); err == nil {
+		t.Errorf("Expected a count over the maximum to fail")
+	}
+}
+
+func TestAdaptiveBlockWriter(t *testing.T) {
+	values := make([]uint64, 2500)
+	for i := range values {
+		switch {
+		case i < 1000:
+			values[i] = uint64(i) * 1000
+		case i < 2000:
+			values[i] = uint64(7 + i/100%2)
+		default:
+			values[i] = uint64(i) << 30
+		}
+	}
+	buff := &writeCounter{}
+	writer := NewAdaptiveBlockWriter(buff, 1000)
+	for _, value := range values {
+		if err := writer.WriteUint64(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buff.writeCount != 3 {
+		t.Errorf("Expected 3 writes but got %v", buff.writeCount)
+	}
+
+	reader := NewAdaptiveBlockReader(bytes.NewReader(buff.Bytes()), 1000)
+	var decoded []uint64
+	var modes []BlockMode
+	for {
+		block, mode, err := reader.ReadBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, block...)
+		modes = append(modes, mode)
+	}
+	if describe.D(decoded) != describe.D(values) {
+		t.Errorf("Values did not round trip")
+	}
+	if expected := []BlockMode{BlockDelta, BlockRLE, BlockFOR}; describe.D(modes) != describe.D(expected) {
+		t.Errorf("Expected modes %v but got %v", expected, modes)
+	}
+
+	truncated := buff.Bytes()[:buff.Len()-1]
+	reader = NewAdaptiveBlockReader(bytes.NewReader(truncated), 1000)
+	var err error
+	for err == nil {
+		_, _, err = reader.ReadBlock()
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestBlockModeString(t *testing.T) {
+	if BlockFOR.String() != "FOR" || BlockMode(99).String() != "BlockMode(99)" {
+		t.Errorf("Unexpected mode names %v, %v", BlockFOR, BlockMode(99))
+	}
+}
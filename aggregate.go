@@ -0,0 +1,85 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// The aggregate functions scan a buffer of consecutive ULEB128 values
+// without collecting them, working in uint64 arithmetic for as long as the
+// values and results fit. count is the number of values scanned. If buffer
+// ends partway through a value, err will be ErrTruncated and the result
+// covers the complete values before it.
+
+// SumEncoded returns the sum of the values in buffer.
+func SumEncoded(buffer []byte) (sum Value, count int, err error) {
+	low := uint64(0)
+	var high *big.Int
+	_, err = DecodeAllFromBytes(buffer, func(asUint uint64, asBigInt *big.Int) {
+		count++
+		if asBigInt != nil {
+			if high == nil {
+				high = new(big.Int)
+			}
+			high.Add(high, asBigInt)
+			return
+		}
+		var carry uint64
+		if low, carry = bits.Add64(low, asUint, 0); carry != 0 {
+			if high == nil {
+				high = new(big.Int)
+			}
+			high.Add(high, bigTwoTo64)
+		}
+	})
+	if high == nil {
+		sum = Uint64Value(low)
+	} else {
+		sum = BigValue(high.Add(high, new(big.Int).SetUint64(low)))
+	}
+	return
+}
+
+// MaxEncoded returns the largest value in buffer, or 0 if it's empty.
+func MaxEncoded(buffer []byte) (max Value, count int, err error) {
+	_, err = DecodeAllFromBytes(buffer, func(asUint uint64, asBigInt *big.Int) {
+		count++
+		if value := NewValue(asUint, asBigInt); count == 1 || value.Cmp(max) > 0 {
+			max = value
+		}
+	})
+	return
+}
+
+// MinEncoded returns the smallest value in buffer, or 0 if it's empty.
+func MinEncoded(buffer []byte) (min Value, count int, err error) {
+	_, err = DecodeAllFromBytes(buffer, func(asUint uint64, asBigInt *big.Int) {
+		count++
+		if value := NewValue(asUint, asBigInt); count == 1 || value.Cmp(min) < 0 {
+			min = value
+		}
+	})
+	return
+}
+
+var bigTwoTo64 = new(big.Int).Lsh(big.NewInt(1), 64)
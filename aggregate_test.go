@@ -0,0 +1,110 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+	"testing"
+)
+
+func assertAggregate(t *testing.T, name string, actual Value, count int, err error, expected Value, expectedCount int) {
+	if err != nil || actual.Cmp(expected) != 0 || count != expectedCount {
+		t.Errorf("Expected %v of %v values to be %v but got %v of %v values (%v)", name, expectedCount, expected, actual, count, err)
+	}
+}
+
+func TestAggregatesUint64(t *testing.T) {
+	buffer := AppendUint64(nil, 5)
+	buffer = AppendUint64(buffer, 300)
+	buffer = AppendUint64(buffer, 2)
+	sum, count, err := SumEncoded(buffer)
+	assertAggregate(t, "sum", sum, count, err, Uint64Value(307), 3)
+	max, count, err := MaxEncoded(buffer)
+	assertAggregate(t, "max", max, count, err, Uint64Value(300), 3)
+	min, count, err := MinEncoded(buffer)
+	assertAggregate(t, "min", min, count, err, Uint64Value(2), 3)
+
+	sum, count, err = SumEncoded(nil)
+	assertAggregate(t, "sum", sum, count, err, Uint64Value(0), 0)
+	max, count, err = MaxEncoded(nil)
+	assertAggregate(t, "max", max, count, err, Uint64Value(0), 0)
+	min, count, err = MinEncoded(nil)
+	assertAggregate(t, "min", min, count, err, Uint64Value(0), 0)
+}
+
+func TestAggregatesBig(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(3), 100)
+	buffer := AppendUint64(nil, 0xffffffffffffffff)
+	buffer = AppendUint64(buffer, 0xffffffffffffffff)
+	buffer = Append(buffer, huge)
+	buffer = AppendUint64(buffer, 7)
+
+	expectedSum := new(big.Int).SetUint64(0xffffffffffffffff)
+	expectedSum.Add(expectedSum, expectedSum)
+	expectedSum.Add(expectedSum, huge)
+	expectedSum.Add(expectedSum, big.NewInt(7))
+	sum, count, err := SumEncoded(buffer)
+	assertAggregate(t, "sum", sum, count, err, BigValue(expectedSum), 4)
+	max, count, err := MaxEncoded(buffer)
+	assertAggregate(t, "max", max, count, err, BigValue(huge), 4)
+	min, count, err := MinEncoded(buffer)
+	assertAggregate(t, "min", min, count, err, Uint64Value(7), 4)
+
+	// A big value first, then only smaller ones
+	buffer = Append(nil, huge)
+	buffer = Append(buffer, new(big.Int).Lsh(big.NewInt(1), 70))
+	min, count, err = MinEncoded(buffer)
+	assertAggregate(t, "min", min, count, err, BigValue(new(big.Int).Lsh(big.NewInt(1), 70)), 2)
+
+	// Redundantly padded small values must still compare as small.
+	padded := []byte{0x85, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00, 0x09}
+	max, count, err = MaxEncoded(padded)
+	assertAggregate(t, "max", max, count, err, Uint64Value(9), 2)
+	min, count, err = MinEncoded(padded)
+	assertAggregate(t, "min", min, count, err, Uint64Value(5), 2)
+	sum, count, err = SumEncoded(padded)
+	assertAggregate(t, "sum", sum, count, err, Uint64Value(14), 2)
+}
+
+func TestAggregatesTruncated(t *testing.T) {
+	buffer := []byte{0x05, 0x06, 0x80}
+	sum, count, err := SumEncoded(buffer)
+	if err != ErrTruncated || count != 2 || sum.Cmp(Uint64Value(11)) != 0 {
+		t.Errorf("Expected a sum of 11 over 2 values with %v but got %v over %v values (%v)", ErrTruncated, sum, count, err)
+	}
+	if _, _, err = MaxEncoded(buffer); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	if _, _, err = MinEncoded(buffer); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
+
+func BenchmarkSumEncoded(b *testing.B) {
+	var buffer []byte
+	for i := uint64(0); i < 1000; i++ {
+		buffer = AppendUint64(buffer, i*i)
+	}
+	b.SetBytes(int64(len(buffer)))
+	for i := 0; i < b.N; i++ {
+		SumEncoded(buffer)
+	}
+}
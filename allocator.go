@@ -0,0 +1,157 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+)
+
+// Allocator provides the memory that a Decoder uses for values that don't fit
+// into a uint64. Supplying one (such as an Arena) lets request-scoped code
+// reclaim all of that memory at once.
+type Allocator interface {
+	// NewBigInt returns a zeroed big.Int.
+	NewBigInt() *big.Int
+	// MakeWords returns an empty word slice with at least the given capacity.
+	MakeWords(capacity int) []big.Word
+}
+
+const arenaIntBlockSize = 64
+const arenaWordBlockSize = 1024
+
+// Arena is an Allocator that carves big.Ints and word slices out of larger
+// blocks. Call Reset to reuse the arena once nothing refers to the values it
+// allocated anymore. The zero value is ready to use. An Arena is not safe for
+// concurrent use.
+type Arena struct {
+	ints  []big.Int
+	words []big.Word
+	// Every block handed out since the last Reset, so that Wipe can reach
+	// them all.
+	intBlocks  [][]big.Int
+	wordBlocks [][]big.Word
+}
+
+// NewBigInt returns a zeroed big.Int from the arena.
+func (a *Arena) NewBigInt() *big.Int {
+	if len(a.ints) == cap(a.ints) {
+		a.ints = make([]big.Int, 0, arenaIntBlockSize)
+		a.intBlocks = append(a.intBlocks, a.ints[:cap(a.ints)])
+	}
+	a.ints = a.ints[:len(a.ints)+1]
+	result := &a.ints[len(a.ints)-1]
+	*result = big.Int{}
+	return result
+}
+
+// MakeWords returns an empty word slice from the arena with exactly the
+// given capacity. Requests larger than the arena's block size are allocated
+// separately.
+func (a *Arena) MakeWords(capacity int) []big.Word {
+	if capacity > arenaWordBlockSize {
+		words := make([]big.Word, 0, capacity)
+		a.wordBlocks = append(a.wordBlocks, words[:capacity])
+		return words
+	}
+	if cap(a.words)-len(a.words) < capacity {
+		a.words = make([]big.Word, 0, arenaWordBlockSize)
+		a.wordBlocks = append(a.wordBlocks, a.words[:cap(a.words)])
+	}
+	start := len(a.words)
+	a.words = a.words[:start+capacity]
+	return a.words[start:start:len(a.words)]
+}
+
+// Reset makes the arena's current blocks available for reuse. Values
+// allocated before the call must no longer be used.
+func (a *Arena) Reset() {
+	a.ints = a.ints[:0]
+	a.words = a.words[:0]
+	a.intBlocks = a.intBlocks[:0]
+	a.wordBlocks = a.wordBlocks[:0]
+	if cap(a.ints) > 0 {
+		a.intBlocks = append(a.intBlocks, a.ints[:cap(a.ints)])
+	}
+	if cap(a.words) > 0 {
+		a.wordBlocks = append(a.wordBlocks, a.words[:cap(a.words)])
+	}
+}
+
+// Wipe zeroes all memory that the arena handed out since the last Reset,
+// and then resets it. Use it instead of Reset when the decoded values were
+// sensitive.
+func (a *Arena) Wipe() {
+	for _, block := range a.intBlocks {
+		for i := range block {
+			block[i] = big.Int{}
+		}
+	}
+	for _, block := range a.wordBlocks {
+		wipeWords(block)
+	}
+	a.Reset()
+}
+
+// The default allocator, which allocates from the heap.
+type heapAllocator struct{}
+
+func (heapAllocator) NewBigInt() *big.Int {
+	return new(big.Int)
+}
+
+func (heapAllocator) MakeWords(capacity int) []big.Word {
+	return make([]big.Word, 0, capacity)
+}
+
+// Allocate a big.Int with enough word capacity to decode encoded.
+func newBigForEncoded(allocator Allocator, encoded []byte) *big.Int {
+	result := allocator.NewBigInt()
+	return result.SetBits(allocator.MakeWords(wordCountForEncoded(encoded)))
+}
+
+// The number of words that setBigFromEncoded needs to decode encoded.
+func wordCountForEncoded(encoded []byte) int {
+	return len(encoded)*7/wordSize() + 1
+}
+
+func wipeWords(words []big.Word) {
+	for i := range words {
+		words[i] = 0
+	}
+}
+
+func wipeBytes(buffer []byte) {
+	for i := range buffer {
+		buffer[i] = 0
+	}
+}
+
+func wipeUint32s(words []uint32) {
+	for i := range words {
+		words[i] = 0
+	}
+}
+
+func wipeUint64s(words []uint64) {
+	for i := range words {
+		words[i] = 0
+	}
+}
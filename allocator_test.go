@@ -0,0 +1,141 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+type countingAllocator struct {
+	ints  int
+	words int
+}
+
+func (a *countingAllocator) NewBigInt() *big.Int {
+	a.ints++
+	return new(big.Int)
+}
+
+func (a *countingAllocator) MakeWords(capacity int) []big.Word {
+	a.words++
+	return make([]big.Word, 0, capacity)
+}
+
+func TestDecoderAllocator(t *testing.T) {
+	huge := newBigFromHex("123456789abcdef0123456789abcdef")
+	buff := &bytes.Buffer{}
+	EncodeUint64(300, buff)
+	Encode(huge, buff)
+	// 2^64-1 in a redundant 11-byte encoding still decodes to a uint64.
+	buff.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x81, 0x00})
+	Encode(huge, buff)
+
+	allocator := &countingAllocator{}
+	decoder := NewBytesDecoder(buff.Bytes(), DecoderOptions{Allocator: allocator})
+	if v, _, _, err := decoder.Decode(); err != nil || v != 300 {
+		t.Errorf("Expected 300 but got %v (%v)", v, err)
+	}
+	if _, v, _, err := decoder.Decode(); err != nil || v == nil || v.Cmp(huge) != 0 {
+		t.Errorf("Expected %v but got %v (%v)", huge, v, err)
+	}
+	if v, b, _, err := decoder.Decode(); err != nil || b != nil || v != 0xffffffffffffffff {
+		t.Errorf("Expected 0xffffffffffffffff but got %x, %v (%v)", v, b, err)
+	}
+	if v, _, err := decoder.DecodeBig(nil); err != nil || v.Cmp(huge) != 0 {
+		t.Errorf("Expected %v but got %v (%v)", huge, v, err)
+	}
+	if allocator.ints != 3 || allocator.words != 3 {
+		t.Errorf("Expected 3 big.Int and 3 word allocations but got %v and %v", allocator.ints, allocator.words)
+	}
+}
+
+func TestArena(t *testing.T) {
+	huge := newBigFromHex("123456789abcdef0123456789abcdef0123456789abcdef")
+	encoded := Append(nil, huge)
+	arena := &Arena{}
+	options := DecoderOptions{Allocator: arena}
+
+	decodeAll := func() {
+		decoder := NewBytesDecoder(encoded, options)
+		if v, _, err := decoder.DecodeBig(nil); err != nil || v.Cmp(huge) != 0 {
+			t.Errorf("Expected %v but got %v (%v)", huge, v, err)
+		}
+		arena.Reset()
+	}
+	decodeAll()
+
+	// Once warmed up, decoding only allocates the Decoder itself.
+	allocs := testing.AllocsPerRun(100, decodeAll)
+	if allocs > 1 {
+		t.Errorf("Expected at most 1 allocation per decode but got %v", allocs)
+	}
+}
+
+func TestArenaLargeWords(t *testing.T) {
+	arena := &Arena{}
+	words := arena.MakeWords(arenaWordBlockSize + 1)
+	if len(words) != 0 || cap(words) != arenaWordBlockSize+1 {
+		t.Errorf("Expected an empty slice with capacity %v but got %v/%v", arenaWordBlockSize+1, len(words), cap(words))
+	}
+	a := arena.MakeWords(3)
+	b := arena.MakeWords(3)
+	a = append(a, 1, 2, 3, 4)
+	b = append(b, 5)
+	if a[0] != 1 || b[0] != 5 {
+		t.Errorf("Expected arena slices not to overlap")
+	}
+}
+
+func TestArenaWipe(t *testing.T) {
+	arena := &Arena{}
+	var values []*big.Int
+	// Enough values to fill several word blocks, plus one oversized value.
+	for i := 0; i < 300; i++ {
+		value := arena.NewBigInt()
+		value.SetBits(arena.MakeWords(8))
+		value.Lsh(big.NewInt(int64(i+1)), 400)
+		values = append(values, value)
+	}
+	oversized := arena.NewBigInt()
+	// Lsh requires one spare word of capacity to work in place.
+	oversized.SetBits(arena.MakeWords(arenaWordBlockSize + 2))
+	oversized.Lsh(big.NewInt(1), uint(arenaWordBlockSize*wordSize()))
+	words := oversized.Bits()
+	blocks := append([][]big.Word{}, arena.wordBlocks...)
+
+	arena.Wipe()
+	for _, value := range values {
+		if value.Sign() != 0 {
+			t.Errorf("Expected all arena big.Ints to be zeroed but got %v", value)
+			break
+		}
+	}
+	for _, block := range append(blocks, words) {
+		for _, word := range block {
+			if word != 0 {
+				t.Errorf("Expected all arena words to be zeroed")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"fmt"
+)
+
+// ValueKind tells how a decoded value is represented.
+type ValueKind int
+
+const (
+	// The value fits into a uint64.
+	KindUint64 ValueKind = iota
+	// The value needs a math.big.Int.
+	KindBig
+)
+
+func (k ValueKind) String() string {
+	if k == KindBig {
+		return "big"
+	}
+	return "uint64"
+}
+
+// Annotation describes where and how a value was encoded, for debugging
+// streams.
+type Annotation struct {
+	// The offset in the input where the value starts.
+	Offset int
+	// The number of bytes the value was encoded in.
+	ByteCount int
+	// False if the value wasn't encoded in its minimal form.
+	Canonical bool
+	Kind      ValueKind
+	Value     Value
+}
+
+func (a Annotation) String() string {
+	canonical := ""
+	if !a.Canonical {
+		canonical = " (non-canonical)"
+	}
+	return fmt.Sprintf("%08x+%d %v = %v%v", a.Offset, a.ByteCount, a.Kind, a.Value, canonical)
+}
+
+// AnnotateFromBytes decodes every value in buffer, returning an annotation
+// for each of them. If buffer ends partway through a value, the annotations
+// of the values before it are returned along with a *DecodeError holding
+// ErrTruncated.
+func AnnotateFromBytes(buffer []byte) (annotations []Annotation, err error) {
+	for offset := 0; offset < len(buffer); {
+		value, byteCount, decodeErr := DecodeValueFromBytes(buffer[offset:])
+		if decodeErr != nil {
+			err = newDecodeError(decodeErr, offset, buffer[offset:])
+			return
+		}
+		kind := KindUint64
+		if value.IsBig() {
+			kind = KindBig
+		}
+		annotations = append(annotations, Annotation{
+			Offset:    offset,
+			ByteCount: byteCount,
+			Canonical: isCanonical(buffer[offset : offset+byteCount]),
+			Kind:      kind,
+			Value:     value,
+		})
+		offset += byteCount
+	}
+	return
+}
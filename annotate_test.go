@@ -0,0 +1,52 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAnnotateFromBytes(t *testing.T) {
+	buffer := []byte{0x05, 0xac, 0x82, 0x00, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02, 0x80}
+	annotations, err := AnnotateFromBytes(buffer)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Err != ErrTruncated || decodeErr.Offset != 14 {
+		t.Errorf("Expected ErrTruncated at offset 14 but got %v", err)
+	}
+	expected := []string{
+		"00000000+1 uint64 = 5",
+		"00000001+3 uint64 = 300 (non-canonical)",
+		"00000004+10 big = 18446744073709551616",
+	}
+	if len(annotations) != len(expected) {
+		t.Fatalf("Expected %v annotations but got %v", len(expected), annotations)
+	}
+	for i, annotation := range annotations {
+		if annotation.String() != expected[i] {
+			t.Errorf("Expected %q but got %q", expected[i], annotation)
+		}
+	}
+
+	if annotations, err = AnnotateFromBytes(nil); err != nil || len(annotations) != 0 {
+		t.Errorf("Expected nothing from empty input but got %v (%v)", annotations, err)
+	}
+}
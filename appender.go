@@ -0,0 +1,132 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"os"
+)
+
+// CheckTail checks that the first size bytes of reader, a file of
+// consecutive ULEB128 values, end on a value boundary. Since only the last
+// byte of a value has a clear continuation bit, this only needs to read back
+// from the end. validSize is the size of the file up to the end of its last
+// complete value. If the file ends partway through a value (as it can after
+// a crash mid-write), err is a *DecodeError holding ErrTruncated.
+func CheckTail(reader io.ReaderAt, size int64) (validSize int64, err error) {
+	var chunk [readerAtChunkSize]byte
+	validSize = size
+	for validSize > 0 {
+		start := validSize - int64(len(chunk))
+		if start < 0 {
+			start = 0
+		}
+		n := int(validSize - start)
+		if _, err = readChunkAt(reader, chunk[:n], start, validSize); err != nil {
+			return
+		}
+		for i := n - 1; i >= 0; i-- {
+			if chunk[i]&continuationMask == 0 {
+				break
+			}
+			validSize--
+		}
+		if validSize > start {
+			break
+		}
+	}
+	if validSize < size {
+		tail := make([]byte, minInt(int(size-validSize), maxDecodeErrorBytes))
+		if _, err = readChunkAt(reader, tail, validSize, size); err != nil {
+			return
+		}
+		decodeErr := newDecodeError(ErrTruncated, int(validSize), tail)
+		decodeErr.ByteCount = int(size - validSize)
+		err = decodeErr
+	}
+	return
+}
+
+// Appender appends ULEB128 values to an existing file of consecutive values
+// (not a count-prefixed block, whose count would need rewriting). Values
+// are encoded with the embedded Encoder.
+type Appender struct {
+	*Encoder
+	file *os.File
+	// The number of bytes of a truncated final value that were cut from the
+	// file when it was opened.
+	RepairedByteCount int
+}
+
+// OpenAppender opens the file at path for appending, creating it if it
+// doesn't exist. If the file ends partway through a value, OpenAppender
+// returns the *DecodeError from CheckTail, unless repair is true, in which
+// case the partial value is cut off so that appending can continue from the
+// last complete value.
+func OpenAppender(path string, repair bool, options EncoderOptions) (appender *Appender, err error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	validSize, err := CheckTail(file, info.Size())
+	if _, truncated := err.(*DecodeError); truncated && repair {
+		err = file.Truncate(validSize)
+	}
+	if err != nil {
+		return
+	}
+	if _, err = file.Seek(validSize, io.SeekStart); err != nil {
+		return
+	}
+	appender = &Appender{
+		Encoder:           NewEncoder(file, options),
+		file:              file,
+		RepairedByteCount: int(info.Size() - validSize),
+	}
+	return
+}
+
+// Sync flushes any buffered values and commits the file to stable storage.
+func (a *Appender) Sync() (err error) {
+	if err = a.Flush(); err != nil {
+		return
+	}
+	return a.file.Sync()
+}
+
+// Close flushes any buffered values and closes the file.
+func (a *Appender) Close() (err error) {
+	err = a.Flush()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTail(t *testing.T) {
+	long := bytes.Repeat([]byte{0x80}, 200)
+	for _, test := range []struct {
+		data      []byte
+		validSize int64
+	}{
+		{nil, 0},
+		{[]byte{0x01}, 1},
+		{[]byte{0x01, 0xac, 0x02}, 3},
+		{[]byte{0x01, 0xac}, 1},
+		{[]byte{0x80, 0x80}, 0},
+		{append([]byte{0x05}, long...), 1},
+		{append(append([]byte{0x05}, long...), 0x01), 202},
+	} {
+		validSize, err := CheckTail(bytes.NewReader(test.data), int64(len(test.data)))
+		if validSize != test.validSize {
+			t.Errorf("Expected %v bytes of %v to be valid but got %v", test.validSize, len(test.data), validSize)
+		}
+		var decodeErr *DecodeError
+		if validSize == int64(len(test.data)) {
+			if err != nil {
+				t.Errorf("Expected no error but got %v", err)
+			}
+		} else if !errors.As(err, &decodeErr) || decodeErr.Err != ErrTruncated ||
+			decodeErr.Offset != int(validSize) || decodeErr.ByteCount != len(test.data)-int(validSize) {
+			t.Errorf("Expected ErrTruncated at %v but got %v", validSize, err)
+		}
+	}
+}
+
+func TestAppender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "values")
+
+	appender, err := OpenAppender(path, false, EncoderOptions{BufferSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appender.EncodeUint64(1)
+	appender.EncodeUint64(300)
+	if err = appender.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash partway through writing a value.
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	file.Write([]byte{0x80, 0x80})
+	file.Close()
+
+	if _, err = OpenAppender(path, false, EncoderOptions{}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+	appender, err = OpenAppender(path, true, EncoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appender.RepairedByteCount != 2 {
+		t.Errorf("Expected 2 bytes to be repaired but got %v", appender.RepairedByteCount)
+	}
+	appender.EncodeUint64(5)
+	if err = appender.Sync(); err != nil {
+		t.Error(err)
+	}
+	if err = appender.Close(); err != nil {
+		t.Error(err)
+	}
+
+	contents, _ := ioutil.ReadFile(path)
+	if expected := []byte{0x01, 0xac, 0x02, 0x05}; !bytes.Equal(contents, expected) {
+		t.Errorf("Expected %v but got %v", expected, contents)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidIndex is returned when an index doesn't match the buffer it's
+// supposed to describe.
+var ErrInvalidIndex = errors.New("uleb128: index doesn't match buffer")
+
+// The sampling interval used when none is given.
+const DefaultIndexInterval = 64
+
+// Index is a sampled index over a buffer of consecutive ULEB128 values,
+// recording the byte offset of every Interval-th value.
+type Index struct {
+	// The number of values between samples.
+	Interval int
+	// Offsets[k] is the byte offset of value k*Interval.
+	Offsets []int
+	// The total number of values in the buffer.
+	Count int
+}
+
+// BuildIndex scans a buffer of consecutive ULEB128 values and builds an
+// index sampling every interval-th value. If interval is less than 1,
+// DefaultIndexInterval is used. If buffer ends partway through a value, err
+// will be ErrTruncated.
+func BuildIndex(buffer []byte, interval int) (index Index, err error) {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+	index.Interval = interval
+	atValueStart := true
+	for offset, b := range buffer {
+		if atValueStart {
+			if index.Count%interval == 0 {
+				index.Offsets = append(index.Offsets, offset)
+			}
+			index.Count++
+		}
+		atValueStart = b&continuationMask == 0
+	}
+	if !atValueStart {
+		err = ErrTruncated
+	}
+	return
+}
+
+// ULEBArray gives random access to a buffer of consecutive ULEB128 values.
+// Get seeks to the nearest sampled offset in its index and then skips
+// forward over at most Interval-1 values, so access time is bounded by the
+// sampling interval rather than by the position.
+type ULEBArray struct {
+	buffer []byte
+	index  Index
+}
+
+// NewULEBArray indexes buffer (as in BuildIndex) and returns an array over
+// it. The buffer is referenced rather than copied.
+func NewULEBArray(buffer []byte, interval int) (array *ULEBArray, err error) {
+	index, err := BuildIndex(buffer, interval)
+	if err != nil {
+		return
+	}
+	array = &ULEBArray{buffer: buffer, index: index}
+	return
+}
+
+// NewULEBArrayWithIndex returns an array over buffer using an index that was
+// built earlier (for example by ULEBArrayBuilder), without rescanning the
+// whole buffer. The index's shape, its sampled offsets and the values after
+// the last sample are checked, returning ErrInvalidIndex if they don't match
+// the buffer: the first offset must be 0, and the offsets must be strictly
+// increasing, inside the buffer and at the start of a value.
+//
+// The number of values between consecutive samples isn't checked, since that
+// would mean scanning the whole buffer. An index that gets it wrong makes Get
+// return the wrong values, or panic if it runs off the end of the buffer.
+func NewULEBArrayWithIndex(buffer []byte, index Index) (array *ULEBArray, err error) {
+	if err = checkIndex(buffer, index); err != nil {
+		return
+	}
+	array = &ULEBArray{buffer: buffer, index: index}
+	return
+}
+
+func checkIndex(buffer []byte, index Index) error {
+	if index.Interval < 1 || index.Count < 0 ||
+		len(index.Offsets) != (index.Count+index.Interval-1)/index.Interval {
+		return ErrInvalidIndex
+	}
+	if index.Count == 0 {
+		if len(buffer) != 0 {
+			return ErrInvalidIndex
+		}
+		return nil
+	}
+	if index.Offsets[0] != 0 {
+		return ErrInvalidIndex
+	}
+	previous := -1
+	for _, offset := range index.Offsets {
+		if offset <= previous || offset >= len(buffer) ||
+			(offset > 0 && buffer[offset-1]&continuationMask != 0) {
+			return ErrInvalidIndex
+		}
+		previous = offset
+	}
+	if buffer[len(buffer)-1]&continuationMask != 0 {
+		return ErrTruncated
+	}
+	tailCount := 0
+	for _, b := range buffer[previous:] {
+		if b&continuationMask == 0 {
+			tailCount++
+		}
+	}
+	if tailCount != index.Count-(len(index.Offsets)-1)*index.Interval {
+		return ErrInvalidIndex
+	}
+	return nil
+}
+
+// Len returns the number of values in the array.
+func (a *ULEBArray) Len() int {
+	return a.index.Count
+}
+
+// Index returns the array's sampled index.
+func (a *ULEBArray) Index() Index {
+	return a.index
+}
+
+// Get returns the value at position i. It panics if i is out of range.
+func (a *ULEBArray) Get(i int) Value {
+	asUint, asBigInt, _, _ := DecodeFromBytes(a.buffer[a.offsetOf(i):])
+	return NewValue(asUint, asBigInt)
+}
+
+// Get the byte offset of the value at position i.
+func (a *ULEBArray) offsetOf(i int) int {
+	if i < 0 || i >= a.index.Count {
+		panic("uleb128: ULEBArray index out of range")
+	}
+	offset := a.index.Offsets[i/a.index.Interval]
+	for skip := i % a.index.Interval; skip > 0; offset++ {
+		if a.buffer[offset] < continuationMask {
+			skip--
+		}
+	}
+	return offset
+}
+
+// ULEBArrayBuilder builds a packed buffer of values along with its sampled
+// index, for use with NewULEBArrayWithIndex.
+type ULEBArrayBuilder struct {
+	buffer []byte
+	index  Index
+}
+
+// NewULEBArrayBuilder returns a builder that samples every interval-th value.
+// If interval is less than 1, DefaultIndexInterval is used.
+func NewULEBArrayBuilder(interval int) *ULEBArrayBuilder {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+	return &ULEBArrayBuilder{index: Index{Interval: interval}}
+}
+
+// Append appends a uint64 value.
+func (b *ULEBArrayBuilder) Append(value uint64) {
+	b.sample()
+	b.buffer = AppendUint64(b.buffer, value)
+}
+
+// AppendBig appends a math.big.Int value (the sign of the value will be
+// ignored).
+func (b *ULEBArrayBuilder) AppendBig(value *big.Int) {
+	b.sample()
+	b.buffer = Append(b.buffer, value)
+}
+
+// Len returns the number of values appended so far.
+func (b *ULEBArrayBuilder) Len() int {
+	return b.index.Count
+}
+
+// Finalize returns the packed buffer and its index, and resets the builder
+// so that it can start a new array with the same interval.
+func (b *ULEBArrayBuilder) Finalize() (buffer []byte, index Index) {
+	buffer, index = b.buffer, b.index
+	*b = ULEBArrayBuilder{index: Index{Interval: index.Interval}}
+	return
+}
+
+func (b *ULEBArrayBuilder) sample() {
+	if b.index.Count%b.index.Interval == 0 {
+		b.index.Offsets = append(b.index.Offsets, len(b.buffer))
+	}
+	b.index.Count++
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestBuildIndex(t *testing.T) {
+	buffer := []byte{0x01, 0xac, 0x02, 0x03, 0x80, 0x80, 0x01, 0x05, 0x06}
+	index, err := BuildIndex(buffer, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := Index{Interval: 2, Offsets: []int{0, 3, 7}, Count: 6}
+	if !reflect.DeepEqual(index, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, index)
+	}
+
+	if index, err = BuildIndex(nil, 0); err != nil || index.Count != 0 || index.Interval != DefaultIndexInterval {
+		t.Errorf("Expected an empty index with the default interval but got %+v (%v)", index, err)
+	}
+	if _, err = BuildIndex([]byte{0x01, 0x80}, 2); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
+
+func TestULEBArray(t *testing.T) {
+	var buffer []byte
+	var expected []Value
+	for i := uint64(0); i < 1000; i++ {
+		value := Uint64Value(i * i * i * i * i * i)
+		if i%97 == 0 {
+			value = BigValue(new(big.Int).Lsh(big.NewInt(int64(i)+1), 100))
+		}
+		buffer = value.AppendTo(buffer)
+		expected = append(expected, value)
+	}
+
+	for _, interval := range []int{1, 3, 64, 2000} {
+		array, err := NewULEBArray(buffer, interval)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if array.Len() != len(expected) {
+			t.Errorf("Expected %v values but got %v", len(expected), array.Len())
+		}
+		for i, value := range expected {
+			if actual := array.Get(i); actual.Cmp(value) != 0 {
+				t.Errorf("Expected value %v to be %v but got %v (interval %v)", i, value, actual, interval)
+				return
+			}
+		}
+	}
+
+	if _, err := NewULEBArray([]byte{0x80}, 1); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
+
+func TestULEBArrayOutOfRange(t *testing.T) {
+	array, _ := NewULEBArray([]byte{0x01, 0x02}, 1)
+	for _, i := range []int{-1, 2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Get(%v) to panic", i)
+				}
+			}()
+			array.Get(i)
+		}()
+	}
+}
+
+func BenchmarkULEBArrayGet(b *testing.B) {
+	var buffer []byte
+	for i := uint64(0); i < 100000; i++ {
+		buffer = AppendUint64(buffer, i*31)
+	}
+	array, _ := NewULEBArray(buffer, DefaultIndexInterval)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		array.Get(i * 7919 % 100000)
+	}
+}
+
+func TestULEBArrayBuilder(t *testing.T) {
+	builder := NewULEBArrayBuilder(4)
+	var expected []Value
+	for i := uint64(0); i < 100; i++ {
+		if i%10 == 0 {
+			value := new(big.Int).Lsh(big.NewInt(int64(i)+1), 80)
+			builder.AppendBig(value)
+			expected = append(expected, BigValue(value))
+		} else {
+			builder.Append(i * 1000)
+			expected = append(expected, Uint64Value(i*1000))
+		}
+	}
+	if builder.Len() != 100 {
+		t.Errorf("Expected 100 values but got %v", builder.Len())
+	}
+	buffer, index := builder.Finalize()
+	if builder.Len() != 0 {
+		t.Errorf("Expected Finalize to reset the builder")
+	}
+
+	scanned, err := BuildIndex(buffer, 4)
+	if err != nil || !reflect.DeepEqual(index, scanned) {
+		t.Errorf("Expected the builder's index %+v to match a scanned index %+v (%v)", index, scanned, err)
+	}
+	array, err := NewULEBArrayWithIndex(buffer, index)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i, value := range expected {
+		if actual := array.Get(i); actual.Cmp(value) != 0 {
+			t.Errorf("Expected value %v to be %v but got %v", i, value, actual)
+		}
+	}
+
+	buffer, index = NewULEBArrayBuilder(0).Finalize()
+	if array, err = NewULEBArrayWithIndex(buffer, index); err != nil || array.Len() != 0 {
+		t.Errorf("Expected an empty array but got %v values (%v)", array.Len(), err)
+	}
+}
+
+func TestULEBArrayWithInvalidIndex(t *testing.T) {
+	buffer := []byte{0x01, 0xac, 0x02, 0x03, 0x04, 0x05}
+	for _, index := range []Index{
+		{Interval: 0, Offsets: []int{0}, Count: 5},
+		{Interval: 2, Offsets: []int{0, 3}, Count: 5},
+		{Interval: 2, Offsets: []int{1, 3, 5}, Count: 5},
+		{Interval: 2, Offsets: []int{-1, 3, 5}, Count: 5},
+		{Interval: 2, Offsets: []int{0, 2, 4}, Count: 5},
+		{Interval: 2, Offsets: []int{0, 4, 3}, Count: 5},
+		{Interval: 2, Offsets: []int{0, 3, 6}, Count: 5},
+		{Interval: 2, Offsets: []int{0, 3, 5}, Count: 6},
+		{Interval: 2, Offsets: []int{0, 3}, Count: 4},
+		{Interval: 2, Offsets: nil, Count: 0},
+	} {
+		if _, err := NewULEBArrayWithIndex(buffer, index); err != ErrInvalidIndex {
+			t.Errorf("Expected index %+v to fail with %v but got %v", index, ErrInvalidIndex, err)
+		}
+	}
+	if _, err := NewULEBArrayWithIndex(buffer, Index{Interval: 2, Offsets: []int{0, 3, 5}, Count: 5}); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewULEBArrayWithIndex([]byte{0x01, 0x80}, Index{Interval: 2, Offsets: []int{0}, Count: 2}); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// ErrEncoderClosed is returned when an AsyncEncoder is used after Close.
+var ErrEncoderClosed = errors.New("uleb128: encoder is closed")
+
+// DefaultAsyncQueueSize is the number of batches an AsyncEncoder queues by
+// default.
+const DefaultAsyncQueueSize = 16
+
+// AsyncEncoder encodes values into batches of about DefaultChunkSize bytes,
+// and hands them to a background goroutine to write, so that producers
+// aren't held up by a slow writer. At most queueSize batches wait to be
+// written at once; when the queue is full, the encode methods block until
+// there's room, which bounds memory use and slows producers down to the
+// writer's pace. A failed write is returned by the next encoder call, Flush,
+// or Close, and later batches are discarded.
+//
+// AsyncEncoder is not safe for concurrent use. Close must be called to stop
+// the background goroutine.
+type AsyncEncoder struct {
+	queue   chan asyncBatch
+	free    chan []byte
+	batch   []byte
+	stopped chan struct{}
+	closed  bool
+
+	mutex sync.Mutex
+	err   error
+}
+
+// A batch of encoded bytes to write, or (if ack isn't nil) a request to be
+// told when everything queued before it has been written.
+type asyncBatch struct {
+	buffer []byte
+	ack    chan struct{}
+}
+
+// NewAsyncEncoder returns an AsyncEncoder that writes to writer, queueing at
+// most queueSize batches (DefaultAsyncQueueSize if less than 1).
+func NewAsyncEncoder(writer io.Writer, queueSize int) *AsyncEncoder {
+	if queueSize < 1 {
+		queueSize = DefaultAsyncQueueSize
+	}
+	e := &AsyncEncoder{
+		queue:   make(chan asyncBatch, queueSize),
+		free:    make(chan []byte, queueSize+1),
+		batch:   make([]byte, 0, DefaultChunkSize+MaxBufferWriteBytes),
+		stopped: make(chan struct{}),
+	}
+	go e.run(writer)
+	return e
+}
+
+// EncodeUint64 queues the encoding of a uint64 value, returning the number of
+// bytes encoded.
+func (e *AsyncEncoder) EncodeUint64(value uint64) (byteCount int, err error) {
+	if err = e.check(); err != nil {
+		return
+	}
+	start := len(e.batch)
+	e.batch = AppendUint64(e.batch, value)
+	byteCount = len(e.batch) - start
+	e.sendIfFull()
+	return
+}
+
+// Encode queues the encoding of a math.big.Int value (the sign of the value
+// will be ignored), returning the number of bytes encoded.
+func (e *AsyncEncoder) Encode(value *big.Int) (byteCount int, err error) {
+	if err = e.check(); err != nil {
+		return
+	}
+	start := len(e.batch)
+	e.batch = Append(e.batch, value)
+	byteCount = len(e.batch) - start
+	e.sendIfFull()
+	return
+}
+
+// Flush queues any partial batch and waits until everything queued so far
+// has been written, returning the first write error.
+func (e *AsyncEncoder) Flush() error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	e.send()
+	ack := make(chan struct{})
+	e.queue <- asyncBatch{ack: ack}
+	<-ack
+	return e.writeErr()
+}
+
+// Close flushes the encoder and stops its background goroutine. It doesn't
+// close the writer.
+func (e *AsyncEncoder) Close() (err error) {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	err = e.Flush()
+	e.closed = true
+	close(e.queue)
+	<-e.stopped
+	return
+}
+
+func (e *AsyncEncoder) check() error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	return e.writeErr()
+}
+
+func (e *AsyncEncoder) writeErr() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.err
+}
+
+func (e *AsyncEncoder) sendIfFull() {
+	if len(e.batch) >= DefaultChunkSize {
+		e.send()
+	}
+}
+
+// Queue the current batch, and start a new one in a recycled buffer if one
+// is available.
+func (e *AsyncEncoder) send() {
+	if len(e.batch) == 0 {
+		return
+	}
+	e.queue <- asyncBatch{buffer: e.batch}
+	select {
+	case e.batch = <-e.free:
+	default:
+		e.batch = make([]byte, 0, DefaultChunkSize+MaxBufferWriteBytes)
+	}
+}
+
+// Write out batches until the queue is closed. After a failed write, later
+// batches are discarded.
+func (e *AsyncEncoder) run(writer io.Writer) {
+	defer close(e.stopped)
+	var err error
+	for batch := range e.queue {
+		if batch.ack != nil {
+			close(batch.ack)
+			continue
+		}
+		if err == nil {
+			if _, err = writer.Write(batch.buffer); err != nil {
+				e.mutex.Lock()
+				e.err = err
+				e.mutex.Unlock()
+			}
+		}
+		select {
+		case e.free <- batch.buffer[:0]:
+		default:
+		}
+	}
+}
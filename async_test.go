@@ -0,0 +1,123 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsyncEncoder(t *testing.T) {
+	buff := &bytes.Buffer{}
+	e := NewAsyncEncoder(buff, 2)
+	var expected []byte
+	for i := uint64(0); i < 10000; i++ {
+		if _, err := e.EncodeUint64(i * 1000); err != nil {
+			t.Fatal(err)
+		}
+		expected = AppendUint64(expected, i*1000)
+	}
+	big1 := new(big.Int).Lsh(big.NewInt(1), 100)
+	if byteCount, err := e.Encode(big1); err != nil || byteCount != EncodedSize(big1) {
+		t.Errorf("Expected %v bytes but got %v (%v)", EncodedSize(big1), byteCount, err)
+	}
+	expected = Append(expected, big1)
+	if err := e.Flush(); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected the flushed output to match")
+	}
+	e.EncodeUint64(5)
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+	if expected = append(expected, 5); !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected Close to flush the last value")
+	}
+	if _, err := e.EncodeUint64(1); err != ErrEncoderClosed {
+		t.Errorf("Expected ErrEncoderClosed but got %v", err)
+	}
+	if err := e.Close(); err != ErrEncoderClosed {
+		t.Errorf("Expected ErrEncoderClosed but got %v", err)
+	}
+}
+
+type gatedWriter struct {
+	gate chan struct{}
+	bytes.Buffer
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	return w.Buffer.Write(p)
+}
+
+func TestAsyncEncoderBackpressure(t *testing.T) {
+	writer := &gatedWriter{gate: make(chan struct{})}
+	queueSize := 2
+	e := NewAsyncEncoder(writer, queueSize)
+	var encoded int64
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100000; i++ {
+			e.EncodeUint64(1000)
+			atomic.AddInt64(&encoded, 2)
+		}
+	}()
+
+	// While the writer is stuck, the producer can only get as far as the
+	// queue, the batch being written, and the batch being filled allow.
+	time.Sleep(50 * time.Millisecond)
+	limit := int64((queueSize + 2) * (DefaultChunkSize + MaxBufferWriteBytes))
+	if progress := atomic.LoadInt64(&encoded); progress > limit {
+		t.Errorf("Expected the producer to be held to %v bytes but it encoded %v", limit, progress)
+	}
+
+	close(writer.gate)
+	wg.Wait()
+	if err := e.Close(); err != nil {
+		t.Error(err)
+	}
+	if writer.Len() != 200000 {
+		t.Errorf("Expected 200000 bytes but got %v", writer.Len())
+	}
+}
+
+func TestAsyncEncoderWriteError(t *testing.T) {
+	e := NewAsyncEncoder(failingWriter{}, 0)
+	e.EncodeUint64(1)
+	if err := e.Flush(); err != errWriteFailed {
+		t.Errorf("Expected %v but got %v", errWriteFailed, err)
+	}
+	if _, err := e.EncodeUint64(2); err != errWriteFailed {
+		t.Errorf("Expected %v but got %v", errWriteFailed, err)
+	}
+	if err := e.Close(); err != errWriteFailed {
+		t.Errorf("Expected %v but got %v", errWriteFailed, err)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// ErrBitmapTooLarge is returned when an encoded bitmap has more words than
+// the caller allows.
+var ErrBitmapTooLarge = errors.New("uleb128: bitmap exceeds maximum size")
+
+// ErrMalformedBitmap is returned when an encoded bitmap refers to bits
+// outside of its declared size.
+var ErrMalformedBitmap = errors.New("uleb128: malformed bitmap")
+
+// A bitmap is encoded as a header of (wordCount << 1 | mode). In dense mode,
+// the header is followed by wordCount words. In sparse mode, it is followed
+// by the number of set bits, and then the position of each set bit, stored as
+// its distance from the position just after the previous set bit. The encoder
+// picks whichever mode produces the smaller output.
+const (
+	bitmapDense  = 0
+	bitmapSparse = 1
+)
+
+// EncodedSizeBitmap returns the number of bytes required to encode this bitmap.
+func EncodedSizeBitmap(bitmap []uint64) int {
+	mode, payloadSize := chooseBitmapMode(bitmap)
+	return EncodedSizeUint64(uint64(len(bitmap))<<1|mode) + payloadSize
+}
+
+// Encode a bitmap, using sparse mode if the bitmap has few enough bits set.
+func EncodeBitmap(bitmap []uint64, writer io.Writer) (byteCount int, err error) {
+	mode, payloadSize := chooseBitmapMode(bitmap)
+	buffer := make([]byte, 0, MaxBufferWriteBytes+payloadSize)
+	buffer = AppendUint64(buffer, uint64(len(bitmap))<<1|mode)
+	if mode == bitmapDense {
+		for _, word := range bitmap {
+			buffer = AppendUint64(buffer, word)
+		}
+	} else {
+		buffer = AppendUint64(buffer, uint64(countSetBits(bitmap)))
+		next := uint64(0)
+		forEachSetBit(bitmap, func(position uint64) {
+			buffer = AppendUint64(buffer, position-next)
+			next = position + 1
+		})
+	}
+	return writer.Write(buffer)
+}
+
+// Decode a bitmap, refusing to allocate more than maxWords words.
+func DecodeBitmap(reader io.Reader, maxWords int) (bitmap []uint64, byteCount int, err error) {
+	buffer := []byte{0}
+	header, byteCount, err := decodeUint64(reader, buffer)
+	if err != nil {
+		return
+	}
+	wordCount := header >> 1
+	if wordCount > uint64(maxWords) {
+		err = ErrBitmapTooLarge
+		return
+	}
+
+	decodeField := func() (value uint64) {
+		var fieldByteCount int
+		value, fieldByteCount, err = decodeUint64(reader, buffer)
+		byteCount += fieldByteCount
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	bitmap = make([]uint64, wordCount)
+	if header&1 == bitmapDense {
+		for i := range bitmap {
+			if bitmap[i] = decodeField(); err != nil {
+				bitmap = nil
+				return
+			}
+		}
+		return
+	}
+
+	bitCount := wordCount * 64
+	setBitCount := decodeField()
+	if err == nil && setBitCount > bitCount {
+		err = ErrMalformedBitmap
+	}
+	next := uint64(0)
+	for i := uint64(0); i < setBitCount && err == nil; i++ {
+		delta := decodeField()
+		if err != nil {
+			break
+		}
+		if delta >= bitCount-next {
+			err = ErrMalformedBitmap
+			break
+		}
+		position := next + delta
+		bitmap[position/64] |= 1 << (position % 64)
+		next = position + 1
+	}
+	if err != nil {
+		bitmap = nil
+	}
+	return
+}
+
+func chooseBitmapMode(bitmap []uint64) (mode uint64, payloadSize int) {
+	denseSize := 0
+	for _, word := range bitmap {
+		denseSize += EncodedSizeUint64(word)
+	}
+
+	sparseSize := EncodedSizeUint64(uint64(countSetBits(bitmap)))
+	next := uint64(0)
+	forEachSetBit(bitmap, func(position uint64) {
+		sparseSize += EncodedSizeUint64(position - next)
+		next = position + 1
+	})
+
+	if sparseSize < denseSize {
+		return bitmapSparse, sparseSize
+	}
+	return bitmapDense, denseSize
+}
+
+func countSetBits(bitmap []uint64) (count int) {
+	for _, word := range bitmap {
+		count += bits.OnesCount64(word)
+	}
+	return
+}
+
+func forEachSetBit(bitmap []uint64, onBit func(position uint64)) {
+	for i, word := range bitmap {
+		for word != 0 {
+			onBit(uint64(i)*64 + uint64(bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+}
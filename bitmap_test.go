@@ -0,0 +1,113 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertBitmap(t *testing.T, bitmap []uint64, expectedBytes ...byte) {
+	expectedByteCount := EncodedSizeBitmap(bitmap)
+	buff := &bytes.Buffer{}
+	actualByteCount, err := EncodeBitmap(bitmap, buff)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("Expected %v to encode to a byte count of %v but got %v", describe.D(bitmap), expectedByteCount, actualByteCount)
+		return
+	}
+	if !reflect.DeepEqual(buff.Bytes(), expectedBytes) {
+		t.Errorf("Expected %v to encode to %v but got %v", describe.D(bitmap), describe.D(expectedBytes), describe.D(buff.Bytes()))
+		return
+	}
+
+	actualBitmap, actualByteCount, err := DecodeBitmap(buff, len(bitmap))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("Expected decoding %v to have a byte count of %v but got %v", describe.D(expectedBytes), expectedByteCount, actualByteCount)
+		return
+	}
+	if !reflect.DeepEqual(actualBitmap, bitmap) {
+		t.Errorf("Expected %v to decode to %v but got %v", describe.D(expectedBytes), describe.D(bitmap), describe.D(actualBitmap))
+	}
+}
+
+func assertDecodeBitmapFails(t *testing.T, maxWords int, expectedErr error, b ...byte) {
+	bitmap, _, err := DecodeBitmap(bytes.NewBuffer(b), maxWords)
+	if err != expectedErr {
+		t.Errorf("Expected decoding %v to fail with %v but got %v (%v)", describe.D(b), expectedErr, err, describe.D(bitmap))
+	}
+}
+
+func TestBitmapDense(t *testing.T) {
+	assertBitmap(t, []uint64{}, 0x00)
+	assertBitmap(t, []uint64{0x0f}, 0x02, 0x0f)
+	assertBitmap(t, []uint64{0}, 0x02, 0x00)
+	assertBitmap(t, []uint64{0x7f, 0x01}, 0x04, 0x7f, 0x01)
+	assertBitmap(t, []uint64{0, 0, 0x11}, 0x06, 0x00, 0x00, 0x11)
+}
+
+func TestBitmapSparse(t *testing.T) {
+	assertBitmap(t, []uint64{0x8000000000000000}, 0x03, 0x01, 0x3f)
+	assertBitmap(t, []uint64{0, 0, 0, 0x100}, 0x09, 0x01, 0xc8, 0x01)
+	assertBitmap(t, []uint64{0, 0, 0, 0x8000000000000000, 0x8000000000000000}, 0x0b, 0x02, 0xff, 0x01, 0x3f)
+}
+
+func TestBitmapRoundTrip(t *testing.T) {
+	bitmap := make([]uint64, 100)
+	for i := range bitmap {
+		bitmap[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+	buff := &bytes.Buffer{}
+	if _, err := EncodeBitmap(bitmap, buff); err != nil {
+		t.Error(err)
+		return
+	}
+	actual, _, err := DecodeBitmap(buff, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(actual, bitmap) {
+		t.Errorf("Bitmap did not survive a round trip")
+	}
+}
+
+func TestBitmapErrors(t *testing.T) {
+	assertDecodeBitmapFails(t, 1, io.EOF)
+	assertDecodeBitmapFails(t, 1, ErrBitmapTooLarge, 0x04, 0x01, 0x01)
+	assertDecodeBitmapFails(t, 2, io.ErrUnexpectedEOF, 0x04, 0x01)
+	assertDecodeBitmapFails(t, 2, io.ErrUnexpectedEOF, 0x04, 0x01, 0x80)
+	assertDecodeBitmapFails(t, 1, ErrMalformedBitmap, 0x03, 0x41)
+	assertDecodeBitmapFails(t, 1, ErrMalformedBitmap, 0x03, 0x01, 0x40)
+	assertDecodeBitmapFails(t, 1, ErrMalformedBitmap, 0x03, 0x02, 0x3f, 0x00)
+	assertDecodeBitmapFails(t, 1, ErrOverflow, 0x02, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02)
+}
@@ -0,0 +1,249 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidBitCount is returned when a bit count or VBR chunk width is out
+// of range.
+var ErrInvalidBitCount = errors.New("uleb128: invalid bit count")
+
+// BitOrder determines how bits are packed into bytes in a bitstream.
+type BitOrder int
+
+const (
+	// Fill each byte starting from its least significant bit, and write
+	// multi-bit values least significant bit first (as in DEFLATE or LLVM
+	// bitcode). In this order, VBR chunks of width 8 are identical to
+	// byte-aligned ULEB128.
+	LSBFirst BitOrder = iota
+	// Fill each byte starting from its most significant bit, and write
+	// multi-bit values most significant bit first (as in H.264).
+	MSBFirst
+)
+
+const bitWriterFlushSize = 256
+
+// BitWriter writes values into a bitstream without any byte alignment.
+// Bytes are buffered internally, so Flush must be called when done.
+type BitWriter struct {
+	writer   io.Writer
+	order    BitOrder
+	buffer   []byte
+	current  byte
+	bitCount uint
+}
+
+// NewBitWriter returns a BitWriter that writes to writer in the given order.
+func NewBitWriter(writer io.Writer, order BitOrder) *BitWriter {
+	return &BitWriter{
+		writer: writer,
+		order:  order,
+		buffer: make([]byte, 0, bitWriterFlushSize),
+	}
+}
+
+// WriteBits writes the low bitCount bits of value (0-64 bits).
+func (w *BitWriter) WriteBits(value uint64, bitCount int) (err error) {
+	if bitCount < 0 || bitCount > 64 {
+		return ErrInvalidBitCount
+	}
+	remaining := uint(bitCount)
+	for remaining > 0 {
+		free := 8 - w.bitCount
+		n := minUint(free, remaining)
+		if w.order == LSBFirst {
+			w.current |= byte(value&maskForBitCount(int(n))) << w.bitCount
+			value >>= n
+		} else {
+			w.current |= byte((value>>(remaining-n))&maskForBitCount(int(n))) << (free - n)
+		}
+		w.bitCount += n
+		remaining -= n
+		if w.bitCount == 8 {
+			w.buffer = append(w.buffer, w.current)
+			w.current = 0
+			w.bitCount = 0
+		}
+	}
+	if len(w.buffer) >= bitWriterFlushSize {
+		err = w.flushBuffer()
+	}
+	return
+}
+
+// WriteVBR writes value as a variable bit rate integer: a sequence of chunks
+// of chunkWidth bits, each holding chunkWidth-1 bits of payload (least
+// significant group first) and a continuation flag as its highest bit.
+func (w *BitWriter) WriteVBR(value uint64, chunkWidth int) (err error) {
+	if chunkWidth < 2 || chunkWidth > 64 {
+		return ErrInvalidBitCount
+	}
+	payloadBits := uint(chunkWidth - 1)
+	payloadMask := maskForBitCount(int(payloadBits))
+	continuation := uint64(1) << payloadBits
+	for value > payloadMask {
+		if err = w.WriteBits(value&payloadMask|continuation, chunkWidth); err != nil {
+			return
+		}
+		value >>= payloadBits
+	}
+	return w.WriteBits(value, chunkWidth)
+}
+
+// WriteUint64 writes value as ULEB128 7-bit groups, without regard to byte
+// alignment.
+func (w *BitWriter) WriteUint64(value uint64) error {
+	return w.WriteVBR(value, 8)
+}
+
+// Align pads the bitstream with zero bits up to the next byte boundary.
+func (w *BitWriter) Align() error {
+	if w.bitCount == 0 {
+		return nil
+	}
+	return w.WriteBits(0, int(8-w.bitCount))
+}
+
+// Flush aligns the bitstream and writes out all buffered bytes.
+func (w *BitWriter) Flush() (err error) {
+	if err = w.Align(); err != nil {
+		return
+	}
+	return w.flushBuffer()
+}
+
+func (w *BitWriter) flushBuffer() (err error) {
+	if len(w.buffer) > 0 {
+		_, err = w.writer.Write(w.buffer)
+		w.buffer = w.buffer[:0]
+	}
+	return
+}
+
+// BitReader reads values from a bitstream produced by a BitWriter.
+type BitReader struct {
+	reader    io.Reader
+	order     BitOrder
+	buffer    []byte
+	available uint
+}
+
+// NewBitReader returns a BitReader that reads from reader in the given order.
+func NewBitReader(reader io.Reader, order BitOrder) *BitReader {
+	return &BitReader{
+		reader: reader,
+		order:  order,
+		buffer: []byte{0},
+	}
+}
+
+// ReadBits reads bitCount bits (0-64 bits). Returns io.EOF if the stream
+// ended before any bits were read, or io.ErrUnexpectedEOF if it ended
+// partway through.
+func (r *BitReader) ReadBits(bitCount int) (value uint64, err error) {
+	if bitCount < 0 || bitCount > 64 {
+		err = ErrInvalidBitCount
+		return
+	}
+	remaining := uint(bitCount)
+	shift := uint(0)
+	for remaining > 0 {
+		if r.available == 0 {
+			if _, err = io.ReadFull(r.reader, r.buffer); err != nil {
+				if remaining != uint(bitCount) {
+					err = unexpectedEOF(err)
+				}
+				return
+			}
+			r.available = 8
+		}
+		n := minUint(r.available, remaining)
+		if r.order == LSBFirst {
+			chunk := uint64(r.buffer[0]>>(8-r.available)) & maskForBitCount(int(n))
+			value |= chunk << shift
+			shift += n
+		} else {
+			chunk := uint64(r.buffer[0]>>(r.available-n)) & maskForBitCount(int(n))
+			value = value<<n | chunk
+		}
+		r.available -= n
+		remaining -= n
+	}
+	return
+}
+
+// ReadVBR reads a variable bit rate integer written by WriteVBR with the same
+// chunk width. Returns ErrOverflow if the value doesn't fit into a uint64, or
+// if it continues past the ceil(64 / (chunkWidth-1)) chunks that a uint64 can
+// need.
+func (r *BitReader) ReadVBR(chunkWidth int) (value uint64, err error) {
+	if chunkWidth < 2 || chunkWidth > 64 {
+		err = ErrInvalidBitCount
+		return
+	}
+	payloadBits := uint(chunkWidth - 1)
+	payloadMask := maskForBitCount(int(payloadBits))
+	continuation := uint64(1) << payloadBits
+	maxChunks := (64 + int(payloadBits) - 1) / int(payloadBits)
+	shift := uint(0)
+	for chunkIndex := 0; chunkIndex < maxChunks; chunkIndex++ {
+		var chunk uint64
+		if chunk, err = r.ReadBits(chunkWidth); err != nil {
+			if chunkIndex > 0 {
+				err = unexpectedEOF(err)
+			}
+			return
+		}
+		payload := chunk & payloadMask
+		if payload>>(64-shift) != 0 {
+			err = ErrOverflow
+			return
+		}
+		value |= payload << shift
+		if chunk&continuation == 0 {
+			return
+		}
+		shift += payloadBits
+	}
+	err = ErrOverflow
+	return
+}
+
+// ReadUint64 reads a value written by WriteUint64.
+func (r *BitReader) ReadUint64() (uint64, error) {
+	return r.ReadVBR(8)
+}
+
+// Align discards any remaining bits in the current byte.
+func (r *BitReader) Align() {
+	r.available = 0
+}
+
+func minUint(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+type bitField struct {
+	value    uint64
+	bitCount int
+}
+
+func assertBits(t *testing.T, order BitOrder, fields []bitField, expectedBytes ...byte) {
+	buff := &bytes.Buffer{}
+	writer := NewBitWriter(buff, order)
+	for _, field := range fields {
+		if err := writer.WriteBits(field.value, field.bitCount); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(buff.Bytes(), expectedBytes) {
+		t.Errorf("Expected %v to encode to %v but got %v", describe.D(fields), describe.D(expectedBytes), describe.D(buff.Bytes()))
+		return
+	}
+
+	reader := NewBitReader(buff, order)
+	for _, field := range fields {
+		value, err := reader.ReadBits(field.bitCount)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if value != field.value {
+			t.Errorf("Expected to read %x (%v bits) but got %x", field.value, field.bitCount, value)
+			return
+		}
+	}
+}
+
+func TestBitsLSBFirst(t *testing.T) {
+	assertBits(t, LSBFirst, nil)
+	assertBits(t, LSBFirst, []bitField{{1, 1}}, 0x01)
+	assertBits(t, LSBFirst, []bitField{{1, 1}, {0, 1}, {3, 2}}, 0x0d)
+	assertBits(t, LSBFirst, []bitField{{0x5, 3}, {0x1ff, 9}}, 0xfd, 0x0f)
+	assertBits(t, LSBFirst, []bitField{{1, 1}, {0xffffffffffffffff, 64}},
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)
+}
+
+func TestBitsMSBFirst(t *testing.T) {
+	assertBits(t, MSBFirst, []bitField{{1, 1}}, 0x80)
+	assertBits(t, MSBFirst, []bitField{{1, 1}, {0, 1}, {3, 2}}, 0xb0)
+	assertBits(t, MSBFirst, []bitField{{0x5, 3}, {0x1ff, 9}}, 0xbf, 0xf0)
+	assertBits(t, MSBFirst, []bitField{{1, 1}, {0xffffffffffffffff, 64}},
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x80)
+}
+
+func TestBitsErrors(t *testing.T) {
+	writer := NewBitWriter(&bytes.Buffer{}, LSBFirst)
+	if err := writer.WriteBits(0, 65); err != ErrInvalidBitCount {
+		t.Errorf("Expected ErrInvalidBitCount but got %v", err)
+	}
+	if err := writer.WriteVBR(0, 1); err != ErrInvalidBitCount {
+		t.Errorf("Expected ErrInvalidBitCount but got %v", err)
+	}
+
+	reader := NewBitReader(bytes.NewBuffer([]byte{0xff}), LSBFirst)
+	if _, err := reader.ReadBits(4); err != nil {
+		t.Error(err)
+	}
+	if _, err := reader.ReadBits(8); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF but got %v", err)
+	}
+	if _, err := reader.ReadBits(1); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
+
+func TestBitsUint64MatchesByteAligned(t *testing.T) {
+	values := []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff}
+	expected := &bytes.Buffer{}
+	actual := &bytes.Buffer{}
+	writer := NewBitWriter(actual, LSBFirst)
+	for _, value := range values {
+		EncodeUint64(value, expected)
+		if err := writer.WriteUint64(value); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(actual.Bytes(), expected.Bytes()) {
+		t.Errorf("Expected %v but got %v", describe.D(expected.Bytes()), describe.D(actual.Bytes()))
+	}
+}
+
+func TestBitsUnaligned(t *testing.T) {
+	values := []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff}
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		for _, chunkWidth := range []int{2, 5, 8, 13, 64} {
+			buff := &bytes.Buffer{}
+			writer := NewBitWriter(buff, order)
+			for _, value := range values {
+				writer.WriteBits(1, 3)
+				if err := writer.WriteVBR(value, chunkWidth); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				t.Error(err)
+				return
+			}
+
+			reader := NewBitReader(buff, order)
+			for _, expected := range values {
+				reader.ReadBits(3)
+				actual, err := reader.ReadVBR(chunkWidth)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if actual != expected {
+					t.Errorf("Order %v, width %v: Expected %x but got %x", order, chunkWidth, expected, actual)
+				}
+			}
+		}
+	}
+}
+
+func TestBitsVBROverflow(t *testing.T) {
+	reader := NewBitReader(bytes.NewBuffer([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}), LSBFirst)
+	if _, err := reader.ReadUint64(); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+}
+
+func TestBitsVBREndless(t *testing.T) {
+	for _, chunkWidth := range []int{2, 8, 33, 64} {
+		// Zero payloads with the continuation bit set, for twice as many
+		// chunks as a uint64 can need.
+		maxChunks := (64 + chunkWidth - 2) / (chunkWidth - 1)
+		buff := &bytes.Buffer{}
+		writer := NewBitWriter(buff, LSBFirst)
+		for i := 0; i < maxChunks*2; i++ {
+			writer.WriteBits(uint64(1)<<uint(chunkWidth-1), chunkWidth)
+		}
+		writer.Flush()
+		reader := NewBitReader(buff, LSBFirst)
+		if _, err := reader.ReadVBR(chunkWidth); err != ErrOverflow {
+			t.Errorf("Chunk width %v: Expected ErrOverflow but got %v", chunkWidth, err)
+		}
+	}
+}
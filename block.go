@@ -0,0 +1,66 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+)
+
+// WriteBlock writes a count-prefixed block: the number of values as a
+// ULEB128, followed by each value as a ULEB128. This is the "vector" layout
+// used by WASM and many other formats.
+func WriteBlock(writer io.Writer, values []uint64) (byteCount int, err error) {
+	size := EncodedSizeUint64(uint64(len(values)))
+	for _, v := range values {
+		size += EncodedSizeUint64(v)
+	}
+	buffer := make([]byte, 0, size)
+	buffer = AppendUint64(buffer, uint64(len(values)))
+	for _, v := range values {
+		buffer = AppendUint64(buffer, v)
+	}
+	return writer.Write(buffer)
+}
+
+// ReadBlock reads a count-prefixed block written by WriteBlock. The count is
+// validated with CheckLength against maxCount before anything is allocated,
+// so a hostile count returns a *LengthError rather than exhausting memory.
+// A value too large for a uint64 returns ErrOverflow, and a stream that ends
+// partway through the block returns io.ErrUnexpectedEOF.
+func ReadBlock(reader io.Reader, maxCount int) (values []uint64, byteCount int, err error) {
+	count, byteCount, err := DecodeLength(reader, maxCount, 8)
+	if err != nil {
+		return
+	}
+	values = make([]uint64, count)
+	buffer := make([]byte, 0, MaxBufferWriteBytes)
+	for i := range values {
+		var valueByteCount int
+		values[i], valueByteCount, err = decodeUint64(reader, buffer)
+		byteCount += valueByteCount
+		if err != nil {
+			values = nil
+			err = unexpectedEOF(err)
+			return
+		}
+	}
+	return
+}
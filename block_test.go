@@ -0,0 +1,106 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestBlock(t *testing.T) {
+	values := []uint64{0, 1, 127, 128, 300, 0xffffffffffffffff}
+	buff := &bytes.Buffer{}
+	byteCount, err := WriteBlock(buff, values)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if byteCount != buff.Len() {
+		t.Errorf("Expected byte count %v but got %v", buff.Len(), byteCount)
+	}
+	if buff.Bytes()[0] != byte(len(values)) {
+		t.Errorf("Expected count prefix %v but got %v", len(values), buff.Bytes()[0])
+	}
+
+	decoded, decodedByteCount, err := ReadBlock(buff, 10)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decodedByteCount != byteCount {
+		t.Errorf("Expected to read %v bytes but read %v", byteCount, decodedByteCount)
+	}
+	if describe.D(decoded) != describe.D(values) {
+		t.Errorf("Expected %v but got %v", describe.D(values), describe.D(decoded))
+	}
+}
+
+func TestBlockEmpty(t *testing.T) {
+	buff := &bytes.Buffer{}
+	WriteBlock(buff, nil)
+	if !bytes.Equal(buff.Bytes(), []byte{0x00}) {
+		t.Errorf("Expected [00] but got %v", describe.D(buff.Bytes()))
+	}
+	decoded, _, err := ReadBlock(buff, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(decoded) != 0 {
+		t.Errorf("Expected no values but got %v", describe.D(decoded))
+	}
+}
+
+func TestBlockCountTooLarge(t *testing.T) {
+	buff := &bytes.Buffer{}
+	WriteBlock(buff, []uint64{1, 2, 3})
+	_, _, err := ReadBlock(buff, 2)
+	if _, ok := err.(*LengthError); !ok {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+
+	// A huge count must be rejected without allocating.
+	_, _, err = ReadBlock(bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff, 0x0f}), 1000)
+	if _, ok := err.(*LengthError); !ok {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+}
+
+func TestBlockTruncated(t *testing.T) {
+	_, _, err := ReadBlock(bytes.NewBuffer([]byte{0x03, 0x01, 0x02}), 10)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+	_, _, err = ReadBlock(bytes.NewBuffer([]byte{0x02, 0x01, 0x80}), 10)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestBlockOverflow(t *testing.T) {
+	data := []byte{0x01, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+	_, _, err := ReadBlock(bytes.NewBuffer(data), 10)
+	if err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// If reader is a *bytes.Reader or a *bufio.Reader that already holds a
+// complete value of at most maxByteCount bytes (MaxBufferWriteBytes if 0 or
+// larger), consume the value and return its bytes. Otherwise return nil
+// without consuming anything, leaving the caller to read byte by byte.
+// buffer must have a capacity of at least MaxBufferWriteBytes. The returned
+// bytes may point into the reader's own buffer, so they are only valid
+// until the reader is next used.
+func readBufferedValue(reader io.Reader, buffer []byte, maxByteCount int) (encoded []byte) {
+	if maxByteCount <= 0 || maxByteCount > MaxBufferWriteBytes {
+		maxByteCount = MaxBufferWriteBytes
+	}
+	switch r := reader.(type) {
+	case *bytes.Reader:
+		if r.Len() == 0 {
+			return
+		}
+		n, _ := r.ReadAt(buffer[:maxByteCount], r.Size()-int64(r.Len()))
+		if encoded = completeValue(buffer[:n]); encoded != nil {
+			r.Seek(int64(len(encoded)), io.SeekCurrent)
+		}
+	case *bufio.Reader:
+		n := r.Buffered()
+		if n > maxByteCount {
+			n = maxByteCount
+		}
+		peeked, _ := r.Peek(n)
+		if encoded = completeValue(peeked); encoded != nil {
+			r.Discard(len(encoded))
+		}
+	}
+	return
+}
+
+// Return the first complete value in buffer, or nil if buffer ends partway
+// through it.
+func completeValue(buffer []byte) []byte {
+	for i, b := range buffer {
+		if b&continuationMask == 0 {
+			return buffer[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"testing"
+)
+
+func bufferedTestReaders(data []byte) map[string]io.Reader {
+	return map[string]io.Reader{
+		"bytes.Reader": bytes.NewReader(data),
+		"bufio.Reader": bufio.NewReaderSize(bytes.NewReader(data), 16),
+		"plain":        ioutil.NopCloser(bytes.NewReader(data)),
+	}
+}
+
+func TestDecodeBufferedReaders(t *testing.T) {
+	big1 := new(big.Int).Lsh(big.NewInt(1), 100)
+	data := AppendUint64(nil, 0)
+	data = AppendUint64(data, 300)
+	data = AppendUint64(data, 0xffffffffffffffff)
+	data = Append(data, big1)
+	data = AppendUint64(data, 5)
+	data = append(data, 0x80)
+
+	for name, reader := range bufferedTestReaders(data) {
+		for _, expected := range []uint64{0, 300, 0xffffffffffffffff} {
+			asUint, asBigInt, _, err := Decode(reader)
+			if err != nil || asBigInt != nil || asUint != expected {
+				t.Errorf("%v: Expected %v but got %v %v (%v)", name, expected, asUint, asBigInt, err)
+			}
+		}
+		if _, asBigInt, byteCount, err := Decode(reader); err != nil || asBigInt == nil || asBigInt.Cmp(big1) != 0 || byteCount != 15 {
+			t.Errorf("%v: Expected %v in 15 bytes but got %v in %v (%v)", name, big1, asBigInt, byteCount, err)
+		}
+		if asUint, _, byteCount, err := Decode(reader); err != nil || asUint != 5 || byteCount != 1 {
+			t.Errorf("%v: Expected 5 but got %v (%v)", name, asUint, err)
+		}
+		if _, _, byteCount, err := Decode(reader); err == nil || byteCount != 1 {
+			t.Errorf("%v: Expected a truncated value but got %v bytes (%v)", name, byteCount, err)
+		}
+	}
+}
+
+func TestDecoderBufferedReaders(t *testing.T) {
+	data := []byte{0xac, 0x02, 0x81, 0x80, 0x80, 0x00, 0x07}
+	for name, reader := range bufferedTestReaders(data) {
+		decoder := NewDecoder(reader, DecoderOptions{MaxByteCount: 3})
+		if value, byteCount, err := decoder.DecodeUint64(); err != nil || value != 300 || byteCount != 2 {
+			t.Errorf("%v: Expected 300 but got %v (%v)", name, value, err)
+		}
+		if _, byteCount, err := decoder.DecodeUint64(); err != ErrTooLong || byteCount != 3 {
+			t.Errorf("%v: Expected ErrTooLong after 3 bytes but got %v bytes (%v)", name, byteCount, err)
+		}
+		if value, _, err := decoder.DecodeUint64(); err != nil || value != 0 {
+			t.Errorf("%v: Expected 0 but got %v (%v)", name, value, err)
+		}
+		if value, _, err := decoder.DecodeUint64(); err != nil || value != 7 {
+			t.Errorf("%v: Expected 7 but got %v (%v)", name, value, err)
+		}
+		if _, _, err := decoder.DecodeUint64(); err != io.EOF {
+			t.Errorf("%v: Expected io.EOF but got %v", name, err)
+		}
+	}
+}
+
+func BenchmarkDecoderBufioReader(b *testing.B) {
+	var data []byte
+	for i := uint64(0); i < 1000; i++ {
+		data = AppendUint64(data, i*i)
+	}
+	source := bytes.NewReader(data)
+	reader := bufio.NewReader(source)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		source.Reset(data)
+		reader.Reset(source)
+		decoder := NewDecoder(reader, DecoderOptions{})
+		for {
+			if _, _, err := decoder.DecodeUint64(); err != nil {
+				break
+			}
+		}
+	}
+}
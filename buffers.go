@@ -0,0 +1,119 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/big"
+	"net"
+)
+
+// BuffersEncoder builds a net.Buffers out of encoded values and payload
+// slices, so that a message interleaving varints with large payloads can be
+// written with a single writev (on connections that support it) and
+// without copying the payloads. Runs of consecutive values are encoded into
+// one internal segment, and each payload becomes a segment of its own.
+type BuffersEncoder struct {
+	buffers   net.Buffers
+	scratch   []byte
+	runStart  int
+	byteCount int
+}
+
+// NewBuffersEncoder returns an empty BuffersEncoder.
+func NewBuffersEncoder() *BuffersEncoder {
+	return &BuffersEncoder{}
+}
+
+// EncodeUint64 encodes a uint64 value.
+func (e *BuffersEncoder) EncodeUint64(value uint64) {
+	start := len(e.scratch)
+	e.scratch = AppendUint64(e.scratch, value)
+	e.byteCount += len(e.scratch) - start
+}
+
+// Encode encodes a math.big.Int value (the sign of the value will be
+// ignored).
+func (e *BuffersEncoder) Encode(value *big.Int) {
+	start := len(e.scratch)
+	e.scratch = Append(e.scratch, value)
+	e.byteCount += len(e.scratch) - start
+}
+
+// AddPayload adds payload as-is, without a length prefix. The slice is
+// referenced rather than copied, so it must not be modified until the
+// buffers have been written.
+func (e *BuffersEncoder) AddPayload(payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+	e.endRun()
+	e.buffers = append(e.buffers, payload)
+	e.byteCount += len(payload)
+}
+
+// EncodeBytes encodes a ULEB128 length followed by payload, which is
+// referenced as in AddPayload.
+func (e *BuffersEncoder) EncodeBytes(payload []byte) {
+	e.EncodeUint64(uint64(len(payload)))
+	e.AddPayload(payload)
+}
+
+// ByteCount returns the total number of bytes in the buffers.
+func (e *BuffersEncoder) ByteCount() int {
+	return e.byteCount
+}
+
+// Buffers returns the encoded segments. They remain valid until Reset is
+// called.
+func (e *BuffersEncoder) Buffers() net.Buffers {
+	e.endRun()
+	return e.buffers
+}
+
+// WriteTo writes all of the buffers to writer (using writev if writer is a
+// connection that supports it), and then resets the encoder.
+func (e *BuffersEncoder) WriteTo(writer io.Writer) (byteCount int64, err error) {
+	buffers := e.Buffers()
+	byteCount, err = buffers.WriteTo(writer)
+	e.Reset()
+	return
+}
+
+// Reset empties the encoder, reusing its internal storage.
+func (e *BuffersEncoder) Reset() {
+	for i := range e.buffers {
+		e.buffers[i] = nil
+	}
+	e.buffers = e.buffers[:0]
+	e.scratch = e.scratch[:0]
+	e.runStart = 0
+	e.byteCount = 0
+}
+
+// Close off the current run of encoded values as a segment. Its capacity is
+// capped so that appending to the segment can't overwrite later values.
+func (e *BuffersEncoder) endRun() {
+	if e.runStart < len(e.scratch) {
+		e.buffers = append(e.buffers, e.scratch[e.runStart:len(e.scratch):len(e.scratch)])
+		e.runStart = len(e.scratch)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestBuffersEncoder(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xaa}, 1000)
+	e := NewBuffersEncoder()
+	e.EncodeUint64(1)
+	e.Encode(big.NewInt(300))
+	e.EncodeBytes(payload)
+	e.EncodeUint64(2)
+	e.AddPayload(nil)
+	e.AddPayload([]byte{0xbb})
+
+	expected := []byte{0x01, 0xac, 0x02, 0xe8, 0x07}
+	expected = append(expected, payload...)
+	expected = append(expected, 0x02, 0xbb)
+	if e.ByteCount() != len(expected) {
+		t.Errorf("Expected a byte count of %v but got %v", len(expected), e.ByteCount())
+	}
+
+	buffers := e.Buffers()
+	if len(buffers) != 4 {
+		t.Errorf("Expected 4 segments but got %v", len(buffers))
+		return
+	}
+	if &buffers[1][0] != &payload[0] {
+		t.Errorf("Expected the payload to be referenced rather than copied")
+	}
+	if !bytes.Equal(buffers[0], []byte{0x01, 0xac, 0x02, 0xe8, 0x07}) {
+		t.Errorf("Expected the leading values to be one segment but got %v", describe.D(buffers[0]))
+	}
+	if cap(buffers[0]) != len(buffers[0]) {
+		t.Errorf("Expected the segment capacity to be capped")
+	}
+
+	buff := &bytes.Buffer{}
+	byteCount, err := e.WriteTo(buff)
+	if err != nil {
+		t.Error(err)
+	}
+	if int(byteCount) != len(expected) || !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v bytes but got %v", len(expected), byteCount)
+	}
+	if e.ByteCount() != 0 || len(e.Buffers()) != 0 {
+		t.Errorf("Expected WriteTo to reset the encoder")
+	}
+
+	// Reuse after a reset
+	e.EncodeUint64(5)
+	buff.Reset()
+	e.WriteTo(buff)
+	if !bytes.Equal(buff.Bytes(), []byte{0x05}) {
+		t.Errorf("Expected [05] but got %v", describe.D(buff.Bytes()))
+	}
+}
+
+func TestBuffersEncoderGrowth(t *testing.T) {
+	// Segments closed off before the scratch space grows must keep their
+	// contents.
+	e := NewBuffersEncoder()
+	payload := []byte{0xff}
+	expected := []byte{}
+	for i := uint64(0); i < 1000; i++ {
+		e.EncodeUint64(i)
+		e.AddPayload(payload)
+		expected = append(AppendUint64(expected, i), payload...)
+	}
+	buff := &bytes.Buffer{}
+	e.WriteTo(buff)
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected the output to match after the scratch space grew")
+	}
+}
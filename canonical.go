@@ -0,0 +1,186 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+)
+
+// CanonicalWriter passes a stream of ULEB128 values through to a writer,
+// rewriting any non-minimal encodings (such as 80 00 or 81 80 00) into their
+// canonical form. The stream must consist of nothing but ULEB128 values.
+// Values of any size are handled without buffering them whole.
+type CanonicalWriter struct {
+	writer        io.Writer
+	canonicalizer canonicalizer
+	buffer        []byte
+}
+
+// NewCanonicalWriter returns a CanonicalWriter that writes to writer.
+func NewCanonicalWriter(writer io.Writer) *CanonicalWriter {
+	return &CanonicalWriter{writer: writer}
+}
+
+// Write canonicalizes the values in p, which may begin or end partway
+// through a value. Bytes that might turn out to be redundant are held back
+// until the end of their value is seen. Returns len(p) on success.
+func (w *CanonicalWriter) Write(p []byte) (byteCount int, err error) {
+	w.buffer = w.canonicalizer.process(w.buffer[:0], p)
+	if len(w.buffer) > 0 {
+		if _, err = w.writer.Write(w.buffer); err != nil {
+			return
+		}
+	}
+	byteCount = len(p)
+	return
+}
+
+// Close checks that the stream ended on a value boundary, returning
+// ErrTruncated if it didn't. It doesn't close the underlying writer.
+func (w *CanonicalWriter) Close() error {
+	if w.canonicalizer.inValue {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// CanonicalReader reads a stream of ULEB128 values from a reader, rewriting
+// any non-minimal encodings into their canonical form. The stream must
+// consist of nothing but ULEB128 values. If it ends partway through a value,
+// Read returns io.ErrUnexpectedEOF.
+type CanonicalReader struct {
+	reader        io.Reader
+	canonicalizer canonicalizer
+	input         []byte
+	output        []byte
+	outputOffset  int
+	err           error
+}
+
+// NewCanonicalReader returns a CanonicalReader that reads from reader.
+func NewCanonicalReader(reader io.Reader) *CanonicalReader {
+	return &CanonicalReader{
+		reader: reader,
+		input:  make([]byte, DefaultChunkSize),
+	}
+}
+
+func (r *CanonicalReader) Read(p []byte) (byteCount int, err error) {
+	for r.outputOffset == len(r.output) {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var readCount int
+		readCount, r.err = r.reader.Read(r.input)
+		r.output = r.canonicalizer.process(r.output[:0], r.input[:readCount])
+		r.outputOffset = 0
+		if r.err == io.EOF && r.canonicalizer.inValue {
+			r.err = io.ErrUnexpectedEOF
+		}
+	}
+	byteCount = copy(p, r.output[r.outputOffset:])
+	r.outputOffset += byteCount
+	return
+}
+
+// Tracks the state of a value that's being canonicalized. The only possible
+// redundancy in an encoding is a run of zero-payload groups at its end, so
+// a run of 80 bytes is counted rather than emitted until a later byte shows
+// whether it's needed. The last group with a nonzero payload is also held
+// back, since its continuation bit must be cleared if the run turns out to
+// be redundant.
+type canonicalizer struct {
+	inValue bool
+	held    byte
+	hasHeld bool
+	zeroRun int
+}
+
+// Append the canonical output for input to output.
+func (c *canonicalizer) process(output []byte, input []byte) []byte {
+	for _, b := range input {
+		c.inValue = true
+		if b&continuationMask != 0 {
+			if b&payloadMask == 0 {
+				c.zeroRun++
+				continue
+			}
+			output = c.release(output)
+			c.held = b
+			c.hasHeld = true
+			continue
+		}
+
+		if b != 0 {
+			output = append(c.release(output), b)
+		} else if c.hasHeld {
+			output = append(output, c.held&payloadMask)
+		} else {
+			output = append(output, 0)
+		}
+		*c = canonicalizer{}
+	}
+	return output
+}
+
+// Append the held group and zero run, which have turned out to be needed.
+func (c *canonicalizer) release(output []byte) []byte {
+	if c.hasHeld {
+		output = append(output, c.held)
+		c.hasHeld = false
+	}
+	for ; c.zeroRun > 0; c.zeroRun-- {
+		output = append(output, continuationMask)
+	}
+	return output
+}
+
+// The encoders in this package are deterministic: a given sequence of values
+// (and options) always encodes to the same bytes, and every integer is
+// written in its canonical form (except for the deliberately redundant
+// padding of PadWithRedundantZero). Where an encoder uses a map internally,
+// as dictionary and adaptive blocks do, its output is ordered by the values
+// rather than by map iteration. The decoders are more lenient, so data from
+// elsewhere should be checked with VerifyDeterministic before it's hashed or
+// signed.
+
+// VerifyDeterministic checks that buffer consists of ULEB128 values in
+// exactly the form this package would encode them in, so that decoding and
+// re-encoding them reproduces buffer byte for byte. It returns a
+// *DecodeError holding ErrNonCanonical for the first value that isn't
+// canonical, or holding ErrTruncated if buffer ends partway through a value.
+func VerifyDeterministic(buffer []byte) error {
+	for offset := 0; offset < len(buffer); {
+		byteCount := NextValueStart(buffer[offset:])
+		encoded := buffer[offset : offset+byteCount]
+		var err error
+		if encoded[byteCount-1]&continuationMask != 0 {
+			err = ErrTruncated
+		} else if !isCanonical(encoded) {
+			err = ErrNonCanonical
+		}
+		if err != nil {
+			return newDecodeError(err, offset, encoded)
+		}
+		offset += byteCount
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/kstenerud/go-describe"
+)
+
+var canonicalTests = []struct {
+	input    []byte
+	expected []byte
+}{
+	{[]byte{0x00}, []byte{0x00}},
+	{[]byte{0x80, 0x00}, []byte{0x00}},
+	{[]byte{0x80, 0x80, 0x80, 0x00}, []byte{0x00}},
+	{[]byte{0x81, 0x80, 0x00}, []byte{0x01}},
+	{[]byte{0xff, 0x80, 0x80, 0x00}, []byte{0x7f}},
+	{[]byte{0x80, 0x81, 0x00}, []byte{0x80, 0x01}},
+	{[]byte{0xac, 0x82, 0x80, 0x00}, []byte{0xac, 0x02}},
+	{[]byte{0xac, 0x02}, []byte{0xac, 0x02}},
+	{[]byte{0x80, 0x80, 0x80, 0x01}, []byte{0x80, 0x80, 0x80, 0x01}},
+	{[]byte{0x81, 0x80, 0x81, 0x80, 0x00}, []byte{0x81, 0x80, 0x01}},
+}
+
+func canonicalTestStream() (input []byte, expected []byte) {
+	for _, test := range canonicalTests {
+		input = append(input, test.input...)
+		expected = append(expected, test.expected...)
+	}
+	return
+}
+
+func TestCanonicalWriter(t *testing.T) {
+	for _, test := range canonicalTests {
+		buff := &bytes.Buffer{}
+		w := NewCanonicalWriter(buff)
+		if _, err := w.Write(test.input); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := w.Close(); err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(buff.Bytes(), test.expected) {
+			t.Errorf("Expected %v to canonicalize to %v but got %v", describe.D(test.input), describe.D(test.expected), describe.D(buff.Bytes()))
+		}
+	}
+
+	// One byte per write, so that every value spans several calls
+	input, expected := canonicalTestStream()
+	buff := &bytes.Buffer{}
+	w := NewCanonicalWriter(buff)
+	for i := range input {
+		if byteCount, err := w.Write(input[i : i+1]); err != nil || byteCount != 1 {
+			t.Errorf("Expected to write 1 byte but got %v (%v)", byteCount, err)
+			return
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+
+	w = NewCanonicalWriter(&bytes.Buffer{})
+	w.Write([]byte{0x01, 0x80})
+	if err := w.Close(); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
+
+func TestCanonicalReader(t *testing.T) {
+	input, expected := canonicalTestStream()
+	for _, reader := range []io.Reader{bytes.NewReader(input), iotest.OneByteReader(bytes.NewReader(input))} {
+		actual, err := ioutil.ReadAll(NewCanonicalReader(reader))
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+		}
+	}
+
+	actual, err := ioutil.ReadAll(NewCanonicalReader(bytes.NewReader([]byte{0x01, 0x81, 0x80})))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+	if !bytes.Equal(actual, []byte{0x01}) {
+		t.Errorf("Expected [01] before the error but got %v", describe.D(actual))
+	}
+}
+
+func TestCanonicalDecodesSame(t *testing.T) {
+	// Every value, padded with redundant groups, must canonicalize to its
+	// minimal encoding.
+	var input, expected []byte
+	for _, value := range []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff} {
+		encoded := AppendUint64(nil, value)
+		for padding := 0; padding < 3; padding++ {
+			padded := append([]byte{}, encoded...)
+			for i := 0; i < padding; i++ {
+				padded[len(padded)-1] |= continuationMask
+				padded = append(padded, 0x00)
+			}
+			input = append(input, padded...)
+			expected = append(expected, encoded...)
+		}
+	}
+	actual, err := ioutil.ReadAll(NewCanonicalReader(bytes.NewReader(input)))
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+	}
+}
+
+func TestVerifyDeterministic(t *testing.T) {
+	for _, test := range canonicalTests {
+		prefix := []byte{0x05, 0xac, 0x02}
+		err := VerifyDeterministic(append(prefix, test.input...))
+		if bytes.Equal(test.input, test.expected) {
+			if err != nil {
+				t.Errorf("Expected %v to verify but got %v", describe.D(test.input), err)
+			}
+			continue
+		}
+		decodeErr, ok := err.(*DecodeError)
+		if !ok || decodeErr.Err != ErrNonCanonical || decodeErr.Offset != len(prefix) || decodeErr.ByteCount != len(test.input) {
+			t.Errorf("Expected %v to fail at offset %v but got %v", describe.D(test.input), len(prefix), err)
+		}
+	}
+
+	_, expected := canonicalTestStream()
+	if err := VerifyDeterministic(expected); err != nil {
+		t.Error(err)
+	}
+	err := VerifyDeterministic(append(expected, 0x80))
+	if decodeErr, ok := err.(*DecodeError); !ok || decodeErr.Err != ErrTruncated || decodeErr.Offset != len(expected) {
+		t.Errorf("Expected %v at offset %v but got %v", ErrTruncated, len(expected), err)
+	}
+}
+
+func TestBlockEncodingIsDeterministic(t *testing.T) {
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = uint64(i%37) << (uint(i) % 50)
+	}
+	dictionary := AppendDictionaryBlock(nil, values)
+	adaptive, _ := AppendAdaptiveBlock(nil, values)
+	for i := 0; i < 20; i++ {
+		if !bytes.Equal(AppendDictionaryBlock(nil, values), dictionary) {
+			t.Fatalf("Dictionary block encoding changed between runs")
+		}
+		if again, _ := AppendAdaptiveBlock(nil, values); !bytes.Equal(again, adaptive) {
+			t.Fatalf("Adaptive block encoding changed between runs")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/big"
+)
+
+// The chunk size used by EncodeChunked when none is given, and by
+// DecodeToWriter
+const DefaultChunkSize = 4096
+
+// EncodeChunked encodes a math.big.Int value (the sign of the value will be
+// ignored) by walking its words and writing the encoding in chunks of at
+// most chunkSize bytes, so that memory use stays bounded no matter how large
+// the value is. If chunkSize is less than 1, DefaultChunkSize is used.
+func EncodeChunked(value *big.Int, writer io.Writer, chunkSize int) (byteCount int, err error) {
+	if chunkSize < 1 {
+		chunkSize = DefaultChunkSize
+	}
+	groupCount := EncodedSize(value)
+	if chunkSize > groupCount {
+		chunkSize = groupCount
+	}
+	chunk := make([]byte, 0, chunkSize)
+
+	words := value.Bits()
+	wordIndex := 0
+	accum := big.Word(0)
+	accumBits := uint(0)
+	for group := 0; group < groupCount; group++ {
+		var payload byte
+		if accumBits >= 7 {
+			payload = byte(accum & payloadMask)
+			accum >>= 7
+			accumBits -= 7
+		} else {
+			next := big.Word(0)
+			if wordIndex < len(words) {
+				next = words[wordIndex]
+				wordIndex++
+			}
+			payload = byte((accum | next<<accumBits) & payloadMask)
+			used := 7 - accumBits
+			accum = next >> used
+			accumBits = uint(wordSize()) - used
+		}
+		if group < groupCount-1 {
+			payload |= continuationMask
+		}
+
+		chunk = append(chunk, payload)
+		if len(chunk) == chunkSize || group == groupCount-1 {
+			var written int
+			written, err = writer.Write(chunk)
+			byteCount += written
+			if err != nil {
+				return
+			}
+			chunk = chunk[:0]
+		}
+	}
+	return
+}
+
+// DecodeToWriter decodes a ULEB128 value of any size without materializing
+// it, streaming its magnitude to sink as minimal little-endian bytes (a value
+// of 0 produces no bytes). Output is written in chunks of DefaultChunkSize
+// bytes. Returns io.EOF if the reader is empty, and ErrTruncated if it ends
+// partway through the value. Readers that implement io.ByteReader (such as
+// bufio.Reader) are read a byte at a time without extra overhead.
+func DecodeToWriter(reader io.Reader, sink io.Writer) (byteCount int, magnitudeByteCount int, err error) {
+	byteReader, ok := reader.(io.ByteReader)
+	if !ok {
+		byteReader = &singleByteReader{reader: reader, buffer: []byte{0}}
+	}
+
+	chunk := make([]byte, 0, DefaultChunkSize)
+	pendingZeros := 0
+	emit := func(b byte) error {
+		if b == 0 {
+			pendingZeros++
+			return nil
+		}
+		for ; pendingZeros > 0; pendingZeros-- {
+			if err := appendToChunk(&chunk, 0, sink, &magnitudeByteCount); err != nil {
+				return err
+			}
+		}
+		return appendToChunk(&chunk, b, sink, &magnitudeByteCount)
+	}
+
+	accum := uint(0)
+	accumBits := uint(0)
+	for {
+		var b byte
+		if b, err = byteReader.ReadByte(); err != nil {
+			if err == io.EOF && byteCount > 0 {
+				err = ErrTruncated
+			}
+			return
+		}
+		byteCount++
+		accum |= uint(b&payloadMask) << accumBits
+		accumBits += 7
+		if accumBits >= 8 {
+			if err = emit(byte(accum)); err != nil {
+				return
+			}
+			accum >>= 8
+			accumBits -= 8
+		}
+		if b&continuationMask == 0 {
+			break
+		}
+	}
+	if accum != 0 {
+		if err = emit(byte(accum)); err != nil {
+			return
+		}
+	}
+	if len(chunk) > 0 {
+		_, err = sink.Write(chunk)
+	}
+	return
+}
+
+func appendToChunk(chunk *[]byte, b byte, sink io.Writer, byteCount *int) (err error) {
+	*chunk = append(*chunk, b)
+	*byteCount++
+	if len(*chunk) == cap(*chunk) {
+		_, err = sink.Write(*chunk)
+		*chunk = (*chunk)[:0]
+	}
+	return
+}
+
+type singleByteReader struct {
+	reader io.Reader
+	buffer []byte
+}
+
+func (r *singleByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.reader, r.buffer); err != nil {
+		return 0, err
+	}
+	return r.buffer[0], nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+)
+
+type chunkRecorder struct {
+	bytes.Buffer
+	largestWrite int
+}
+
+func (w *chunkRecorder) Write(p []byte) (int, error) {
+	if len(p) > w.largestWrite {
+		w.largestWrite = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+func assertEncodeChunked(t *testing.T, value *big.Int, chunkSize int) {
+	expected := &bytes.Buffer{}
+	if _, err := Encode(value, expected); err != nil {
+		t.Error(err)
+		return
+	}
+
+	actual := &chunkRecorder{}
+	byteCount, err := EncodeChunked(value, actual, chunkSize)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if byteCount != expected.Len() {
+		t.Errorf("Expected %x to encode to %v bytes but got %v", value, expected.Len(), byteCount)
+	}
+	if !bytes.Equal(actual.Bytes(), expected.Bytes()) {
+		t.Errorf("Chunk size %v: Expected %x to encode to %v but got %v", chunkSize, value, expected.Bytes(), actual.Bytes())
+	}
+	if chunkSize > 0 && actual.largestWrite > chunkSize {
+		t.Errorf("Expected writes of at most %v bytes but got %v", chunkSize, actual.largestWrite)
+	}
+}
+
+func TestEncodeChunked(t *testing.T) {
+	values := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(0x7f),
+		big.NewInt(0x80),
+		newBigFromHex("ffffffffffffffff"),
+		newBigFromHex("10000000000000000"),
+		newBigFromHex("0123456789abcdef0123456789abcdef0123456789abcdef"),
+	}
+	huge := big.NewInt(1)
+	huge.Lsh(huge, 100000)
+	huge.Sub(huge, big.NewInt(12345))
+	values = append(values, huge)
+
+	for _, value := range values {
+		for _, chunkSize := range []int{0, 1, 3, 7, 100} {
+			assertEncodeChunked(t, value, chunkSize)
+		}
+	}
+}
+
+type onlyReader struct {
+	reader io.Reader
+}
+
+func (r onlyReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+func assertDecodeToWriter(t *testing.T, value *big.Int, encoded []byte) {
+	for _, reader := range []io.Reader{bytes.NewReader(encoded), onlyReader{bytes.NewReader(encoded)}} {
+		sink := &bytes.Buffer{}
+		byteCount, magnitudeByteCount, err := DecodeToWriter(reader, sink)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if byteCount != len(encoded) {
+			t.Errorf("Expected to consume %v bytes but got %v", len(encoded), byteCount)
+		}
+
+		magnitude := sink.Bytes()
+		if magnitudeByteCount != len(magnitude) {
+			t.Errorf("Expected a magnitude byte count of %v but got %v", len(magnitude), magnitudeByteCount)
+		}
+		bigEndian := make([]byte, len(magnitude))
+		for i, b := range magnitude {
+			bigEndian[len(magnitude)-1-i] = b
+		}
+		if !bytes.Equal(bigEndian, value.Bytes()) {
+			t.Errorf("Expected magnitude %x but got %x", value.Bytes(), bigEndian)
+		}
+	}
+}
+
+func TestDecodeToWriter(t *testing.T) {
+	huge := big.NewInt(1)
+	huge.Lsh(huge, 100000)
+	huge.Sub(huge, big.NewInt(12345))
+	for _, value := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(0x7f),
+		big.NewInt(0x80),
+		big.NewInt(0x100),
+		newBigFromHex("10000000000000000"),
+		newBigFromHex("0123456789abcdef0123456789abcdef0123456789abcdef"),
+		huge,
+	} {
+		assertDecodeToWriter(t, value, Append(nil, value))
+	}
+	assertDecodeToWriter(t, big.NewInt(0x80), []byte{0x80, 0x81, 0x80, 0x80, 0x00})
+
+	if _, _, err := DecodeToWriter(bytes.NewReader(nil), &bytes.Buffer{}); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if _, _, err := DecodeToWriter(bytes.NewReader([]byte{0x80}), &bytes.Buffer{}); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+}
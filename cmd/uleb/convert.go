@@ -0,0 +1,203 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// Returns the position in window (which starts one byte before the desired
+// split point) of the first value starting at or after window[1], or
+// len(window) if there isn't one in the window.
+type boundaryScanner func(window []byte) int
+
+// The codecs whose streams can be split at an arbitrary point.
+var boundaryScanners = map[string]boundaryScanner{
+	"uleb128": uleb128.NextValueStart,
+	"syncvarint": func(window []byte) int {
+		return 1 + uleb128.NextSyncVarint(window[1:])
+	},
+}
+
+func convertCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "uleb128", "the codec of the input")
+	to := flags.String("to", "uleb128", "the codec to write")
+	outputPath := flags.String("o", "", "the file to write (default stdout)")
+	workers := flags.Int("j", runtime.GOMAXPROCS(0), "the number of shards to convert at once")
+	shardSize := flags.Int64("shard-size", 16<<20, "the approximate size of each shard in bytes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("convert needs exactly one input file")
+	}
+	src, err := lookupCodec(*from)
+	if err != nil {
+		return err
+	}
+	dst, err := lookupCodec(*to)
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	info, err := input.Stat()
+	if err != nil {
+		return err
+	}
+	output := stdout
+	var outputFile *os.File
+	if *outputPath != "" {
+		if outputFile, err = os.Create(*outputPath); err != nil {
+			return err
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+
+	start := time.Now()
+	stats, err := convertShards(dst, src, boundaryScanners[*from], input, info.Size(), output, *workers, *shardSize)
+	if err != nil {
+		return err
+	}
+	if outputFile != nil {
+		if err = outputFile.Close(); err != nil {
+			return err
+		}
+	}
+	reportConversion(stderr, stats, time.Since(start))
+	return nil
+}
+
+func reportConversion(writer io.Writer, stats uleb128.TranscodeStats, elapsed time.Duration) {
+	change := 0.0
+	if stats.BytesRead > 0 {
+		change = float64(stats.BytesWritten-stats.BytesRead) / float64(stats.BytesRead) * 100
+	}
+	throughput := float64(stats.BytesRead) / (1 << 20) / elapsed.Seconds()
+	fmt.Fprintf(writer, "converted %v values: %v bytes -> %v bytes (%+.1f%%) in %v (%.1f MiB/s)\n",
+		stats.Values, stats.BytesRead, stats.BytesWritten, change, elapsed.Round(time.Millisecond), throughput)
+}
+
+type shard struct {
+	start int64
+	end   int64
+}
+
+type shardResult struct {
+	output []byte
+	stats  uleb128.TranscodeStats
+	err    error
+}
+
+// Convert input in shards, up to workers at a time, writing the results to
+// output in order. Without a boundary scanner, the input is one shard.
+func convertShards(dst uleb128.Codec, src uleb128.Codec, scanner boundaryScanner, input io.ReaderAt, size int64,
+	output io.Writer, workers int, shardSize int64) (stats uleb128.TranscodeStats, err error) {
+	if workers < 1 {
+		workers = 1
+	}
+	if scanner == nil || shardSize < 1 {
+		shardSize = size
+	}
+
+	results := make(chan chan shardResult, workers)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(results)
+		for start := int64(0); start < size; {
+			end, err := nextShardEnd(scanner, input, start+shardSize, size)
+			result := make(chan shardResult, 1)
+			select {
+			case results <- result:
+			case <-done:
+				return
+			}
+			if err != nil {
+				result <- shardResult{err: err}
+				return
+			}
+			go func(s shard) {
+				result <- convertShard(dst, src, input, s)
+			}(shard{start, end})
+			start = end
+		}
+	}()
+
+	for result := range results {
+		r := <-result
+		if _, err = output.Write(r.output); err != nil {
+			return
+		}
+		if r.err != nil {
+			err = fmt.Errorf("offset %v: %v", stats.BytesRead+r.stats.BytesRead, r.err)
+		}
+		stats.Values += r.stats.Values
+		stats.BytesRead += r.stats.BytesRead
+		stats.BytesWritten += r.stats.BytesWritten
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func convertShard(dst uleb128.Codec, src uleb128.Codec, input io.ReaderAt, s shard) shardResult {
+	data := make([]byte, s.end-s.start)
+	if _, err := input.ReadAt(data, s.start); err != nil {
+		return shardResult{err: err}
+	}
+	output, stats, err := uleb128.TranscodeBytes(dst, src, make([]byte, 0, len(data)), data)
+	return shardResult{output: output, stats: stats, err: err}
+}
+
+// Find the first value boundary at or after position.
+func nextShardEnd(scanner boundaryScanner, input io.ReaderAt, position int64, size int64) (end int64, err error) {
+	var window [64]byte
+	for position < size {
+		n := int64(len(window))
+		if remaining := size - position + 1; remaining < n {
+			n = remaining
+		}
+		if _, err = input.ReadAt(window[:n], position-1); err != nil {
+			return
+		}
+		if offset := int64(scanner(window[:n])); offset < n {
+			return position - 1 + offset, nil
+		}
+		position += n - 1
+	}
+	return size, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func convertTestValues() (uleb []byte, sync []byte, count int) {
+	for i := uint64(0); i < 20000; i++ {
+		value := i * i * i * 7919
+		uleb = uleb128.ULEB128Codec.AppendTo(uleb, value)
+		sync = uleb128.SyncVarintCodec.AppendTo(sync, value)
+		count++
+	}
+	return
+}
+
+func TestConvertShards(t *testing.T) {
+	uleb, sync, count := convertTestValues()
+	for _, test := range []struct {
+		dst, src string
+		from, to []byte
+	}{
+		{"syncvarint", "uleb128", uleb, sync},
+		{"uleb128", "syncvarint", sync, uleb},
+	} {
+		dst, _ := uleb128.LookupCodec(test.dst)
+		src, _ := uleb128.LookupCodec(test.src)
+		for _, shardSize := range []int64{1, 7, 1000, 1 << 30} {
+			output := &bytes.Buffer{}
+			stats, err := convertShards(dst, src, boundaryScanners[test.src], bytes.NewReader(test.from), int64(len(test.from)), output, 4, shardSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(output.Bytes(), test.to) {
+				t.Errorf("%v -> %v with %v byte shards doesn't match", test.src, test.dst, shardSize)
+			}
+			expected := uleb128.TranscodeStats{Values: int64(count), BytesRead: int64(len(test.from)), BytesWritten: int64(len(test.to))}
+			if stats != expected {
+				t.Errorf("Expected %+v but got %+v", expected, stats)
+			}
+		}
+	}
+}
+
+func TestConvertShardsError(t *testing.T) {
+	input := []byte{0x01, 0x02, 0x03, 0x80}
+	output := &bytes.Buffer{}
+	_, err := convertShards(uleb128.SyncVarintCodec, uleb128.ULEB128Codec, boundaryScanners["uleb128"],
+		bytes.NewReader(input), int64(len(input)), output, 2, 1)
+	if err == nil || err.Error() != "offset 3: "+uleb128.ErrTruncated.Error() {
+		t.Errorf("Expected a truncation error at offset 3 but got %v", err)
+	}
+	if !bytes.Equal(output.Bytes(), []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("Expected the complete values to be written but got %v", output.Bytes())
+	}
+}
+
+func TestConvertCommand(t *testing.T) {
+	uleb, sync, _ := convertTestValues()
+	dir, err := ioutil.TempDir("", "uleb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	inputPath := filepath.Join(dir, "input")
+	outputPath := filepath.Join(dir, "output")
+	if err = ioutil.WriteFile(inputPath, uleb, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := &bytes.Buffer{}
+	args := []string{"convert", "-to", "syncvarint", "-o", outputPath, "-shard-size", "4096", inputPath}
+	if err = run(args, nil, &bytes.Buffer{}, report); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(converted, sync) {
+		t.Errorf("Converted file doesn't match")
+	}
+	if !strings.HasPrefix(report.String(), "converted 20000 values: ") {
+		t.Errorf("Unexpected report %q", report.String())
+	}
+
+	for _, args := range [][]string{
+		{"convert"},
+		{"convert", filepath.Join(dir, "missing")},
+		{"convert", "-from", "nonsense", inputPath},
+	} {
+		if err = run(args, nil, &bytes.Buffer{}, &bytes.Buffer{}); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Command uleb is a toolbox for working with files of packed integers.
+//
+// Usage:
+//
+//	uleb transcode [-from codec] [-to codec] < input > output
+//	uleb convert [-from codec] [-to codec] [-o output] [-j workers] [-shard-size bytes] input
+//
+// The transcode command converts a packed stream from one codec to another,
+// preserving every value exactly. Codecs are given by their registered
+// names (see uleb128.CodecNames), and both default to "uleb128".
+//
+// The convert command does the same for a file, splitting it into shards at
+// value boundaries and converting up to -j shards at once. It reports the
+// throughput and the change in size when done. Only "uleb128" and
+// "syncvarint" input can be split; other codecs are converted as a single
+// shard.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "uleb: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command (available: transcode, convert)")
+	}
+	switch args[0] {
+	case "transcode":
+		return transcodeCommand(args[1:], stdin, stdout, stderr)
+	case "convert":
+		return convertCommand(args[1:], stdout, stderr)
+	}
+	return fmt.Errorf("unknown command %q (available: transcode, convert)", args[0])
+}
+
+func transcodeCommand(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("transcode", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "uleb128", "the codec of the input")
+	to := flags.String("to", "uleb128", "the codec to write")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	src, err := lookupCodec(*from)
+	if err != nil {
+		return err
+	}
+	dst, err := lookupCodec(*to)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(stdout)
+	stats, err := uleb128.Transcode(dst, src, writer, stdin)
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return fmt.Errorf("offset %v: %v", stats.BytesRead, err)
+	}
+	return nil
+}
+
+func lookupCodec(name string) (uleb128.Codec, error) {
+	codec, ok := uleb128.LookupCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q (available: %v)", name, strings.Join(uleb128.CodecNames(), ", "))
+	}
+	return codec, nil
+}
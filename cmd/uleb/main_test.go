@@ -0,0 +1,71 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func TestTranscodeCommand(t *testing.T) {
+	values := []uint64{0, 300, 1 << 40, 0xffffffffffffffff}
+	var input, expected []byte
+	for _, value := range values {
+		input = uleb128.ULEB128Codec.AppendTo(input, value)
+		expected = uleb128.SyncVarintCodec.AppendTo(expected, value)
+	}
+
+	output := &bytes.Buffer{}
+	if err := run([]string{"transcode", "-to", "syncvarint"}, bytes.NewReader(input), output, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", expected, output.Bytes())
+	}
+
+	roundTrip := &bytes.Buffer{}
+	if err := run([]string{"transcode", "-from", "syncvarint"}, output, roundTrip, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(roundTrip.Bytes(), input) {
+		t.Errorf("Expected %v but got %v", input, roundTrip.Bytes())
+	}
+}
+
+func TestTranscodeCommandErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"nonsense"},
+		{"transcode", "-to", "nonsense"},
+		{"transcode", "-from", "nonsense"},
+		{"transcode", "-bad-flag"},
+	} {
+		if err := run(args, bytes.NewReader(nil), &bytes.Buffer{}, &bytes.Buffer{}); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+	err := run([]string{"transcode"}, bytes.NewReader([]byte{0x01, 0x80}), &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || err.Error() != "offset 1: "+uleb128.ErrTruncated.Error() {
+		t.Errorf("Expected a truncation error at offset 1 but got %v", err)
+	}
+}
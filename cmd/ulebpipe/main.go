@@ -0,0 +1,126 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Command ulebpipe converts between newline-delimited integers and packed
+// ULEB128 byte streams.
+//
+// Usage:
+//
+//	ulebpipe [-d] [-x] < input > output
+//
+// By default, ulebpipe reads one non-negative integer per line (decimal, or
+// hex with a 0x prefix, of any size) and writes their packed ULEB128
+// encodings. With -d, it reads a packed stream and writes one decimal
+// integer per line (hex with -x).
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func main() {
+	decode := flag.Bool("d", false, "decode a packed ULEB128 stream to integers")
+	hex := flag.Bool("x", false, "when decoding, print integers in hex")
+	flag.Parse()
+
+	writer := bufio.NewWriter(os.Stdout)
+	var err error
+	if *decode {
+		err = decodeStream(bufio.NewReader(os.Stdin), writer, *hex)
+	} else {
+		err = encodeStream(os.Stdin, writer)
+	}
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ulebpipe: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func encodeStream(reader io.Reader, writer io.Writer) error {
+	encoder := uleb128.NewEncoder(writer, uleb128.EncoderOptions{BufferSize: 4096})
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<24)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		value, ok := parseInteger(line)
+		if !ok || value.Sign() < 0 {
+			return fmt.Errorf("line %v: %q is not a non-negative integer", lineNumber, line)
+		}
+		var err error
+		if value.IsUint64() {
+			_, err = encoder.EncodeUint64(value.Uint64())
+		} else {
+			_, err = encoder.Encode(value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func decodeStream(reader io.Reader, writer io.Writer, hex bool) error {
+	decoder := uleb128.NewDecoder(reader, uleb128.DecoderOptions{})
+	format := "%d\n"
+	if hex {
+		format = "0x%x\n"
+	}
+	for {
+		value, _, err := decoder.DecodeValue()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("offset %v: %v", decoder.ByteCount(), err)
+		}
+		if _, err = fmt.Fprintf(writer, format, value.Big()); err != nil {
+			return err
+		}
+	}
+}
+
+// Parse a decimal integer, or a hex integer if it has a 0x prefix. Other
+// Go literal forms (leading-zero octal, 0b, 0o, underscores) are rejected
+// rather than being silently read in another base.
+func parseInteger(text string) (value *big.Int, ok bool) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		return new(big.Int).SetString(text[2:], 16)
+	}
+	return new(big.Int).SetString(text, 10)
+}
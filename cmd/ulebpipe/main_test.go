@@ -0,0 +1,72 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeStream(t *testing.T) {
+	input := "0\n300\n\n  0x80  \n010\n18446744073709551616\n"
+	expected := []byte{0x00, 0xac, 0x02, 0x80, 0x01, 0x0a, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+	output := &bytes.Buffer{}
+	if err := encodeStream(strings.NewReader(input), output); err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(output.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", expected, output.Bytes())
+	}
+}
+
+func TestEncodeStreamErrors(t *testing.T) {
+	for _, input := range []string{"abc\n", "1\n-5\n", "0b101\n", "0o17\n", "1_000\n", "0x\n", "0x-5\n"} {
+		if err := encodeStream(strings.NewReader(input), &bytes.Buffer{}); err == nil {
+			t.Errorf("Expected encoding %q to fail", input)
+		}
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	input := []byte{0x00, 0xac, 0x02, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+
+	output := &bytes.Buffer{}
+	if err := decodeStream(bytes.NewReader(input), output, false); err != nil {
+		t.Error(err)
+	}
+	if expected := "0\n300\n18446744073709551616\n"; output.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, output.String())
+	}
+
+	output.Reset()
+	if err := decodeStream(bytes.NewReader(input), output, true); err != nil {
+		t.Error(err)
+	}
+	if expected := "0x0\n0x12c\n0x10000000000000000\n"; output.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, output.String())
+	}
+
+	if err := decodeStream(bytes.NewReader([]byte{0x01, 0x80}), &bytes.Buffer{}, false); err == nil {
+		t.Errorf("Expected a truncated stream to fail")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Command ulebseed writes a corpus of interesting ULEB128 encodings
+// (boundary values, maximal lengths, overlong forms and truncations) for
+// seeding fuzzers of formats built on ULEB128.
+//
+// Usage:
+//
+//	ulebseed [-raw] [-o dir]
+//
+// By default, each seed is written to dir (the current directory if not
+// specified) as a file in the Go fuzzing corpus format, named after a hash
+// of its contents like "go test" names them. Point -o at a fuzz target's
+// corpus directory, such as testdata/fuzz/FuzzDecode. With -raw, seeds are
+// written as raw bytes instead, for use with other fuzzing engines.
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kstenerud/go-uleb128/uleb128test"
+)
+
+func main() {
+	dir := flag.String("o", ".", "the directory to write the corpus files to")
+	raw := flag.Bool("raw", false, "write raw bytes rather than Go fuzz corpus files")
+	flag.Parse()
+
+	count, err := writeCorpus(*dir, uleb128test.SeedCorpus(), *raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ulebseed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "ulebseed: wrote %v seeds to %v\n", count, *dir)
+}
+
+func writeCorpus(dir string, seeds [][]byte, raw bool) (count int, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	for _, seed := range seeds {
+		contents := seed
+		if !raw {
+			contents = []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", seed))
+		}
+		name := fmt.Sprintf("%x", sha256.Sum256(contents))[:16]
+		if err = ioutil.WriteFile(filepath.Join(dir, name), contents, 0644); err != nil {
+			return
+		}
+		count++
+	}
+	return
+}
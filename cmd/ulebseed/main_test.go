@@ -0,0 +1,87 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteCorpus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulebseed")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	seeds := [][]byte{{}, {0x80, 0x01}, {0x80}}
+	count, err := writeCorpus(dir, seeds, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if count != len(seeds) {
+		t.Errorf("Expected %v seeds but wrote %v", len(seeds), count)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != len(seeds) {
+		t.Errorf("Expected %v files but got %v", len(seeds), len(files))
+	}
+	found := false
+	for _, file := range files {
+		contents, _ := ioutil.ReadFile(file)
+		if !strings.HasPrefix(string(contents), "go test fuzz v1\n[]byte(") {
+			t.Errorf("Expected %v to be in Go fuzz corpus format but got %q", file, contents)
+		}
+		if string(contents) == "go test fuzz v1\n[]byte(\"\\x80\\x01\")\n" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a corpus file holding [80 01]")
+	}
+}
+
+func TestWriteCorpusRaw(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulebseed")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := writeCorpus(dir, [][]byte{{0xac, 0x02}}, true); err != nil {
+		t.Error(err)
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Errorf("Expected 1 file but got %v", len(files))
+		return
+	}
+	if contents, _ := ioutil.ReadFile(files[0]); string(contents) != "\xac\x02" {
+		t.Errorf("Expected raw bytes [ac 02] but got %x", contents)
+	}
+}
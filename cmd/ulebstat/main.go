@@ -0,0 +1,239 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Command ulebstat scans files of packed ULEB128 values and reports
+// statistics useful when choosing an encoding: the value count, the spread
+// of encoded sizes, non-canonical encodings, the minimum, maximum and mean
+// value, and the size the data would have under alternative codecs.
+//
+// Usage:
+//
+//	ulebstat [file ...]
+//
+// With no files, ulebstat reads from standard input. Each file is reported
+// separately.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "ulebstat: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("ulebstat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return report(stdout, "<stdin>", stdin)
+	}
+	for i, name := range flags.Args() {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = report(stdout, name, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scan everything in reader and write a report on it to writer. A stream
+// that ends partway through a value is reported up to that point, and then
+// returned as an error.
+func report(writer io.Writer, name string, reader io.Reader) error {
+	s := newStreamStats()
+	decoder := uleb128.NewDecoder(bufio.NewReader(reader), uleb128.DecoderOptions{})
+	var err error
+	for {
+		var value uleb128.Value
+		var byteCount int
+		offset := decoder.ByteCount()
+		if value, byteCount, err = decoder.DecodeValue(); err != nil {
+			if err == io.EOF {
+				err = nil
+			} else {
+				err = fmt.Errorf("%v: offset %v: %v", name, offset, err)
+			}
+			break
+		}
+		s.add(value, byteCount, offset)
+	}
+	s.finish()
+	s.write(writer, name)
+	return err
+}
+
+// The largest encoded size that gets its own line in the size breakdown.
+const maxListedSize = 10
+
+// An estimate of the size of the stream under an alternative codec.
+type estimate struct {
+	name      string
+	byteCount int
+	// False once a value comes up that the codec can't represent.
+	available bool
+}
+
+type streamStats struct {
+	count             int
+	byteCount         int
+	nonCanonical      int
+	firstNonCanonical int
+	min               uleb128.Value
+	max               uleb128.Value
+	sum               *big.Int
+	sizeCounts        [maxListedSize + 2]int
+
+	canonical  estimate
+	syncVarint estimate
+	delta      estimate
+	adaptive   estimate
+	previous   uint64
+	block      []uint64
+	scratch    []byte
+}
+
+func newStreamStats() *streamStats {
+	return &streamStats{
+		sum:        new(big.Int),
+		canonical:  estimate{name: "uleb128 (canonical)", available: true},
+		syncVarint: estimate{name: "syncvarint", available: true},
+		delta:      estimate{name: "delta zigzag uleb128", available: true},
+		adaptive:   estimate{name: "adaptive blocks", available: true},
+		block:      make([]uint64, 0, uleb128.DefaultAdaptiveBlockSize),
+	}
+}
+
+func (s *streamStats) add(value uleb128.Value, byteCount int, offset int) {
+	if s.count == 0 || value.Cmp(s.min) < 0 {
+		s.min = value
+	}
+	if s.count == 0 || value.Cmp(s.max) > 0 {
+		s.max = value
+	}
+	s.count++
+	s.byteCount += byteCount
+	s.sum.Add(s.sum, value.Big())
+	if byteCount > maxListedSize {
+		s.sizeCounts[maxListedSize+1]++
+	} else {
+		s.sizeCounts[byteCount]++
+	}
+	if byteCount != value.EncodedSize() {
+		if s.nonCanonical == 0 {
+			s.firstNonCanonical = offset
+		}
+		s.nonCanonical++
+	}
+
+	s.canonical.byteCount += value.EncodedSize()
+	asUint, ok := value.Uint64()
+	if !ok {
+		s.syncVarint.available = false
+		s.delta.available = false
+		s.adaptive.available = false
+		return
+	}
+	s.syncVarint.byteCount += uleb128.EncodedSizeSyncVarint(asUint)
+	s.delta.byteCount += uleb128.EncodedSizeInt64Zigzag(int64(asUint - s.previous))
+	s.previous = asUint
+	if s.block = append(s.block, asUint); len(s.block) == cap(s.block) {
+		s.flushBlock()
+	}
+}
+
+func (s *streamStats) flushBlock() {
+	if len(s.block) > 0 && s.adaptive.available {
+		s.scratch, _ = uleb128.AppendAdaptiveBlock(s.scratch[:0], s.block)
+		s.adaptive.byteCount += len(s.scratch)
+	}
+	s.block = s.block[:0]
+}
+
+func (s *streamStats) finish() {
+	s.flushBlock()
+}
+
+func (s *streamStats) write(writer io.Writer, name string) {
+	fmt.Fprintf(writer, "%v:\n", name)
+	fmt.Fprintf(writer, "  values:        %v\n", s.count)
+	fmt.Fprintf(writer, "  bytes:         %v\n", s.byteCount)
+	if s.count == 0 {
+		return
+	}
+	if s.nonCanonical > 0 {
+		fmt.Fprintf(writer, "  non-canonical: %v (first at offset %v)\n", s.nonCanonical, s.firstNonCanonical)
+	} else {
+		fmt.Fprintf(writer, "  non-canonical: 0\n")
+	}
+	mean := new(big.Rat).SetFrac(s.sum, big.NewInt(int64(s.count)))
+	fmt.Fprintf(writer, "  min:           %v\n", s.min)
+	fmt.Fprintf(writer, "  max:           %v\n", s.max)
+	fmt.Fprintf(writer, "  mean:          %v\n", mean.FloatString(2))
+
+	fmt.Fprintf(writer, "  encoded sizes:\n")
+	for size, count := range s.sizeCounts {
+		if count == 0 {
+			continue
+		}
+		label := fmt.Sprintf("%v", size)
+		if size > maxListedSize {
+			label = fmt.Sprintf("%v+", size)
+		}
+		fmt.Fprintf(writer, "    %3v bytes: %v (%.1f%%)\n", label, count, percent(count, s.count))
+	}
+
+	fmt.Fprintf(writer, "  alternative codecs:\n")
+	for _, e := range []estimate{s.canonical, s.syncVarint, s.delta, s.adaptive} {
+		if !e.available {
+			fmt.Fprintf(writer, "    %-22v n/a (values over 64 bits)\n", e.name)
+			continue
+		}
+		fmt.Fprintf(writer, "    %-22v %v bytes (%+.1f%%)\n", e.name, e.byteCount, percent(e.byteCount-s.byteCount, s.byteCount))
+	}
+}
+
+func percent(part int, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(whole)
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func runStat(t *testing.T, args []string, input []byte) (output string, err error) {
+	stdout := &bytes.Buffer{}
+	err = run(args, bytes.NewReader(input), stdout, &bytes.Buffer{})
+	return stdout.String(), err
+}
+
+func assertContains(t *testing.T, output string, expected ...string) {
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Expected the report to contain %q but got:\n%v", line, output)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	input := []byte{0x01, 0xac, 0x02, 0x85, 0x00, 0x03}
+	output, err := runStat(t, nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output,
+		"<stdin>:\n",
+		"values:        4\n",
+		"bytes:         6\n",
+		"non-canonical: 1 (first at offset 3)\n",
+		"min:           1\n",
+		"max:           300\n",
+		"mean:          77.25\n",
+		"  1 bytes: 2 (50.0%)\n",
+		"  2 bytes: 2 (50.0%)\n",
+		"uleb128 (canonical)    5 bytes (-16.7%)\n",
+		"syncvarint             5 bytes (-16.7%)\n",
+		"delta zigzag uleb128   6 bytes (+0.0%)\n",
+	)
+}
+
+func TestReportBigValues(t *testing.T) {
+	input := uleb128.AppendUint64(nil, 5)
+	input = uleb128.Append(input, new(big.Int).Lsh(big.NewInt(1), 100))
+	output, err := runStat(t, nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, output,
+		"max:           1267650600228229401496703205376\n",
+		"11+ bytes: 1 (50.0%)\n",
+		"syncvarint             n/a (values over 64 bits)\n",
+	)
+}
+
+func TestReportFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulebstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	good := filepath.Join(dir, "good")
+	truncated := filepath.Join(dir, "truncated")
+	ioutil.WriteFile(good, []byte{0x01, 0x02}, 0644)
+	ioutil.WriteFile(truncated, []byte{0x07, 0x80}, 0644)
+
+	output, err := runStat(t, []string{good, truncated}, nil)
+	if err == nil || !strings.Contains(err.Error(), "offset 1") {
+		t.Errorf("Expected a truncation error at offset 1 but got %v", err)
+	}
+	assertContains(t, output, good+":\n  values:        2\n", truncated+":\n  values:        1\n")
+
+	if _, err = runStat(t, []string{filepath.Join(dir, "missing")}, nil); err == nil {
+		t.Errorf("Expected a missing file to fail")
+	}
+}
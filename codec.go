@@ -0,0 +1,147 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/bits"
+)
+
+// Codec is a wire format for uint64 values. It allows applications to select
+// the integer encoding at runtime (for example from configuration) rather
+// than by calling a particular set of functions.
+//
+// All codecs report errors the same way: io.EOF when there's no input at
+// all, ErrTruncated when the input ends partway through a value, and
+// ErrOverflow when a value doesn't fit into a uint64. A codec may also return
+// errors specific to its format.
+type Codec interface {
+	// MaxEncodedSize returns the maximum number of bytes that a canonically
+	// encoded uint64 occupies in this format.
+	MaxEncodedSize() int
+
+	// EncodedSize returns the number of bytes required to encode value.
+	EncodedSize(value uint64) int
+
+	// AppendTo appends the encoded value to buffer, returning the extended
+	// buffer.
+	AppendTo(buffer []byte, value uint64) []byte
+
+	// Encode writes the encoded value to writer.
+	Encode(value uint64, writer io.Writer) (byteCount int, err error)
+
+	// DecodeFromBytes decodes a value from the start of buffer.
+	DecodeFromBytes(buffer []byte) (value uint64, byteCount int, err error)
+
+	// Decode reads a value from reader.
+	Decode(reader io.Reader) (value uint64, byteCount int, err error)
+}
+
+// ULEB128Codec is the Codec for ULEB128 values.
+var ULEB128Codec Codec = ulebCodec{}
+
+// SyncVarintCodec is the Codec for sync varints (see EncodeSyncVarint).
+var SyncVarintCodec Codec = syncVarintCodec{}
+
+type ulebCodec struct{}
+
+func (ulebCodec) MaxEncodedSize() int {
+	return MaxBufferWriteBytes
+}
+
+func (ulebCodec) EncodedSize(value uint64) int {
+	return EncodedSizeUint64(value)
+}
+
+func (ulebCodec) AppendTo(buffer []byte, value uint64) []byte {
+	return AppendUint64(buffer, value)
+}
+
+func (ulebCodec) Encode(value uint64, writer io.Writer) (byteCount int, err error) {
+	return EncodeUint64(value, writer)
+}
+
+func (ulebCodec) DecodeFromBytes(buffer []byte) (value uint64, byteCount int, err error) {
+	value, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err == nil && asBigInt != nil {
+		err = ErrOverflow
+	}
+	return
+}
+
+func (ulebCodec) Decode(reader io.Reader) (value uint64, byteCount int, err error) {
+	value, byteCount, err = decodeUint64(reader, nil)
+	if err == io.ErrUnexpectedEOF {
+		err = ErrTruncated
+	}
+	return
+}
+
+type syncVarintCodec struct{}
+
+func (syncVarintCodec) MaxEncodedSize() int {
+	return MaxSyncVarintBytes
+}
+
+func (syncVarintCodec) EncodedSize(value uint64) int {
+	return EncodedSizeSyncVarint(value)
+}
+
+func (syncVarintCodec) AppendTo(buffer []byte, value uint64) []byte {
+	var encoded [MaxSyncVarintBytes]byte
+	byteCount := EncodeSyncVarintToBytes(value, encoded[:])
+	return append(buffer, encoded[:byteCount]...)
+}
+
+func (syncVarintCodec) Encode(value uint64, writer io.Writer) (byteCount int, err error) {
+	return EncodeSyncVarint(value, writer)
+}
+
+func (syncVarintCodec) DecodeFromBytes(buffer []byte) (value uint64, byteCount int, err error) {
+	return DecodeSyncVarintFromBytes(buffer)
+}
+
+func (syncVarintCodec) Decode(reader io.Reader) (value uint64, byteCount int, err error) {
+	var encoded [MaxSyncVarintBytes]byte
+	if byteCount, err = io.ReadFull(reader, encoded[:1]); err != nil {
+		return
+	}
+	switch leadingOnes := bits.LeadingZeros8(^encoded[0]); leadingOnes {
+	case 0:
+		return uint64(encoded[0]), 1, nil
+	case 1:
+		err = ErrInvalidSyncVarint
+		return
+	case 8:
+		byteCount = MaxSyncVarintBytes
+	default:
+		byteCount = leadingOnes
+	}
+	n, err := io.ReadFull(reader, encoded[1:byteCount])
+	if err != nil {
+		byteCount = n + 1
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrTruncated
+		}
+		return
+	}
+	return DecodeSyncVarintFromBytes(encoded[:byteCount])
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+var codecTestValues = []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff}
+
+func assertCodec(t *testing.T, codec Codec) {
+	buff := &bytes.Buffer{}
+	var appended []byte
+	for _, value := range codecTestValues {
+		byteCount, err := codec.Encode(value, buff)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if byteCount != codec.EncodedSize(value) {
+			t.Errorf("Expected %v to encode to %v bytes but got %v", value, codec.EncodedSize(value), byteCount)
+		}
+		if byteCount > codec.MaxEncodedSize() {
+			t.Errorf("Expected at most %v bytes but got %v", codec.MaxEncodedSize(), byteCount)
+		}
+		appended = codec.AppendTo(appended, value)
+	}
+	if !bytes.Equal(appended, buff.Bytes()) {
+		t.Errorf("Expected AppendTo to produce %v but got %v", describe.D(buff.Bytes()), describe.D(appended))
+	}
+
+	remaining := appended
+	for _, expected := range codecTestValues {
+		value, byteCount, err := codec.DecodeFromBytes(remaining)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if value != expected {
+			t.Errorf("Expected %x but got %x", expected, value)
+		}
+		remaining = remaining[byteCount:]
+
+		value, byteCount, err = codec.Decode(buff)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if value != expected || byteCount != codec.EncodedSize(expected) {
+			t.Errorf("Expected %x (%v bytes) but got %x (%v bytes)", expected, codec.EncodedSize(expected), value, byteCount)
+		}
+	}
+
+	if _, _, err := codec.Decode(buff); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if _, _, err := codec.DecodeFromBytes(nil); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	truncated := codec.AppendTo(nil, 300)
+	truncated = truncated[:len(truncated)-1]
+	if _, _, err := codec.Decode(bytes.NewBuffer(truncated)); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+	if _, _, err := codec.DecodeFromBytes(truncated); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+}
+
+func TestULEB128Codec(t *testing.T) {
+	assertCodec(t, ULEB128Codec)
+
+	tooBig := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+	if _, _, err := ULEB128Codec.DecodeFromBytes(tooBig); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+	if _, _, err := ULEB128Codec.Decode(bytes.NewBuffer(tooBig)); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+	if value, _, err := ULEB128Codec.Decode(io.MultiReader(bytes.NewReader([]byte{0xac}), bytes.NewReader([]byte{0x02}))); err != nil || value != 300 {
+		t.Errorf("Expected 300 but got %v (%v)", value, err)
+	}
+
+	encoded := []byte{0xac, 0x02}
+	buffer := &bytes.Buffer{}
+	allocations := testing.AllocsPerRun(100, func() {
+		buffer.Reset()
+		buffer.Write(encoded)
+		ULEB128Codec.Decode(buffer)
+	})
+	if allocations != 0 {
+		t.Errorf("Expected Decode not to allocate but got %v allocations", allocations)
+	}
+}
+
+func TestSyncVarintCodec(t *testing.T) {
+	assertCodec(t, SyncVarintCodec)
+
+	if _, _, err := SyncVarintCodec.Decode(bytes.NewBuffer([]byte{0x80})); err != ErrInvalidSyncVarint {
+		t.Errorf("Expected ErrInvalidSyncVarint but got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Compression ids for use in a header. Zstandard isn't in the standard
+// library, so its id is only reserved: register an implementation under it
+// with RegisterCompressor to use it.
+const (
+	CompressionIDNone    = 0
+	CompressionIDDeflate = 1
+	CompressionIDZstd    = 2
+)
+
+// ErrUnknownCompression is returned when a header names a compression that
+// has no registered Compressor.
+var ErrUnknownCompression = errors.New("uleb128: unknown compression")
+
+// Compressor compresses and decompresses the data following a header.
+type Compressor interface {
+	// NewWriter returns a writer that compresses what's written to it into
+	// writer. Closing it flushes the compressed data, but doesn't close
+	// writer.
+	NewWriter(writer io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses the data in reader.
+	NewReader(reader io.Reader) (io.ReadCloser, error)
+}
+
+var compressorRegistry = struct {
+	sync.RWMutex
+	compressors map[uint64]Compressor
+}{
+	compressors: map[uint64]Compressor{
+		CompressionIDNone:    noCompressor{},
+		CompressionIDDeflate: deflateCompressor{},
+	},
+}
+
+// RegisterCompressor makes a compressor available by id to
+// LookupCompressor and the container functions. It's intended to be called
+// from the init function of a package providing an external compressor.
+// RegisterCompressor panics if compressor is nil or id is already
+// registered.
+func RegisterCompressor(id uint64, compressor Compressor) {
+	if compressor == nil {
+		panic("uleb128: RegisterCompressor with nil compressor")
+	}
+	compressorRegistry.Lock()
+	defer compressorRegistry.Unlock()
+	if _, exists := compressorRegistry.compressors[id]; exists {
+		panic(fmt.Sprintf("uleb128: compression id %v is already registered", id))
+	}
+	compressorRegistry.compressors[id] = compressor
+}
+
+// LookupCompressor returns the compressor registered under id. The package
+// registers CompressionIDNone and CompressionIDDeflate.
+func LookupCompressor(id uint64) (compressor Compressor, ok bool) {
+	compressorRegistry.RLock()
+	defer compressorRegistry.RUnlock()
+	compressor, ok = compressorRegistry.compressors[id]
+	return
+}
+
+// NewContainerWriter writes header to writer, and returns a writer for the
+// data that follows it, which compresses the data as the header's
+// compression id says. Close the returned writer when done to flush the
+// compressed data; it doesn't close writer.
+func NewContainerWriter(writer io.Writer, header Header) (payload io.WriteCloser, err error) {
+	compressor, err := header.compressor()
+	if err != nil {
+		return
+	}
+	if _, err = WriteHeader(writer, header); err != nil {
+		return
+	}
+	return compressor.NewWriter(writer)
+}
+
+// NewContainerReader reads a header from reader (see ReadHeader), and returns
+// it along with a reader of the decompressed data that follows it.
+func NewContainerReader(reader io.Reader, maxExtensionSize int) (header Header, payload io.ReadCloser, err error) {
+	if header, _, err = ReadHeader(reader, maxExtensionSize); err != nil {
+		return
+	}
+	compressor, err := header.compressor()
+	if err != nil {
+		return
+	}
+	payload, err = compressor.NewReader(reader)
+	return
+}
+
+// Get the compressor for the data following the header.
+func (h Header) compressor() (compressor Compressor, err error) {
+	compressor, ok := LookupCompressor(h.CompressionID)
+	if !ok {
+		err = ErrUnknownCompression
+	}
+	return
+}
+
+type noCompressor struct{}
+
+func (noCompressor) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{writer}, nil
+}
+
+func (noCompressor) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(reader), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+type deflateCompressor struct{}
+
+func (deflateCompressor) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(writer, flate.DefaultCompression)
+}
+
+func (deflateCompressor) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(reader), nil
+}
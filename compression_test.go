@@ -0,0 +1,160 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func writeTestContainer(t *testing.T, header Header, values []uint64) []byte {
+	buff := &bytes.Buffer{}
+	payload, err := NewContainerWriter(buff, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = WriteBlock(payload, values); err != nil {
+		t.Fatal(err)
+	}
+	if err = payload.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buff.Bytes()
+}
+
+func TestContainerCompression(t *testing.T) {
+	values := make([]uint64, 10000)
+	for i := range values {
+		values[i] = 1000000 + uint64(i%7)*1000
+	}
+	plainSize := 0
+	for _, compressionID := range []uint64{CompressionIDNone, CompressionIDDeflate} {
+		header := NewHeader(CodecIDULEB128)
+		header.CompressionID = compressionID
+		encoded := writeTestContainer(t, header, values)
+		if compressionID == CompressionIDNone {
+			plainSize = len(encoded)
+		} else if len(encoded) > plainSize/10 {
+			t.Errorf("Expected deflate to shrink %v bytes by at least 90%% but got %v", plainSize, len(encoded))
+		}
+
+		decodedHeader, payload, err := NewContainerReader(bytes.NewReader(encoded), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decodedHeader, header) {
+			t.Errorf("Expected header %+v but got %+v", header, decodedHeader)
+		}
+		decoded, _, err := ReadBlock(payload, len(values))
+		if err != nil || !reflect.DeepEqual(decoded, values) {
+			t.Errorf("Expected the values to round trip with compression %v (%v)", compressionID, err)
+		}
+		if err = payload.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+}
+
+func TestContainerUnknownCompression(t *testing.T) {
+	header := NewHeader(CodecIDULEB128)
+	header.CompressionID = CompressionIDZstd
+	if _, err := NewContainerWriter(&bytes.Buffer{}, header); err != ErrUnknownCompression {
+		t.Errorf("Expected ErrUnknownCompression but got %v", err)
+	}
+	if _, _, err := NewContainerReader(bytes.NewReader(AppendHeader(nil, header)), 0); err != ErrUnknownCompression {
+		t.Errorf("Expected ErrUnknownCompression but got %v", err)
+	}
+	if _, _, err := NewContainerReader(bytes.NewReader(nil), 0); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
+
+type reversingCompressor struct{}
+
+func (reversingCompressor) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{writer}, nil
+}
+
+func (reversingCompressor) NewReader(reader io.Reader) (io.ReadCloser, error) {
+	return nil, ErrMalformedBlock
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	RegisterCompressor(1000, reversingCompressor{})
+	if compressor, ok := LookupCompressor(1000); !ok || compressor != (reversingCompressor{}) {
+		t.Errorf("Expected the registered compressor")
+	}
+	header := NewHeader(CodecIDULEB128)
+	header.CompressionID = 1000
+	if _, _, err := NewContainerReader(bytes.NewReader(AppendHeader(nil, header)), 0); err != ErrMalformedBlock {
+		t.Errorf("Expected the compressor's error but got %v", err)
+	}
+
+	assertPanics := func(name string, register func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected %v to panic", name)
+			}
+		}()
+		register()
+	}
+	assertPanics("a duplicate id", func() { RegisterCompressor(CompressionIDDeflate, reversingCompressor{}) })
+	assertPanics("a nil compressor", func() { RegisterCompressor(1001, nil) })
+}
+
+func TestContainerFrames(t *testing.T) {
+	records := [][]byte{[]byte("one"), {}, []byte("three")}
+	buff := &bytes.Buffer{}
+	payload, err := NewContainerWriter(buff, NewHeader(CodecIDULEB128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewFrameWriter(payload)
+	for _, record := range records {
+		if _, err = writer.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = payload.Close(); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buff.Bytes()
+
+	_, decoded, err := NewContainerReader(bytes.NewReader(encoded), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := NewFrameReader(decoded, 10)
+	for _, expected := range records {
+		if actual, err := reader.NextFrame(); err != nil || !bytes.Equal(actual, expected) {
+			t.Errorf("Expected frame %q but got %q (%v)", expected, actual, err)
+		}
+	}
+
+	// Data written by a newer, incompatible version is rejected up front.
+	encoded[len(HeaderMagic)] = HeaderVersion + 1
+	if _, _, err = NewContainerReader(bytes.NewReader(encoded), 0); err != ErrUnsupportedVersion {
+		t.Errorf("Expected %v but got %v", ErrUnsupportedVersion, err)
+	}
+}
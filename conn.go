@@ -0,0 +1,143 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// ErrMessageTooLarge is returned when a message is longer than the maximum
+// message size.
+var ErrMessageTooLarge = errors.New("uleb128: message exceeds maximum size")
+
+// ErrMalformedMessage is returned when a peer sends a length prefix that no
+// well-behaved encoder would produce.
+var ErrMalformedMessage = errors.New("uleb128: malformed message length prefix")
+
+// ConnCodec sends and receives ULEB128 length-prefixed messages over a
+// connection. After Receive returns ErrMessageTooLarge or
+// ErrMalformedMessage, the stream is no longer synchronized and the
+// connection should be closed.
+//
+// One goroutine may Send while another Receives, but each direction must only
+// be used by one goroutine at a time.
+type ConnCodec struct {
+	// If nonzero, each Send must complete within this duration.
+	WriteTimeout time.Duration
+	// If nonzero, each Receive must complete within this duration.
+	ReadTimeout time.Duration
+
+	conn           net.Conn
+	reader         *bufio.Reader
+	maxMessageSize uint64
+	sendHeader     []byte
+	receiveHeader  []byte
+}
+
+// NewConnCodec returns a ConnCodec that exchanges messages of at most
+// maxMessageSize bytes over conn.
+func NewConnCodec(conn net.Conn, maxMessageSize int) *ConnCodec {
+	return &ConnCodec{
+		conn:           conn,
+		reader:         bufio.NewReader(conn),
+		maxMessageSize: uint64(maxMessageSize),
+		sendHeader:     make([]byte, MaxBufferWriteBytes),
+		receiveHeader:  make([]byte, MaxBufferWriteBytes),
+	}
+}
+
+// Send writes message as a single length-prefixed frame.
+func (c *ConnCodec) Send(message []byte) (err error) {
+	if uint64(len(message)) > c.maxMessageSize {
+		return ErrMessageTooLarge
+	}
+	if c.WriteTimeout > 0 {
+		if err = c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout)); err != nil {
+			return
+		}
+	}
+	headerByteCount := EncodeUint64ToBytes(uint64(len(message)), c.sendHeader)
+	frame := make([]byte, headerByteCount+len(message))
+	copy(frame, c.sendHeader[:headerByteCount])
+	copy(frame[headerByteCount:], message)
+	_, err = c.conn.Write(frame)
+	return
+}
+
+// Receive reads the next message. Returns io.EOF if the peer closed the
+// connection cleanly between messages, and io.ErrUnexpectedEOF if it closed
+// partway through one.
+func (c *ConnCodec) Receive() (message []byte, err error) {
+	if c.ReadTimeout > 0 {
+		if err = c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
+			return
+		}
+	}
+	length, err := c.readLength()
+	if err != nil {
+		return
+	}
+	message = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, message); err != nil {
+		message = nil
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	return
+}
+
+func (c *ConnCodec) readLength() (length uint64, err error) {
+	byteCount := 0
+	for {
+		var b byte
+		if b, err = c.reader.ReadByte(); err != nil {
+			if err == io.EOF && byteCount > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		if byteCount == len(c.receiveHeader) {
+			err = ErrMalformedMessage
+			return
+		}
+		c.receiveHeader[byteCount] = b
+		byteCount++
+		if b&continuationMask == 0 {
+			break
+		}
+	}
+
+	asUint, asBigInt, _, err := DecodeFromBytes(c.receiveHeader[:byteCount])
+	if err != nil {
+		return
+	}
+	if asBigInt != nil || asUint > c.maxMessageSize {
+		err = ErrMessageTooLarge
+		return
+	}
+	length = asUint
+	return
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertConnReceiveError(t *testing.T, maxMessageSize int, expectedErr error, b ...byte) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	go func() {
+		remote.Write(b)
+		remote.Close()
+	}()
+
+	codec := NewConnCodec(local, maxMessageSize)
+	if _, err := codec.Receive(); err != expectedErr {
+		t.Errorf("Expected receiving %v to fail with %v but got %v", describe.D(b), expectedErr, err)
+	}
+}
+
+func TestConnCodecRoundTrip(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	messages := [][]byte{{0x01, 0x02}, {}, make([]byte, 500)}
+	sender := NewConnCodec(remote, 500)
+	go func() {
+		for _, message := range messages {
+			if err := sender.Send(message); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	receiver := NewConnCodec(local, 500)
+	receiver.ReadTimeout = 5 * time.Second
+	for _, expected := range messages {
+		actual, err := receiver.Receive()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("Expected message %v but got %v", describe.D(expected), describe.D(actual))
+		}
+	}
+}
+
+func TestConnCodecFullDuplex(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	// Each codec sends and receives at the same time from different
+	// goroutines, which must not race (run with -race).
+	messages := [][]byte{{0x01}, make([]byte, 200), {}, {0x02, 0x03}}
+	var wg sync.WaitGroup
+	for _, conn := range []net.Conn{local, remote} {
+		codec := NewConnCodec(conn, 200)
+		codec.ReadTimeout = 5 * time.Second
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for _, message := range messages {
+				if err := codec.Send(message); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, expected := range messages {
+				actual, err := codec.Receive()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(actual, expected) {
+					t.Errorf("Expected message %v but got %v", describe.D(expected), describe.D(actual))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConnCodecErrors(t *testing.T) {
+	assertConnReceiveError(t, 10, io.EOF)
+	assertConnReceiveError(t, 10, io.ErrUnexpectedEOF, 0x80)
+	assertConnReceiveError(t, 10, io.ErrUnexpectedEOF, 0x03, 0x01)
+	assertConnReceiveError(t, 10, ErrMessageTooLarge, 0x0b)
+	assertConnReceiveError(t, 10, ErrMessageTooLarge,
+		0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01)
+	assertConnReceiveError(t, 10, ErrMalformedMessage,
+		0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01)
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	if err := NewConnCodec(local, 2).Send([]byte{1, 2, 3}); err != ErrMessageTooLarge {
+		t.Errorf("Expected ErrMessageTooLarge but got %v", err)
+	}
+}
+
+func TestConnCodecTimeout(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	codec := NewConnCodec(local, 10)
+	codec.ReadTimeout = time.Millisecond
+	_, err := codec.Receive()
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Expected a timeout error but got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+)
+
+// DecodeConstantTimeFromBytes decodes a uint64 from the start of buffer in
+// time that depends only on the encoded length, never on the value's bits.
+// Use it for secret values, where data-dependent early exits could leak
+// information through timing. Values encoded in more than
+// MaxBufferWriteBytes bytes return ErrTooLong, and values that don't fit
+// into a uint64 return ErrOverflow.
+func DecodeConstantTimeFromBytes(buffer []byte) (value uint64, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	for {
+		if byteCount == MaxBufferWriteBytes {
+			err = ErrTooLong
+			return
+		}
+		if byteCount >= len(buffer) {
+			err = ErrTruncated
+			return
+		}
+		b := buffer[byteCount]
+		byteCount++
+		if b&continuationMask == 0 {
+			break
+		}
+	}
+	value, err = decodeConstantTime(buffer[:byteCount])
+	return
+}
+
+// DecodeConstantTime reads and decodes a uint64 in time that depends only on
+// the encoded length. Errors are the same as for DecodeConstantTimeFromBytes.
+func DecodeConstantTime(reader io.Reader) (value uint64, byteCount int, err error) {
+	var encoded [MaxBufferWriteBytes]byte
+	for {
+		if byteCount == MaxBufferWriteBytes {
+			err = ErrTooLong
+			return
+		}
+		if _, err = io.ReadFull(reader, encoded[byteCount:byteCount+1]); err != nil {
+			if err == io.EOF && byteCount > 0 {
+				err = ErrTruncated
+			}
+			return
+		}
+		byteCount++
+		if encoded[byteCount-1]&continuationMask == 0 {
+			break
+		}
+	}
+	value, err = decodeConstantTime(encoded[:byteCount])
+	return
+}
+
+// Decode a complete encoding without branching on any payload bits. Bits
+// that fall beyond 64 are collected and checked only once at the end.
+func decodeConstantTime(encoded []byte) (value uint64, err error) {
+	lostBits := uint64(0)
+	for i, b := range encoded {
+		payload := uint64(b & payloadMask)
+		shift := uint(i * 7)
+		value |= payload << shift
+		if shift < 64 {
+			lostBits |= payload >> (64 - shift)
+		} else {
+			lostBits |= payload
+		}
+	}
+	if lostBits != 0 {
+		value = 0
+		err = ErrOverflow
+	}
+	return
+}
+
+// EncodeConstantTimeToBytes encodes value, which is declared to be at most
+// bitWidth bits wide (1-64), in time that depends only on bitWidth. Every
+// 7-bit group that a bitWidth-wide value could need is computed and
+// written, with the continuation bits derived arithmetically rather than by
+// branching. The canonical encoding occupies the first byteCount bytes, and
+// the remaining bytes up to the group count are zero. Assumes that there's
+// enough room in buffer for (bitWidth+6)/7 bytes.
+// Returns ErrInvalidBitCount if bitWidth is out of range, or ErrOverflow if
+// value doesn't fit into bitWidth bits.
+func EncodeConstantTimeToBytes(value uint64, bitWidth int, buffer []byte) (byteCount int, err error) {
+	if bitWidth < 1 || bitWidth > 64 {
+		err = ErrInvalidBitCount
+		return
+	}
+	if value&^maskForBitCount(bitWidth) != 0 {
+		err = ErrOverflow
+		return
+	}
+	groupCount := (bitWidth + 6) / 7
+	buffer = buffer[:groupCount]
+	byteCount = 1
+	for i := range buffer {
+		more := isNonZeroBit(value >> (uint(i+1) * 7))
+		buffer[i] = byte((value>>(uint(i)*7))&payloadMask) | byte(more<<7)
+		byteCount += int(more)
+	}
+	return
+}
+
+// EncodeConstantTime encodes value as described in EncodeConstantTimeToBytes
+// and writes the canonical encoding to writer.
+func EncodeConstantTime(value uint64, bitWidth int, writer io.Writer) (byteCount int, err error) {
+	var buffer [MaxBufferWriteBytes]byte
+	if byteCount, err = EncodeConstantTimeToBytes(value, bitWidth, buffer[:]); err != nil {
+		return
+	}
+	return writer.Write(buffer[:byteCount])
+}
+
+// Returns 1 if value is nonzero, or 0 otherwise, without branching.
+func isNonZeroBit(value uint64) uint64 {
+	return (value | -value) >> 63
+}
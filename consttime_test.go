@@ -0,0 +1,111 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDecodeConstantTime(t *testing.T) {
+	for _, expected := range []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff} {
+		encoded := AppendUint64(nil, expected)
+		value, byteCount, err := DecodeConstantTimeFromBytes(encoded)
+		if err != nil || value != expected || byteCount != len(encoded) {
+			t.Errorf("Expected %x (%v bytes) but got %x (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+		value, byteCount, err = DecodeConstantTime(bytes.NewBuffer(encoded))
+		if err != nil || value != expected || byteCount != len(encoded) {
+			t.Errorf("Expected %x (%v bytes) but got %x (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+	}
+
+	// Redundant encodings decode as long as they fit.
+	if value, _, err := DecodeConstantTimeFromBytes([]byte{0x81, 0x80, 0x00}); err != nil || value != 1 {
+		t.Errorf("Expected 1 but got %v (%v)", value, err)
+	}
+}
+
+func assertDecodeConstantTimeFails(t *testing.T, data []byte, expected error) {
+	if _, _, err := DecodeConstantTimeFromBytes(data); err != expected {
+		t.Errorf("Expected %v to fail with %v but got %v", data, expected, err)
+	}
+	if _, _, err := DecodeConstantTime(bytes.NewBuffer(data)); err != expected {
+		t.Errorf("Expected %v to fail with %v from a reader but got %v", data, expected, err)
+	}
+}
+
+func TestDecodeConstantTimeErrors(t *testing.T) {
+	assertDecodeConstantTimeFails(t, nil, io.EOF)
+	assertDecodeConstantTimeFails(t, []byte{0x80}, ErrTruncated)
+	assertDecodeConstantTimeFails(t, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}, ErrOverflow)
+	assertDecodeConstantTimeFails(t, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, ErrTooLong)
+}
+
+func TestEncodeConstantTime(t *testing.T) {
+	for _, value := range []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff} {
+		expected := AppendUint64(nil, value)
+		buffer := bytes.Repeat([]byte{0xaa}, MaxBufferWriteBytes)
+		byteCount, err := EncodeConstantTimeToBytes(value, 64, buffer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(buffer[:byteCount], expected) {
+			t.Errorf("Expected %x to encode to %v but got %v", value, expected, buffer[:byteCount])
+		}
+		for _, b := range buffer[byteCount:] {
+			if b != 0 {
+				t.Errorf("Expected the bytes after the encoding of %x to be zeroed but got %v", value, buffer)
+				break
+			}
+		}
+
+		buff := &bytes.Buffer{}
+		if _, err := EncodeConstantTime(value, 64, buff); err != nil || !bytes.Equal(buff.Bytes(), expected) {
+			t.Errorf("Expected %x to encode to %v but got %v (%v)", value, expected, buff.Bytes(), err)
+		}
+	}
+
+	// A 32-bit width only touches 5 bytes.
+	buffer := bytes.Repeat([]byte{0xaa}, MaxBufferWriteBytes)
+	if byteCount, err := EncodeConstantTimeToBytes(300, 32, buffer); err != nil || byteCount != 2 {
+		t.Errorf("Expected 2 bytes but got %v (%v)", byteCount, err)
+	}
+	expected := []byte{0xac, 0x02, 0x00, 0x00, 0x00, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected %v but got %v", expected, buffer)
+	}
+}
+
+func TestEncodeConstantTimeErrors(t *testing.T) {
+	buffer := make([]byte, MaxBufferWriteBytes)
+	if _, err := EncodeConstantTimeToBytes(1, 0, buffer); err != ErrInvalidBitCount {
+		t.Errorf("Expected ErrInvalidBitCount but got %v", err)
+	}
+	if _, err := EncodeConstantTimeToBytes(1, 65, buffer); err != ErrInvalidBitCount {
+		t.Errorf("Expected ErrInvalidBitCount but got %v", err)
+	}
+	if _, err := EncodeConstantTimeToBytes(0x100, 8, buffer); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDecimal is returned when a string isn't an unsigned base-10
+// integer.
+var ErrInvalidDecimal = errors.New("uleb128: invalid unsigned decimal string")
+
+// EncodeDecimalString parses an unsigned base-10 integer of any size
+// (surrounding whitespace is ignored) and encodes it. Signs, separators and
+// other non-digit characters return ErrInvalidDecimal.
+func EncodeDecimalString(decimal string, writer io.Writer) (byteCount int, err error) {
+	asUint, asBigInt, err := parseDecimal(decimal)
+	if err != nil {
+		return
+	}
+	if asBigInt != nil {
+		return Encode(asBigInt, writer)
+	}
+	return EncodeUint64(asUint, writer)
+}
+
+func parseDecimal(decimal string) (asUint uint64, asBigInt *big.Int, err error) {
+	decimal = strings.TrimSpace(decimal)
+	if decimal == "" {
+		err = ErrInvalidDecimal
+		return
+	}
+	for i := 0; i < len(decimal); i++ {
+		if decimal[i] < '0' || decimal[i] > '9' {
+			err = ErrInvalidDecimal
+			return
+		}
+	}
+	if value, parseErr := strconv.ParseUint(decimal, 10, 64); parseErr == nil {
+		asUint = value
+		return
+	}
+	var ok bool
+	if asBigInt, ok = new(big.Int).SetString(decimal, 10); !ok {
+		asBigInt = nil
+		err = ErrInvalidDecimal
+	}
+	return
+}
+
+// DecodeToString decodes a value and returns its base-10 representation,
+// for display and logging paths that only need the value as text. Values
+// too large for a uint64 are converted straight from their 7-bit groups to
+// decimal digits, without building a big.Int. A stream that ends partway
+// through the value returns io.ErrUnexpectedEOF.
+func DecodeToString(reader io.Reader) (decimal string, byteCount int, err error) {
+	buffer := []byte{0}
+	var groups []byte
+	for {
+		if _, err = io.ReadFull(reader, buffer); err != nil {
+			if byteCount > 0 {
+				err = unexpectedEOF(err)
+			}
+			return
+		}
+		byteCount++
+		groups = append(groups, buffer[0]&payloadMask)
+		if buffer[0]&continuationMask == 0 {
+			break
+		}
+	}
+	decimal = groupsToDecimal(groups)
+	return
+}
+
+// The base of the limbs used by groupsToDecimal: the largest power of 10
+// that fits into a uint32.
+const (
+	decimalLimbBase   = 1000000000
+	decimalLimbDigits = 9
+)
+
+// Convert little-endian 7-bit groups to a base-10 string.
+func groupsToDecimal(groups []byte) string {
+	if asUint, ok := groupsToUint64(groups); ok {
+		return strconv.FormatUint(asUint, 10)
+	}
+
+	// Little-endian base 10^9 limbs, built by Horner's method from the most
+	// significant group down.
+	var limbs []uint32
+	for i := len(groups) - 1; i >= 0; i-- {
+		carry := uint64(groups[i])
+		for j, limb := range limbs {
+			x := uint64(limb)<<7 + carry
+			limbs[j] = uint32(x % decimalLimbBase)
+			carry = x / decimalLimbBase
+		}
+		for carry > 0 {
+			limbs = append(limbs, uint32(carry%decimalLimbBase))
+			carry /= decimalLimbBase
+		}
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(limbs) * decimalLimbDigits)
+	builder.WriteString(strconv.FormatUint(uint64(limbs[len(limbs)-1]), 10))
+	for i := len(limbs) - 2; i >= 0; i-- {
+		digits := strconv.FormatUint(uint64(limbs[i]), 10)
+		builder.WriteString(strings.Repeat("0", decimalLimbDigits-len(digits)))
+		builder.WriteString(digits)
+	}
+	return builder.String()
+}
+
+// Combine little-endian 7-bit groups into a uint64, if they fit.
+func groupsToUint64(groups []byte) (value uint64, ok bool) {
+	for i, group := range groups {
+		shift := uint(i) * 7
+		if group == 0 {
+			continue
+		}
+		if shift >= 64 || uint64(group)>>(64-shift) != 0 {
+			return 0, false
+		}
+		value |= uint64(group) << shift
+	}
+	return value, true
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertEncodeDecimal(t *testing.T, decimal string, expected ...byte) {
+	buff := &bytes.Buffer{}
+	byteCount, err := EncodeDecimalString(decimal, buff)
+	if err != nil {
+		t.Errorf("%q: %v", decimal, err)
+		return
+	}
+	if byteCount != len(expected) || !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %q to encode to %v but got %v", decimal, describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func assertEncodeDecimalFails(t *testing.T, decimal string) {
+	if _, err := EncodeDecimalString(decimal, &bytes.Buffer{}); err != ErrInvalidDecimal {
+		t.Errorf("Expected %q to fail with ErrInvalidDecimal but got %v", decimal, err)
+	}
+}
+
+func TestEncodeDecimalString(t *testing.T) {
+	assertEncodeDecimal(t, "0", 0x00)
+	assertEncodeDecimal(t, "300", 0xac, 0x02)
+	assertEncodeDecimal(t, " 104543565\n", 0xcd, 0xea, 0xec, 0x31)
+	assertEncodeDecimal(t, "18446744073709551615", 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)
+	assertEncodeDecimal(t, "10000000000000000000000000000",
+		0x80, 0x80, 0x80, 0x80, 0x91, 0xcc, 0xc0, 0x92, 0xbe, 0xbc, 0xb9, 0xfe, 0x84, 0x04)
+
+	assertEncodeDecimalFails(t, "")
+	assertEncodeDecimalFails(t, "  ")
+	assertEncodeDecimalFails(t, "-1")
+	assertEncodeDecimalFails(t, "+1")
+	assertEncodeDecimalFails(t, "1_000")
+	assertEncodeDecimalFails(t, "0x10")
+	assertEncodeDecimalFails(t, "99999999999999999999999999999a")
+}
+
+func TestDecodeToString(t *testing.T) {
+	for _, expected := range []string{"0", "300", "18446744073709551615", "18446744073709551616", "10000000000000000000000000000"} {
+		buff := &bytes.Buffer{}
+		encodedByteCount, err := EncodeDecimalString(expected, buff)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		actual, byteCount, err := DecodeToString(buff)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if actual != expected || byteCount != encodedByteCount {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes)", expected, encodedByteCount, actual, byteCount)
+		}
+	}
+
+	if _, _, err := DecodeToString(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an error decoding an empty stream")
+	}
+	if _, _, err := DecodeToString(bytes.NewBuffer([]byte{0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestDecodeToStringMatchesBig(t *testing.T) {
+	padded := append(bytes.Repeat([]byte{0x80}, 20), 0x00)
+	if actual, byteCount, err := DecodeToString(bytes.NewBuffer(padded)); err != nil || actual != "0" || byteCount != len(padded) {
+		t.Errorf("Expected a padded zero to give 0 but got %v (%v)", actual, err)
+	}
+
+	value := big.NewInt(1)
+	for i := 0; i < 300; i++ {
+		// Covers limb boundaries, and limbs that need zero padding.
+		value.Mul(value, big.NewInt(10))
+		for _, candidate := range []*big.Int{value, new(big.Int).Sub(value, big.NewInt(1)), new(big.Int).Add(value, big.NewInt(7))} {
+			actual, _, err := DecodeToString(bytes.NewBuffer(Append(nil, candidate)))
+			if err != nil || actual != candidate.String() {
+				t.Errorf("Expected %v but got %v (%v)", candidate, actual, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,428 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// DecoderOptions configures a Decoder. The zero value accepts everything
+// that DecodeFromBytes does.
+type DecoderOptions struct {
+	// Values encoded in more than this many bytes are rejected with
+	// ErrTooLong. If 0, there is no limit.
+	MaxByteCount int
+	// If true, values that aren't in their minimal form are rejected with
+	// ErrNonCanonical.
+	RejectNonCanonical bool
+	// If true, values that don't fit into a uint64 are rejected with
+	// ErrOverflow by every decode method, as they are by DecodeUint64.
+	RejectOverflow bool
+	// If not nil, values that don't fit into a uint64 are stored in memory
+	// obtained from this allocator rather than from the heap.
+	Allocator Allocator
+	// If true, the bytes read from a reader are wiped from the internal
+	// buffer as soon as each value is decoded, so that encoded values don't
+	// linger in reusable memory.
+	Zeroize bool
+	// If true, the decoder counts the allocations it makes, which are
+	// reported by Stats.
+	CollectStats bool
+	// If true, decoding failures other than io.EOF are returned as a
+	// *DecodeError holding the offset and bytes of the offending value.
+	// Use errors.Is to test for the underlying error.
+	DetailedErrors bool
+	// If not nil, every successfully decoded value is added to this
+	// histogram.
+	Histogram *Histogram
+	// If greater than 0, the maximum number of bytes the decoder may consume
+	// in total. A value that would take it past this returns a
+	// *DecoderBudgetError, and the decoder reads at most one byte past the
+	// budget to find that out.
+	MaxTotalByteCount int
+	// If greater than 0, the maximum number of values the decoder may
+	// decode. Decoding another value returns a *DecoderBudgetError without
+	// consuming any input.
+	MaxValueCount int
+	// If true, input that ends cleanly between values returns ErrTruncated
+	// rather than io.EOF. Use this when a value is known to be present, such
+	// as within a framed message, so that a missing value isn't mistaken for
+	// the end of a stream.
+	ExpectValue bool
+	// If not nil, called with each successfully decoded value and the bytes
+	// it was decoded from.
+	AfterDecode DecodeHook
+}
+
+// DecodeHook is called with a decoded value and the bytes it was decoded
+// from, which are only valid until the hook returns. If it returns an error,
+// the decoder call returns that error instead of the value.
+type DecodeHook func(value Value, encoded []byte) error
+
+// DecoderBudgetError is returned when a Decoder reaches
+// DecoderOptions.MaxTotalByteCount or DecoderOptions.MaxValueCount.
+type DecoderBudgetError struct {
+	// True if the value budget was reached, false if the byte budget was.
+	Values bool
+	// The budget that was reached.
+	Max int
+	// The number of bytes (or values) consumed before the failed call.
+	Count int
+}
+
+func (e *DecoderBudgetError) Error() string {
+	unit := "bytes"
+	if e.Values {
+		unit = "values"
+	}
+	return fmt.Sprintf("uleb128: decoding past %v %v would exceed the budget of %v %v",
+		e.Count, unit, e.Max, unit)
+}
+
+// The maximum number of offending bytes that a DecodeError holds.
+const maxDecodeErrorBytes = 16
+
+// DecodeError describes a value that failed to decode, for logging without
+// needing to reproduce the input.
+type DecodeError struct {
+	// The underlying error (ErrTruncated, ErrTooLong, etc).
+	Err error
+	// The offset in the decoder's input where the offending value starts.
+	Offset int
+	// The first bytes of the offending value (at most 16).
+	Bytes []byte
+	// The total number of bytes of the offending value that were consumed.
+	ByteCount int
+}
+
+func (e *DecodeError) Error() string {
+	ellipsis := ""
+	if e.ByteCount > len(e.Bytes) {
+		ellipsis = " ..."
+	}
+	return fmt.Sprintf("%v at offset %v (bytes [% x%v])", e.Err, e.Offset, e.Bytes, ellipsis)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Build a *DecodeError for the offending bytes encoded, found at offset.
+func newDecodeError(err error, offset int, encoded []byte) *DecodeError {
+	snippet := encoded
+	if len(snippet) > maxDecodeErrorBytes {
+		snippet = snippet[:maxDecodeErrorBytes]
+	}
+	return &DecodeError{
+		Err:       err,
+		Offset:    offset,
+		Bytes:     append([]byte(nil), snippet...),
+		ByteCount: len(encoded),
+	}
+}
+
+// Decoder decodes consecutive ULEB128 values from either a reader or a byte
+// slice. It returns io.EOF when the input ends cleanly between values (unless
+// DecoderOptions.ExpectValue is set), and ErrTruncated when it ends partway
+// through one.
+type Decoder struct {
+	reader     io.Reader
+	remaining  []byte
+	options    DecoderOptions
+	scratch    []byte
+	byteBuffer []byte
+	byteCount  int
+	valueCount int
+	stats      Stats
+}
+
+// NewDecoder returns a Decoder that reads from reader. The Decoder reads
+// one byte at a time and never reads past the end of a value, so wrap slow
+// readers in a bufio.Reader. Values already held in the buffer of a
+// *bufio.Reader or *bytes.Reader are taken from it directly.
+func NewDecoder(reader io.Reader, options DecoderOptions) *Decoder {
+	return &Decoder{
+		reader:     reader,
+		options:    options,
+		scratch:    make([]byte, 0, MaxBufferWriteBytes),
+		byteBuffer: []byte{0},
+	}
+}
+
+// StrictDecoderOptions returns the options for decoding untrusted input
+// that must be exactly as this package would encode it: values must be
+// canonical and fit into a uint64 (and so be at most 10 bytes long), and
+// failures are reported as a *DecodeError. Since no value needs more than a
+// uint64, the decoder never allocates while decoding.
+func StrictDecoderOptions() DecoderOptions {
+	return DecoderOptions{
+		MaxByteCount:       MaxBufferWriteBytes,
+		RejectNonCanonical: true,
+		RejectOverflow:     true,
+		DetailedErrors:     true,
+	}
+}
+
+// NewStrictDecoder returns a Decoder that reads from reader using
+// StrictDecoderOptions.
+func NewStrictDecoder(reader io.Reader) *Decoder {
+	return NewDecoder(reader, StrictDecoderOptions())
+}
+
+// NewBytesDecoder returns a Decoder that decodes directly from buffer.
+func NewBytesDecoder(buffer []byte, options DecoderOptions) *Decoder {
+	return &Decoder{
+		remaining: buffer,
+		options:   options,
+	}
+}
+
+// Decode the next value.
+// If the result is small enough to fit into type uint64, asBigInt will be nil
+// and asUint will contain the result.
+func (d *Decoder) Decode() (asUint uint64, asBigInt *big.Int, byteCount int, err error) {
+	return d.decode(false)
+}
+
+// DecodeUint64 decodes the next value, returning ErrOverflow if it doesn't
+// fit into a uint64.
+func (d *Decoder) DecodeUint64() (value uint64, byteCount int, err error) {
+	value, _, byteCount, err = d.decode(true)
+	return
+}
+
+func (d *Decoder) decode(requireUint64 bool) (asUint uint64, asBigInt *big.Int, byteCount int, err error) {
+	start := d.byteCount
+	encoded, err := d.nextInBudget()
+	defer d.wipe()
+	byteCount = len(encoded)
+	d.byteCount += byteCount
+	if err == nil && d.options.RejectNonCanonical && !isCanonical(encoded) {
+		err = ErrNonCanonical
+	}
+	if err != nil {
+		err = d.detailedError(err, start, encoded)
+		return
+	}
+	if len(encoded) > 9 {
+		asBigInt = setBigFromEncoded(d.newBig(encoded), encoded)
+		if asBigInt.BitLen() <= 64 {
+			asUint = asBigInt.Uint64()
+			asBigInt = nil
+		}
+	} else {
+		asUint, asBigInt, _, err = DecodeFromBytes(encoded)
+	}
+	if asBigInt != nil && (requireUint64 || d.options.RejectOverflow) {
+		asBigInt = nil
+		err = d.detailedError(ErrOverflow, start, encoded)
+		return
+	}
+	if hook := d.options.AfterDecode; hook != nil {
+		if err = hook(NewValue(asUint, asBigInt), encoded); err != nil {
+			asUint, asBigInt = 0, nil
+			err = d.detailedError(err, start, encoded)
+			return
+		}
+	}
+	if histogram := d.options.Histogram; histogram != nil {
+		if asBigInt != nil {
+			histogram.AddBig(asBigInt)
+		} else {
+			histogram.Add(asUint)
+		}
+	}
+	return
+}
+
+// DecodeBig decodes the next value as a math.big.Int regardless of its
+// magnitude, storing it in result (reusing its storage) if it's not nil, or
+// in a new big.Int (from the allocator, if there is one) otherwise.
+func (d *Decoder) DecodeBig(result *big.Int) (value *big.Int, byteCount int, err error) {
+	start := d.byteCount
+	encoded, err := d.nextInBudget()
+	defer d.wipe()
+	byteCount = len(encoded)
+	d.byteCount += byteCount
+	if err == nil && d.options.RejectNonCanonical && !isCanonical(encoded) {
+		err = ErrNonCanonical
+	}
+	if err == nil && d.options.RejectOverflow {
+		_, err = decodeConstantTime(encoded)
+	}
+	if err != nil {
+		err = d.detailedError(err, start, encoded)
+		return
+	}
+	if result == nil {
+		result = d.newBig(encoded)
+	} else if wordCount := wordCountForEncoded(encoded); cap(result.Bits()) < wordCount {
+		result.SetBits(make([]big.Word, 0, wordCount))
+		d.countAllocations(1)
+	}
+	value = setBigFromEncoded(result, encoded)
+	if hook := d.options.AfterDecode; hook != nil {
+		if err = hook(BigValue(value), encoded); err != nil {
+			value = nil
+			err = d.detailedError(err, start, encoded)
+			return
+		}
+	}
+	if d.options.Histogram != nil {
+		d.options.Histogram.AddBig(value)
+	}
+	return
+}
+
+// Stats returns the allocations this decoder has made since it was created.
+// Only collected if DecoderOptions.CollectStats is set.
+func (d *Decoder) Stats() Stats {
+	return d.stats
+}
+
+// Get a big.Int with enough capacity to decode encoded, from the allocator
+// or else the heap.
+func (d *Decoder) newBig(encoded []byte) *big.Int {
+	allocator := d.options.Allocator
+	if allocator == nil {
+		allocator = heapAllocator{}
+		d.countAllocations(2)
+	}
+	return newBigForEncoded(allocator, encoded)
+}
+
+func (d *Decoder) countAllocations(count int) {
+	if d.options.CollectStats {
+		d.stats.Allocations += count
+	}
+}
+
+// ByteCount returns the total number of bytes this decoder has consumed.
+func (d *Decoder) ByteCount() int {
+	return d.byteCount
+}
+
+func (d *Decoder) detailedError(err error, offset int, encoded []byte) error {
+	if !d.options.DetailedErrors || err == io.EOF {
+		return err
+	}
+	return newDecodeError(err, offset, encoded)
+}
+
+func (d *Decoder) wipe() {
+	if d.options.Zeroize && d.reader != nil {
+		wipeBytes(d.scratch)
+		wipeBytes(d.byteBuffer)
+	}
+}
+
+// Read the bytes of the next value like next, but fail with a
+// *DecoderBudgetError if that would go over the decoder's budgets.
+func (d *Decoder) nextInBudget() (encoded []byte, err error) {
+	if max := d.options.MaxValueCount; max > 0 && d.valueCount >= max {
+		err = &DecoderBudgetError{Values: true, Max: max, Count: d.valueCount}
+		return
+	}
+	max := d.options.MaxTotalByteCount
+	if max > 0 && d.byteCount > max {
+		err = &DecoderBudgetError{Max: max, Count: d.byteCount}
+		return
+	}
+	encoded, err = d.next()
+	if max > 0 && err != io.EOF && d.byteCount+len(encoded) > max {
+		err = &DecoderBudgetError{Max: max, Count: d.byteCount}
+	}
+	if err == nil {
+		d.valueCount++
+	}
+	return
+}
+
+// Read the bytes of the next value, stopping early if the value is longer
+// than the maximum byte count, or goes more than one byte past the total
+// byte budget.
+func (d *Decoder) next() (encoded []byte, err error) {
+	maxByteCount := d.options.MaxByteCount
+	if d.options.MaxTotalByteCount > 0 {
+		remaining := d.options.MaxTotalByteCount - d.byteCount + 1
+		if maxByteCount == 0 || remaining < maxByteCount {
+			maxByteCount = remaining
+		}
+	}
+	if d.reader == nil {
+		end := 0
+		for {
+			if end == len(d.remaining) {
+				err = ErrTruncated
+				break
+			}
+			if maxByteCount > 0 && end == maxByteCount {
+				err = ErrTooLong
+				break
+			}
+			end++
+			if d.remaining[end-1]&continuationMask == 0 {
+				break
+			}
+		}
+		if end == 0 && err == ErrTruncated && !d.options.ExpectValue {
+			err = io.EOF
+		}
+		encoded = d.remaining[:end]
+		d.remaining = d.remaining[end:]
+		return
+	}
+
+	if encoded = readBufferedValue(d.reader, d.scratch[:0], maxByteCount); encoded != nil {
+		d.scratch = append(d.scratch[:0], encoded...)
+		encoded = d.scratch
+		return
+	}
+
+	d.scratch = d.scratch[:0]
+	byteBuffer := d.byteBuffer
+	for {
+		if maxByteCount > 0 && len(d.scratch) == maxByteCount {
+			err = ErrTooLong
+			break
+		}
+		if _, err = io.ReadFull(d.reader, byteBuffer); err != nil {
+			if err == io.EOF && (len(d.scratch) > 0 || d.options.ExpectValue) {
+				err = ErrTruncated
+			}
+			break
+		}
+		capacity := cap(d.scratch)
+		d.scratch = append(d.scratch, byteBuffer[0])
+		if cap(d.scratch) != capacity && d.options.CollectStats {
+			d.stats.Allocations++
+			d.stats.BufferGrowths++
+		}
+		if byteBuffer[0]&continuationMask == 0 {
+			break
+		}
+	}
+	encoded = d.scratch
+	return
+}
@@ -0,0 +1,330 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+type decodeResult struct {
+	value     uint64
+	byteCount int
+	err       error
+}
+
+func assertDecoder(t *testing.T, options DecoderOptions, data []byte, expected ...decodeResult) {
+	decoders := map[string]*Decoder{
+		"reader": NewDecoder(bytes.NewBuffer(data), options),
+		"bytes":  NewBytesDecoder(data, options),
+	}
+	for name, decoder := range decoders {
+		for _, expectedResult := range expected {
+			value, byteCount, err := decoder.DecodeUint64()
+			if err != expectedResult.err || byteCount != expectedResult.byteCount || (err == nil && value != expectedResult.value) {
+				t.Errorf("%v decoder on %v: Expected (%x, %v, %v) but got (%x, %v, %v)",
+					name, describe.D(data), expectedResult.value, expectedResult.byteCount, expectedResult.err, value, byteCount, err)
+				break
+			}
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	assertDecoder(t, DecoderOptions{}, nil, decodeResult{0, 0, io.EOF})
+	assertDecoder(t, DecoderOptions{}, []byte{0x01, 0x80, 0x01},
+		decodeResult{1, 1, nil},
+		decodeResult{0x80, 2, nil},
+		decodeResult{0, 0, io.EOF})
+	assertDecoder(t, DecoderOptions{}, []byte{0x01, 0x80},
+		decodeResult{1, 1, nil},
+		decodeResult{0, 1, ErrTruncated})
+	assertDecoder(t, DecoderOptions{}, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02},
+		decodeResult{0, 10, ErrOverflow})
+}
+
+func TestDecoderLimits(t *testing.T) {
+	assertDecoder(t, DecoderOptions{MaxByteCount: 2}, []byte{0xff, 0x7f, 0x80, 0x80, 0x01},
+		decodeResult{0x3fff, 2, nil},
+		decodeResult{0, 2, ErrTooLong})
+	assertDecoder(t, DecoderOptions{RejectNonCanonical: true}, []byte{0x00, 0x80, 0x00},
+		decodeResult{0, 1, nil},
+		decodeResult{0, 2, ErrNonCanonical})
+	assertDecoder(t, DecoderOptions{}, []byte{0x80, 0x00},
+		decodeResult{0, 2, nil})
+}
+
+func TestDecoderByteCount(t *testing.T) {
+	decoder := NewBytesDecoder([]byte{0x01, 0x80, 0x01, 0x05}, DecoderOptions{})
+	for i := 0; i < 3; i++ {
+		decoder.Decode()
+	}
+	if decoder.ByteCount() != 4 {
+		t.Errorf("Expected byte count of 4 but got %v", decoder.ByteCount())
+	}
+}
+
+func TestDecoderDecodeBig(t *testing.T) {
+	data := []byte{0x05, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02, 0x80, 0x00}
+	decoder := NewBytesDecoder(data, DecoderOptions{})
+	result := new(big.Int)
+	for _, expected := range []string{"5", "18446744073709551616", "0"} {
+		value, _, err := decoder.DecodeBig(result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if value != result {
+			t.Errorf("Expected DecodeBig to reuse the supplied big.Int")
+		}
+		if value.String() != expected {
+			t.Errorf("Expected %v but got %v", expected, value)
+		}
+	}
+
+	if _, _, err := DecodeBigFromBytes([]byte{0x80}, nil); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+	if value, _, err := DecodeBigFromBytes([]byte{0x7f}, nil); err != nil || value.Int64() != 0x7f {
+		t.Errorf("Expected 0x7f but got %v (%v)", value, err)
+	}
+}
+
+func TestDecoderZeroize(t *testing.T) {
+	data := AppendUint64(nil, 0x0123456789abcdef)
+	decoder := NewDecoder(bytes.NewBuffer(data), DecoderOptions{Zeroize: true})
+	if v, _, err := decoder.DecodeUint64(); err != nil || v != 0x0123456789abcdef {
+		t.Errorf("Expected 0x0123456789abcdef but got %x (%v)", v, err)
+	}
+	for _, b := range decoder.scratch[:cap(decoder.scratch)] {
+		if b != 0 {
+			t.Errorf("Expected the scratch buffer to be wiped but got %v", describe.D(decoder.scratch[:cap(decoder.scratch)]))
+			break
+		}
+	}
+	if decoder.byteBuffer[0] != 0 {
+		t.Errorf("Expected the byte buffer to be wiped but got %v", decoder.byteBuffer[0])
+	}
+}
+
+func assertDecodeError(t *testing.T, options DecoderOptions, data []byte, skip int, expectedErr error, expectedOffset int, expectedBytes []byte) {
+	options.DetailedErrors = true
+	decoders := map[string]*Decoder{
+		"reader": NewDecoder(bytes.NewBuffer(data), options),
+		"bytes":  NewBytesDecoder(data, options),
+	}
+	for name, decoder := range decoders {
+		for i := 0; i < skip; i++ {
+			decoder.Decode()
+		}
+		_, _, err := decoder.DecodeUint64()
+		decodeErr, ok := err.(*DecodeError)
+		if !ok {
+			t.Errorf("%v decoder: Expected a *DecodeError but got %v", name, err)
+			continue
+		}
+		if !errors.Is(err, expectedErr) || decodeErr.Offset != expectedOffset || !bytes.Equal(decodeErr.Bytes, expectedBytes) {
+			t.Errorf("%v decoder: Expected %v at offset %v with bytes %v but got %v", name, expectedErr, expectedOffset, describe.D(expectedBytes), err)
+		}
+	}
+}
+
+func TestDecoderDetailedErrors(t *testing.T) {
+	assertDecodeError(t, DecoderOptions{}, []byte{0x01, 0x80, 0x80}, 1, ErrTruncated, 1, []byte{0x80, 0x80})
+	assertDecodeError(t, DecoderOptions{RejectNonCanonical: true}, []byte{0x01, 0x02, 0x81, 0x00}, 2, ErrNonCanonical, 2, []byte{0x81, 0x00})
+	assertDecodeError(t, DecoderOptions{MaxByteCount: 2}, []byte{0x80, 0x80, 0x80, 0x01}, 0, ErrTooLong, 0, []byte{0x80, 0x80})
+
+	tooBig := append(bytes.Repeat([]byte{0xff}, 20), 0x01)
+	assertDecodeError(t, DecoderOptions{}, tooBig, 0, ErrOverflow, 0, tooBig[:16])
+
+	decoder := NewBytesDecoder(tooBig, DecoderOptions{DetailedErrors: true})
+	_, _, err := decoder.DecodeUint64()
+	expected := "uleb128: value overflows uint64 at offset 0 (bytes [ff ff ff ff ff ff ff ff ff ff ff ff ff ff ff ff ...])"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q but got %q", expected, err)
+	}
+
+	// Clean end of input is never wrapped.
+	if _, _, err := NewBytesDecoder(nil, DecoderOptions{DetailedErrors: true}).DecodeUint64(); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if _, _, err := NewBytesDecoder([]byte{0x80}, DecoderOptions{DetailedErrors: true}).DecodeBig(nil); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+}
+
+func assertBudgetError(t *testing.T, err error, values bool, max int, count int) {
+	budgetErr, ok := err.(*DecoderBudgetError)
+	if !ok {
+		t.Errorf("Expected a *DecoderBudgetError but got %v", err)
+		return
+	}
+	if budgetErr.Values != values || budgetErr.Max != max || budgetErr.Count != count {
+		t.Errorf("Expected budget %v/%v/%v but got %v", values, max, count, describe.D(budgetErr))
+	}
+}
+
+func TestDecoderBudget(t *testing.T) {
+	data := []byte{0x01, 0xac, 0x02, 0x80, 0x80, 0x80, 0x01, 0x05}
+	for _, decoder := range []*Decoder{
+		NewBytesDecoder(data, DecoderOptions{MaxTotalByteCount: 4}),
+		NewDecoder(bytes.NewReader(data), DecoderOptions{MaxTotalByteCount: 4}),
+	} {
+		assertDecoderValue(t, decoder, 1)
+		assertDecoderValue(t, decoder, 300)
+		_, _, err := decoder.DecodeUint64()
+		assertBudgetError(t, err, false, 4, 3)
+		if decoder.ByteCount() != 5 {
+			t.Errorf("Expected to stop one byte past the budget but consumed %v", decoder.ByteCount())
+		}
+		_, _, err = decoder.DecodeBig(nil)
+		assertBudgetError(t, err, false, 4, 5)
+	}
+
+	// Exactly exhausting the budget is fine, and a clean end is still io.EOF.
+	decoder := NewBytesDecoder(data[:3], DecoderOptions{MaxTotalByteCount: 3})
+	assertDecoderValue(t, decoder, 1)
+	assertDecoderValue(t, decoder, 300)
+	if _, _, _, err := decoder.Decode(); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+
+	decoder = NewBytesDecoder(data, DecoderOptions{MaxValueCount: 2, DetailedErrors: true})
+	assertDecoderValue(t, decoder, 1)
+	assertDecoderValue(t, decoder, 300)
+	_, _, _, err := decoder.Decode()
+	var budgetErr *DecoderBudgetError
+	if !errors.As(err, &budgetErr) || !budgetErr.Values || budgetErr.Max != 2 || budgetErr.Count != 2 {
+		t.Errorf("Expected the value budget to be reached but got %v", err)
+	}
+	if decoder.ByteCount() != 3 {
+		t.Errorf("Expected no input to be consumed but consumed %v", decoder.ByteCount())
+	}
+}
+
+func assertDecoderValue(t *testing.T, decoder *Decoder, expected uint64) {
+	value, _, err := decoder.DecodeUint64()
+	if err != nil || value != expected {
+		t.Errorf("Expected %v but got %v (%v)", expected, value, err)
+	}
+}
+
+func TestDecoderExpectValue(t *testing.T) {
+	for _, expectValue := range []bool{false, true} {
+		expected := io.EOF
+		if expectValue {
+			expected = ErrTruncated
+		}
+		options := DecoderOptions{ExpectValue: expectValue}
+		for name, decoder := range map[string]*Decoder{
+			"bytes":  NewBytesDecoder([]byte{0x05}, options),
+			"reader": NewDecoder(ioutil.NopCloser(bytes.NewReader([]byte{0x05})), options),
+		} {
+			assertDecoderValue(t, decoder, 5)
+			if _, _, err := decoder.DecodeUint64(); err != expected {
+				t.Errorf("%v decoder with ExpectValue %v: Expected %v but got %v", name, expectValue, expected, err)
+			}
+		}
+	}
+
+	decoder := NewBytesDecoder(nil, DecoderOptions{ExpectValue: true, DetailedErrors: true})
+	if _, _, err := decoder.DecodeBig(nil); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+}
+
+func TestDecoderAfterDecode(t *testing.T) {
+	errRejected := errors.New("rejected")
+	var seen []Value
+	options := DecoderOptions{
+		DetailedErrors: true,
+		AfterDecode: func(value Value, encoded []byte) error {
+			if len(encoded) != value.EncodedSize() {
+				return ErrNonCanonical
+			}
+			seen = append(seen, value)
+			if value.Cmp(Uint64Value(1000)) > 0 {
+				return errRejected
+			}
+			return nil
+		},
+	}
+	decoder := NewBytesDecoder([]byte{0xac, 0x02, 0x05, 0x81, 0x00, 0xd0, 0x0f, 0x07}, options)
+	assertDecoderValue(t, decoder, 300)
+	if value, _, err := decoder.DecodeBig(nil); err != nil || value.Int64() != 5 {
+		t.Errorf("Expected 5 but got %v (%v)", value, err)
+	}
+	if _, _, err := decoder.DecodeUint64(); !errors.Is(err, ErrNonCanonical) {
+		t.Errorf("Expected the hook to reject a non-canonical value but got %v", err)
+	}
+	_, _, err := decoder.DecodeUint64()
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Err != errRejected || decodeErr.Offset != 5 {
+		t.Errorf("Expected the hook to reject 2000 at offset 5 but got %v", err)
+	}
+	assertDecoderValue(t, decoder, 7)
+	if expected := "[300 5 2000 7]"; fmt.Sprint(seen) != expected {
+		t.Errorf("Expected the hook to see %v but got %v", expected, seen)
+	}
+}
+
+func TestStrictDecoder(t *testing.T) {
+	maxUint64 := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	for _, test := range []struct {
+		encoded []byte
+		err     error
+	}{
+		{[]byte{0xac, 0x02}, nil},
+		{maxUint64, nil},
+		{[]byte{0xac, 0x82, 0x00}, ErrNonCanonical},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}, ErrOverflow},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, ErrTooLong},
+		{[]byte{0xac}, ErrTruncated},
+	} {
+		for _, decode := range []func(*Decoder) error{
+			func(d *Decoder) error { _, _, _, err := d.Decode(); return err },
+			func(d *Decoder) error { _, _, err := d.DecodeBig(nil); return err },
+			func(d *Decoder) error { _, _, err := d.DecodeUint64(); return err },
+		} {
+			decoder := NewStrictDecoder(ioutil.NopCloser(bytes.NewReader(test.encoded)))
+			err := decode(decoder)
+			if !errors.Is(err, test.err) {
+				t.Errorf("Expected %v to give %v but got %v", describe.D(test.encoded), test.err, err)
+			}
+			if _, ok := err.(*DecodeError); err != nil && !ok {
+				t.Errorf("Expected a *DecodeError but got %v", err)
+			}
+		}
+	}
+
+	decoder := NewStrictDecoder(bytes.NewReader(maxUint64))
+	decoder.DecodeUint64()
+	if _, _, err := decoder.DecodeUint64(); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrMalformedDelta is returned when a delta instruction is invalid, or
+// refers to bytes outside of the source.
+var ErrMalformedDelta = errors.New("uleb128: malformed delta instruction")
+
+// DeltaOp identifies the kind of a delta instruction.
+type DeltaOp byte
+
+const (
+	// Append Data to the output.
+	DeltaAdd DeltaOp = iota
+	// Append Length bytes from the source, starting at Offset.
+	DeltaCopy
+	// Append Length copies of Byte.
+	DeltaRun
+)
+
+// DeltaInstruction is a single VCDIFF-style delta instruction.
+//
+// Each instruction is encoded as an op byte, followed by the ULEB128 length,
+// followed by the op's operand: the source offset as a ULEB128 for copy, the
+// literal bytes for add, or the repeated byte for run.
+type DeltaInstruction struct {
+	Op     DeltaOp
+	Offset uint64
+	Length uint64
+	Data   []byte
+	Byte   byte
+}
+
+// EncodedSizeDeltaInstruction returns the number of bytes required to encode
+// this instruction.
+func EncodedSizeDeltaInstruction(instruction DeltaInstruction) int {
+	switch instruction.Op {
+	case DeltaAdd:
+		return 1 + EncodedSizeUint64(uint64(len(instruction.Data))) + len(instruction.Data)
+	case DeltaCopy:
+		return 1 + EncodedSizeUint64(instruction.Length) + EncodedSizeUint64(instruction.Offset)
+	default:
+		return 1 + EncodedSizeUint64(instruction.Length) + 1
+	}
+}
+
+// Encode a delta instruction. The length of an add instruction is taken
+// from its data.
+func EncodeDeltaInstruction(instruction DeltaInstruction, writer io.Writer) (byteCount int, err error) {
+	buffer := make([]byte, 0, EncodedSizeDeltaInstruction(instruction))
+	buffer = append(buffer, byte(instruction.Op))
+	switch instruction.Op {
+	case DeltaAdd:
+		buffer = AppendUint64(buffer, uint64(len(instruction.Data)))
+		buffer = append(buffer, instruction.Data...)
+	case DeltaCopy:
+		buffer = AppendUint64(buffer, instruction.Length)
+		buffer = AppendUint64(buffer, instruction.Offset)
+	case DeltaRun:
+		buffer = AppendUint64(buffer, instruction.Length)
+		buffer = append(buffer, instruction.Byte)
+	default:
+		err = ErrMalformedDelta
+		return
+	}
+	return writer.Write(buffer)
+}
+
+// Decode a delta instruction, rejecting any instruction longer than maxLength
+// bytes. Returns io.EOF if the stream ends cleanly between instructions.
+func DecodeDeltaInstruction(reader io.Reader, maxLength int) (instruction DeltaInstruction, byteCount int, err error) {
+	buffer := []byte{0}
+	if _, err = io.ReadFull(reader, buffer); err != nil {
+		return
+	}
+	byteCount = 1
+	instruction.Op = DeltaOp(buffer[0])
+	if instruction.Op > DeltaRun {
+		err = ErrMalformedDelta
+		return
+	}
+
+	length, fieldByteCount, err := DecodeLength(reader, maxLength, 1)
+	byteCount += fieldByteCount
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	instruction.Length = uint64(length)
+
+	switch instruction.Op {
+	case DeltaAdd:
+		instruction.Data = make([]byte, length)
+		fieldByteCount, err = io.ReadFull(reader, instruction.Data)
+	case DeltaCopy:
+		instruction.Offset, fieldByteCount, err = decodeUint64(reader, buffer)
+	case DeltaRun:
+		fieldByteCount, err = io.ReadFull(reader, buffer)
+		instruction.Byte = buffer[0]
+	}
+	byteCount += fieldByteCount
+	err = unexpectedEOF(err)
+	return
+}
+
+// ApplyDelta reconstructs a target from source and a list of delta
+// instructions, refusing to build an output larger than maxOutputSize bytes.
+func ApplyDelta(source []byte, instructions []DeltaInstruction, maxOutputSize int) (target []byte, err error) {
+	outputSize := uint64(0)
+	for _, instruction := range instructions {
+		length := instruction.Length
+		if instruction.Op == DeltaAdd {
+			length = uint64(len(instruction.Data))
+		}
+		if instruction.Op == DeltaCopy &&
+			(instruction.Offset > uint64(len(source)) || length > uint64(len(source))-instruction.Offset) {
+			err = ErrMalformedDelta
+			return
+		}
+		if length > uint64(maxOutputSize)-outputSize {
+			err = &LengthError{Length: outputSize + length, MaxLength: maxOutputSize, ElementSize: 1}
+			return
+		}
+		outputSize += length
+	}
+
+	target = make([]byte, 0, outputSize)
+	for _, instruction := range instructions {
+		switch instruction.Op {
+		case DeltaAdd:
+			target = append(target, instruction.Data...)
+		case DeltaCopy:
+			target = append(target, source[instruction.Offset:instruction.Offset+instruction.Length]...)
+		case DeltaRun:
+			for i := uint64(0); i < instruction.Length; i++ {
+				target = append(target, instruction.Byte)
+			}
+		default:
+			target = nil
+			err = ErrMalformedDelta
+			return
+		}
+	}
+	return
+}
+
+// Convert an io.EOF that happens partway through a structure into
+// io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertDeltaInstruction(t *testing.T, instruction DeltaInstruction, expectedBytes ...byte) {
+	expectedByteCount := EncodedSizeDeltaInstruction(instruction)
+	buff := &bytes.Buffer{}
+	actualByteCount, err := EncodeDeltaInstruction(instruction, buff)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("Expected %v to encode to a byte count of %v but got %v", describe.D(instruction), expectedByteCount, actualByteCount)
+		return
+	}
+	if !reflect.DeepEqual(buff.Bytes(), expectedBytes) {
+		t.Errorf("Expected %v to encode to %v but got %v", describe.D(instruction), describe.D(expectedBytes), describe.D(buff.Bytes()))
+		return
+	}
+
+	actual, actualByteCount, err := DecodeDeltaInstruction(buff, 1000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("Expected decoding %v to have a byte count of %v but got %v", describe.D(expectedBytes), expectedByteCount, actualByteCount)
+		return
+	}
+	if !reflect.DeepEqual(actual, instruction) {
+		t.Errorf("Expected %v to decode to %v but got %v", describe.D(expectedBytes), describe.D(instruction), describe.D(actual))
+	}
+}
+
+func assertDecodeDeltaFails(t *testing.T, expectedErr error, b ...byte) {
+	_, _, err := DecodeDeltaInstruction(bytes.NewBuffer(b), 10)
+	if err != expectedErr {
+		t.Errorf("Expected decoding %v to fail with %v but got %v", describe.D(b), expectedErr, err)
+	}
+}
+
+func TestDeltaInstructions(t *testing.T) {
+	assertDeltaInstruction(t, DeltaInstruction{Op: DeltaAdd, Length: 2, Data: []byte{0x0a, 0x0b}}, 0x00, 0x02, 0x0a, 0x0b)
+	assertDeltaInstruction(t, DeltaInstruction{Op: DeltaCopy, Offset: 300, Length: 128}, 0x01, 0x80, 0x01, 0xac, 0x02)
+	assertDeltaInstruction(t, DeltaInstruction{Op: DeltaRun, Length: 5, Byte: 0xff}, 0x02, 0x05, 0xff)
+}
+
+func TestDeltaDecodeErrors(t *testing.T) {
+	assertDecodeDeltaFails(t, io.EOF)
+	assertDecodeDeltaFails(t, ErrMalformedDelta, 0x03, 0x00)
+	assertDecodeDeltaFails(t, io.ErrUnexpectedEOF, 0x00)
+	assertDecodeDeltaFails(t, io.ErrUnexpectedEOF, 0x00, 0x02, 0x01)
+	assertDecodeDeltaFails(t, io.ErrUnexpectedEOF, 0x01, 0x02, 0x80)
+	assertDecodeDeltaFails(t, io.ErrUnexpectedEOF, 0x02, 0x02)
+
+	_, _, err := DecodeDeltaInstruction(bytes.NewBuffer([]byte{0x00, 0x0b}), 10)
+	if _, ok := err.(*LengthError); !ok {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	source := []byte("the quick brown fox")
+	instructions := []DeltaInstruction{
+		{Op: DeltaCopy, Offset: 4, Length: 6},
+		{Op: DeltaAdd, Data: []byte("red ")},
+		{Op: DeltaRun, Length: 3, Byte: 'z'},
+		{Op: DeltaCopy, Offset: 16, Length: 3},
+	}
+	target, err := ApplyDelta(source, instructions, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(target) != "quick red zzzfox" {
+		t.Errorf("Expected \"quick red zzzfox\" but got %q", target)
+	}
+
+	if _, err = ApplyDelta(source, instructions, 15); err == nil {
+		t.Errorf("Expected output limit to be exceeded")
+	}
+	if _, err = ApplyDelta(source, []DeltaInstruction{{Op: DeltaCopy, Offset: 16, Length: 4}}, 100); err != ErrMalformedDelta {
+		t.Errorf("Expected ErrMalformedDelta but got %v", err)
+	}
+}
+
+func TestDecodeDeltaEndlessLength(t *testing.T) {
+	reader := &continuationReader{}
+	_, _, err := DecodeDeltaInstruction(io.MultiReader(bytes.NewReader([]byte{byte(DeltaRun)}), reader), 100)
+	if lengthError, ok := err.(*LengthError); !ok || lengthError.Length != math.MaxUint64 {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+	if reader.byteCount > MaxBufferWriteBytes {
+		t.Errorf("Expected to read at most %v bytes but read %v", MaxBufferWriteBytes, reader.byteCount)
+	}
+}
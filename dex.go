@@ -0,0 +1,67 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math"
+)
+
+// EncodeUleb128p1 encodes a value in the DEX uleb128p1 form, which stores
+// value+1 as ULEB128 so that -1 (commonly used as "no index") encodes as the
+// single byte 0x00. Returns the number of bytes encoded.
+func EncodeUleb128p1(value int32, writer io.Writer) (byteCount int, err error) {
+	return EncodeUint64(uint64(uint32(value)+1), writer)
+}
+
+// AppendUleb128p1 appends the uleb128p1 encoding of value to buffer,
+// returning the extended buffer.
+func AppendUleb128p1(buffer []byte, value int32) []byte {
+	return AppendUint64(buffer, uint64(uint32(value)+1))
+}
+
+// DecodeUleb128p1 decodes a uleb128p1 value. A stream that ends partway
+// through the value returns io.ErrUnexpectedEOF, and an encoding wider than
+// 32 bits returns ErrOverflow.
+func DecodeUleb128p1(reader io.Reader) (value int32, byteCount int, err error) {
+	asUint, byteCount, err := decodeUint64(reader, []byte{0})
+	if err != nil {
+		return
+	}
+	if asUint > math.MaxUint32 {
+		err = ErrOverflow
+		return
+	}
+	value = int32(uint32(asUint) - 1)
+	return
+}
+
+// DecodeUleb128p1FromBytes decodes a uleb128p1 value from the start of
+// buffer. Errors are the same as for DecodeFromBytes, plus ErrOverflow if
+// the encoding is wider than 32 bits.
+func DecodeUleb128p1FromBytes(buffer []byte) (value int32, byteCount int, err error) {
+	asUint, byteCount, err := decodeUintFromBytes(buffer, 32)
+	if err != nil {
+		return
+	}
+	value = int32(uint32(asUint) - 1)
+	return
+}
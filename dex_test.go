@@ -0,0 +1,79 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestUleb128p1(t *testing.T) {
+	for _, test := range []struct {
+		value   int32
+		encoded []byte
+	}{
+		{-1, []byte{0x00}},
+		{0, []byte{0x01}},
+		{1, []byte{0x02}},
+		{126, []byte{0x7f}},
+		{127, []byte{0x80, 0x01}},
+		{math.MaxInt32, []byte{0x80, 0x80, 0x80, 0x80, 0x08}},
+		{-2, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+	} {
+		encoded := AppendUleb128p1(nil, test.value)
+		if !bytes.Equal(encoded, test.encoded) {
+			t.Errorf("Expected %v to encode to %x but got %x", test.value, test.encoded, encoded)
+		}
+		var buffer bytes.Buffer
+		if byteCount, err := EncodeUleb128p1(test.value, &buffer); err != nil || byteCount != len(test.encoded) || !bytes.Equal(buffer.Bytes(), test.encoded) {
+			t.Errorf("Expected %v to encode to %x but got %x (%v)", test.value, test.encoded, buffer.Bytes(), err)
+		}
+		value, byteCount, err := DecodeUleb128p1FromBytes(test.encoded)
+		if err != nil || value != test.value || byteCount != len(test.encoded) {
+			t.Errorf("Expected %x to decode to %v but got %v (%v)", test.encoded, test.value, value, err)
+		}
+		value, byteCount, err = DecodeUleb128p1(bytes.NewReader(test.encoded))
+		if err != nil || value != test.value || byteCount != len(test.encoded) {
+			t.Errorf("Expected %x to decode to %v but got %v (%v)", test.encoded, test.value, value, err)
+		}
+	}
+}
+
+func TestUleb128p1Errors(t *testing.T) {
+	tooWide := []byte{0x80, 0x80, 0x80, 0x80, 0x10}
+	if _, _, err := DecodeUleb128p1FromBytes(tooWide); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	if _, _, err := DecodeUleb128p1(bytes.NewReader(tooWide)); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	if _, _, err := DecodeUleb128p1FromBytes([]byte{0x80}); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	if _, _, err := DecodeUleb128p1(bytes.NewReader([]byte{0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+	if _, _, err := DecodeUleb128p1(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("Expected %v but got %v", io.EOF, err)
+	}
+}
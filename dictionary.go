@@ -0,0 +1,203 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrMalformedDictionary is returned when a dictionary block refers to a
+// dictionary entry that doesn't exist.
+var ErrMalformedDictionary = errors.New("uleb128: malformed dictionary block")
+
+// A dictionary block is encoded as a header of (count << 1 | mode). In plain
+// mode, the header is followed by count values. In dictionary mode, it is
+// followed by the number of dictionary entries, the entries themselves
+// (most frequent first), and then a code for each value: 0 means that the
+// value itself follows, and n means dictionary entry n-1.
+const (
+	dictionaryPlain = 0
+	dictionaryCoded = 1
+)
+
+// AppendDictionaryBlock appends values to buffer as a dictionary block.
+// Values that occur often enough to pay for their dictionary entry are
+// replaced by small indices into the dictionary, and the rest are stored as
+// escaped literals. If the dictionary wouldn't make the block smaller, the
+// values are stored plainly instead.
+func AppendDictionaryBlock(buffer []byte, values []uint64) []byte {
+	dictionary, codedSize := buildDictionary(values)
+	plainSize := 0
+	for _, value := range values {
+		plainSize += EncodedSizeUint64(value)
+	}
+	if codedSize >= plainSize {
+		buffer = AppendUint64(buffer, uint64(len(values))<<1|dictionaryPlain)
+		for _, value := range values {
+			buffer = AppendUint64(buffer, value)
+		}
+		return buffer
+	}
+
+	buffer = AppendUint64(buffer, uint64(len(values))<<1|dictionaryCoded)
+	return appendDictionaryCoded(buffer, values, dictionary)
+}
+
+// DecodeDictionaryBlockFromBytes decodes a dictionary block written by
+// AppendDictionaryBlock from the start of buffer. The count is validated
+// with CheckLength against maxCount before anything is allocated. A buffer
+// that ends before all values are decoded returns ErrTruncated.
+func DecodeDictionaryBlockFromBytes(buffer []byte, maxCount int) (values []uint64, byteCount int, err error) {
+	header, byteCount, err := decodeUint64FromBytes(buffer)
+	if err != nil {
+		return
+	}
+	count, err := CheckLength(header>>1, nil, maxCount, 8)
+	if err != nil {
+		return
+	}
+
+	decodeField := func() (value uint64) {
+		var fieldByteCount int
+		value, fieldByteCount, err = decodeUint64FromBytes(buffer[byteCount:])
+		byteCount += fieldByteCount
+		return
+	}
+
+	values = make([]uint64, count)
+	if header&1 == dictionaryPlain {
+		for i := range values {
+			if values[i] = decodeField(); err != nil {
+				values = nil
+				return
+			}
+		}
+		return
+	}
+
+	var codedByteCount int
+	codedByteCount, err = decodeDictionaryCoded(buffer[byteCount:], values)
+	byteCount += codedByteCount
+	if err != nil {
+		values = nil
+	}
+	return
+}
+
+// Append the dictionary and then the code for each value.
+func appendDictionaryCoded(buffer []byte, values []uint64, dictionary []uint64) []byte {
+	buffer = AppendUint64(buffer, uint64(len(dictionary)))
+	codes := make(map[uint64]uint64, len(dictionary))
+	for i, value := range dictionary {
+		buffer = AppendUint64(buffer, value)
+		codes[value] = uint64(i) + 1
+	}
+	for _, value := range values {
+		if code, ok := codes[value]; ok {
+			buffer = AppendUint64(buffer, code)
+		} else {
+			buffer = append(buffer, 0)
+			buffer = AppendUint64(buffer, value)
+		}
+	}
+	return buffer
+}
+
+// Decode a dictionary and then len(values) codes into values.
+func decodeDictionaryCoded(buffer []byte, values []uint64) (byteCount int, err error) {
+	decodeField := func() (value uint64) {
+		var fieldByteCount int
+		value, fieldByteCount, err = decodeUint64FromBytes(buffer[byteCount:])
+		byteCount += fieldByteCount
+		return
+	}
+
+	// Every dictionary entry is at least one byte, so a valid dictionary
+	// can't have more entries than there are bytes left.
+	dictionarySize := decodeField()
+	if err == nil && dictionarySize > uint64(len(buffer)-byteCount) {
+		err = ErrTruncated
+	}
+	if err != nil {
+		return
+	}
+	dictionary := make([]uint64, dictionarySize)
+	for i := range dictionary {
+		if dictionary[i] = decodeField(); err != nil {
+			return
+		}
+	}
+	for i := range values {
+		code := decodeField()
+		if err == nil {
+			switch {
+			case code == 0:
+				values[i] = decodeField()
+			case code > dictionarySize:
+				err = ErrMalformedDictionary
+			default:
+				values[i] = dictionary[code-1]
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Choose the dictionary entries for values, most frequent first, and return
+// them along with the size of the dictionary-coded payload.
+func buildDictionary(values []uint64) (dictionary []uint64, codedSize int) {
+	frequencies := make(map[uint64]int)
+	for _, value := range values {
+		frequencies[value]++
+	}
+	candidates := make([]uint64, 0, len(frequencies))
+	for value := range frequencies {
+		candidates = append(candidates, value)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if frequencies[a] != frequencies[b] {
+			return frequencies[a] > frequencies[b]
+		}
+		return a < b
+	})
+
+	// An entry pays off if its codes plus the entry itself are smaller than
+	// escaping every occurrence.
+	for _, value := range candidates {
+		frequency := frequencies[value]
+		valueSize := EncodedSizeUint64(value)
+		codeSize := EncodedSizeUint64(uint64(len(dictionary)) + 1)
+		literalSize := (1 + valueSize) * frequency
+		if entrySize := codeSize*frequency + valueSize; entrySize < literalSize {
+			dictionary = append(dictionary, value)
+			codedSize += entrySize
+		} else {
+			codedSize += literalSize
+		}
+	}
+	codedSize += EncodedSizeUint64(uint64(len(dictionary)))
+	return
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertDictionaryBlock(t *testing.T, values []uint64) []byte {
+	encoded := AppendDictionaryBlock(nil, values)
+	decoded, byteCount, err := DecodeDictionaryBlockFromBytes(encoded, len(values))
+	if err != nil {
+		t.Error(err)
+		return encoded
+	}
+	if byteCount != len(encoded) {
+		t.Errorf("Expected to decode %v bytes but decoded %v", len(encoded), byteCount)
+	}
+	if len(values) == 0 && len(decoded) == 0 {
+		return encoded
+	}
+	if describe.D(decoded) != describe.D(values) {
+		t.Errorf("Expected %v but got %v", describe.D(values), describe.D(decoded))
+	}
+	return encoded
+}
+
+func TestDictionaryBlock(t *testing.T) {
+	assertDictionaryBlock(t, nil)
+	assertDictionaryBlock(t, []uint64{0})
+	assertDictionaryBlock(t, []uint64{0xffffffffffffffff, 0, 0xffffffffffffffff})
+
+	// Header, one dictionary entry (1000), then codes with 7 escaped.
+	expected := []byte{0x0d, 0x01, 0xe8, 0x07, 0x01, 0x01, 0x00, 0x07, 0x01, 0x01, 0x01}
+	actual := assertDictionaryBlock(t, []uint64{1000, 1000, 7, 1000, 1000, 1000})
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+	}
+}
+
+func TestDictionaryBlockFallsBack(t *testing.T) {
+	values := make([]uint64, 500)
+	for i := range values {
+		values[i] = uint64(i) * 1000
+	}
+	encoded := assertDictionaryBlock(t, values)
+	if encoded[0]&1 != dictionaryPlain {
+		t.Errorf("Expected distinct values to be stored plainly")
+	}
+	if expected := AppendUint64(nil, uint64(len(values))<<1); !bytes.HasPrefix(encoded, expected) {
+		t.Errorf("Expected the plain header %v", describe.D(expected))
+	}
+}
+
+func TestDictionaryBlockSkewed(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	common := []uint64{1 << 40, 1 << 50, 123456789}
+	values := make([]uint64, 10000)
+	plainSize := 0
+	for i := range values {
+		if random.Intn(10) == 0 {
+			values[i] = random.Uint64()
+		} else {
+			values[i] = common[random.Intn(len(common))]
+		}
+		plainSize += EncodedSizeUint64(values[i])
+	}
+	encoded := assertDictionaryBlock(t, values)
+	if encoded[0]&1 != dictionaryCoded {
+		t.Errorf("Expected skewed values to be dictionary coded")
+	}
+	if len(encoded) > plainSize/3 {
+		t.Errorf("Expected at most %v bytes but got %v", plainSize/3, len(encoded))
+	}
+}
+
+func TestDictionaryBlockErrors(t *testing.T) {
+	for _, test := range []struct {
+		encoded []byte
+		err     error
+	}{
+		{[]byte{}, ErrTruncated},
+		{[]byte{0x04, 0x01}, ErrTruncated},
+		{[]byte{0x05, 0x01, 0x07, 0x01}, ErrTruncated},
+		{[]byte{0x05, 0x01, 0x07, 0x01, 0x02}, ErrMalformedDictionary},
+		{[]byte{0x05, 0x01, 0x07, 0x01, 0x00}, ErrTruncated},
+		{[]byte{0x03, 0x7f}, ErrTruncated},
+	} {
+		if _, _, err := DecodeDictionaryBlockFromBytes(test.encoded, 10); err != test.err {
+			t.Errorf("Expected %v to give %v but got %v", describe.D(test.encoded), test.err, err)
+		}
+	}
+	if _, _, err := DecodeDictionaryBlockFromBytes([]byte{0x40}, 10); err == nil {
+		t.Errorf("Expected a count over the maximum to fail")
+	}
+}
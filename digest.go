@@ -0,0 +1,45 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"hash"
+	"io"
+)
+
+// DigestValue writes the canonical encoding of value to h. Since every value
+// has exactly one canonical encoding, equal values always produce equal
+// digests, no matter how they were originally encoded.
+func DigestValue(h hash.Hash, value Value) {
+	var buffer [MaxBufferWriteBytes]byte
+	h.Write(value.AppendTo(buffer[:0]))
+}
+
+// DigestStream reads a stream of ULEB128 values from reader until it ends,
+// and writes their canonical encodings to h, returning the number of bytes
+// hashed. Two streams of the same values produce the same digest, even if
+// one of them contains redundant encodings. Canonical encodings are
+// self-delimiting, so different sequences of values can't hash the same
+// bytes. If the stream ends partway through a value, err will be
+// io.ErrUnexpectedEOF.
+func DigestStream(h hash.Hash, reader io.Reader) (byteCount int64, err error) {
+	return io.Copy(h, NewCanonicalReader(reader))
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math/big"
+	"testing"
+)
+
+func TestDigestValue(t *testing.T) {
+	digest := func(value Value) []byte {
+		h := sha256.New()
+		DigestValue(h, value)
+		return h.Sum(nil)
+	}
+
+	// A big.Int holding a small value digests the same as the uint64.
+	if !bytes.Equal(digest(Uint64Value(300)), digest(BigValue(big.NewInt(300)))) {
+		t.Errorf("Expected equal values to have equal digests")
+	}
+	if bytes.Equal(digest(Uint64Value(300)), digest(Uint64Value(301))) {
+		t.Errorf("Expected different values to have different digests")
+	}
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	expected := sha256.Sum256(Append(nil, huge))
+	if !bytes.Equal(digest(BigValue(huge)), expected[:]) {
+		t.Errorf("Expected the digest of the canonical encoding")
+	}
+}
+
+func TestDigestStream(t *testing.T) {
+	input, canonical := canonicalTestStream()
+	h := sha256.New()
+	byteCount, err := DigestStream(h, bytes.NewReader(input))
+	if err != nil {
+		t.Error(err)
+	}
+	if byteCount != int64(len(canonical)) {
+		t.Errorf("Expected to hash %v bytes but hashed %v", len(canonical), byteCount)
+	}
+	if expected := sha256.Sum256(canonical); !bytes.Equal(h.Sum(nil), expected[:]) {
+		t.Errorf("Expected the digest of the canonical stream")
+	}
+
+	if _, err = DigestStream(sha256.New(), bytes.NewReader([]byte{0x01, 0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// ErrZeroValue is returned when attempting to Elias-encode 0. Elias codes
+// only represent positive integers; store value+1 if 0 must be encoded.
+var ErrZeroValue = errors.New("uleb128: Elias codes cannot encode 0")
+
+// EncodedBitSizeEliasGamma returns the number of bits that WriteEliasGamma
+// would write for value, or 0 if value is 0.
+func EncodedBitSizeEliasGamma(value uint64) int {
+	if value == 0 {
+		return 0
+	}
+	return 2*bits.Len64(value) - 1
+}
+
+// EncodedBitSizeEliasDelta returns the number of bits that WriteEliasDelta
+// would write for value, or 0 if value is 0.
+func EncodedBitSizeEliasDelta(value uint64) int {
+	if value == 0 {
+		return 0
+	}
+	bitLength := bits.Len64(value)
+	return EncodedBitSizeEliasGamma(uint64(bitLength)) + bitLength - 1
+}
+
+// WriteEliasGamma writes a positive value as an Elias gamma code: N zero
+// bits, a 1 bit, and then the low N bits of value, where N is the index of
+// value's highest set bit. With MSBFirst ordering this is the textbook bit
+// string. Returns ErrZeroValue if value is 0.
+func (w *BitWriter) WriteEliasGamma(value uint64) (err error) {
+	if value == 0 {
+		return ErrZeroValue
+	}
+	n := bits.Len64(value) - 1
+	for i := 0; i < n; i++ {
+		if err = w.WriteBits(0, 1); err != nil {
+			return
+		}
+	}
+	if err = w.WriteBits(1, 1); err != nil {
+		return
+	}
+	return w.WriteBits(value, n)
+}
+
+// ReadEliasGamma reads a value written by WriteEliasGamma. Returns io.EOF if
+// the stream ended before any bits were read, io.ErrUnexpectedEOF if it ended
+// partway through, or ErrOverflow if the value doesn't fit into a uint64.
+func (r *BitReader) ReadEliasGamma() (value uint64, err error) {
+	n, err := r.readZeroRun(63)
+	if err != nil {
+		return
+	}
+	if value, err = r.ReadBits(n); err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	value |= 1 << uint(n)
+	return
+}
+
+// WriteEliasDelta writes a positive value as an Elias delta code: the bit
+// length of value as an Elias gamma code, followed by the bits of value below
+// its highest set bit. Returns ErrZeroValue if value is 0.
+func (w *BitWriter) WriteEliasDelta(value uint64) (err error) {
+	if value == 0 {
+		return ErrZeroValue
+	}
+	bitLength := bits.Len64(value)
+	if err = w.WriteEliasGamma(uint64(bitLength)); err != nil {
+		return
+	}
+	return w.WriteBits(value, bitLength-1)
+}
+
+// ReadEliasDelta reads a value written by WriteEliasDelta. Errors are the
+// same as for ReadEliasGamma.
+func (r *BitReader) ReadEliasDelta() (value uint64, err error) {
+	bitLength, err := r.ReadEliasGamma()
+	if err != nil {
+		return
+	}
+	if bitLength > 64 {
+		err = ErrOverflow
+		return
+	}
+	n := int(bitLength) - 1
+	if value, err = r.ReadBits(n); err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	value |= 1 << uint(n)
+	return
+}
+
+// Read zero bits up to and including the terminating 1 bit, returning the
+// number of zeroes. Returns ErrOverflow if there are more than maxCount.
+func (r *BitReader) readZeroRun(maxCount int) (count int, err error) {
+	for {
+		var bit uint64
+		if bit, err = r.ReadBits(1); err != nil {
+			if count > 0 {
+				err = unexpectedEOF(err)
+			}
+			return
+		}
+		if bit == 1 {
+			return
+		}
+		if count++; count > maxCount {
+			err = ErrOverflow
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+var eliasTestValues = []uint64{1, 2, 3, 4, 7, 8, 17, 300, 0x0123456789abcdef, 0x8000000000000000, 0xffffffffffffffff}
+
+func TestEliasGammaBits(t *testing.T) {
+	buff := &bytes.Buffer{}
+	writer := NewBitWriter(buff, MSBFirst)
+	// 1 = 1, 2 = 010, 5 = 00101, 1 = 1 -> 1010 0010 1100 0000
+	for _, v := range []uint64{1, 2, 5, 1} {
+		writer.WriteEliasGamma(v)
+	}
+	writer.Flush()
+	expected := []byte{0xa2, 0xc0}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func TestEliasDeltaBits(t *testing.T) {
+	buff := &bytes.Buffer{}
+	writer := NewBitWriter(buff, MSBFirst)
+	// 1 = 1, 2 = 0100, 17 = 00101 0001 -> 1010 0001 0100 0100
+	for _, v := range []uint64{1, 2, 17} {
+		writer.WriteEliasDelta(v)
+	}
+	writer.Flush()
+	expected := []byte{0xa1, 0x44}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func TestEliasRoundTrip(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		buff := &bytes.Buffer{}
+		writer := NewBitWriter(buff, order)
+		expectedBitCount := 0
+		for _, v := range eliasTestValues {
+			if err := writer.WriteEliasGamma(v); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := writer.WriteEliasDelta(v); err != nil {
+				t.Error(err)
+				return
+			}
+			expectedBitCount += EncodedBitSizeEliasGamma(v) + EncodedBitSizeEliasDelta(v)
+		}
+		writer.Flush()
+		if expectedByteCount := (expectedBitCount + 7) / 8; buff.Len() != expectedByteCount {
+			t.Errorf("Order %v: Expected %v bytes but got %v", order, expectedByteCount, buff.Len())
+		}
+
+		reader := NewBitReader(buff, order)
+		for _, expected := range eliasTestValues {
+			gamma, err := reader.ReadEliasGamma()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			delta, err := reader.ReadEliasDelta()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if gamma != expected || delta != expected {
+				t.Errorf("Order %v: Expected %x but got gamma %x, delta %x", order, expected, gamma, delta)
+			}
+		}
+	}
+}
+
+func TestEliasErrors(t *testing.T) {
+	writer := NewBitWriter(&bytes.Buffer{}, MSBFirst)
+	if err := writer.WriteEliasGamma(0); err != ErrZeroValue {
+		t.Errorf("Expected %v but got %v", ErrZeroValue, err)
+	}
+	if err := writer.WriteEliasDelta(0); err != ErrZeroValue {
+		t.Errorf("Expected %v but got %v", ErrZeroValue, err)
+	}
+
+	if _, err := NewBitReader(&bytes.Buffer{}, MSBFirst).ReadEliasGamma(); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if _, err := NewBitReader(bytes.NewBuffer([]byte{0x00}), MSBFirst).ReadEliasGamma(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF but got %v", err)
+	}
+	if _, err := NewBitReader(bytes.NewBuffer(make([]byte, 9)), MSBFirst).ReadEliasGamma(); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+	// Gamma-coded bit length of 65: 000000 1 000001
+	if _, err := NewBitReader(bytes.NewBuffer([]byte{0x02, 0x08}), MSBFirst).ReadEliasDelta(); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+// A fixed-capacity cache of uint64 encodings. It's 2-way set associative:
+// a value hashes to one set of two entries, and a miss replaces whichever of
+// the two was used least recently. This approximates a full LRU for small
+// working sets without the map and list upkeep that would cost more than
+// encoding.
+type encodeCache struct {
+	sets  []encodeCacheSet
+	shift uint
+}
+
+type encodeCacheSet struct {
+	entries [2]encodeCacheEntry
+	// Index of the least recently used entry.
+	lru uint8
+}
+
+type encodeCacheEntry struct {
+	value     uint64
+	byteCount uint8
+	encoded   [MaxBufferWriteBytes]byte
+}
+
+// Make a cache holding at least size entries.
+func newEncodeCache(size int) *encodeCache {
+	setBits := uint(0)
+	for 2<<setBits < size {
+		setBits++
+	}
+	return &encodeCache{
+		sets:  make([]encodeCacheSet, 1<<setBits),
+		shift: 64 - setBits,
+	}
+}
+
+// Append the encoding of value to buffer, using the cached encoding if there
+// is one, and otherwise caching it in place of the least recently used entry
+// in its set.
+func (c *encodeCache) append(buffer []byte, value uint64) []byte {
+	set := &c.sets[(value*0x9e3779b97f4a7c15)>>c.shift]
+	way := set.lru
+	if set.entries[0].byteCount != 0 && set.entries[0].value == value {
+		way = 0
+	} else if set.entries[1].byteCount != 0 && set.entries[1].value == value {
+		way = 1
+	} else {
+		entry := &set.entries[way]
+		entry.value = value
+		entry.byteCount = uint8(EncodeUint64ToBytes(value, entry.encoded[:]))
+	}
+	set.lru = way ^ 1
+	entry := &set.entries[way]
+	// Copying the whole fixed-size array avoids a variable-length copy.
+	start := len(buffer)
+	buffer = growBuffer(buffer, MaxBufferWriteBytes)
+	copy(buffer[start:], entry.encoded[:])
+	return buffer[:start+int(entry.byteCount)]
+}
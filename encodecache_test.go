@@ -0,0 +1,129 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEncodeCacheEviction(t *testing.T) {
+	c := newEncodeCache(1)
+	if len(c.sets) != 1 {
+		t.Errorf("Expected 1 set but got %v", len(c.sets))
+	}
+	assertCached := func(expected ...uint64) {
+		var actual []uint64
+		for _, entry := range c.sets[0].entries {
+			if entry.byteCount != 0 {
+				actual = append(actual, entry.value)
+			}
+		}
+		sort.Slice(actual, func(i, j int) bool { return actual[i] < actual[j] })
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected cached values %v but got %v", expected, actual)
+		}
+	}
+	c.append(nil, 1)
+	c.append(nil, 2)
+	assertCached(1, 2)
+	c.append(nil, 1)
+	c.append(nil, 3)
+	assertCached(1, 3)
+	c.append(nil, 4)
+	assertCached(3, 4)
+	if encoded := c.append(nil, 300); !bytes.Equal(encoded, []byte{0xac, 0x02}) {
+		t.Errorf("Expected [ac 02] but got %v", encoded)
+	}
+	if encoded := c.append([]byte{0x01}, 300); !bytes.Equal(encoded, []byte{0x01, 0xac, 0x02}) {
+		t.Errorf("Expected [01 ac 02] but got %v", encoded)
+	}
+
+	// Value 0 must not be mistaken for an empty entry's value.
+	c = newEncodeCache(1)
+	if encoded := c.append(nil, 0); !bytes.Equal(encoded, []byte{0x00}) {
+		t.Errorf("Expected [00] but got %v", encoded)
+	}
+
+	for size, expectedSets := range map[int]int{2: 1, 3: 2, 4: 2, 5: 4, 16: 8, 17: 16} {
+		if sets := len(newEncodeCache(size).sets); sets != expectedSets {
+			t.Errorf("Expected a cache of size %v to have %v sets but got %v", size, expectedSets, sets)
+		}
+	}
+}
+
+func TestEncoderCache(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = uint64(random.Intn(20)) << uint(random.Intn(60))
+	}
+
+	for _, size := range []int{1, 2, 8, 100} {
+		expected := &bytes.Buffer{}
+		actual := &bytes.Buffer{}
+		plain := NewEncoder(expected, EncoderOptions{})
+		cached := NewEncoder(actual, EncoderOptions{CacheSize: size})
+		for _, value := range values {
+			plain.EncodeUint64(value)
+			byteCount, err := cached.EncodeUint64(value)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if byteCount != EncodedSizeUint64(value) {
+				t.Errorf("Expected %v to encode to %v bytes but got %v", value, EncodedSizeUint64(value), byteCount)
+			}
+		}
+		if !bytes.Equal(actual.Bytes(), expected.Bytes()) {
+			t.Errorf("Expected a cache of size %v to produce the same encoding as no cache", size)
+		}
+	}
+
+	if e := NewEncoder(&bytes.Buffer{}, EncoderOptions{CacheSize: 8, Zeroize: true}); e.cache != nil {
+		t.Errorf("Expected Zeroize to disable the cache")
+	}
+}
+
+func BenchmarkEncoderCache(b *testing.B) {
+	random := rand.New(rand.NewSource(1))
+	values := make([]uint64, 1024)
+	for i := range values {
+		values[i] = 0xfedcba9876543210 + uint64(random.Intn(16))
+	}
+	for _, size := range []int{0, 16} {
+		e := NewEncoder(discardWriter{}, EncoderOptions{BufferSize: 4096, CacheSize: size})
+		b.Run(map[bool]string{false: "uncached", true: "cached"}[size > 0], func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				e.EncodeUint64(values[i%len(values)])
+			}
+		})
+	}
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
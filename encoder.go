@@ -0,0 +1,450 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// EncoderOptions configures an Encoder.
+type EncoderOptions struct {
+	// Encoded bytes are collected until there are at least this many, and
+	// then written in a single call. If 0, every value is written as soon as
+	// it's encoded. When buffering, Flush must be called when done.
+	BufferSize int
+
+	// How PadTo fills the space up to the next boundary.
+	PaddingMode PaddingMode
+
+	// If true, the encoder wipes memory that held encoded bytes as soon as
+	// it's done with it, so that encoded values don't linger in reusable
+	// memory. This covers the internal buffer whenever it's flushed or a
+	// value is discarded, the old buffer whenever the buffer grows, and the
+	// words a math.big.Int value is copied into while it's encoded. It
+	// doesn't cover the values passed in, memory held by the writer, or
+	// copies made by the Go runtime (such as when a goroutine stack grows).
+	Zeroize bool
+
+	// If true, the encoder counts the allocations it makes, which are
+	// reported by Stats.
+	CollectStats bool
+
+	// If greater than 0, EncodeUint64 keeps the encodings of about this many
+	// recently encoded values, and copies them out directly when a value
+	// repeats. Encoding a uint64 is already cheap, so a cache hit only saves
+	// time on long encodings; measure with your own data before enabling it.
+	// Ignored if Zeroize is set, since the cache would keep encoded values in
+	// memory.
+	CacheSize int
+
+	// If true, the encoder's methods may be called from multiple goroutines
+	// at once. Each call's output is kept contiguous in the stream, but the
+	// order of concurrent calls is unspecified.
+	Concurrent bool
+
+	// If greater than 0, the maximum number of bytes the encoder may emit.
+	// A call that would go over it emits nothing and returns a
+	// *BudgetError, so the output never overshoots a fixed-size slot.
+	MaxByteCount int
+
+	// If not nil, called with each value passed to EncodeUint64, Encode,
+	// EncodeUint64s, WriteSeq or WriteSeqBig, and the bytes it encoded to,
+	// before they're emitted.
+	BeforeEncode EncodeHook
+}
+
+// EncodeHook is called with a value and its encoding before the encoding is
+// emitted. It may modify the encoded bytes in place, but not change their
+// length. If it returns an error, nothing is emitted for the value and the
+// encoder call returns that error.
+type EncodeHook func(value Value, encoded []byte) error
+
+// BudgetError is returned when an encoder call would take the output past
+// EncoderOptions.MaxByteCount.
+type BudgetError struct {
+	// The encoder's byte budget.
+	MaxByteCount int
+	// The number of bytes encoded before the call that failed.
+	ByteCount int
+	// The number of bytes the failed call would have encoded.
+	Requested int
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("uleb128: encoding %v more bytes after %v would exceed the budget of %v bytes",
+		e.Requested, e.ByteCount, e.MaxByteCount)
+}
+
+// PaddingMode determines what Encoder.PadTo emits as padding.
+type PaddingMode int
+
+const (
+	// Pad with a single value 0, encoded redundantly using as many bytes as
+	// are needed (80 80 ... 00). A decoder sees one extra value.
+	PadWithRedundantZero PaddingMode = iota
+	// Pad with a run of single-byte 0 values. A decoder sees one extra value
+	// per padding byte, but each of them is canonically encoded.
+	PadWithZeroValues
+)
+
+// Encoder encodes ULEB128 values to a writer.
+type Encoder struct {
+	writer    io.Writer
+	options   EncoderOptions
+	buffer    []byte
+	byteCount int
+	capacity  int
+	stats     Stats
+	cache     *encodeCache
+	mutex     sync.Mutex
+}
+
+// NewEncoder returns an Encoder that writes to writer.
+func NewEncoder(writer io.Writer, options EncoderOptions) *Encoder {
+	e := &Encoder{
+		writer:  writer,
+		options: options,
+		buffer:  make([]byte, 0, options.BufferSize+MaxBufferWriteBytes),
+	}
+	e.capacity = cap(e.buffer)
+	if options.CacheSize > 0 && !options.Zeroize {
+		e.cache = newEncodeCache(options.CacheSize)
+	}
+	return e
+}
+
+// EncodeUint64 encodes a uint64 value, returning the number of bytes encoded.
+func (e *Encoder) EncodeUint64(value uint64) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	start := len(e.buffer)
+	e.appendUint64(value)
+	if err = e.hook(start, value, nil); err != nil {
+		return
+	}
+	return e.finish(start)
+}
+
+// Encode encodes a math.big.Int value (the sign of the value will be
+// ignored), returning the number of bytes encoded.
+func (e *Encoder) Encode(value *big.Int) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	start := len(e.buffer)
+	e.appendBig(value)
+	if err = e.hook(start, 0, value); err != nil {
+		return
+	}
+	return e.finish(start)
+}
+
+// EncodeUint64s encodes values in order, returning the number of bytes
+// encoded. Runs of values below 0x80, which encode to a single byte each,
+// are copied into the buffer in one pass rather than encoded one by one.
+// Values are batched into writes of at least DefaultChunkSize bytes (or
+// BufferSize, if larger), and in Concurrent mode they stay contiguous in the
+// stream. If the encoder's byte budget runs out, the values before the one
+// that didn't fit are kept.
+func (e *Encoder) EncodeUint64s(values []uint64) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	for len(values) > 0 {
+		start := len(e.buffer)
+		if run := e.smallValueRun(values); run > 0 && e.options.BeforeEncode == nil {
+			e.reserve(run)
+			e.buffer = e.buffer[:start+run]
+			for i, value := range values[:run] {
+				e.buffer[start+i] = byte(value)
+			}
+			values = values[run:]
+		} else {
+			e.appendUint64(values[0])
+			err = e.hook(start, values[0], nil)
+			values = values[1:]
+			if err != nil {
+				return
+			}
+		}
+		var runByteCount int
+		runByteCount, err = e.commit(start)
+		byteCount += runByteCount
+		if err != nil {
+			return
+		}
+		if err = e.flushBatch(); err != nil {
+			return
+		}
+	}
+	err = e.flushIfFull()
+	return
+}
+
+// Count the single-byte values at the start of values, up to a chunk's
+// worth and no more than the byte budget allows.
+func (e *Encoder) smallValueRun(values []uint64) (run int) {
+	limit := DefaultChunkSize
+	if e.options.MaxByteCount > 0 {
+		limit = minInt(limit, e.options.MaxByteCount-e.byteCount)
+	}
+	for run < len(values) && run < limit && values[run] < continuationMask {
+		run++
+	}
+	return
+}
+
+// EncodeBytes encodes value as a ULEB128 length followed by its bytes.
+func (e *Encoder) EncodeBytes(value []byte) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	start := len(e.buffer)
+	e.reserve(MaxBufferWriteBytes + len(value))
+	e.buffer = AppendUint64(e.buffer, uint64(len(value)))
+	e.buffer = append(e.buffer, value...)
+	return e.finish(start)
+}
+
+// EncodeString encodes value as a ULEB128 length followed by its bytes.
+func (e *Encoder) EncodeString(value string) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	start := len(e.buffer)
+	e.reserve(MaxBufferWriteBytes + len(value))
+	e.buffer = AppendUint64(e.buffer, uint64(len(value)))
+	e.buffer = append(e.buffer, value...)
+	return e.finish(start)
+}
+
+// PadTo emits padding so that the next value starts on a multiple of
+// alignment bytes from the beginning of the stream, returning the number of
+// padding bytes encoded. The padding form is chosen by
+// EncoderOptions.PaddingMode. Note that the padding is itself made up of
+// values, so the reader must know to expect and skip it.
+func (e *Encoder) PadTo(alignment int) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	if alignment <= 1 {
+		return
+	}
+	padding := (alignment - e.byteCount%alignment) % alignment
+	if padding == 0 {
+		return
+	}
+	start := len(e.buffer)
+	e.reserve(padding)
+	switch e.options.PaddingMode {
+	case PadWithZeroValues:
+		for i := 0; i < padding; i++ {
+			e.buffer = append(e.buffer, 0)
+		}
+	default:
+		for i := 1; i < padding; i++ {
+			e.buffer = append(e.buffer, continuationMask)
+		}
+		e.buffer = append(e.buffer, 0)
+	}
+	return e.finish(start)
+}
+
+// ByteCount returns the total number of bytes encoded so far, including any
+// that are still buffered.
+func (e *Encoder) ByteCount() int {
+	e.lock()
+	defer e.unlock()
+	return e.byteCount
+}
+
+// Flush writes out any buffered bytes.
+func (e *Encoder) Flush() (err error) {
+	e.lock()
+	defer e.unlock()
+	return e.flush()
+}
+
+func (e *Encoder) flush() (err error) {
+	if len(e.buffer) > 0 {
+		_, err = e.writer.Write(e.buffer)
+		if e.options.Zeroize {
+			wipeBytes(e.buffer)
+		}
+		e.buffer = e.buffer[:0]
+	}
+	return
+}
+
+// Stats returns the allocations this encoder has made since it was created.
+// Only collected if EncoderOptions.CollectStats is set.
+func (e *Encoder) Stats() Stats {
+	e.lock()
+	defer e.unlock()
+	return e.stats
+}
+
+func (e *Encoder) appendUint64(value uint64) {
+	e.reserve(MaxBufferWriteBytes)
+	if e.cache != nil {
+		e.buffer = e.cache.append(e.buffer, value)
+	} else {
+		e.buffer = AppendUint64(e.buffer, value)
+	}
+}
+
+func (e *Encoder) appendBig(value *big.Int) {
+	if e.options.CollectStats && encodeAllocates(value) {
+		e.stats.Allocations++
+	}
+	start := len(e.buffer)
+	e.reserve(EncodedSize(value))
+	byteCount := encodeToBytes(value, e.buffer[start:cap(e.buffer)], NativeEngine, e.options.Zeroize)
+	e.buffer = e.buffer[:start+byteCount]
+}
+
+// Make room in the buffer for byteCount more bytes, so that appending them
+// doesn't reallocate. If Zeroize is set, a buffer that has to be replaced is
+// wiped first.
+func (e *Encoder) reserve(byteCount int) {
+	e.buffer = growBufferZeroizing(e.buffer, byteCount, e.options.Zeroize)[:len(e.buffer)]
+}
+
+func (e *Encoder) finish(start int) (byteCount int, err error) {
+	if byteCount, err = e.commit(start); err != nil {
+		return
+	}
+	err = e.flushIfFull()
+	return
+}
+
+// Pass the value (asUint, or asBigInt if it's not nil) and the bytes it
+// encoded to after start to the BeforeEncode hook, discarding the bytes if
+// the hook fails.
+func (e *Encoder) hook(start int, asUint uint64, asBigInt *big.Int) (err error) {
+	if e.options.BeforeEncode == nil {
+		return
+	}
+	if err = e.options.BeforeEncode(NewValue(asUint, asBigInt), e.buffer[start:]); err != nil {
+		e.discard(start)
+	}
+	return
+}
+
+// Drop the bytes appended to the buffer since start.
+func (e *Encoder) discard(start int) {
+	if e.options.Zeroize {
+		wipeBytes(e.buffer[start:])
+	}
+	e.buffer = e.buffer[:start]
+}
+
+// Record the bytes appended to the buffer since start, or discard them and
+// return a *BudgetError if they don't fit into the budget.
+func (e *Encoder) commit(start int) (byteCount int, err error) {
+	if e.options.MaxByteCount > 0 && e.byteCount+len(e.buffer)-start > e.options.MaxByteCount {
+		err = &BudgetError{
+			MaxByteCount: e.options.MaxByteCount,
+			ByteCount:    e.byteCount,
+			Requested:    len(e.buffer) - start,
+		}
+		e.discard(start)
+		return
+	}
+	byteCount = e.record(start)
+	return
+}
+
+// Account for the bytes appended to the buffer since start, returning how
+// many there were.
+func (e *Encoder) record(start int) (byteCount int) {
+	if cap(e.buffer) != e.capacity {
+		e.capacity = cap(e.buffer)
+		if e.options.CollectStats {
+			e.stats.Allocations++
+			e.stats.BufferGrowths++
+		}
+	}
+	byteCount = len(e.buffer) - start
+	e.byteCount += byteCount
+	return
+}
+
+func (e *Encoder) flushBatch() error {
+	if len(e.buffer) >= e.options.BufferSize && len(e.buffer) >= DefaultChunkSize {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *Encoder) flushIfFull() error {
+	if len(e.buffer) >= e.options.BufferSize {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *Encoder) lock() {
+	if e.options.Concurrent {
+		e.mutex.Lock()
+	}
+}
+
+func (e *Encoder) unlock() {
+	if e.options.Concurrent {
+		e.mutex.Unlock()
+	}
+}
+
+// SizeAccumulator mirrors the Encoder API, but only sums the number of bytes
+// each call would encode. This allows a serializer to compute its exact
+// output size using the same sequence of calls it later uses to encode.
+type SizeAccumulator struct {
+	size int
+}
+
+// AddUint64 adds the encoded size of a uint64 value.
+func (a *SizeAccumulator) AddUint64(value uint64) {
+	a.size += EncodedSizeUint64(value)
+}
+
+// AddBig adds the encoded size of a math.big.Int value.
+func (a *SizeAccumulator) AddBig(value *big.Int) {
+	a.size += EncodedSize(value)
+}
+
+// AddBytes adds the encoded size of a length-prefixed byte slice.
+func (a *SizeAccumulator) AddBytes(value []byte) {
+	a.size += EncodedSizeUint64(uint64(len(value))) + len(value)
+}
+
+// AddString adds the encoded size of a length-prefixed string.
+func (a *SizeAccumulator) AddString(value string) {
+	a.size += EncodedSizeUint64(uint64(len(value))) + len(value)
+}
+
+// Size returns the total number of bytes added so far.
+func (a *SizeAccumulator) Size() int {
+	return a.size
+}
+
+// Reset sets the total back to 0.
+func (a *SizeAccumulator) Reset() {
+	a.size = 0
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+//go:build go1.23
+
+package uleb128
+
+import (
+	"iter"
+	"math/big"
+)
+
+// WriteSeq encodes every value produced by seq, returning the number of
+// bytes encoded. Values are batched into writes of at least DefaultChunkSize
+// bytes (or BufferSize, if larger), even when the encoder is unbuffered. In
+// Concurrent mode, the values from one call stay contiguous in the stream.
+// If the encoder's byte budget runs out, the values before the one that
+// didn't fit are kept.
+func (e *Encoder) WriteSeq(seq iter.Seq[uint64]) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	for value := range seq {
+		start := len(e.buffer)
+		e.appendUint64(value)
+		if err = e.hook(start, value, nil); err != nil {
+			return
+		}
+		var valueByteCount int
+		valueByteCount, err = e.commit(start)
+		byteCount += valueByteCount
+		if err != nil {
+			return
+		}
+		if err = e.flushBatch(); err != nil {
+			return
+		}
+	}
+	err = e.flushIfFull()
+	return
+}
+
+// WriteSeqBig is like WriteSeq, but for math.big.Int values (their signs will
+// be ignored).
+func (e *Encoder) WriteSeqBig(seq iter.Seq[*big.Int]) (byteCount int, err error) {
+	e.lock()
+	defer e.unlock()
+	for value := range seq {
+		start := len(e.buffer)
+		e.appendBig(value)
+		if err = e.hook(start, 0, value); err != nil {
+			return
+		}
+		var valueByteCount int
+		valueByteCount, err = e.commit(start)
+		byteCount += valueByteCount
+		if err != nil {
+			return
+		}
+		if err = e.flushBatch(); err != nil {
+			return
+		}
+	}
+	err = e.flushIfFull()
+	return
+}
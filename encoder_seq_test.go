@@ -0,0 +1,116 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+//go:build go1.23
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestEncoderWriteSeq(t *testing.T) {
+	values := make([]uint64, 3000)
+	for i := range values {
+		values[i] = 300
+	}
+	expected := &bytes.Buffer{}
+	EncodeManyUint64(expected, values...)
+
+	// An unbuffered encoder still batches the sequence.
+	w := &writeCounter{}
+	e := NewEncoder(w, EncoderOptions{})
+	byteCount, err := e.WriteSeq(slices.Values(values))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if byteCount != expected.Len() || e.ByteCount() != expected.Len() {
+		t.Errorf("Expected a byte count of %v but got %v (total %v)", expected.Len(), byteCount, e.ByteCount())
+	}
+	if !bytes.Equal(w.Bytes(), expected.Bytes()) {
+		t.Errorf("Expected WriteSeq to produce the same bytes as EncodeManyUint64")
+	}
+	if w.writeCount != 2 {
+		t.Errorf("Expected 2 writes but got %v", w.writeCount)
+	}
+}
+
+func TestEncoderWriteSeqBig(t *testing.T) {
+	values := []*big.Int{big.NewInt(0), big.NewInt(300), new(big.Int).Lsh(big.NewInt(1), 100)}
+	expected := &bytes.Buffer{}
+	EncodeMany(expected, values...)
+
+	buff := &bytes.Buffer{}
+	e := NewEncoder(buff, EncoderOptions{BufferSize: 1000})
+	byteCount, err := e.WriteSeqBig(slices.Values(values))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buff.Len() != 0 {
+		t.Errorf("Expected a buffered encoder to hold the values until flushed")
+	}
+	if err = e.Flush(); err != nil {
+		t.Error(err)
+	}
+	if byteCount != expected.Len() || !bytes.Equal(buff.Bytes(), expected.Bytes()) {
+		t.Errorf("Expected %v (%v bytes) but got %v (%v bytes)", expected.Bytes(), expected.Len(), buff.Bytes(), byteCount)
+	}
+}
+
+func TestEncoderWriteSeqError(t *testing.T) {
+	pulled := 0
+	seq := func(yield func(uint64) bool) {
+		for {
+			pulled++
+			if !yield(0xffffffffffffffff) {
+				return
+			}
+		}
+	}
+	e := NewEncoder(failingWriter{}, EncoderOptions{})
+	if _, err := e.WriteSeq(seq); err != errWriteFailed {
+		t.Errorf("Expected %v but got %v", errWriteFailed, err)
+	}
+	// The first batch is at least DefaultChunkSize bytes of 10-byte values.
+	if expected := (DefaultChunkSize + 9) / 10; pulled != expected {
+		t.Errorf("Expected the sequence to stop after %v values but got %v", expected, pulled)
+	}
+}
+
+func TestEncoderWriteSeqBudget(t *testing.T) {
+	buff := &bytes.Buffer{}
+	e := NewEncoder(buff, EncoderOptions{MaxByteCount: 5})
+	byteCount, err := e.WriteSeq(slices.Values([]uint64{1, 300, 300, 300}))
+	if _, ok := err.(*BudgetError); !ok {
+		t.Errorf("Expected a *BudgetError but got %v", err)
+	}
+	if err = e.Flush(); err != nil {
+		t.Error(err)
+	}
+	expected := []byte{0x01, 0xac, 0x02, 0xac, 0x02}
+	if byteCount != len(expected) || !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v (%v bytes) but got %v (%v bytes)", expected, len(expected), buff.Bytes(), byteCount)
+	}
+}
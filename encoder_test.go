@@ -0,0 +1,389 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+type writeCounter struct {
+	bytes.Buffer
+	writeCount int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writeCount++
+	return w.Buffer.Write(p)
+}
+
+func TestEncoder(t *testing.T) {
+	big128 := big.NewInt(1)
+	big128.Lsh(big128, 128)
+	expected := []byte{0x01, 0x80, 0x01,
+		0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x04}
+
+	for _, bufferSize := range []int{0, 4, 100} {
+		actual := &writeCounter{}
+		encoder := NewEncoder(actual, EncoderOptions{BufferSize: bufferSize})
+		if byteCount, err := encoder.EncodeUint64(1); err != nil || byteCount != 1 {
+			t.Errorf("Expected 1 byte but got %v (%v)", byteCount, err)
+		}
+		if byteCount, err := encoder.EncodeUint64(0x80); err != nil || byteCount != 2 {
+			t.Errorf("Expected 2 bytes but got %v (%v)", byteCount, err)
+		}
+		if byteCount, err := encoder.Encode(big128); err != nil || byteCount != 19 {
+			t.Errorf("Expected 19 bytes but got %v (%v)", byteCount, err)
+		}
+		if err := encoder.Flush(); err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(actual.Bytes(), expected) {
+			t.Errorf("Buffer size %v: Expected %v but got %v", bufferSize, describe.D(expected), describe.D(actual.Bytes()))
+		}
+
+		expectedWriteCount := map[int]int{0: 3, 4: 1, 100: 1}[bufferSize]
+		if actual.writeCount != expectedWriteCount {
+			t.Errorf("Buffer size %v: Expected %v writes but got %v", bufferSize, expectedWriteCount, actual.writeCount)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	buffer := []byte{0xaa}
+	buffer = AppendUint64(buffer, 300)
+	buffer = Append(buffer, big.NewInt(0x80))
+	expected := []byte{0xaa, 0xac, 0x02, 0x80, 0x01}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buffer))
+	}
+}
+
+func TestEncoderBytesAndStrings(t *testing.T) {
+	buff := &bytes.Buffer{}
+	encoder := NewEncoder(buff, EncoderOptions{})
+	if byteCount, err := encoder.EncodeString("abc"); err != nil || byteCount != 4 {
+		t.Errorf("Expected 4 bytes but got %v (%v)", byteCount, err)
+	}
+	if byteCount, err := encoder.EncodeBytes(make([]byte, 200)); err != nil || byteCount != 202 {
+		t.Errorf("Expected 202 bytes but got %v (%v)", byteCount, err)
+	}
+	expected := append([]byte{0x03, 'a', 'b', 'c', 0xc8, 0x01}, make([]byte, 200)...)
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func TestSizeAccumulator(t *testing.T) {
+	huge := newBigFromHex("123456789abcdef0123456789")
+	buff := &bytes.Buffer{}
+	encoder := NewEncoder(buff, EncoderOptions{})
+	accumulator := &SizeAccumulator{}
+
+	encoder.EncodeUint64(300)
+	accumulator.AddUint64(300)
+	encoder.Encode(huge)
+	accumulator.AddBig(huge)
+	encoder.EncodeString("hello")
+	accumulator.AddString("hello")
+	encoder.EncodeBytes(make([]byte, 130))
+	accumulator.AddBytes(make([]byte, 130))
+
+	if accumulator.Size() != buff.Len() {
+		t.Errorf("Expected accumulated size %v to match encoded size %v", accumulator.Size(), buff.Len())
+	}
+	accumulator.Reset()
+	if accumulator.Size() != 0 {
+		t.Errorf("Expected size 0 after reset but got %v", accumulator.Size())
+	}
+}
+
+func TestEncoderPadTo(t *testing.T) {
+	assertPadding := func(mode PaddingMode, expected []byte) {
+		buff := &bytes.Buffer{}
+		encoder := NewEncoder(buff, EncoderOptions{PaddingMode: mode})
+		encoder.EncodeUint64(300)
+		byteCount, err := encoder.PadTo(8)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if byteCount != 6 {
+			t.Errorf("Expected 6 padding bytes but got %v", byteCount)
+		}
+		if encoder.ByteCount() != 8 {
+			t.Errorf("Expected byte count 8 but got %v", encoder.ByteCount())
+		}
+		if !bytes.Equal(buff.Bytes(), expected) {
+			t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+		}
+		if byteCount, _ = encoder.PadTo(8); byteCount != 0 {
+			t.Errorf("Expected no padding when already aligned but got %v", byteCount)
+		}
+	}
+
+	assertPadding(PadWithRedundantZero, []byte{0xac, 0x02, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00})
+	assertPadding(PadWithZeroValues, []byte{0xac, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+}
+
+func TestEncoderPadToDecodes(t *testing.T) {
+	buff := &bytes.Buffer{}
+	encoder := NewEncoder(buff, EncoderOptions{BufferSize: 100})
+	encoder.EncodeUint64(1)
+	encoder.PadTo(4)
+	encoder.EncodeUint64(2)
+	encoder.Flush()
+
+	decoder := NewBytesDecoder(buff.Bytes(), DecoderOptions{})
+	for _, expected := range []uint64{1, 0, 2} {
+		v, _, err := decoder.DecodeUint64()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if v != expected {
+			t.Errorf("Expected %v but got %v", expected, v)
+		}
+	}
+	if decoder.ByteCount() != 5 {
+		t.Errorf("Expected 5 bytes decoded but got %v", decoder.ByteCount())
+	}
+}
+
+func TestEncoderZeroize(t *testing.T) {
+	buff := &bytes.Buffer{}
+	encoder := NewEncoder(buff, EncoderOptions{BufferSize: 100, Zeroize: true})
+	encoder.EncodeUint64(0x0123456789abcdef)
+	encoder.Flush()
+	for _, b := range encoder.buffer[:cap(encoder.buffer)] {
+		if b != 0 {
+			t.Errorf("Expected the buffer to be wiped but got %v", describe.D(encoder.buffer[:cap(encoder.buffer)]))
+			break
+		}
+	}
+	if _, v, _, err := Decode(buff); err != nil || v != nil {
+		t.Errorf("Expected the flushed data to decode but got %v", err)
+	}
+}
+
+func TestEncoderZeroizeGrow(t *testing.T) {
+	buff := &bytes.Buffer{}
+	encoder := NewEncoder(buff, EncoderOptions{BufferSize: 16, Zeroize: true})
+	encoder.EncodeUint64(0x0123456789abcdef)
+	old := encoder.buffer[:cap(encoder.buffer)]
+	encoder.EncodeBytes(bytes.Repeat([]byte{0xff}, 100))
+	if cap(encoder.buffer) == cap(old) {
+		t.Errorf("Expected the buffer to grow past %v bytes", cap(old))
+	}
+	for _, b := range old {
+		if b != 0 {
+			t.Errorf("Expected the old buffer to be wiped but got %v", describe.D(old))
+			break
+		}
+	}
+	encoder.Flush()
+	if _, v, _, err := Decode(buff); err != nil || v != nil {
+		t.Errorf("Expected the flushed data to decode but got %v", err)
+	}
+}
+
+func TestEncoderConcurrent(t *testing.T) {
+	const goroutineCount = 8
+	const valueCount = 500
+	buff := &bytes.Buffer{}
+	e := NewEncoder(buff, EncoderOptions{BufferSize: 64, Concurrent: true})
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutineCount; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < valueCount; i++ {
+				e.EncodeString(fmt.Sprintf("goroutine %v value %v", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := e.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+	if e.ByteCount() != buff.Len() {
+		t.Errorf("Expected a byte count of %v but got %v", buff.Len(), e.ByteCount())
+	}
+
+	// Every string must come out intact, and in order for its goroutine.
+	next := make([]int, goroutineCount)
+	data := buff.Bytes()
+	for len(data) > 0 {
+		length, byteCount, err := decodeUintFromBytes(data, 64)
+		if err != nil || int(length) > len(data)-byteCount {
+			t.Errorf("Expected a length-prefixed string but got %v (%v)", describe.D(data), err)
+			return
+		}
+		var g, i int
+		value := string(data[byteCount : byteCount+int(length)])
+		if _, err := fmt.Sscanf(value, "goroutine %d value %d", &g, &i); err != nil || g < 0 || g >= goroutineCount || i != next[g] {
+			t.Errorf("Unexpected string %q", value)
+			return
+		}
+		next[g]++
+		data = data[byteCount+int(length):]
+	}
+	for g, count := range next {
+		if count != valueCount {
+			t.Errorf("Expected %v strings from goroutine %v but got %v", valueCount, g, count)
+		}
+	}
+}
+
+func TestEncoderBudget(t *testing.T) {
+	buff := &bytes.Buffer{}
+	e := NewEncoder(buff, EncoderOptions{MaxByteCount: 6, BufferSize: 100})
+	if _, err := e.EncodeUint64(300); err != nil {
+		t.Error(err)
+	}
+	if _, err := e.EncodeString("abc"); err != nil {
+		t.Error(err)
+	}
+	byteCount, err := e.EncodeUint64(300)
+	budgetErr, ok := err.(*BudgetError)
+	if !ok {
+		t.Fatalf("Expected a *BudgetError but got %v", err)
+	}
+	if byteCount != 0 || *budgetErr != (BudgetError{MaxByteCount: 6, ByteCount: 6, Requested: 2}) {
+		t.Errorf("Unexpected result %v, %+v", byteCount, budgetErr)
+	}
+	if _, err = e.Encode(big.NewInt(0)); err == nil {
+		t.Errorf("Expected a value past the budget to fail")
+	}
+	if _, err = e.PadTo(8); err == nil {
+		t.Errorf("Expected padding past the budget to fail")
+	}
+	if err = e.Flush(); err != nil {
+		t.Error(err)
+	}
+	expected := []byte{0xac, 0x02, 0x03, 'a', 'b', 'c'}
+	if e.ByteCount() != len(expected) || !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func TestEncoderEncodeUint64s(t *testing.T) {
+	values := []uint64{1, 2, 3, 300, 4, 0x7f, 0x80, 0xffffffffffffffff}
+	for i := 0; i < 5000; i++ {
+		values = append(values, uint64(i%128))
+	}
+	values = append(values, 1000)
+
+	expected := []byte{}
+	for _, value := range values {
+		expected = AppendUint64(expected, value)
+	}
+	writer := &writeCounter{}
+	e := NewEncoder(writer, EncoderOptions{})
+	byteCount, err := e.EncodeUint64s(values)
+	if err != nil || byteCount != len(expected) {
+		t.Errorf("Expected %v bytes but got %v (%v)", len(expected), byteCount, err)
+	}
+	if !bytes.Equal(writer.Bytes(), expected) {
+		t.Errorf("Expected EncodeUint64s to match per-value encoding")
+	}
+	if writer.writeCount != 2 {
+		t.Errorf("Expected 2 batched writes but got %v", writer.writeCount)
+	}
+
+	buff := &bytes.Buffer{}
+	e = NewEncoder(buff, EncoderOptions{MaxByteCount: 4})
+	byteCount, err = e.EncodeUint64s([]uint64{1, 2, 300, 3})
+	if _, ok := err.(*BudgetError); !ok || byteCount != 4 {
+		t.Errorf("Expected a *BudgetError after 4 bytes but got %v (%v)", byteCount, err)
+	}
+	e.Flush()
+	e = NewEncoder(buff, EncoderOptions{MaxByteCount: 3})
+	if byteCount, err = e.EncodeUint64s([]uint64{1, 2, 3, 4, 5}); err == nil || byteCount != 3 {
+		t.Errorf("Expected a run of small values to stop at the budget but got %v (%v)", byteCount, err)
+	}
+	e.Flush()
+	if expected := []byte{1, 2, 0xac, 0x02, 1, 2, 3}; !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func BenchmarkEncoderSmallValues(b *testing.B) {
+	values := make([]uint64, 1024)
+	for i := range values {
+		values[i] = uint64(i % 100)
+	}
+	e := NewEncoder(discardWriter{}, EncoderOptions{BufferSize: 4096})
+	b.Run("EncodeUint64", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, value := range values {
+				e.EncodeUint64(value)
+			}
+		}
+	})
+	b.Run("EncodeUint64s", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			e.EncodeUint64s(values)
+		}
+	})
+}
+
+func TestEncoderBeforeEncode(t *testing.T) {
+	errRejected := errors.New("rejected")
+	var seen []string
+	buff := &bytes.Buffer{}
+	e := NewEncoder(buff, EncoderOptions{BeforeEncode: func(value Value, encoded []byte) error {
+		seen = append(seen, fmt.Sprintf("%v:% x", value, encoded))
+		if value.Cmp(Uint64Value(1000)) == 0 {
+			return errRejected
+		}
+		for i := range encoded {
+			encoded[i] ^= 0x01
+		}
+		return nil
+	}})
+	e.EncodeUint64(300)
+	e.Encode(big.NewInt(2))
+	if byteCount, err := e.EncodeUint64s([]uint64{3, 1000, 4}); err != errRejected || byteCount != 1 {
+		t.Errorf("Expected the hook to reject 1000 after 1 byte but got %v (%v)", byteCount, err)
+	}
+	if _, err := e.EncodeUint64(1000); err != errRejected {
+		t.Errorf("Expected the hook to reject 1000 but got %v", err)
+	}
+	e.Flush()
+
+	expectedSeen := []string{"300:ac 02", "2:02", "3:03", "1000:e8 07", "1000:e8 07"}
+	if fmt.Sprint(seen) != fmt.Sprint(expectedSeen) {
+		t.Errorf("Expected the hook to see %v but got %v", expectedSeen, seen)
+	}
+	if expected := []byte{0xad, 0x03, 0x03, 0x02}; !bytes.Equal(buff.Bytes(), expected) || e.ByteCount() != len(expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
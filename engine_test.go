@@ -0,0 +1,63 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertEnginesAgree(t *testing.T, value *big.Int) {
+	expected := make([]byte, EncodedSize(value))
+	expected = expected[:EncodeToBytesWithEngine(value, expected, NativeEngine)]
+	for _, engine := range []Engine{Engine32, Engine64} {
+		actual := make([]byte, EncodedSize(value))
+		actual = actual[:EncodeToBytesWithEngine(value, actual, engine)]
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("Engine %v: Expected %v to encode to %v but got %v", engine, value, describe.D(expected), describe.D(actual))
+		}
+	}
+	if decoded, _, err := DecodeBigFromBytes(expected, nil); err != nil || decoded.Cmp(value) != 0 {
+		t.Errorf("Expected %v to round-trip but got %v (%v)", value, decoded, err)
+	}
+}
+
+func TestEnginesAgree(t *testing.T) {
+	assertEnginesAgree(t, big.NewInt(0))
+	assertEnginesAgree(t, big.NewInt(1))
+	assertEnginesAgree(t, newBigFromHex("ffffffff"))
+	assertEnginesAgree(t, newBigFromHex("100000000"))
+	assertEnginesAgree(t, newBigFromHex("ffffffffffffffff"))
+	assertEnginesAgree(t, newBigFromHex("10000000000000000"))
+
+	// Exercise every bit length through enough words to cycle the shift
+	// tables of both engines several times.
+	random := rand.New(rand.NewSource(1))
+	for bitLength := 1; bitLength <= 2000; bitLength++ {
+		value := new(big.Int).Rand(random, new(big.Int).Lsh(big.NewInt(1), uint(bitLength)))
+		value.SetBit(value, bitLength-1, 1)
+		assertEnginesAgree(t, value)
+	}
+}
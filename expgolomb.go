@@ -0,0 +1,127 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math"
+)
+
+// EncodedBitSizeExpGolomb returns the number of bits that WriteExpGolomb
+// would write for value.
+func EncodedBitSizeExpGolomb(value uint64) int {
+	if value == math.MaxUint64 {
+		return 129
+	}
+	return EncodedBitSizeEliasGamma(value + 1)
+}
+
+// EncodedBitSizeSignedExpGolomb returns the number of bits that
+// WriteSignedExpGolomb would write for value, or 0 if value is
+// math.MinInt64.
+func EncodedBitSizeSignedExpGolomb(value int64) int {
+	if value == math.MinInt64 {
+		return 0
+	}
+	return EncodedBitSizeExpGolomb(signedExpGolombCodeNum(value))
+}
+
+// WriteExpGolomb writes value as an order-0 exponential-Golomb code (the
+// ue(v) syntax element of H.264 and H.265): the Elias gamma code of
+// value+1. Use MSBFirst ordering for H.264-style bitstreams.
+func (w *BitWriter) WriteExpGolomb(value uint64) (err error) {
+	if value != math.MaxUint64 {
+		return w.WriteEliasGamma(value + 1)
+	}
+	// value+1 is 2^64: 64 zeroes, a 1, and 64 more zeroes.
+	for i := 0; i < 64; i++ {
+		if err = w.WriteBits(0, 1); err != nil {
+			return
+		}
+	}
+	if err = w.WriteBits(1, 1); err != nil {
+		return
+	}
+	return w.WriteBits(0, 64)
+}
+
+// ReadExpGolomb reads a value written by WriteExpGolomb. Returns io.EOF if
+// the stream ended before any bits were read, io.ErrUnexpectedEOF if it
+// ended partway through, or ErrOverflow if the value doesn't fit into a
+// uint64.
+func (r *BitReader) ReadExpGolomb() (value uint64, err error) {
+	n, err := r.readZeroRun(64)
+	if err != nil {
+		return
+	}
+	low, err := r.ReadBits(n)
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	if n == 64 {
+		if low != 0 {
+			err = ErrOverflow
+			return
+		}
+		value = math.MaxUint64
+		return
+	}
+	value = (1<<uint(n) | low) - 1
+	return
+}
+
+// WriteSignedExpGolomb writes value as a signed exponential-Golomb code
+// (the se(v) syntax element of H.264 and H.265), which maps positive k to
+// 2k-1 and non-positive k to -2k before writing it with WriteExpGolomb.
+// math.MinInt64 maps outside of the uint64 range and returns ErrOverflow.
+func (w *BitWriter) WriteSignedExpGolomb(value int64) error {
+	if value == math.MinInt64 {
+		return ErrOverflow
+	}
+	return w.WriteExpGolomb(signedExpGolombCodeNum(value))
+}
+
+// ReadSignedExpGolomb reads a value written by WriteSignedExpGolomb. Errors
+// are the same as for ReadExpGolomb.
+func (r *BitReader) ReadSignedExpGolomb() (value int64, err error) {
+	codeNum, err := r.ReadExpGolomb()
+	if err != nil {
+		return
+	}
+	if codeNum&1 == 0 {
+		value = -int64(codeNum / 2)
+		return
+	}
+	magnitude := codeNum/2 + 1
+	if magnitude > math.MaxInt64 {
+		err = ErrOverflow
+		return
+	}
+	value = int64(magnitude)
+	return
+}
+
+func signedExpGolombCodeNum(value int64) uint64 {
+	if value > 0 {
+		return uint64(value)*2 - 1
+	}
+	return uint64(-value) * 2
+}
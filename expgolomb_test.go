@@ -0,0 +1,132 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestExpGolombBits(t *testing.T) {
+	buff := &bytes.Buffer{}
+	writer := NewBitWriter(buff, MSBFirst)
+	// 0 = 1, 1 = 010, 2 = 011, 3 = 00100 -> 1010 0110 0100 0000
+	for _, v := range []uint64{0, 1, 2, 3} {
+		writer.WriteExpGolomb(v)
+	}
+	writer.Flush()
+	expected := []byte{0xa6, 0x40}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func TestSignedExpGolombBits(t *testing.T) {
+	buff := &bytes.Buffer{}
+	writer := NewBitWriter(buff, MSBFirst)
+	// 0 = 1, 1 = 010, -1 = 011, 2 = 00100, -2 = 00101 -> 1010 0110 0100 0010 1
+	for _, v := range []int64{0, 1, -1, 2, -2} {
+		writer.WriteSignedExpGolomb(v)
+	}
+	writer.Flush()
+	expected := []byte{0xa6, 0x42, 0x80}
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+}
+
+func TestExpGolombRoundTrip(t *testing.T) {
+	unsigned := []uint64{0, 1, 2, 300, 0x0123456789abcdef, math.MaxUint64 - 1, math.MaxUint64}
+	signed := []int64{0, 1, -1, 300, -300, math.MaxInt64, math.MinInt64 + 1}
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		buff := &bytes.Buffer{}
+		writer := NewBitWriter(buff, order)
+		bitCount := 0
+		for _, v := range unsigned {
+			if err := writer.WriteExpGolomb(v); err != nil {
+				t.Error(err)
+				return
+			}
+			bitCount += EncodedBitSizeExpGolomb(v)
+		}
+		for _, v := range signed {
+			if err := writer.WriteSignedExpGolomb(v); err != nil {
+				t.Error(err)
+				return
+			}
+			bitCount += EncodedBitSizeSignedExpGolomb(v)
+		}
+		writer.Flush()
+		if expected := (bitCount + 7) / 8; buff.Len() != expected {
+			t.Errorf("Order %v: Expected %v bytes but got %v", order, expected, buff.Len())
+		}
+
+		reader := NewBitReader(buff, order)
+		for _, expected := range unsigned {
+			actual, err := reader.ReadExpGolomb()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if actual != expected {
+				t.Errorf("Order %v: Expected %x but got %x", order, expected, actual)
+			}
+		}
+		for _, expected := range signed {
+			actual, err := reader.ReadSignedExpGolomb()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if actual != expected {
+				t.Errorf("Order %v: Expected %v but got %v", order, expected, actual)
+			}
+		}
+	}
+}
+
+func TestExpGolombErrors(t *testing.T) {
+	writer := NewBitWriter(&bytes.Buffer{}, MSBFirst)
+	if err := writer.WriteSignedExpGolomb(math.MinInt64); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+
+	// 64 zeroes, a 1, then a nonzero 64-bit suffix exceeds 2^64-1.
+	data := append(make([]byte, 8), 0x80, 0, 0, 0, 0, 0, 0, 0, 0x80)
+	if _, err := NewBitReader(bytes.NewBuffer(data), MSBFirst).ReadExpGolomb(); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+	if _, err := NewBitReader(bytes.NewBuffer(make([]byte, 9)), MSBFirst).ReadExpGolomb(); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+
+	// The code number 2^64-1 maps to +2^63, which doesn't fit into an int64.
+	buff := &bytes.Buffer{}
+	writer = NewBitWriter(buff, MSBFirst)
+	writer.WriteExpGolomb(math.MaxUint64)
+	writer.Flush()
+	if _, err := NewBitReader(buff, MSBFirst).ReadSignedExpGolomb(); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+}
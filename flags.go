@@ -0,0 +1,166 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/big"
+)
+
+// Flag is the position of a bit in a Flags field. Protocols define their
+// flags as constants, starting from 0.
+type Flag uint
+
+// Flags is a set of bit flags that's encoded as a single ULEB128 value, for
+// feature negotiation and similar protocol fields. Any number of flags is
+// supported, but flags 0-63 are the cheapest. Flags that a receiver doesn't
+// know about are kept when decoding, so they survive being re-encoded. The
+// zero value has no flags set.
+type Flags struct {
+	low uint64
+	// Flags 64 and up, shifted down by 64. Never modified in place, so that
+	// copies of a Flags don't share changes.
+	high *big.Int
+}
+
+// FlagsFromUint64 returns the flags set in the bits of value.
+func FlagsFromUint64(value uint64) Flags {
+	return Flags{low: value}
+}
+
+// Set sets a flag.
+func (f *Flags) Set(flag Flag) {
+	if flag < 64 {
+		f.low |= 1 << flag
+		return
+	}
+	f.high = f.setHighBit(flag, 1)
+}
+
+// Clear clears a flag.
+func (f *Flags) Clear(flag Flag) {
+	if flag < 64 {
+		f.low &^= 1 << flag
+		return
+	}
+	if f.Test(flag) {
+		f.high = f.setHighBit(flag, 0)
+	}
+}
+
+// Test reports whether a flag is set.
+func (f Flags) Test(flag Flag) bool {
+	if flag < 64 {
+		return f.low&(1<<flag) != 0
+	}
+	return f.high != nil && f.high.Bit(int(flag-64)) != 0
+}
+
+// Uint64 returns flags 0-63 as the bits of a uint64.
+func (f Flags) Uint64() uint64 {
+	return f.low
+}
+
+// IsZero reports whether no flags are set.
+func (f Flags) IsZero() bool {
+	return f.low == 0 && !f.hasHigh()
+}
+
+// Unknown returns the flags that are set in f but not in known, such as the
+// flags a peer sent that this side doesn't support.
+func (f Flags) Unknown(known Flags) (unknown Flags) {
+	unknown.low = f.low &^ known.low
+	if f.hasHigh() {
+		unknown.high = new(big.Int).Set(f.high)
+		if known.hasHigh() {
+			unknown.high.AndNot(unknown.high, known.high)
+		}
+	}
+	return
+}
+
+// EncodedSize returns the number of bytes the flags encode to.
+func (f Flags) EncodedSize() int {
+	if f.hasHigh() {
+		return EncodedSize(f.big())
+	}
+	return EncodedSizeUint64(f.low)
+}
+
+// AppendTo appends the encoded flags to buffer.
+func (f Flags) AppendTo(buffer []byte) []byte {
+	if f.hasHigh() {
+		return Append(buffer, f.big())
+	}
+	return AppendUint64(buffer, f.low)
+}
+
+// Encode writes the encoded flags to writer.
+func (f Flags) Encode(writer io.Writer) (byteCount int, err error) {
+	return writer.Write(f.AppendTo(make([]byte, 0, f.EncodedSize())))
+}
+
+// DecodeFlags reads flags from reader. Errors are the same as for Decode.
+func DecodeFlags(reader io.Reader) (flags Flags, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := Decode(reader)
+	if err == nil {
+		flags = newFlags(asUint, asBigInt)
+	}
+	return
+}
+
+// DecodeFlagsFromBytes decodes flags from the start of buffer. Errors are the
+// same as for DecodeFromBytes.
+func DecodeFlagsFromBytes(buffer []byte) (flags Flags, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err == nil {
+		flags = newFlags(asUint, asBigInt)
+	}
+	return
+}
+
+func newFlags(asUint uint64, asBigInt *big.Int) (flags Flags) {
+	if asBigInt == nil {
+		return Flags{low: asUint}
+	}
+	flags.low = new(big.Int).And(asBigInt, maxUint64Big).Uint64()
+	flags.high = new(big.Int).Rsh(asBigInt, 64)
+	return
+}
+
+var maxUint64Big = new(big.Int).SetUint64(^uint64(0))
+
+func (f Flags) hasHigh() bool {
+	return f.high != nil && f.high.Sign() != 0
+}
+
+func (f Flags) big() *big.Int {
+	value := new(big.Int).Lsh(f.high, 64)
+	return value.Or(value, new(big.Int).SetUint64(f.low))
+}
+
+func (f Flags) setHighBit(flag Flag, bit uint) *big.Int {
+	high := f.high
+	if high == nil {
+		high = new(big.Int)
+	}
+	return new(big.Int).SetBit(high, int(flag-64), bit)
+}
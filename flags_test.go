@@ -0,0 +1,118 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+const (
+	flagCompression Flag = 0
+	flagEncryption  Flag = 7
+	flagFuture      Flag = 100
+)
+
+func TestFlagsSetClearTest(t *testing.T) {
+	var flags Flags
+	if !flags.IsZero() || flags.Test(flagCompression) || flags.Test(flagFuture) {
+		t.Errorf("Expected the zero value to have no flags set")
+	}
+	flags.Set(flagCompression)
+	flags.Set(flagEncryption)
+	flags.Set(flagFuture)
+	if !flags.Test(flagCompression) || !flags.Test(flagEncryption) || !flags.Test(flagFuture) || flags.Test(1) || flags.Test(99) {
+		t.Errorf("Unexpected flags %v", describe.D(flags.AppendTo(nil)))
+	}
+	if flags.Uint64() != 0x81 {
+		t.Errorf("Expected low bits 0x81 but got %x", flags.Uint64())
+	}
+
+	copied := flags
+	copied.Clear(flagFuture)
+	copied.Clear(flagEncryption)
+	if !flags.Test(flagFuture) || !flags.Test(flagEncryption) {
+		t.Errorf("Expected clearing a copy to leave the original unchanged")
+	}
+	if copied.Test(flagFuture) || copied.Test(flagEncryption) || !copied.Test(flagCompression) {
+		t.Errorf("Expected only the compression flag to remain")
+	}
+	copied.Clear(flagCompression)
+	copied.Clear(200)
+	if !copied.IsZero() {
+		t.Errorf("Expected no flags to remain")
+	}
+}
+
+func TestFlagsEncoding(t *testing.T) {
+	flags := FlagsFromUint64(0x81)
+	if expected := []byte{0x81, 0x01}; !bytes.Equal(flags.AppendTo(nil), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(flags.AppendTo(nil)))
+	}
+
+	flags.Set(flagFuture)
+	encoded := flags.AppendTo(nil)
+	if len(encoded) != flags.EncodedSize() || len(encoded) != 15 {
+		t.Errorf("Expected 15 bytes but got %v (size %v)", len(encoded), flags.EncodedSize())
+	}
+
+	// A receiver that only knows the low flags keeps the high one.
+	decoded, byteCount, err := DecodeFlagsFromBytes(encoded)
+	if err != nil || byteCount != len(encoded) {
+		t.Errorf("Expected to decode %v bytes but got %v (%v)", len(encoded), byteCount, err)
+	}
+	if !decoded.Test(flagFuture) || !decoded.Test(flagEncryption) {
+		t.Errorf("Expected the decoded flags to keep all bits")
+	}
+	buffer := &bytes.Buffer{}
+	if _, err = decoded.Encode(buffer); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), encoded) {
+		t.Errorf("Expected %v but got %v", describe.D(encoded), describe.D(buffer.Bytes()))
+	}
+	if decoded, _, err = DecodeFlags(buffer); err != nil || !bytes.Equal(decoded.AppendTo(nil), encoded) {
+		t.Errorf("Expected flags to round trip through a stream (%v)", err)
+	}
+}
+
+func TestFlagsUnknown(t *testing.T) {
+	var known, received Flags
+	known.Set(flagCompression)
+	known.Set(flagEncryption)
+	received.Set(flagCompression)
+	received.Set(3)
+	received.Set(flagFuture)
+
+	unknown := received.Unknown(known)
+	if unknown.Test(flagCompression) || !unknown.Test(3) || !unknown.Test(flagFuture) {
+		t.Errorf("Unexpected unknown flags %v", describe.D(unknown.AppendTo(nil)))
+	}
+	known.Set(flagFuture)
+	if unknown = received.Unknown(known); unknown.Test(flagFuture) || !unknown.Test(3) {
+		t.Errorf("Unexpected unknown flags %v", describe.D(unknown.AppendTo(nil)))
+	}
+	if !known.Unknown(known).IsZero() {
+		t.Errorf("Expected no unknown flags")
+	}
+}
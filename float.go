@@ -0,0 +1,138 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math"
+	"math/bits"
+)
+
+// The float helpers encode the IEEE 754 bit pattern of a value as a
+// ULEB128 integer. When reverse is true, the bytes of the bit pattern are
+// reversed before encoding, which moves the sign, exponent, and high
+// mantissa bits to the low end. Values with short mantissas (small
+// integers, halves, quarters and so on) then encode in a few bytes rather
+// than the full 10 (or 5 for float32). Both sides must agree on reverse.
+
+// EncodeFloat64Bits encodes the bit pattern of a float64 value.
+func EncodeFloat64Bits(value float64, reverse bool, writer io.Writer) (byteCount int, err error) {
+	return EncodeUint64(float64ToBits(value, reverse), writer)
+}
+
+// AppendFloat64Bits appends the encoded bit pattern of a float64 value to
+// buffer, returning the extended buffer.
+func AppendFloat64Bits(buffer []byte, value float64, reverse bool) []byte {
+	return AppendUint64(buffer, float64ToBits(value, reverse))
+}
+
+// DecodeFloat64Bits decodes a float64 that was encoded with the same
+// setting of reverse. A stream that ends partway through the value returns
+// io.ErrUnexpectedEOF, and a bit pattern wider than 64 bits returns
+// ErrOverflow.
+func DecodeFloat64Bits(reader io.Reader, reverse bool) (value float64, byteCount int, err error) {
+	asUint, byteCount, err := decodeUint64(reader, []byte{0})
+	if err != nil {
+		return
+	}
+	value = float64FromBits(asUint, reverse)
+	return
+}
+
+// DecodeFloat64BitsFromBytes decodes a float64 from the start of buffer.
+// Errors are the same as for DecodeFromBytes, plus ErrOverflow if the bit
+// pattern is wider than 64 bits.
+func DecodeFloat64BitsFromBytes(buffer []byte, reverse bool) (value float64, byteCount int, err error) {
+	asUint, byteCount, err := decodeUintFromBytes(buffer, 64)
+	if err != nil {
+		return
+	}
+	value = float64FromBits(asUint, reverse)
+	return
+}
+
+// EncodeFloat32Bits encodes the bit pattern of a float32 value.
+func EncodeFloat32Bits(value float32, reverse bool, writer io.Writer) (byteCount int, err error) {
+	return EncodeUint64(float32ToBits(value, reverse), writer)
+}
+
+// AppendFloat32Bits appends the encoded bit pattern of a float32 value to
+// buffer, returning the extended buffer.
+func AppendFloat32Bits(buffer []byte, value float32, reverse bool) []byte {
+	return AppendUint64(buffer, float32ToBits(value, reverse))
+}
+
+// DecodeFloat32Bits decodes a float32 that was encoded with the same
+// setting of reverse. A stream that ends partway through the value returns
+// io.ErrUnexpectedEOF, and a bit pattern wider than 32 bits returns
+// ErrOverflow.
+func DecodeFloat32Bits(reader io.Reader, reverse bool) (value float32, byteCount int, err error) {
+	asUint, byteCount, err := decodeUint64(reader, []byte{0})
+	if err != nil {
+		return
+	}
+	if asUint > math.MaxUint32 {
+		err = ErrOverflow
+		return
+	}
+	value = float32FromBits(uint32(asUint), reverse)
+	return
+}
+
+// DecodeFloat32BitsFromBytes decodes a float32 from the start of buffer.
+// Errors are the same as for DecodeFromBytes, plus ErrOverflow if the bit
+// pattern is wider than 32 bits.
+func DecodeFloat32BitsFromBytes(buffer []byte, reverse bool) (value float32, byteCount int, err error) {
+	asUint, byteCount, err := decodeUintFromBytes(buffer, 32)
+	if err != nil {
+		return
+	}
+	value = float32FromBits(uint32(asUint), reverse)
+	return
+}
+
+func float64ToBits(value float64, reverse bool) uint64 {
+	if reverse {
+		return bits.ReverseBytes64(math.Float64bits(value))
+	}
+	return math.Float64bits(value)
+}
+
+func float64FromBits(value uint64, reverse bool) float64 {
+	if reverse {
+		value = bits.ReverseBytes64(value)
+	}
+	return math.Float64frombits(value)
+}
+
+func float32ToBits(value float32, reverse bool) uint64 {
+	if reverse {
+		return uint64(bits.ReverseBytes32(math.Float32bits(value)))
+	}
+	return uint64(math.Float32bits(value))
+}
+
+func float32FromBits(value uint32, reverse bool) float32 {
+	if reverse {
+		value = bits.ReverseBytes32(value)
+	}
+	return math.Float32frombits(value)
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+var floatTestValues = []float64{0, 1, -1, 0.5, 100, 1e100, math.Pi, math.SmallestNonzeroFloat64, math.Inf(1), math.Inf(-1)}
+
+func assertFloat64Bits(t *testing.T, value float64, reverse bool, expected []byte) {
+	encoded := AppendFloat64Bits(nil, value, reverse)
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("Expected %v to encode to %v but got %v", value, describe.D(expected), describe.D(encoded))
+	}
+}
+
+func TestFloat64BitsEncoding(t *testing.T) {
+	// 1.0 = 0x3ff0000000000000, reversed = 0xf03f
+	assertFloat64Bits(t, 1, true, []byte{0xbf, 0xe0, 0x03})
+	assertFloat64Bits(t, 1, false, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0xf8, 0x3f})
+	assertFloat64Bits(t, 0, true, []byte{0x00})
+	assertFloat64Bits(t, 0, false, []byte{0x00})
+}
+
+func TestFloat64BitsRoundTrip(t *testing.T) {
+	for _, reverse := range []bool{false, true} {
+		for _, expected := range append(floatTestValues, math.NaN()) {
+			encoded := AppendFloat64Bits(nil, expected, reverse)
+			value, byteCount, err := DecodeFloat64BitsFromBytes(encoded, reverse)
+			if err != nil || math.Float64bits(value) != math.Float64bits(expected) || byteCount != len(encoded) {
+				t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+			}
+
+			buff := &bytes.Buffer{}
+			if _, err := EncodeFloat64Bits(expected, reverse, buff); err != nil {
+				t.Error(err)
+				return
+			}
+			value, byteCount, err = DecodeFloat64Bits(buff, reverse)
+			if err != nil || math.Float64bits(value) != math.Float64bits(expected) || byteCount != len(encoded) {
+				t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+			}
+		}
+	}
+}
+
+func TestFloat32BitsRoundTrip(t *testing.T) {
+	// 1.0 = 0x3f800000, reversed = 0x803f
+	if encoded := AppendFloat32Bits(nil, 1, true); !bytes.Equal(encoded, []byte{0xbf, 0x80, 0x02}) {
+		t.Errorf("Expected 1 to encode to [bf 80 02] but got %v", describe.D(encoded))
+	}
+	for _, reverse := range []bool{false, true} {
+		for _, v := range floatTestValues {
+			expected := float32(v)
+			encoded := AppendFloat32Bits(nil, expected, reverse)
+			value, byteCount, err := DecodeFloat32BitsFromBytes(encoded, reverse)
+			if err != nil || math.Float32bits(value) != math.Float32bits(expected) || byteCount != len(encoded) {
+				t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+			}
+
+			buff := &bytes.Buffer{}
+			if _, err := EncodeFloat32Bits(expected, reverse, buff); err != nil {
+				t.Error(err)
+				return
+			}
+			value, byteCount, err = DecodeFloat32Bits(buff, reverse)
+			if err != nil || math.Float32bits(value) != math.Float32bits(expected) || byteCount != len(encoded) {
+				t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+			}
+		}
+	}
+}
+
+func TestFloatBitsErrors(t *testing.T) {
+	if _, _, err := DecodeFloat64BitsFromBytes(nil, true); err != io.EOF {
+		t.Errorf("Expected %v but got %v", io.EOF, err)
+	}
+	if _, _, err := DecodeFloat64BitsFromBytes([]byte{0x80}, true); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	if _, _, err := DecodeFloat64Bits(bytes.NewBuffer([]byte{0x80}), true); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	tooWide := AppendUint64(nil, 1<<32)
+	if _, _, err := DecodeFloat32BitsFromBytes(tooWide, false); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	if _, _, err := DecodeFloat32Bits(bytes.NewBuffer(tooWide), false); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	tooWide = []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+	if _, _, err := DecodeFloat64BitsFromBytes(tooWide, false); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+}
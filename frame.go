@@ -0,0 +1,151 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// ErrFrameTooLarge is returned when a frame's length prefix exceeds the
+// maximum frame size.
+var ErrFrameTooLarge = errors.New("uleb128: frame exceeds maximum size")
+
+// FrameWriter is an io.Writer that emits every Write call as a separate
+// record: a ULEB128 length prefix followed by the written bytes.
+type FrameWriter struct {
+	writer io.Writer
+	header []byte
+}
+
+// NewFrameWriter returns a FrameWriter that writes records to writer.
+func NewFrameWriter(writer io.Writer) *FrameWriter {
+	return &FrameWriter{
+		writer: writer,
+		header: make([]byte, MaxBufferWriteBytes),
+	}
+}
+
+// Write emits p as a single length-prefixed record.
+// n is the number of bytes of p that were written (excluding the prefix).
+func (w *FrameWriter) Write(p []byte) (n int, err error) {
+	headerByteCount := EncodeUint64ToBytes(uint64(len(p)), w.header)
+	if _, err = w.writer.Write(w.header[:headerByteCount]); err != nil {
+		return
+	}
+	return w.writer.Write(p)
+}
+
+// FrameReader reads records produced by a FrameWriter. Read never returns
+// bytes from more than one frame, so a Read with a buffer at least as large
+// as the frame returns exactly that frame.
+type FrameReader struct {
+	reader       io.Reader
+	maxFrameSize uint64
+	remaining    uint64
+	inFrame      bool
+	byteBuffer   []byte
+}
+
+// NewFrameReader returns a FrameReader that reads records from reader,
+// rejecting any frame longer than maxFrameSize bytes with ErrFrameTooLarge.
+func NewFrameReader(reader io.Reader, maxFrameSize int) *FrameReader {
+	return &FrameReader{
+		reader:       reader,
+		maxFrameSize: uint64(maxFrameSize),
+		byteBuffer:   []byte{0},
+	}
+}
+
+// NextFrame reads the next complete frame, discarding whatever is left of the
+// frame currently being read. Returns io.EOF if the stream ends cleanly on a
+// frame boundary, and io.ErrUnexpectedEOF if it ends partway through a frame.
+func (r *FrameReader) NextFrame() (frame []byte, err error) {
+	if err = r.skipRemaining(); err != nil {
+		return
+	}
+	length, err := r.readLength()
+	if err != nil {
+		return
+	}
+	frame = make([]byte, length)
+	if _, err = io.ReadFull(r.reader, frame); err != nil {
+		frame = nil
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	return
+}
+
+// Read reads from the current frame, starting a new one if the previous frame
+// has been fully consumed. A zero-length frame yields a read of 0 bytes.
+func (r *FrameReader) Read(p []byte) (n int, err error) {
+	if !r.inFrame {
+		if r.remaining, err = r.readLength(); err != nil {
+			return
+		}
+		r.inFrame = true
+	}
+	if uint64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err = io.ReadFull(r.reader, p)
+	r.remaining -= uint64(n)
+	if r.remaining == 0 {
+		r.inFrame = false
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+func (r *FrameReader) readLength() (length uint64, err error) {
+	if length, _, err = decodeUint64(r.reader, r.byteBuffer); err != nil {
+		if err == ErrOverflow {
+			err = ErrFrameTooLarge
+		}
+		return
+	}
+	if length > r.maxFrameSize {
+		length = 0
+		err = ErrFrameTooLarge
+	}
+	return
+}
+
+func (r *FrameReader) skipRemaining() (err error) {
+	if !r.inFrame {
+		return
+	}
+	var skipped int64
+	skipped, err = io.CopyN(ioutil.Discard, r.reader, int64(r.remaining))
+	r.remaining -= uint64(skipped)
+	if r.remaining == 0 {
+		r.inFrame = false
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertFrameWriter(t *testing.T, records [][]byte, expectedBytes ...byte) {
+	buff := &bytes.Buffer{}
+	writer := NewFrameWriter(buff)
+	for _, record := range records {
+		n, err := writer.Write(record)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if n != len(record) {
+			t.Errorf("Expected writing %v to report %v bytes but got %v", describe.D(record), len(record), n)
+			return
+		}
+	}
+	if !reflect.DeepEqual(buff.Bytes(), expectedBytes) {
+		t.Errorf("Expected records %v to frame as %v but got %v", describe.D(records), describe.D(expectedBytes), describe.D(buff.Bytes()))
+	}
+}
+
+func TestFrameWriter(t *testing.T) {
+	assertFrameWriter(t, [][]byte{{}}, 0x00)
+	assertFrameWriter(t, [][]byte{{0x01}}, 0x01, 0x01)
+	assertFrameWriter(t, [][]byte{{0x01, 0x02}, {}, {0x03}}, 0x02, 0x01, 0x02, 0x00, 0x01, 0x03)
+
+	record := make([]byte, 200)
+	expected := append([]byte{0xc8, 0x01}, record...)
+	assertFrameWriter(t, [][]byte{record}, expected...)
+}
+
+func assertNextFrames(t *testing.T, maxFrameSize int, expectedFrames [][]byte, expectedErr error, b ...byte) {
+	reader := NewFrameReader(bytes.NewBuffer(b), maxFrameSize)
+	for _, expectedFrame := range expectedFrames {
+		actualFrame, err := reader.NextFrame()
+		if err != nil {
+			t.Errorf("Reading frames from %v: %v", describe.D(b), err)
+			return
+		}
+		if !bytes.Equal(actualFrame, expectedFrame) {
+			t.Errorf("Expected frame %v but got %v", describe.D(expectedFrame), describe.D(actualFrame))
+			return
+		}
+	}
+	if _, err := reader.NextFrame(); err != expectedErr {
+		t.Errorf("Expected final NextFrame on %v to return %v but got %v", describe.D(b), expectedErr, err)
+	}
+}
+
+func TestFrameReaderNextFrame(t *testing.T) {
+	assertNextFrames(t, 10, nil, io.EOF)
+	assertNextFrames(t, 10, [][]byte{{}}, io.EOF, 0x00)
+	assertNextFrames(t, 10, [][]byte{{0x01, 0x02}, {}, {0x03}}, io.EOF, 0x02, 0x01, 0x02, 0x00, 0x01, 0x03)
+	assertNextFrames(t, 1, [][]byte{{0x01}}, ErrFrameTooLarge, 0x01, 0x01, 0x02, 0x01, 0x02)
+	assertNextFrames(t, 10, nil, io.ErrUnexpectedEOF, 0x02, 0x01)
+	assertNextFrames(t, 10, nil, io.ErrUnexpectedEOF, 0x80)
+	assertNextFrames(t, 1000, nil, ErrFrameTooLarge,
+		0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01)
+}
+
+func TestFrameReaderRead(t *testing.T) {
+	reader := NewFrameReader(bytes.NewBuffer([]byte{0x03, 0x01, 0x02, 0x03, 0x02, 0x04, 0x05}), 10)
+	buffer := make([]byte, 10)
+
+	n, err := reader.Read(buffer[:2])
+	if err != nil || !bytes.Equal(buffer[:n], []byte{0x01, 0x02}) {
+		t.Errorf("Expected partial frame [1 2] but got %v (%v)", describe.D(buffer[:n]), err)
+	}
+	n, err = reader.Read(buffer)
+	if err != nil || !bytes.Equal(buffer[:n], []byte{0x03}) {
+		t.Errorf("Expected rest of frame [3] but got %v (%v)", describe.D(buffer[:n]), err)
+	}
+	n, err = reader.Read(buffer)
+	if err != nil || !bytes.Equal(buffer[:n], []byte{0x04, 0x05}) {
+		t.Errorf("Expected frame [4 5] but got %v (%v)", describe.D(buffer[:n]), err)
+	}
+	if _, err = reader.Read(buffer); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	records := [][]byte{{0x01}, make([]byte, 300), {}, {0x02, 0x03}}
+	buff := &bytes.Buffer{}
+	writer := NewFrameWriter(buff)
+	for _, record := range records {
+		if _, err := writer.Write(record); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	reader := NewFrameReader(buff, 300)
+	for _, record := range records {
+		frame, err := reader.NextFrame()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(frame, record) {
+			t.Errorf("Expected frame %v but got %v", describe.D(record), describe.D(frame))
+		}
+	}
+}
+
+func TestFrameReaderEndlessLength(t *testing.T) {
+	reader := &continuationReader{}
+	if _, err := NewFrameReader(reader, 1<<30).NextFrame(); err != ErrFrameTooLarge {
+		t.Errorf("Expected %v but got %v", ErrFrameTooLarge, err)
+	}
+	reader = &continuationReader{}
+	if _, err := NewFrameReader(reader, 1<<30).Read(make([]byte, 10)); err != ErrFrameTooLarge {
+		t.Errorf("Expected %v but got %v", ErrFrameTooLarge, err)
+	}
+	if reader.byteCount > MaxBufferWriteBytes {
+		t.Errorf("Expected to read at most %v bytes but read %v", MaxBufferWriteBytes, reader.byteCount)
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/kstenerud/go-uleb128
 
-go 1.11
+go 1.13
 
 require github.com/kstenerud/go-describe v1.2.13
@@ -0,0 +1,221 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// A header identifies a standalone file or stream of encoded data, so that
+// its layout can change in later versions of this library without breaking
+// data that's already been written. It consists of:
+//
+//   - The four bytes of HeaderMagic.
+//   - The format version as a ULEB128.
+//   - The codec id as a ULEB128.
+//   - The compression id as a ULEB128 (see Compressor).
+//   - The length of any extension fields as a ULEB128, followed by the
+//     extension fields themselves.
+//
+// The frame, block and array layouts don't carry a header of their own,
+// since they're usually embedded in other formats (WASM vectors, messages on
+// a connection, tables inside a file) whose bytes the caller doesn't control.
+// To store one of them as a standalone file, write it through
+// NewContainerWriter and read it back through NewContainerReader, which
+// handle the header and any compression it declares.
+//
+// Compatibility rules:
+//
+//   - HeaderMagic never changes.
+//   - A change that older readers can't safely ignore increments the version.
+//     Readers accept every version up to HeaderVersion, and return
+//     ErrUnsupportedVersion for anything newer.
+//   - A change that older readers can safely ignore is added as an extension
+//     field without changing the version. Readers that don't know about a
+//     field skip it using the extension length.
+//
+// Since the bytes of HeaderMagic also form a valid run of ULEB128 values,
+// a header can't be detected in data that may not have one; whether data
+// is headered must be known from context.
+
+// HeaderMagic is the first four bytes of every header.
+var HeaderMagic = [4]byte{'U', 'L', 'E', 'B'}
+
+// HeaderVersion is the newest header version this library writes and reads.
+const HeaderVersion = 1
+
+// Codec ids for use in a header.
+const (
+	CodecIDULEB128    = 0
+	CodecIDSyncVarint = 1
+)
+
+// ErrBadMagic is returned when data that should start with a header doesn't.
+var ErrBadMagic = errors.New("uleb128: missing header magic")
+
+// ErrUnsupportedVersion is returned when a header has a version newer than
+// HeaderVersion.
+var ErrUnsupportedVersion = errors.New("uleb128: unsupported header version")
+
+// Header describes how the data following it was written.
+type Header struct {
+	Version uint64
+	CodecID uint64
+	// How the data following the header is compressed.
+	CompressionID uint64
+	// Extension fields this library version doesn't interpret. They're kept
+	// when decoding so that they can be written back unchanged.
+	Extensions []byte
+}
+
+// NewHeader returns a header for the current version with the given codec.
+func NewHeader(codecID uint64) Header {
+	return Header{Version: HeaderVersion, CodecID: codecID}
+}
+
+// Codec returns the built-in codec identified by the header's codec id.
+func (h Header) Codec() (codec Codec, ok bool) {
+	switch h.CodecID {
+	case CodecIDULEB128:
+		return ULEB128Codec, true
+	case CodecIDSyncVarint:
+		return SyncVarintCodec, true
+	}
+	return
+}
+
+// EncodedSize returns the number of bytes the header encodes to.
+func (h Header) EncodedSize() int {
+	return len(HeaderMagic) + EncodedSizeUint64(h.Version) + EncodedSizeUint64(h.CodecID) +
+		EncodedSizeUint64(h.CompressionID) + EncodedSizeUint64(uint64(len(h.Extensions))) +
+		len(h.Extensions)
+}
+
+// AppendHeader appends the encoded header to buffer.
+func AppendHeader(buffer []byte, header Header) []byte {
+	buffer = append(buffer, HeaderMagic[:]...)
+	buffer = AppendUint64(buffer, header.Version)
+	buffer = AppendUint64(buffer, header.CodecID)
+	buffer = AppendUint64(buffer, header.CompressionID)
+	buffer = AppendUint64(buffer, uint64(len(header.Extensions)))
+	return append(buffer, header.Extensions...)
+}
+
+// WriteHeader writes the encoded header to writer.
+func WriteHeader(writer io.Writer, header Header) (byteCount int, err error) {
+	return writer.Write(AppendHeader(make([]byte, 0, header.EncodedSize()), header))
+}
+
+// DecodeHeaderFromBytes decodes a header from the start of buffer. If buffer
+// is empty, err will be io.EOF, and if it ends partway through the header,
+// err will be ErrTruncated.
+func DecodeHeaderFromBytes(buffer []byte) (header Header, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	if len(buffer) < len(HeaderMagic) {
+		if !bytes.HasPrefix(HeaderMagic[:], buffer) {
+			err = ErrBadMagic
+		} else {
+			err = ErrTruncated
+		}
+		return
+	}
+	if !bytes.Equal(buffer[:len(HeaderMagic)], HeaderMagic[:]) {
+		err = ErrBadMagic
+		return
+	}
+	byteCount = len(HeaderMagic)
+	var extensionSize uint64
+	fields := header.fields(&extensionSize)
+	for i := range fields {
+		var fieldSize int
+		if *fields[i], fieldSize, err = decodeUint64FromBytes(buffer[byteCount:]); err != nil {
+			return
+		}
+		byteCount += fieldSize
+		if i == 0 && (header.Version == 0 || header.Version > HeaderVersion) {
+			err = ErrUnsupportedVersion
+			return
+		}
+	}
+	if extensionSize > uint64(len(buffer)-byteCount) {
+		err = ErrTruncated
+		return
+	}
+	end := byteCount + int(extensionSize)
+	if extensionSize > 0 {
+		header.Extensions = buffer[byteCount:end:end]
+	}
+	byteCount = end
+	return
+}
+
+// ReadHeader reads a header from reader. If the stream ends before the
+// header starts, err will be io.EOF, and if it ends partway through,
+// err will be io.ErrUnexpectedEOF. Extension fields longer than
+// maxExtensionSize return ErrTooLong.
+func ReadHeader(reader io.Reader, maxExtensionSize int) (header Header, byteCount int, err error) {
+	var magic [len(HeaderMagic)]byte
+	if byteCount, err = io.ReadFull(reader, magic[:]); err != nil {
+		return
+	}
+	if magic != HeaderMagic {
+		err = ErrBadMagic
+		return
+	}
+	buffer := []byte{0}
+	var extensionSize uint64
+	fields := header.fields(&extensionSize)
+	for i := range fields {
+		var fieldSize int
+		*fields[i], fieldSize, err = decodeUint64(reader, buffer)
+		byteCount += fieldSize
+		if err != nil {
+			err = unexpectedEOF(err)
+			return
+		}
+		if i == 0 && (header.Version == 0 || header.Version > HeaderVersion) {
+			err = ErrUnsupportedVersion
+			return
+		}
+	}
+	if extensionSize > uint64(maxExtensionSize) {
+		err = ErrTooLong
+		return
+	}
+	if extensionSize > 0 {
+		header.Extensions = make([]byte, extensionSize)
+		var n int
+		n, err = io.ReadFull(reader, header.Extensions)
+		byteCount += n
+		err = unexpectedEOF(err)
+	}
+	return
+}
+
+// Return the ULEB128 fields of a header in order, for decoding into.
+func (h *Header) fields(extensionSize *uint64) [4]*uint64 {
+	return [4]*uint64{&h.Version, &h.CodecID, &h.CompressionID, extensionSize}
+}
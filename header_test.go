@@ -0,0 +1,105 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	for _, header := range []Header{
+		NewHeader(CodecIDULEB128),
+		NewHeader(CodecIDSyncVarint),
+		{Version: HeaderVersion, CodecID: 300, Extensions: []byte{1, 2, 3}},
+		{Version: HeaderVersion, CodecID: CodecIDULEB128, CompressionID: 1000},
+	} {
+		encoded := AppendHeader(nil, header)
+		if len(encoded) != header.EncodedSize() {
+			t.Errorf("Expected size %v but got %v", header.EncodedSize(), len(encoded))
+		}
+
+		decoded, byteCount, err := DecodeHeaderFromBytes(append(encoded, 0xff))
+		if err != nil {
+			t.Error(err)
+		}
+		if byteCount != len(encoded) || !reflect.DeepEqual(decoded, header) {
+			t.Errorf("Expected %+v (%v bytes) but got %+v (%v bytes)", header, len(encoded), decoded, byteCount)
+		}
+
+		buffer := &bytes.Buffer{}
+		if _, err = WriteHeader(buffer, header); err != nil {
+			t.Error(err)
+		}
+		decoded, byteCount, err = ReadHeader(buffer, 100)
+		if err != nil {
+			t.Error(err)
+		}
+		if byteCount != len(encoded) || !reflect.DeepEqual(decoded, header) {
+			t.Errorf("Expected %+v (%v bytes) but got %+v (%v bytes)", header, len(encoded), decoded, byteCount)
+		}
+	}
+}
+
+func TestHeaderEncoding(t *testing.T) {
+	expected := []byte{'U', 'L', 'E', 'B', 0x01, 0x01, 0x00, 0x00}
+	actual := AppendHeader(nil, NewHeader(CodecIDSyncVarint))
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+	}
+
+	codec, ok := NewHeader(CodecIDSyncVarint).Codec()
+	if !ok || codec != SyncVarintCodec {
+		t.Errorf("Expected the syncvarint codec")
+	}
+	if _, ok = NewHeader(99).Codec(); ok {
+		t.Errorf("Expected codec id 99 to be unknown")
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	for _, test := range []struct {
+		encoded   []byte
+		bytesErr  error
+		streamErr error
+	}{
+		{[]byte{}, io.EOF, io.EOF},
+		{[]byte{'U', 'L'}, ErrTruncated, io.ErrUnexpectedEOF},
+		{[]byte{'U', 'X'}, ErrBadMagic, io.ErrUnexpectedEOF},
+		{[]byte{'U', 'L', 'E', 'X', 0x01, 0x00, 0x00}, ErrBadMagic, ErrBadMagic},
+		{[]byte{'U', 'L', 'E', 'B', 0x01, 0x00}, ErrTruncated, io.ErrUnexpectedEOF},
+		{[]byte{'U', 'L', 'E', 'B', 0x01, 0x00, 0x00, 0x03, 0x01}, ErrTruncated, io.ErrUnexpectedEOF},
+		{[]byte{'U', 'L', 'E', 'B', 0x00, 0x00, 0x00, 0x00}, ErrUnsupportedVersion, ErrUnsupportedVersion},
+		{[]byte{'U', 'L', 'E', 'B', HeaderVersion + 1, 0x00, 0x00, 0x00}, ErrUnsupportedVersion, ErrUnsupportedVersion},
+		{[]byte{'U', 'L', 'E', 'B', 0x01, 0x00, 0x00, 0x80, 0x01}, ErrTruncated, ErrTooLong},
+	} {
+		if _, _, err := DecodeHeaderFromBytes(test.encoded); err != test.bytesErr {
+			t.Errorf("Expected %v to give %v but got %v", describe.D(test.encoded), test.bytesErr, err)
+		}
+		if _, _, err := ReadHeader(bytes.NewReader(test.encoded), 100); err != test.streamErr {
+			t.Errorf("Expected reading %v to give %v but got %v", describe.D(test.encoded), test.streamErr, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ErrTrailingData is returned when input that should hold exactly one value
+// has bytes left over after it.
+var ErrTrailingData = errors.New("uleb128: trailing data after value")
+
+// EncodeToHex encodes a math.big.Int value (the sign of the value will be
+// ignored) and returns the encoding as a lowercase hex string.
+func EncodeToHex(value *big.Int) string {
+	return hex.EncodeToString(Append(nil, value))
+}
+
+// EncodeUint64ToHex encodes a uint64 value and returns the encoding as a
+// lowercase hex string.
+func EncodeUint64ToHex(value uint64) string {
+	return hex.EncodeToString(AppendUint64(nil, value))
+}
+
+// DecodeHex decodes a hex string (whitespace is ignored) that holds exactly
+// one encoded value.
+func DecodeHex(hexString string) (value Value, err error) {
+	encoded, err := hex.DecodeString(strings.Join(strings.Fields(hexString), ""))
+	if err != nil {
+		return
+	}
+	value, byteCount, err := DecodeValueFromBytes(encoded)
+	if err == nil && byteCount != len(encoded) {
+		value = Value{}
+		err = ErrTrailingData
+	}
+	return
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"testing"
+)
+
+func TestHex(t *testing.T) {
+	if actual := EncodeUint64ToHex(300); actual != "ac02" {
+		t.Errorf("Expected \"ac02\" but got %q", actual)
+	}
+	huge := newBigFromHex("10000000000000000")
+	if actual := EncodeToHex(huge); actual != "80808080808080808002" {
+		t.Errorf("Expected \"80808080808080808002\" but got %q", actual)
+	}
+
+	value, err := DecodeHex("ac 02")
+	if err != nil || value != Uint64Value(300) {
+		t.Errorf("Expected 300 but got %v (%v)", value, err)
+	}
+	value, err = DecodeHex("80808080808080808002")
+	if err != nil || value.Cmp(BigValue(huge)) != 0 {
+		t.Errorf("Expected %v but got %v (%v)", huge, value, err)
+	}
+}
+
+func TestHexErrors(t *testing.T) {
+	assertDecodeHexFails := func(hexString string, expectedErr error) {
+		_, err := DecodeHex(hexString)
+		if err == nil || (expectedErr != nil && err != expectedErr) {
+			t.Errorf("Expected decoding %q to fail with %v but got %v", hexString, expectedErr, err)
+		}
+	}
+	assertDecodeHexFails("", io.EOF)
+	assertDecodeHexFails("80", ErrTruncated)
+	assertDecodeHexFails("0101", ErrTrailingData)
+	assertDecodeHexFails("zz", nil)
+	assertDecodeHexFails("801", nil)
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// The bucket for values of more than 64 bits.
+const histogramBigBucket = 65
+
+// Histogram is a streaming sketch of a value distribution, with one bucket
+// per bit length. It uses a fixed amount of memory no matter how many values
+// are added, and its buckets map directly onto encoded sizes, which makes it
+// a cheap way to profile live traffic when choosing an encoding. A Decoder
+// feeds one as it decodes if DecoderOptions.Histogram is set.
+//
+// Histogram is not safe for concurrent use. To profile several streams at
+// once, give each its own Histogram and combine them with Merge.
+type Histogram struct {
+	counts [histogramBigBucket + 1]uint64
+	count  uint64
+}
+
+// Add adds a uint64 value.
+func (h *Histogram) Add(value uint64) {
+	h.addBitLength(bits.Len64(value))
+}
+
+// AddBig adds a math.big.Int value (the sign of the value will be ignored).
+func (h *Histogram) AddBig(value *big.Int) {
+	h.addBitLength(value.BitLen())
+}
+
+// Merge adds all of the values in other.
+func (h *Histogram) Merge(other *Histogram) {
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	h.count += other.count
+}
+
+// Reset removes all values.
+func (h *Histogram) Reset() {
+	*h = Histogram{}
+}
+
+// Count returns the number of values added.
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// BucketCount returns the number of values that need exactly bitLength bits
+// (0 for the value 0). All values of more than 64 bits share one bucket,
+// which any bitLength over 64 refers to.
+func (h *Histogram) BucketCount(bitLength int) uint64 {
+	if bitLength < 0 {
+		return 0
+	}
+	if bitLength > histogramBigBucket {
+		bitLength = histogramBigBucket
+	}
+	return h.counts[bitLength]
+}
+
+// Quantile returns the smallest bit length that at least a fraction q (0 to
+// 1) of the values fit into. A result of 65 means more than 64 bits. If no
+// values have been added, the result is 0.
+func (h *Histogram) Quantile(q float64) (bitLength int) {
+	if h.count == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(h.count)))
+	if target < 1 {
+		target = 1
+	}
+	seen := uint64(0)
+	for bitLength = range h.counts {
+		if seen += h.counts[bitLength]; seen >= target {
+			return
+		}
+	}
+	return histogramBigBucket
+}
+
+// MeanEncodedSize returns the average ULEB128 encoded size of the values, in
+// bytes. Values of more than 64 bits are counted as 10 bytes, so the result
+// is a lower bound if there are any.
+func (h *Histogram) MeanEncodedSize() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	total := uint64(0)
+	for bitLength, count := range h.counts {
+		total += count * uint64(encodedSizeForBitLength(bitLength))
+	}
+	return float64(total) / float64(h.count)
+}
+
+func (h *Histogram) addBitLength(bitLength int) {
+	if bitLength > histogramBigBucket {
+		bitLength = histogramBigBucket
+	}
+	h.counts[bitLength]++
+	h.count++
+}
+
+func encodedSizeForBitLength(bitLength int) int {
+	if bitLength > 64 {
+		bitLength = 64
+	}
+	if bitLength == 0 {
+		return 1
+	}
+	return (bitLength + 6) / 7
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	var histogram Histogram
+	if histogram.Quantile(0.5) != 0 || histogram.MeanEncodedSize() != 0 {
+		t.Errorf("Expected an empty histogram to report 0")
+	}
+	for i := 0; i < 90; i++ {
+		histogram.Add(100)
+	}
+	for i := 0; i < 9; i++ {
+		histogram.Add(1 << 20)
+	}
+	histogram.AddBig(new(big.Int).Lsh(big.NewInt(1), 100))
+
+	if histogram.Count() != 100 {
+		t.Errorf("Expected 100 values but got %v", histogram.Count())
+	}
+	for bitLength, expected := range map[int]uint64{7: 90, 21: 9, 65: 1, 200: 1, 0: 0, -1: 0} {
+		if actual := histogram.BucketCount(bitLength); actual != expected {
+			t.Errorf("Expected %v values of %v bits but got %v", expected, bitLength, actual)
+		}
+	}
+	for q, expected := range map[float64]int{0: 7, 0.5: 7, 0.9: 7, 0.91: 21, 0.99: 21, 1: 65} {
+		if actual := histogram.Quantile(q); actual != expected {
+			t.Errorf("Expected quantile %v to be %v bits but got %v", q, expected, actual)
+		}
+	}
+	if expected := (90*1 + 9*3 + 1*10) / 100.0; histogram.MeanEncodedSize() != expected {
+		t.Errorf("Expected a mean size of %v but got %v", expected, histogram.MeanEncodedSize())
+	}
+
+	var other Histogram
+	other.Add(0)
+	histogram.Merge(&other)
+	if histogram.Count() != 101 || histogram.BucketCount(0) != 1 {
+		t.Errorf("Expected the merged value to be counted")
+	}
+	histogram.Reset()
+	if histogram.Count() != 0 || histogram.BucketCount(7) != 0 {
+		t.Errorf("Expected Reset to remove all values")
+	}
+}
+
+func TestDecoderHistogram(t *testing.T) {
+	var buffer []byte
+	for _, value := range []uint64{0, 1, 300, 300} {
+		buffer = AppendUint64(buffer, value)
+	}
+	buffer = Append(buffer, new(big.Int).Lsh(big.NewInt(1), 70))
+	buffer = AppendUint64(buffer, 5)
+
+	histogram := &Histogram{}
+	decoder := NewBytesDecoder(buffer, DecoderOptions{Histogram: histogram})
+	for i := 0; i < 5; i++ {
+		if _, _, _, err := decoder.Decode(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := decoder.DecodeBig(nil); err != nil {
+		t.Fatal(err)
+	}
+	for bitLength, expected := range map[int]uint64{0: 1, 1: 1, 9: 2, 65: 1, 3: 1} {
+		if actual := histogram.BucketCount(bitLength); actual != expected {
+			t.Errorf("Expected %v values of %v bits but got %v", expected, bitLength, actual)
+		}
+	}
+	if histogram.Count() != 6 {
+		t.Errorf("Expected 6 values but got %v", histogram.Count())
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var dexMagic = []byte{'d', 'e', 'x', '\n'}
+
+const dexHeaderSize = 0x70
+
+// DEX annotates the LEB128 fields of an Android DEX file: the UTF-16 length
+// of every string_data_item, and every field of every class_data_item.
+func DEX(file []byte) (fields []Field, err error) {
+	if len(file) < dexHeaderSize || !bytes.Equal(file[:4], dexMagic) {
+		err = ErrMalformed
+		return
+	}
+	c := &cursor{data: file}
+	defer func() { fields = c.fields }()
+
+	stringIDsSize := binary.LittleEndian.Uint32(file[0x38:])
+	stringIDsOffset := binary.LittleEndian.Uint32(file[0x3c:])
+	for i := uint32(0); i < stringIDsSize; i++ {
+		var dataOffset uint32
+		if dataOffset, err = dexUint32(file, uint64(stringIDsOffset)+uint64(i)*4); err != nil {
+			return
+		}
+		c.offset = int(dataOffset)
+		if _, err = c.uleb(fmt.Sprintf("string %v utf16_size", i)); err != nil {
+			return
+		}
+	}
+
+	classDefsSize := binary.LittleEndian.Uint32(file[0x60:])
+	classDefsOffset := binary.LittleEndian.Uint32(file[0x64:])
+	for i := uint32(0); i < classDefsSize; i++ {
+		var classDataOffset uint32
+		if classDataOffset, err = dexUint32(file, uint64(classDefsOffset)+uint64(i)*32+24); err != nil {
+			return
+		}
+		if classDataOffset == 0 {
+			continue
+		}
+		c.offset = int(classDataOffset)
+		if err = inspectDEXClassData(c, fmt.Sprintf("class %v", i)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func inspectDEXClassData(c *cursor, name string) (err error) {
+	var counts [4]uint64
+	for i, countName := range []string{"static_fields_size", "instance_fields_size", "direct_methods_size", "virtual_methods_size"} {
+		if counts[i], err = c.uleb(name + " " + countName); err != nil {
+			return
+		}
+	}
+	for i, listName := range []string{"static_field", "instance_field"} {
+		for j := uint64(0); j < counts[i]; j++ {
+			prefix := fmt.Sprintf("%v %v %v", name, listName, j)
+			if _, err = c.uleb(prefix + " field_idx_diff"); err != nil {
+				return
+			}
+			if _, err = c.uleb(prefix + " access_flags"); err != nil {
+				return
+			}
+		}
+	}
+	for i, listName := range []string{"direct_method", "virtual_method"} {
+		for j := uint64(0); j < counts[i+2]; j++ {
+			prefix := fmt.Sprintf("%v %v %v", name, listName, j)
+			if _, err = c.uleb(prefix + " method_idx_diff"); err != nil {
+				return
+			}
+			if _, err = c.uleb(prefix + " access_flags"); err != nil {
+				return
+			}
+			if _, err = c.uleb(prefix + " code_off"); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func dexUint32(file []byte, offset uint64) (value uint32, err error) {
+	if offset+4 > uint64(len(file)) {
+		err = fmt.Errorf("inspect: DEX offset %v out of range: %v", offset, ErrMalformed)
+		return
+	}
+	value = binary.LittleEndian.Uint32(file[offset:])
+	return
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package inspect
+
+import (
+	"fmt"
+)
+
+const dwarfFormImplicitConst = 0x21
+
+// DWARFAbbrev annotates the LEB128 fields of a DWARF .debug_abbrev section:
+// each abbreviation's code and tag, and each attribute's name and form
+// (plus the value of DW_FORM_implicit_const attributes).
+func DWARFAbbrev(section []byte) (fields []Field, err error) {
+	c := &cursor{data: section}
+	defer func() { fields = c.fields }()
+
+	for table := 0; c.offset < len(section); table++ {
+		for entry := 0; ; entry++ {
+			prefix := fmt.Sprintf("table %v abbrev %v", table, entry)
+			var code uint64
+			if code, err = c.uleb(prefix + " code"); err != nil {
+				return
+			}
+			if code == 0 {
+				break
+			}
+			if _, err = c.uleb(prefix + " tag"); err != nil {
+				return
+			}
+			if _, err = c.byte(prefix + " children"); err != nil {
+				return
+			}
+			for attribute := 0; ; attribute++ {
+				attributePrefix := fmt.Sprintf("%v attribute %v", prefix, attribute)
+				var name, form uint64
+				if name, err = c.uleb(attributePrefix + " name"); err != nil {
+					return
+				}
+				if form, err = c.uleb(attributePrefix + " form"); err != nil {
+					return
+				}
+				if name == 0 && form == 0 {
+					break
+				}
+				if form == dwarfFormImplicitConst {
+					if _, err = c.sleb(attributePrefix + " value"); err != nil {
+						return
+					}
+				}
+			}
+		}
+	}
+	return
+}
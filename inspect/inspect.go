@@ -0,0 +1,194 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package inspect annotates the LEB128 fields inside well-known binary
+// container formats, as a debugging aid for toolchain developers.
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// ErrMalformed is returned when a container doesn't match its expected
+// structure.
+var ErrMalformed = errors.New("inspect: malformed container")
+
+// Kind identifies how a field is encoded.
+type Kind int
+
+const (
+	ULEB128 Kind = iota
+	SLEB128
+)
+
+func (k Kind) String() string {
+	if k == SLEB128 {
+		return "sleb128"
+	}
+	return "uleb128"
+}
+
+// Field describes one LEB128-encoded field within a container.
+type Field struct {
+	Name      string
+	Offset    int
+	ByteCount int
+	Kind      Kind
+	// Set for ULEB128 fields.
+	Unsigned uleb128.Value
+	// Set for SLEB128 fields.
+	Signed int64
+	// False if the field wasn't encoded in its minimal form.
+	Canonical bool
+}
+
+func (f Field) String() string {
+	value := f.Unsigned.String()
+	if f.Kind == SLEB128 {
+		value = fmt.Sprintf("%d", f.Signed)
+	}
+	canonical := ""
+	if !f.Canonical {
+		canonical = " (non-canonical)"
+	}
+	return fmt.Sprintf("%08x+%d %v %v = %v%v", f.Offset, f.ByteCount, f.Kind, f.Name, value, canonical)
+}
+
+// NonCanonical returns the fields that weren't encoded in their minimal form.
+func NonCanonical(fields []Field) (result []Field) {
+	for _, field := range fields {
+		if !field.Canonical {
+			result = append(result, field)
+		}
+	}
+	return
+}
+
+// cursor walks a container, recording each LEB128 field it reads.
+type cursor struct {
+	data   []byte
+	offset int
+	fields []Field
+}
+
+func (c *cursor) uleb(name string) (value uint64, err error) {
+	decoded, byteCount, err := uleb128.DecodeValueFromBytes(c.data[c.offset:])
+	if err != nil {
+		return c.fail(name, err)
+	}
+	c.fields = append(c.fields, Field{
+		Name:      name,
+		Offset:    c.offset,
+		ByteCount: byteCount,
+		Kind:      ULEB128,
+		Unsigned:  decoded,
+		Canonical: byteCount == decoded.EncodedSize(),
+	})
+	c.offset += byteCount
+	value, ok := decoded.Uint64()
+	if !ok {
+		return c.fail(name, uleb128.ErrOverflow)
+	}
+	return
+}
+
+func (c *cursor) sleb(name string) (value int64, err error) {
+	value, byteCount, err := decodeSLEB(c.data[c.offset:])
+	if err != nil {
+		_, err = c.fail(name, err)
+		return
+	}
+	c.fields = append(c.fields, Field{
+		Name:      name,
+		Offset:    c.offset,
+		ByteCount: byteCount,
+		Kind:      SLEB128,
+		Signed:    value,
+		Canonical: byteCount == uleb128.EncodedSizeInt64(value),
+	})
+	c.offset += byteCount
+	return
+}
+
+func (c *cursor) byte(name string) (value byte, err error) {
+	if c.offset >= len(c.data) {
+		_, err = c.fail(name, io.ErrUnexpectedEOF)
+		return
+	}
+	value = c.data[c.offset]
+	c.offset++
+	return
+}
+
+func (c *cursor) skip(name string, byteCount uint64) (err error) {
+	if byteCount > uint64(len(c.data)-c.offset) {
+		_, err = c.fail(name, io.ErrUnexpectedEOF)
+		return
+	}
+	c.offset += int(byteCount)
+	return
+}
+
+// Read a ULEB128 length followed by that many bytes of text.
+func (c *cursor) name(name string) (value string, err error) {
+	length, err := c.uleb(name + " length")
+	if err != nil {
+		return
+	}
+	start := c.offset
+	if err = c.skip(name, length); err != nil {
+		return
+	}
+	value = string(c.data[start:c.offset])
+	return
+}
+
+func (c *cursor) fail(name string, cause error) (uint64, error) {
+	return 0, fmt.Errorf("inspect: %v at offset %v: %v", name, c.offset, cause)
+}
+
+// Decode a signed LEB128 value (at most 10 bytes).
+func decodeSLEB(data []byte) (value int64, byteCount int, err error) {
+	shift := uint(0)
+	for {
+		if byteCount == len(data) {
+			err = uleb128.ErrTruncated
+			return
+		}
+		if byteCount == uleb128.MaxBufferWriteBytes {
+			err = uleb128.ErrTooLong
+			return
+		}
+		b := data[byteCount]
+		byteCount++
+		value |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				value |= -1 << shift
+			}
+			return
+		}
+	}
+}
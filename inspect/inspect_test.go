@@ -0,0 +1,220 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package inspect
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func assertFields(t *testing.T, fields []Field, err error, expected ...string) {
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var actual []string
+	for _, field := range fields {
+		actual = append(actual, field.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestWASM(t *testing.T) {
+	module := []byte{
+		0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x00, 0x85, 0x00, 0x04, 'n', 'a', 'm', 'e',
+	}
+	fields, err := WASM(module)
+	assertFields(t, fields, err,
+		"00000009+1 uleb128 section 0 (type) size = 4",
+		"0000000a+1 uleb128 section 0 (type) count = 1",
+		"0000000f+1 uleb128 section 1 (function) size = 2",
+		"00000010+1 uleb128 section 1 (function) count = 1",
+		"00000011+1 uleb128 section 1 (function) function 0 type index = 0",
+		"00000013+2 uleb128 section 2 (custom) size = 5 (non-canonical)",
+		"00000015+1 uleb128 section 2 (custom) name length = 4",
+	)
+	if len(NonCanonical(fields)) != 1 {
+		t.Errorf("Expected 1 non-canonical field")
+	}
+
+	if _, err = WASM(module[:len(module)-1]); err == nil {
+		t.Errorf("Expected a truncated module to fail")
+	}
+	if _, err = WASM([]byte("not wasm")); err != ErrMalformed {
+		t.Errorf("Expected ErrMalformed but got %v", err)
+	}
+}
+
+var wasmNameModule = []byte{
+	0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00,
+	0x00, 0x1f, 0x04, 'n', 'a', 'm', 'e',
+	0x00, 0x04, 0x03, 'm', 'o', 'd',
+	0x01, 0x07, 0x01, 0x00, 0x04, 'm', 'a', 'i', 'n',
+	0x02, 0x06, 0x01, 0x00, 0x01, 0x01, 0x01, 'x',
+	0x07, 0x01, 0x00,
+}
+
+func TestWASMNameSection(t *testing.T) {
+	fields, err := WASM(wasmNameModule)
+	assertFields(t, fields, err,
+		"00000009+1 uleb128 section 0 (custom) size = 31",
+		"0000000a+1 uleb128 section 0 (custom) name length = 4",
+		"00000010+1 uleb128 section 0 (custom) subsection 0 size = 4",
+		"00000011+1 uleb128 section 0 (custom) subsection 0 module name length = 3",
+		"00000016+1 uleb128 section 0 (custom) subsection 1 size = 7",
+		"00000017+1 uleb128 section 0 (custom) subsection 1 function count = 1",
+		"00000018+1 uleb128 section 0 (custom) subsection 1 function 0 index = 0",
+		"00000019+1 uleb128 section 0 (custom) subsection 1 function 0 name length = 4",
+		"0000001f+1 uleb128 section 0 (custom) subsection 2 size = 6",
+		"00000020+1 uleb128 section 0 (custom) subsection 2 function count = 1",
+		"00000021+1 uleb128 section 0 (custom) subsection 2 function 0 index = 0",
+		"00000022+1 uleb128 section 0 (custom) subsection 2 function 0 local count = 1",
+		"00000023+1 uleb128 section 0 (custom) subsection 2 function 0 local 0 index = 1",
+		"00000024+1 uleb128 section 0 (custom) subsection 2 function 0 local 0 name length = 1",
+		"00000027+1 uleb128 section 0 (custom) subsection 3 size = 1",
+	)
+}
+
+func TestWASMNames(t *testing.T) {
+	names, err := WASMNames(wasmNameModule)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := WASMNameSection{
+		Module:    "mod",
+		Functions: WASMNameMap{0: "main"},
+		Locals:    map[uint32]WASMNameMap{0: {1: "x"}},
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v but got %v", expected, names)
+	}
+
+	if _, err = WASMNames(wasmNameModule[:len(wasmNameModule)-1]); err == nil {
+		t.Errorf("Expected a truncated module to fail")
+	}
+
+	// A subsection that claims more bytes than the section holds
+	overrun := append([]byte{}, wasmNameModule...)
+	overrun[0x10] = 0x20
+	if _, err = WASMNames(overrun); err == nil {
+		t.Errorf("Expected an overrunning subsection to fail")
+	}
+
+	tooBig := []byte{
+		0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x0f, 0x04, 'n', 'a', 'm', 'e',
+		0x01, 0x08, 0x01, 0x80, 0x80, 0x80, 0x80, 0x10, 0x01, 'f',
+	}
+	if _, err = WASMNames(tooBig); err == nil || !strings.Contains(err.Error(), "index") {
+		t.Errorf("Expected a 33-bit function index to fail")
+	}
+
+	names, err = WASMNames([]byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00})
+	if err != nil || !reflect.DeepEqual(names, WASMNameSection{}) {
+		t.Errorf("Expected no names but got %v (%v)", names, err)
+	}
+}
+
+func TestDEX(t *testing.T) {
+	file := make([]byte, 0x9c)
+	copy(file, "dex\n035\x00")
+	binary.LittleEndian.PutUint32(file[0x38:], 1)
+	binary.LittleEndian.PutUint32(file[0x3c:], 0x70)
+	binary.LittleEndian.PutUint32(file[0x60:], 1)
+	binary.LittleEndian.PutUint32(file[0x64:], 0x7c)
+	binary.LittleEndian.PutUint32(file[0x70:], 0x74)
+	copy(file[0x74:], "\x05hello\x00")
+	binary.LittleEndian.PutUint32(file[0x7c+24:], 0x9c)
+	file = append(file, 0x00, 0x01, 0x01, 0x00, 0x02, 0x01, 0x03, 0x81, 0x80, 0x04, 0xa0, 0x01)
+
+	fields, err := DEX(file)
+	assertFields(t, fields, err,
+		"00000074+1 uleb128 string 0 utf16_size = 5",
+		"0000009c+1 uleb128 class 0 static_fields_size = 0",
+		"0000009d+1 uleb128 class 0 instance_fields_size = 1",
+		"0000009e+1 uleb128 class 0 direct_methods_size = 1",
+		"0000009f+1 uleb128 class 0 virtual_methods_size = 0",
+		"000000a0+1 uleb128 class 0 instance_field 0 field_idx_diff = 2",
+		"000000a1+1 uleb128 class 0 instance_field 0 access_flags = 1",
+		"000000a2+1 uleb128 class 0 direct_method 0 method_idx_diff = 3",
+		"000000a3+3 uleb128 class 0 direct_method 0 access_flags = 65537",
+		"000000a6+2 uleb128 class 0 direct_method 0 code_off = 160",
+	)
+
+	if _, err = DEX(file[:0xa5]); err == nil {
+		t.Errorf("Expected a truncated file to fail")
+	}
+}
+
+func TestDWARFAbbrev(t *testing.T) {
+	section := []byte{
+		0x01, 0x11, 0x01, 0x03, 0x08, 0x0b, 0x21, 0x7d, 0x00, 0x00, 0x00,
+		0x81, 0x00, 0x24, 0x00, 0x00, 0x00, 0x00,
+	}
+	fields, err := DWARFAbbrev(section)
+	assertFields(t, fields, err,
+		"00000000+1 uleb128 table 0 abbrev 0 code = 1",
+		"00000001+1 uleb128 table 0 abbrev 0 tag = 17",
+		"00000003+1 uleb128 table 0 abbrev 0 attribute 0 name = 3",
+		"00000004+1 uleb128 table 0 abbrev 0 attribute 0 form = 8",
+		"00000005+1 uleb128 table 0 abbrev 0 attribute 1 name = 11",
+		"00000006+1 uleb128 table 0 abbrev 0 attribute 1 form = 33",
+		"00000007+1 sleb128 table 0 abbrev 0 attribute 1 value = -3",
+		"00000008+1 uleb128 table 0 abbrev 0 attribute 2 name = 0",
+		"00000009+1 uleb128 table 0 abbrev 0 attribute 2 form = 0",
+		"0000000a+1 uleb128 table 0 abbrev 1 code = 0",
+		"0000000b+2 uleb128 table 1 abbrev 0 code = 1 (non-canonical)",
+		"0000000d+1 uleb128 table 1 abbrev 0 tag = 36",
+		"0000000f+1 uleb128 table 1 abbrev 0 attribute 0 name = 0",
+		"00000010+1 uleb128 table 1 abbrev 0 attribute 0 form = 0",
+		"00000011+1 uleb128 table 1 abbrev 1 code = 0",
+	)
+}
+
+func TestSLEB(t *testing.T) {
+	assertSLEB := func(expected int64, b ...byte) {
+		value, byteCount, err := decodeSLEB(b)
+		if err != nil || value != expected || byteCount != len(b) {
+			t.Errorf("Expected %v to decode to %v but got %v (%v bytes, %v)", b, expected, value, byteCount, err)
+		}
+		if size := uleb128.EncodedSizeInt64(value); size != len(b) {
+			t.Errorf("Expected %v to have an encoded size of %v but got %v", expected, len(b), size)
+		}
+	}
+	assertSLEB(0, 0x00)
+	assertSLEB(-1, 0x7f)
+	assertSLEB(63, 0x3f)
+	assertSLEB(64, 0xc0, 0x00)
+	assertSLEB(-64, 0x40)
+	assertSLEB(-65, 0xbf, 0x7f)
+	assertSLEB(-9223372036854775808, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f)
+	assertSLEB(9223372036854775807, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00)
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package inspect
+
+import (
+	"bytes"
+	"fmt"
+)
+
+var wasmMagic = []byte{0x00, 'a', 's', 'm'}
+
+var wasmSectionNames = []string{
+	"custom", "type", "import", "function", "table", "memory", "global",
+	"export", "start", "element", "code", "data", "datacount",
+}
+
+// WASM annotates the LEB128 fields of a WebAssembly module: each section's
+// size, the names of custom sections, the element counts of vector sections,
+// and the contents of the function and start sections. Section contents are
+// otherwise skipped.
+// A "name" custom section is annotated down to its individual indices and
+// name lengths.
+func WASM(module []byte) (fields []Field, err error) {
+	fields, err = inspectWASM(module, &WASMNameSection{})
+	return
+}
+
+// Inspect a module, storing the contents of any "name" section in names.
+func inspectWASM(module []byte, names *WASMNameSection) (fields []Field, err error) {
+	if len(module) < 8 || !bytes.Equal(module[:4], wasmMagic) {
+		err = ErrMalformed
+		return
+	}
+	c := &cursor{data: module, offset: 8}
+	defer func() { fields = c.fields }()
+
+	for index := 0; c.offset < len(module); index++ {
+		var id byte
+		if id, err = c.byte("section id"); err != nil {
+			return
+		}
+		name := fmt.Sprintf("section %v", index)
+		if int(id) < len(wasmSectionNames) {
+			name = fmt.Sprintf("section %v (%v)", index, wasmSectionNames[id])
+		}
+		var size uint64
+		if size, err = c.uleb(name + " size"); err != nil {
+			return
+		}
+		if size > uint64(len(module)-c.offset) {
+			_, err = c.fail(name, ErrMalformed)
+			return
+		}
+		end := c.offset + int(size)
+		section := &cursor{data: module[:end], offset: c.offset}
+		err = inspectWASMSection(section, id, name, names)
+		c.fields = append(c.fields, section.fields...)
+		if err != nil {
+			return
+		}
+		c.offset = end
+	}
+	return
+}
+
+func inspectWASMSection(c *cursor, id byte, name string, names *WASMNameSection) (err error) {
+	switch id {
+	case 0:
+		var customName string
+		if customName, err = c.name(name + " name"); err != nil {
+			return
+		}
+		if customName == "name" {
+			return inspectWASMNameSection(c, name, names)
+		}
+	case 3:
+		var count uint64
+		if count, err = c.uleb(name + " count"); err != nil {
+			return
+		}
+		for i := uint64(0); i < count; i++ {
+			if _, err = c.uleb(fmt.Sprintf("%v function %v type index", name, i)); err != nil {
+				return
+			}
+		}
+	case 8:
+		_, err = c.uleb(name + " function index")
+	case 12:
+		_, err = c.uleb(name + " count")
+	default:
+		if int(id) < len(wasmSectionNames) {
+			_, err = c.uleb(name + " count")
+		}
+	}
+	return
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package inspect
+
+import (
+	"fmt"
+	"math"
+)
+
+// WASMNameMap maps function (or local) indices to their names.
+type WASMNameMap map[uint32]string
+
+// WASMNameSection holds the contents of a WebAssembly "name" custom section.
+// Subsections other than the module, function and local names are skipped.
+type WASMNameSection struct {
+	Module    string
+	Functions WASMNameMap
+	// Local names, keyed by function index.
+	Locals map[uint32]WASMNameMap
+}
+
+const (
+	wasmModuleNames   = 0
+	wasmFunctionNames = 1
+	wasmLocalNames    = 2
+)
+
+// WASMNames reads the "name" custom section of a WebAssembly module. The
+// result is empty if the module doesn't have one. Returns an error if the
+// module is malformed anywhere that WASM would report it.
+func WASMNames(module []byte) (names WASMNameSection, err error) {
+	_, err = inspectWASM(module, &names)
+	return
+}
+
+func inspectWASMNameSection(c *cursor, name string, names *WASMNameSection) (err error) {
+	for subsection := 0; c.offset < len(c.data); subsection++ {
+		prefix := fmt.Sprintf("%v subsection %v", name, subsection)
+		var id byte
+		if id, err = c.byte(prefix + " id"); err != nil {
+			return
+		}
+		var size uint64
+		if size, err = c.uleb(prefix + " size"); err != nil {
+			return
+		}
+		if size > uint64(len(c.data)-c.offset) {
+			_, err = c.fail(prefix, ErrMalformed)
+			return
+		}
+		end := c.offset + int(size)
+		sub := &cursor{data: c.data[:end], offset: c.offset}
+		switch id {
+		case wasmModuleNames:
+			names.Module, err = sub.name(prefix + " module name")
+		case wasmFunctionNames:
+			names.Functions, err = inspectWASMNameMap(sub, prefix+" function")
+		case wasmLocalNames:
+			names.Locals, err = inspectWASMIndirectNameMap(sub, prefix+" function")
+		}
+		c.fields = append(c.fields, sub.fields...)
+		if err != nil {
+			return
+		}
+		c.offset = end
+	}
+	return
+}
+
+func inspectWASMNameMap(c *cursor, prefix string) (names WASMNameMap, err error) {
+	var count uint64
+	if count, err = c.uleb(prefix + " count"); err != nil {
+		return
+	}
+	names = make(WASMNameMap)
+	for i := uint64(0); i < count; i++ {
+		var index uint32
+		if index, err = c.wasmIndex(fmt.Sprintf("%v %v index", prefix, i)); err != nil {
+			return
+		}
+		if names[index], err = c.name(fmt.Sprintf("%v %v name", prefix, i)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func inspectWASMIndirectNameMap(c *cursor, prefix string) (names map[uint32]WASMNameMap, err error) {
+	var count uint64
+	if count, err = c.uleb(prefix + " count"); err != nil {
+		return
+	}
+	names = make(map[uint32]WASMNameMap)
+	for i := uint64(0); i < count; i++ {
+		entryPrefix := fmt.Sprintf("%v %v", prefix, i)
+		var index uint32
+		if index, err = c.wasmIndex(entryPrefix + " index"); err != nil {
+			return
+		}
+		if names[index], err = inspectWASMNameMap(c, entryPrefix+" local"); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Read a ULEB128 index, which WASM limits to 32 bits.
+func (c *cursor) wasmIndex(name string) (index uint32, err error) {
+	value, err := c.uleb(name)
+	if err != nil {
+		return
+	}
+	if value > math.MaxUint32 {
+		_, err = c.fail(name, ErrMalformed)
+		return
+	}
+	index = uint32(value)
+	return
+}
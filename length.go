@@ -0,0 +1,99 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// MaxAllocationSize is a process-wide upper bound (in bytes) on the size of a
+// single allocation sized by a decoded length. CheckLength rejects any length
+// whose allocation would exceed it, regardless of the caller's own maximum.
+// Set it during initialization; it is not safe to modify concurrently.
+var MaxAllocationSize int64 = 1 << 30
+
+// LengthError describes a decoded length that was rejected by CheckLength.
+type LengthError struct {
+	// The decoded length (math.MaxUint64 if it didn't fit into a uint64).
+	Length uint64
+	// The maximum length that the caller allowed.
+	MaxLength int
+	// The size of each element the length refers to.
+	ElementSize int
+	// True if the length was within MaxLength, but allocating that many
+	// elements would have exceeded MaxAllocationSize.
+	AllocationTooLarge bool
+}
+
+func (e *LengthError) Error() string {
+	if e.AllocationTooLarge {
+		return fmt.Sprintf("uleb128: length %v of %v-byte elements exceeds the maximum allocation size of %v bytes",
+			e.Length, e.ElementSize, MaxAllocationSize)
+	}
+	return fmt.Sprintf("uleb128: length %v exceeds the maximum of %v", e.Length, e.MaxLength)
+}
+
+// CheckLength validates a decoded length before it is used to allocate
+// elements of elementSize bytes each. It returns a *LengthError if the length
+// exceeds maxLength, or if the allocation would exceed MaxAllocationSize.
+func CheckLength(asUint uint64, asBigInt *big.Int, maxLength int, elementSize int) (length int, err error) {
+	if asBigInt != nil {
+		asUint = math.MaxUint64
+	}
+	if maxLength < 0 || asUint > uint64(maxLength) {
+		err = &LengthError{
+			Length:      asUint,
+			MaxLength:   maxLength,
+			ElementSize: elementSize,
+		}
+		return
+	}
+	if elementSize > 0 && asUint > uint64(MaxAllocationSize)/uint64(elementSize) {
+		err = &LengthError{
+			Length:             asUint,
+			MaxLength:          maxLength,
+			ElementSize:        elementSize,
+			AllocationTooLarge: true,
+		}
+		return
+	}
+	length = int(asUint)
+	return
+}
+
+// DecodeLength decodes a length and validates it with CheckLength. At most
+// MaxBufferWriteBytes bytes are read, and a length too large for a uint64 is
+// rejected with a *LengthError without decoding the rest of it. A stream that
+// ends partway through the length returns io.ErrUnexpectedEOF.
+func DecodeLength(reader io.Reader, maxLength int, elementSize int) (length int, byteCount int, err error) {
+	asUint, byteCount, err := decodeUint64(reader, []byte{0})
+	if err == ErrOverflow {
+		asUint, err = math.MaxUint64, nil
+	}
+	if err != nil {
+		return
+	}
+	length, err = CheckLength(asUint, nil, maxLength, elementSize)
+	return
+}
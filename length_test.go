@@ -0,0 +1,99 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func assertLengthAccepted(t *testing.T, value uint64, maxLength int, elementSize int) {
+	length, err := CheckLength(value, nil, maxLength, elementSize)
+	if err != nil {
+		t.Errorf("Expected length %v (max %v, element size %v) to be accepted but got %v", value, maxLength, elementSize, err)
+		return
+	}
+	if uint64(length) != value {
+		t.Errorf("Expected length %v but got %v", value, length)
+	}
+}
+
+func assertLengthRejected(t *testing.T, asUint uint64, asBigInt *big.Int, maxLength int, elementSize int, expectedAllocationTooLarge bool) {
+	_, err := CheckLength(asUint, asBigInt, maxLength, elementSize)
+	lengthErr, ok := err.(*LengthError)
+	if !ok {
+		t.Errorf("Expected length %v (max %v, element size %v) to be rejected with a *LengthError but got %v", asUint, maxLength, elementSize, err)
+		return
+	}
+	if lengthErr.AllocationTooLarge != expectedAllocationTooLarge {
+		t.Errorf("Expected AllocationTooLarge to be %v in %v", expectedAllocationTooLarge, lengthErr)
+	}
+}
+
+func TestCheckLength(t *testing.T) {
+	assertLengthAccepted(t, 0, 0, 1)
+	assertLengthAccepted(t, 10, 10, 1)
+	assertLengthAccepted(t, 10, 10, 0)
+	assertLengthAccepted(t, uint64(MaxAllocationSize)/8, math.MaxInt32, 8)
+
+	assertLengthRejected(t, 11, nil, 10, 1, false)
+	assertLengthRejected(t, 0, nil, -1, 1, false)
+	assertLengthRejected(t, math.MaxUint64, nil, math.MaxInt32, 1, false)
+	assertLengthRejected(t, uint64(MaxAllocationSize)/8+1, nil, math.MaxInt32, 8, true)
+
+	huge := big.NewInt(1)
+	huge.Lsh(huge, 100)
+	assertLengthRejected(t, 0, huge, math.MaxInt32, 1, false)
+}
+
+func TestDecodeLength(t *testing.T) {
+	length, byteCount, err := DecodeLength(bytes.NewBuffer([]byte{0x80, 0x01}), 200, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if length != 128 || byteCount != 2 {
+		t.Errorf("Expected length 128 from 2 bytes but got %v from %v", length, byteCount)
+	}
+
+	if _, _, err = DecodeLength(bytes.NewBuffer([]byte{0x80, 0x01}), 100, 4); err == nil {
+		t.Errorf("Expected length 128 to exceed max of 100")
+	}
+}
+
+func TestDecodeLengthEndless(t *testing.T) {
+	reader := &continuationReader{}
+	_, byteCount, err := DecodeLength(reader, math.MaxInt32, 1)
+	lengthError, ok := err.(*LengthError)
+	if !ok || lengthError.Length != math.MaxUint64 {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+	if byteCount > MaxBufferWriteBytes || reader.byteCount > MaxBufferWriteBytes {
+		t.Errorf("Expected to read at most %v bytes but read %v", MaxBufferWriteBytes, reader.byteCount)
+	}
+
+	if _, _, err = DecodeLength(bytes.NewBuffer([]byte{0x80}), 10, 1); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
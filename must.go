@@ -0,0 +1,93 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/big"
+)
+
+// The Must functions panic instead of returning an error. They're meant for
+// tests and package-level fixtures, where an error means a programming
+// mistake rather than bad input.
+
+// MustEncode is like Encode, but panics on error.
+func MustEncode(value *big.Int, writer io.Writer) (byteCount int) {
+	byteCount, err := Encode(value, writer)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// MustEncodeUint64 is like EncodeUint64, but panics on error.
+func MustEncodeUint64(value uint64, writer io.Writer) (byteCount int) {
+	byteCount, err := EncodeUint64(value, writer)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// MustAppendDecimal appends the encoding of an unsigned base-10 integer of
+// any size, panicking if the string isn't valid (see EncodeDecimalString).
+func MustAppendDecimal(buffer []byte, decimal string) []byte {
+	asUint, asBigInt, err := parseDecimal(decimal)
+	if err != nil {
+		panic(err)
+	}
+	if asBigInt != nil {
+		return Append(buffer, asBigInt)
+	}
+	return AppendUint64(buffer, asUint)
+}
+
+// MustDecodeBytes decodes buffer, which must hold exactly one value, and
+// panics otherwise.
+func MustDecodeBytes(buffer []byte) (value Value) {
+	value, byteCount, err := DecodeValueFromBytes(buffer)
+	if err != nil {
+		panic(err)
+	}
+	if byteCount != len(buffer) {
+		panic(ErrTrailingData)
+	}
+	return
+}
+
+// MustDecodeUint64Bytes decodes buffer, which must hold exactly one value
+// that fits into a uint64, and panics otherwise.
+func MustDecodeUint64Bytes(buffer []byte) uint64 {
+	value, ok := MustDecodeBytes(buffer).Uint64()
+	if !ok {
+		panic(ErrOverflow)
+	}
+	return value
+}
+
+// MustDecodeHex is like DecodeHex, but panics on error.
+func MustDecodeHex(hexString string) (value Value) {
+	value, err := DecodeHex(hexString)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+type failingWriter struct{}
+
+var errWriteFailed = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func assertPanicsWith(t *testing.T, expected error, function func()) {
+	defer func() {
+		if actual := recover(); actual != expected {
+			t.Errorf("Expected a panic with %v but got %v", expected, actual)
+		}
+	}()
+	function()
+}
+
+func TestMust(t *testing.T) {
+	buff := &bytes.Buffer{}
+	if byteCount := MustEncodeUint64(300, buff); byteCount != 2 {
+		t.Errorf("Expected 2 bytes but got %v", byteCount)
+	}
+	if byteCount := MustEncode(newBigFromHex("10000000000000000"), buff); byteCount != 10 {
+		t.Errorf("Expected 10 bytes but got %v", byteCount)
+	}
+	expected := MustAppendDecimal(MustAppendDecimal(nil, "300"), "18446744073709551616")
+	if !bytes.Equal(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buff.Bytes()))
+	}
+
+	if v := MustDecodeUint64Bytes([]byte{0xac, 0x02}); v != 300 {
+		t.Errorf("Expected 300 but got %v", v)
+	}
+	if v := MustDecodeBytes(expected[2:]); v.String() != "18446744073709551616" {
+		t.Errorf("Expected 18446744073709551616 but got %v", v)
+	}
+	if v := MustDecodeHex("ac 02"); v != Uint64Value(300) {
+		t.Errorf("Expected 300 but got %v", v)
+	}
+}
+
+func TestMustPanics(t *testing.T) {
+	assertPanicsWith(t, errWriteFailed, func() { MustEncodeUint64(1, failingWriter{}) })
+	assertPanicsWith(t, errWriteFailed, func() { MustEncode(newBigFromHex("1"), failingWriter{}) })
+	assertPanicsWith(t, ErrInvalidDecimal, func() { MustAppendDecimal(nil, "-1") })
+	assertPanicsWith(t, ErrTruncated, func() { MustDecodeBytes([]byte{0x80}) })
+	assertPanicsWith(t, ErrTrailingData, func() { MustDecodeBytes([]byte{0x01, 0x02}) })
+	assertPanicsWith(t, ErrOverflow, func() { MustDecodeUint64Bytes(MustAppendDecimal(nil, "18446744073709551616")) })
+	assertPanicsWith(t, ErrTrailingData, func() { MustDecodeHex("01 02") })
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrPacketFull is returned by Packer.Add when a value doesn't fit into the
+// current packet. Flush the packet and add the value again.
+var ErrPacketFull = errors.New("uleb128: packet is full")
+
+// ErrPacketTooSmall is returned when a value's encoding is larger than the
+// maximum packet size, so that it can't fit into any packet.
+var ErrPacketTooSmall = errors.New("uleb128: value is larger than the maximum packet size")
+
+// Packer packs encoded values into packets of at most a fixed number of
+// bytes, splitting only at value boundaries. It's meant for datagram
+// senders, where each packet must fit into the path MTU (less any headers
+// the caller adds).
+type Packer struct {
+	maxPacketSize int
+	packet        []byte
+	count         int
+}
+
+// NewPacker returns a Packer that makes packets of at most maxPacketSize
+// bytes.
+func NewPacker(maxPacketSize int) *Packer {
+	return &Packer{
+		maxPacketSize: maxPacketSize,
+		packet:        make([]byte, 0, maxPacketSize),
+	}
+}
+
+// Add adds a uint64 value to the current packet. If it doesn't fit, the
+// packet is left unchanged and err will be ErrPacketFull (or
+// ErrPacketTooSmall if it wouldn't fit into an empty packet either).
+func (p *Packer) Add(value uint64) (err error) {
+	if err = p.reserve(EncodedSizeUint64(value)); err == nil {
+		p.packet = AppendUint64(p.packet, value)
+		p.count++
+	}
+	return
+}
+
+// AddBig adds a math.big.Int value (the sign of the value will be ignored)
+// in the same way as Add.
+func (p *Packer) AddBig(value *big.Int) (err error) {
+	if err = p.reserve(EncodedSize(value)); err == nil {
+		p.packet = Append(p.packet, value)
+		p.count++
+	}
+	return
+}
+
+// Len returns the number of values in the current packet.
+func (p *Packer) Len() int {
+	return p.count
+}
+
+// ByteCount returns the size of the current packet in bytes.
+func (p *Packer) ByteCount() int {
+	return len(p.packet)
+}
+
+// Flush returns the current packet and the number of values in it, and
+// starts a new one. The packet's storage is reused, so it's only valid until
+// the next call to Add, AddBig or Pack.
+func (p *Packer) Flush() (packet []byte, count int) {
+	packet, count = p.packet, p.count
+	p.packet = p.packet[:0]
+	p.count = 0
+	return
+}
+
+// Pack starts a new packet, fills it with as many of values as will fit, and
+// returns it along with the number of values it holds. The rest of the
+// values are values[count:]. If the first value can't fit into any packet,
+// err will be ErrPacketTooSmall. The packet is only valid as in Flush.
+func (p *Packer) Pack(values []uint64) (packet []byte, count int, err error) {
+	p.Flush()
+	for _, value := range values {
+		if err = p.Add(value); err != nil {
+			break
+		}
+	}
+	packet, count = p.Flush()
+	if count > 0 {
+		err = nil
+	}
+	return
+}
+
+func (p *Packer) reserve(byteCount int) error {
+	if byteCount > p.maxPacketSize {
+		return ErrPacketTooSmall
+	}
+	if len(p.packet)+byteCount > p.maxPacketSize {
+		return ErrPacketFull
+	}
+	return nil
+}
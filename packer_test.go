@@ -0,0 +1,107 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestPackerPack(t *testing.T) {
+	var values []uint64
+	for i := uint64(0); i < 1000; i++ {
+		values = append(values, i*i*i)
+	}
+	packer := NewPacker(100)
+	var rejoined []byte
+	remaining := values
+	for len(remaining) > 0 {
+		packet, count, err := packer.Pack(remaining)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(packet) > 100 {
+			t.Errorf("Expected at most 100 bytes but got %v", len(packet))
+		}
+		if count < len(remaining) && len(packet)+EncodedSizeUint64(remaining[count]) <= 100 {
+			t.Errorf("Value %v would have fit into a %v byte packet", remaining[count], len(packet))
+		}
+		decodedCount := 0
+		if _, err = DecodeAllFromBytes(packet, func(uint64, *big.Int) { decodedCount++ }); err != nil || decodedCount != count {
+			t.Errorf("Expected %v values but got %v (%v)", count, decodedCount, err)
+		}
+		rejoined = append(rejoined, packet...)
+		remaining = remaining[count:]
+	}
+
+	var expected []byte
+	for _, value := range values {
+		expected = AppendUint64(expected, value)
+	}
+	if !bytes.Equal(rejoined, expected) {
+		t.Errorf("Packets don't rejoin into the original encoding")
+	}
+}
+
+func TestPackerAdd(t *testing.T) {
+	packer := NewPacker(4)
+	for _, value := range []uint64{1, 300} {
+		if err := packer.Add(value); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := packer.Add(300); err != ErrPacketFull {
+		t.Errorf("Expected %v but got %v", ErrPacketFull, err)
+	}
+	if err := packer.AddBig(big.NewInt(5)); err != nil {
+		t.Error(err)
+	}
+	if packer.Len() != 3 || packer.ByteCount() != 4 {
+		t.Errorf("Expected 3 values in 4 bytes but got %v in %v", packer.Len(), packer.ByteCount())
+	}
+	packet, count := packer.Flush()
+	if expected := []byte{0x01, 0xac, 0x02, 0x05}; !bytes.Equal(packet, expected) || count != 3 {
+		t.Errorf("Expected %v (3 values) but got %v (%v values)", expected, packet, count)
+	}
+	if packer.Len() != 0 || packer.ByteCount() != 0 {
+		t.Errorf("Expected an empty packet after Flush")
+	}
+
+	if err := packer.Add(1 << 28); err != ErrPacketTooSmall {
+		t.Errorf("Expected %v but got %v", ErrPacketTooSmall, err)
+	}
+	if err := packer.AddBig(new(big.Int).Lsh(big.NewInt(1), 64)); err != ErrPacketTooSmall {
+		t.Errorf("Expected %v but got %v", ErrPacketTooSmall, err)
+	}
+}
+
+func TestPackerPackTooSmall(t *testing.T) {
+	packer := NewPacker(2)
+	values := []uint64{1, 1 << 20, 2}
+	packet, count, err := packer.Pack(values)
+	if err != nil || count != 1 || !bytes.Equal(packet, []byte{0x01}) {
+		t.Errorf("Expected one value but got %v, %v, %v", packet, count, err)
+	}
+	if _, count, err = packer.Pack(values[count:]); err != ErrPacketTooSmall || count != 0 {
+		t.Errorf("Expected %v but got %v (%v values)", ErrPacketTooSmall, err, count)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrMalformedPFOR is returned when a PFOR-delta block is structurally
+// invalid (bad bit width or exception index).
+var ErrMalformedPFOR = errors.New("uleb128: malformed PFOR-delta block")
+
+// The number of values in each PFOR-delta sub-block.
+const pforBlockSize = 128
+
+// AppendPFORDelta appends values to buffer using patched frame-of-reference
+// encoding of the differences between consecutive values. It's intended for
+// sorted sequences such as index postings, but any sequence round-trips
+// exactly since differences wrap around.
+//
+// The output is the value count as a ULEB128, followed by sub-blocks of 128
+// differences (the last may be shorter). Each sub-block holds a bit width
+// byte, the exception count as a ULEB128, the low bits of every difference
+// packed LSB-first, and then for each exception the ULEB128 distance from
+// the previous exception's index and the ULEB128 high bits of its value.
+// The bit width is chosen per sub-block to minimize its size, so a few large
+// outliers become exceptions rather than widening every slot.
+func AppendPFORDelta(buffer []byte, values []uint64) []byte {
+	buffer = AppendUint64(buffer, uint64(len(values)))
+	deltas := make([]uint64, 0, pforBlockSize)
+	previous := uint64(0)
+	for len(values) > 0 {
+		count := minInt(pforBlockSize, len(values))
+		deltas = deltas[:0]
+		for _, v := range values[:count] {
+			deltas = append(deltas, v-previous)
+			previous = v
+		}
+		buffer = appendPFORBlock(buffer, deltas)
+		values = values[count:]
+	}
+	return buffer
+}
+
+// DecodePFORDeltaFromBytes decodes a PFOR-delta block written by
+// AppendPFORDelta from the start of buffer. The count is validated with
+// CheckLength against maxCount before anything is allocated. A buffer that
+// ends before all values are decoded returns ErrTruncated.
+func DecodePFORDeltaFromBytes(buffer []byte, maxCount int) (values []uint64, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		err = truncatedIfEOF(err)
+		return
+	}
+	count, err := CheckLength(asUint, asBigInt, maxCount, 8)
+	if err != nil {
+		return
+	}
+	values = make([]uint64, 0, count)
+	previous := uint64(0)
+	for len(values) < count {
+		start := len(values)
+		var blockByteCount int
+		values, blockByteCount, err = decodePFORBlock(buffer[byteCount:], values, minInt(pforBlockSize, count-start))
+		byteCount += blockByteCount
+		if err != nil {
+			values = nil
+			return
+		}
+		for i := start; i < len(values); i++ {
+			values[i] += previous
+			previous = values[i]
+		}
+	}
+	return
+}
+
+func appendPFORBlock(buffer []byte, deltas []uint64) []byte {
+	bitWidth := pforBestBitWidth(deltas)
+	mask := maskForBitCount(int(bitWidth))
+	exceptionCount := 0
+	for _, d := range deltas {
+		if d > mask {
+			exceptionCount++
+		}
+	}
+	buffer = append(buffer, byte(bitWidth))
+	buffer = AppendUint64(buffer, uint64(exceptionCount))
+	buffer = appendPackedBits(buffer, deltas, bitWidth)
+	previousIndex := 0
+	for i, d := range deltas {
+		if d > mask {
+			buffer = AppendUint64(buffer, uint64(i-previousIndex))
+			buffer = AppendUint64(buffer, d>>bitWidth)
+			previousIndex = i
+		}
+	}
+	return buffer
+}
+
+func decodePFORBlock(buffer []byte, values []uint64, count int) (result []uint64, byteCount int, err error) {
+	result = values
+	if len(buffer) < 1 {
+		err = ErrTruncated
+		return
+	}
+	bitWidth := uint(buffer[0])
+	if bitWidth > 64 {
+		err = ErrMalformedPFOR
+		return
+	}
+	byteCount = 1
+	exceptionCount, asBigInt, n, err := DecodeFromBytes(buffer[byteCount:])
+	byteCount += n
+	if err != nil {
+		err = truncatedIfEOF(err)
+		return
+	}
+	if asBigInt != nil || exceptionCount > uint64(count) {
+		err = ErrMalformedPFOR
+		return
+	}
+	packedByteCount := (count*int(bitWidth) + 7) / 8
+	if len(buffer)-byteCount < packedByteCount {
+		err = ErrTruncated
+		return
+	}
+	start := len(result)
+	result = unpackBits(result, buffer[byteCount:byteCount+packedByteCount], count, bitWidth)
+	byteCount += packedByteCount
+
+	index := uint64(0)
+	for i := uint64(0); i < exceptionCount; i++ {
+		var distance, high uint64
+		if distance, n, err = decodeUint64FromBytes(buffer[byteCount:]); err != nil {
+			return
+		}
+		byteCount += n
+		if high, n, err = decodeUint64FromBytes(buffer[byteCount:]); err != nil {
+			return
+		}
+		byteCount += n
+		index += distance
+		if index >= uint64(count) || (i > 0 && distance == 0) || bitWidth == 64 {
+			err = ErrMalformedPFOR
+			return
+		}
+		result[start+int(index)] |= high << bitWidth
+	}
+	return
+}
+
+// Choose the bit width that gives the smallest encoded sub-block.
+func pforBestBitWidth(deltas []uint64) (bestWidth uint) {
+	bestSize := -1
+	for bitWidth := uint(0); bitWidth <= 64; bitWidth++ {
+		mask := maskForBitCount(int(bitWidth))
+		size := (len(deltas)*int(bitWidth) + 7) / 8
+		exceptionCount := 0
+		previousIndex := 0
+		for i, d := range deltas {
+			if d > mask {
+				size += EncodedSizeUint64(uint64(i-previousIndex)) + EncodedSizeUint64(d>>bitWidth)
+				previousIndex = i
+				exceptionCount++
+			}
+		}
+		size += EncodedSizeUint64(uint64(exceptionCount))
+		if bestSize < 0 || size < bestSize {
+			bestSize = size
+			bestWidth = bitWidth
+		}
+	}
+	return
+}
+
+func appendPackedBits(buffer []byte, values []uint64, bitWidth uint) []byte {
+	mask := maskForBitCount(int(bitWidth))
+	accumulator := uint64(0)
+	accumulatedBits := uint(0)
+	for _, v := range values {
+		v &= mask
+		for remaining := bitWidth; remaining > 0; {
+			n := minUint(remaining, 64-accumulatedBits)
+			accumulator |= (v & maskForBitCount(int(n))) << accumulatedBits
+			accumulatedBits += n
+			v >>= n
+			remaining -= n
+			for accumulatedBits >= 8 {
+				buffer = append(buffer, byte(accumulator))
+				accumulator >>= 8
+				accumulatedBits -= 8
+			}
+		}
+	}
+	if accumulatedBits > 0 {
+		buffer = append(buffer, byte(accumulator))
+	}
+	return buffer
+}
+
+func unpackBits(values []uint64, packed []byte, count int, bitWidth uint) []uint64 {
+	bitPos := uint(0)
+	for i := 0; i < count; i++ {
+		v := uint64(0)
+		for got := uint(0); got < bitWidth; {
+			offset := bitPos % 8
+			n := minUint(8-offset, bitWidth-got)
+			v |= (uint64(packed[bitPos/8]>>offset) & maskForBitCount(int(n))) << got
+			got += n
+			bitPos += n
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func decodeUint64FromBytes(buffer []byte) (value uint64, byteCount int, err error) {
+	value, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		err = truncatedIfEOF(err)
+		return
+	}
+	if asBigInt != nil {
+		err = ErrOverflow
+	}
+	return
+}
+
+func truncatedIfEOF(err error) error {
+	if err == io.EOF {
+		return ErrTruncated
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertPFORDelta(t *testing.T, values []uint64) []byte {
+	encoded := AppendPFORDelta(nil, values)
+	decoded, byteCount, err := DecodePFORDeltaFromBytes(encoded, len(values))
+	if err != nil {
+		t.Error(err)
+		return encoded
+	}
+	if byteCount != len(encoded) {
+		t.Errorf("Expected to decode %v bytes but decoded %v", len(encoded), byteCount)
+	}
+	if len(values) == 0 && len(decoded) == 0 {
+		return encoded
+	}
+	if describe.D(decoded) != describe.D(values) {
+		t.Errorf("Expected %v but got %v", describe.D(values), describe.D(decoded))
+	}
+	return encoded
+}
+
+func TestPFORDelta(t *testing.T) {
+	assertPFORDelta(t, nil)
+	assertPFORDelta(t, []uint64{0})
+	assertPFORDelta(t, []uint64{5, 5, 5})
+	assertPFORDelta(t, []uint64{0xffffffffffffffff, 0, 0xffffffffffffffff})
+
+	// Unsorted input wraps, but still round-trips.
+	assertPFORDelta(t, []uint64{100, 3, 50, 1})
+
+	postings := make([]uint64, 1000)
+	for i := range postings {
+		postings[i] = uint64(i * 3)
+	}
+	encoded := assertPFORDelta(t, postings)
+	// Every difference is 3, so each sub-block packs into 2 bits per value.
+	if expected := 2 + 7*(1+1+32) + (1 + 1 + 26); len(encoded) != expected {
+		t.Errorf("Expected %v bytes but got %v", expected, len(encoded))
+	}
+}
+
+func TestPFORDeltaExceptions(t *testing.T) {
+	postings := make([]uint64, pforBlockSize)
+	position := uint64(0)
+	for i := range postings {
+		position += 1
+		if i == 40 || i == 90 {
+			position += 1000000
+		}
+		postings[i] = position
+	}
+	encoded := assertPFORDelta(t, postings)
+	// The two outliers become exceptions rather than widening all slots.
+	// The count 128 takes two bytes.
+	if encoded[2] != 1 {
+		t.Errorf("Expected a bit width of 1 but got %v", encoded[2])
+	}
+	if encoded[3] != 2 {
+		t.Errorf("Expected 2 exceptions but got %v", encoded[3])
+	}
+}
+
+func TestPFORDeltaDecodeErrors(t *testing.T) {
+	encoded := AppendPFORDelta(nil, []uint64{1, 2, 3, 1000})
+	for i := 0; i < len(encoded); i++ {
+		if _, _, err := DecodePFORDeltaFromBytes(encoded[:i], 10); err != ErrTruncated {
+			t.Errorf("Expected %v with %v bytes but got %v", ErrTruncated, i, err)
+		}
+	}
+	if _, _, err := DecodePFORDeltaFromBytes(encoded, 3); err == nil {
+		t.Errorf("Expected a count above the maximum to fail")
+	}
+	if _, _, err := DecodePFORDeltaFromBytes([]byte{0x01, 65, 0x00, 0x00}, 10); err != ErrMalformedPFOR {
+		t.Errorf("Expected %v but got %v", ErrMalformedPFOR, err)
+	}
+	// Exception index past the end of the sub-block.
+	if _, _, err := DecodePFORDeltaFromBytes([]byte{0x01, 0x01, 0x01, 0x01, 0x05, 0x01}, 10); err != ErrMalformedPFOR {
+		t.Errorf("Expected %v but got %v", ErrMalformedPFOR, err)
+	}
+}
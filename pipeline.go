@@ -0,0 +1,113 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// EncodeChan encodes values from a channel until it's closed, returning nil,
+// or until ctx is done, returning ctx.Err(). Values that are already waiting
+// in the channel are batched into a single write of up to DefaultChunkSize
+// bytes, and a partial batch is written as soon as the channel runs dry, so
+// values aren't delayed waiting for more to arrive.
+func EncodeChan(ctx context.Context, values <-chan uint64, writer io.Writer) (byteCount int, err error) {
+	buffer := make([]byte, 0, DefaultChunkSize+MaxBufferWriteBytes)
+	write := func() error {
+		if len(buffer) == 0 {
+			return nil
+		}
+		written, err := writer.Write(buffer)
+		byteCount += written
+		buffer = buffer[:0]
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err = write(); err == nil {
+				err = ctx.Err()
+			}
+			return
+		case value, ok := <-values:
+			if !ok {
+				err = write()
+				return
+			}
+			buffer = AppendUint64(buffer, value)
+		default:
+			if err = write(); err != nil {
+				return
+			}
+			// Nothing is waiting, so block until something happens.
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			case value, ok := <-values:
+				if !ok {
+					return
+				}
+				buffer = AppendUint64(buffer, value)
+			}
+		}
+		if len(buffer) >= DefaultChunkSize {
+			if err = write(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// DecodeChan decodes values from reader and sends them to a channel until
+// the reader ends cleanly, returning nil, or until ctx is done, returning
+// ctx.Err(). If the reader ends partway through a value, err is
+// ErrTruncated. The values channel is closed when DecodeChan returns. The
+// reader is read through a bufio.Reader, and cancellation is only noticed
+// between values, so a Read that blocks isn't interrupted.
+func DecodeChan(ctx context.Context, reader io.Reader, values chan<- Value) (byteCount int, err error) {
+	defer close(values)
+	decoder := NewDecoder(bufio.NewReader(reader), DecoderOptions{})
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var value Value
+		var valueByteCount int
+		value, valueByteCount, err = decoder.DecodeValue()
+		byteCount += valueByteCount
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case values <- value:
+		}
+	}
+}
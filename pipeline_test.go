@@ -0,0 +1,158 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/big"
+	"testing"
+)
+
+var pipelineTestValues = []uint64{0, 1, 0x7f, 0x80, 300, 0x0123456789abcdef, 0xffffffffffffffff}
+
+func TestEncodeChan(t *testing.T) {
+	values := make(chan uint64, len(pipelineTestValues))
+	for _, value := range pipelineTestValues {
+		values <- value
+	}
+	close(values)
+
+	expected := &bytes.Buffer{}
+	EncodeManyUint64(expected, pipelineTestValues...)
+	w := &writeCounter{}
+	byteCount, err := EncodeChan(context.Background(), values, w)
+	if err != nil {
+		t.Error(err)
+	}
+	if byteCount != expected.Len() || !bytes.Equal(w.Bytes(), expected.Bytes()) {
+		t.Errorf("Expected %v (%v bytes) but got %v (%v bytes)", expected.Bytes(), expected.Len(), w.Bytes(), byteCount)
+	}
+	if w.writeCount != 1 {
+		t.Errorf("Expected waiting values to be batched into 1 write but got %v", w.writeCount)
+	}
+}
+
+func TestEncodeChanErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := EncodeChan(ctx, make(chan uint64), &bytes.Buffer{}); err != context.Canceled {
+		t.Errorf("Expected %v but got %v", context.Canceled, err)
+	}
+
+	values := make(chan uint64, 1)
+	values <- 1
+	if _, err := EncodeChan(context.Background(), values, failingWriter{}); err != errWriteFailed {
+		t.Errorf("Expected %v but got %v", errWriteFailed, err)
+	}
+}
+
+func TestDecodeChan(t *testing.T) {
+	encoded := &bytes.Buffer{}
+	EncodeManyUint64(encoded, pipelineTestValues...)
+	encoded.Write([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02})
+	expectedByteCount := encoded.Len()
+
+	values := make(chan Value, 100)
+	byteCount, err := DecodeChan(context.Background(), encoded, values)
+	if err != nil || byteCount != expectedByteCount {
+		t.Errorf("Expected %v bytes but got %v (%v)", expectedByteCount, byteCount, err)
+	}
+	var actual []Value
+	for value := range values {
+		actual = append(actual, value)
+	}
+	if len(actual) != len(pipelineTestValues)+1 {
+		t.Errorf("Expected %v values but got %v", len(pipelineTestValues)+1, len(actual))
+		return
+	}
+	for i, expected := range pipelineTestValues {
+		if value, ok := actual[i].Uint64(); !ok || value != expected {
+			t.Errorf("Expected %v but got %v", expected, actual[i])
+		}
+	}
+	if actual[len(actual)-1].Big().Cmp(new(big.Int).Lsh(big.NewInt(1), 64)) != 0 {
+		t.Errorf("Expected 2^64 but got %v", actual[len(actual)-1])
+	}
+}
+
+func TestDecodeChanErrors(t *testing.T) {
+	values := make(chan Value, 10)
+	if _, err := DecodeChan(context.Background(), bytes.NewBuffer([]byte{0x01, 0x80}), values); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	if value := <-values; value.Cmp(Uint64Value(1)) != 0 {
+		t.Errorf("Expected 1 but got %v", value)
+	}
+
+	// Nobody receives, so the send can only be abandoned by cancelling.
+	ctx, cancel := context.WithCancel(context.Background())
+	values = make(chan Value)
+	done := make(chan error)
+	go func() {
+		_, err := DecodeChan(ctx, bytes.NewBuffer([]byte{0x01}), values)
+		done <- err
+	}()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected %v but got %v", context.Canceled, err)
+	}
+	if _, ok := <-values; ok {
+		t.Errorf("Expected the channel to be closed")
+	}
+}
+
+func TestChanPipeline(t *testing.T) {
+	reader, writer := io.Pipe()
+	in := make(chan uint64)
+	out := make(chan Value)
+	go func() {
+		_, err := EncodeChan(context.Background(), in, writer)
+		writer.CloseWithError(err)
+	}()
+	go func() {
+		for i := uint64(0); i < 1000; i++ {
+			in <- i * 1000003
+		}
+		close(in)
+	}()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := DecodeChan(context.Background(), reader, out)
+		errs <- err
+	}()
+
+	next := uint64(0)
+	for value := range out {
+		if actual, ok := value.Uint64(); !ok || actual != next*1000003 {
+			t.Errorf("Expected %v but got %v", next*1000003, value)
+			return
+		}
+		next++
+	}
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+	if next != 1000 {
+		t.Errorf("Expected 1000 values but got %v", next)
+	}
+}
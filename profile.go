@@ -0,0 +1,197 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrUnsupportedSigned is returned when decoding or encoding a signed value
+// with a Profile whose ecosystem has no signed values.
+var ErrUnsupportedSigned = errors.New("uleb128: profile has no signed encoding")
+
+// SignedEncoding identifies how an ecosystem encodes signed integers.
+type SignedEncoding int
+
+const (
+	// The ecosystem has no signed LEB128 values.
+	SignedNone SignedEncoding = iota
+	// Signed values are SLEB128 (two's complement, sign extended).
+	SignedSLEB128
+	// Signed values are zigzag mapped and then ULEB128 encoded.
+	SignedZigZag
+)
+
+// Profile gathers the rules an ecosystem imposes on LEB128 values, so that
+// they can be decoded the same way that ecosystem's own parsers would.
+//
+// The Profile variables are shared, so copy one before modifying it.
+type Profile struct {
+	Name string
+	// The number of bits a value may hold. Values that don't fit are
+	// rejected with ErrOverflow. If 0, values may be any size.
+	MaxBits int
+	// How signed values are encoded.
+	Signed SignedEncoding
+	// The options used to decode unsigned values.
+	Options DecoderOptions
+}
+
+// ProfileWASM follows the WebAssembly binary format for u32 values (indices,
+// counts and sizes) and s32 values: at most 5 bytes, padding allowed, and
+// unused bits in the last byte must be zero (or a sign extension).
+var ProfileWASM = Profile{
+	Name:    "wasm",
+	MaxBits: 32,
+	Signed:  SignedSLEB128,
+	Options: DecoderOptions{
+		MaxByteCount:   5,
+		RejectOverflow: true,
+	},
+}
+
+// ProfileDWARF follows DWARF, whose values may be padded to any length
+// (linkers commonly pad values so they can be patched in place). DWARF doesn't
+// bound the size of a value, but DecodeUint64FromBytes and
+// DecodeInt64FromBytes return ErrOverflow for values that don't fit into 64
+// bits; decode those with the profile's Decoder (Decode or DecodeBig) instead.
+var ProfileDWARF = Profile{
+	Name:   "dwarf",
+	Signed: SignedSLEB128,
+}
+
+// ProfileMultiformats follows the multiformats unsigned-varint spec: at most
+// 9 bytes holding 63 bits, always minimally encoded, and unsigned only.
+var ProfileMultiformats = Profile{
+	Name:    "multiformats",
+	MaxBits: 63,
+	Signed:  SignedNone,
+	Options: DecoderOptions{
+		MaxByteCount:       9,
+		RejectNonCanonical: true,
+		RejectOverflow:     true,
+	},
+}
+
+// ProfileProtobuf follows protobuf varints: at most 10 bytes holding 64 bits,
+// padding allowed, and signed (sint) values zigzag mapped.
+var ProfileProtobuf = Profile{
+	Name:    "protobuf",
+	MaxBits: 64,
+	Signed:  SignedZigZag,
+	Options: DecoderOptions{
+		MaxByteCount:   MaxBufferWriteBytes,
+		RejectOverflow: true,
+	},
+}
+
+// NewDecoder returns a Decoder that reads from reader using the profile's
+// options. It doesn't enforce MaxBits below 64.
+func (p Profile) NewDecoder(reader io.Reader) *Decoder {
+	return NewDecoder(reader, p.Options)
+}
+
+// DecodeUint64FromBytes decodes an unsigned value from the start of buffer
+// following the profile's rules. Returns io.EOF if buffer is empty.
+func (p Profile) DecodeUint64FromBytes(buffer []byte) (value uint64, byteCount int, err error) {
+	value, byteCount, err = NewBytesDecoder(buffer, p.Options).DecodeUint64()
+	if err == nil && !p.fitsUint64(value) {
+		value = 0
+		err = p.detailedError(ErrOverflow, buffer[:byteCount])
+	}
+	return
+}
+
+// DecodeInt64FromBytes decodes a signed value from the start of buffer
+// following the profile's rules. Returns io.EOF if buffer is empty, and
+// ErrUnsupportedSigned if the ecosystem has no signed values.
+func (p Profile) DecodeInt64FromBytes(buffer []byte) (value int64, byteCount int, err error) {
+	switch p.Signed {
+	case SignedZigZag:
+		var asUint uint64
+		asUint, byteCount, err = p.DecodeUint64FromBytes(buffer)
+		value = UnZigZag64(asUint)
+		return
+	case SignedSLEB128:
+		return p.decodeSLEB(buffer)
+	default:
+		err = ErrUnsupportedSigned
+		return
+	}
+}
+
+// AppendInt64 appends a signed value to buffer using the profile's signed
+// encoding, returning the extended buffer. Returns ErrUnsupportedSigned if
+// the ecosystem has no signed values.
+func (p Profile) AppendInt64(buffer []byte, value int64) ([]byte, error) {
+	switch p.Signed {
+	case SignedZigZag:
+		return AppendUint64(buffer, ZigZag64(value)), nil
+	case SignedSLEB128:
+		return AppendInt64(buffer, value), nil
+	default:
+		return buffer, ErrUnsupportedSigned
+	}
+}
+
+func (p Profile) decodeSLEB(buffer []byte) (value int64, byteCount int, err error) {
+	limited := buffer
+	if max := p.Options.MaxByteCount; max > 0 && len(limited) > max {
+		limited = limited[:max]
+	}
+	value, byteCount, err = DecodeInt64FromBytes(limited)
+	switch {
+	case err == io.EOF:
+		return
+	case err == ErrTruncated && len(limited) < len(buffer):
+		err = ErrTooLong
+	case err != nil:
+	case !p.fitsInt64(value):
+		err = ErrOverflow
+	case p.Options.RejectNonCanonical && byteCount != EncodedSizeInt64(value):
+		err = ErrNonCanonical
+	}
+	if err != nil {
+		value = 0
+		err = p.detailedError(err, limited[:byteCount])
+	}
+	return
+}
+
+func (p Profile) fitsUint64(value uint64) bool {
+	return p.MaxBits <= 0 || p.MaxBits >= 64 || value>>uint(p.MaxBits) == 0
+}
+
+func (p Profile) fitsInt64(value int64) bool {
+	if p.MaxBits <= 0 || p.MaxBits >= 64 {
+		return true
+	}
+	high := value >> uint(p.MaxBits-1)
+	return high == 0 || high == -1
+}
+
+func (p Profile) detailedError(err error, encoded []byte) error {
+	if p.Options.DetailedErrors {
+		return newDecodeError(err, 0, encoded)
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestProfileUnsigned(t *testing.T) {
+	for _, test := range []struct {
+		profile  Profile
+		encoded  []byte
+		expected uint64
+		err      error
+	}{
+		{ProfileWASM, []byte{0xe5, 0x8e, 0x26}, 624485, nil},
+		{ProfileWASM, []byte{0x80, 0x80, 0x80, 0x80, 0x00}, 0, nil},
+		{ProfileWASM, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}, 0xffffffff, nil},
+		{ProfileWASM, []byte{0xff, 0xff, 0xff, 0xff, 0x1f}, 0, ErrOverflow},
+		{ProfileWASM, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 0, ErrTooLong},
+		{ProfileDWARF, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 0, nil},
+		{ProfileDWARF, append(bytes.Repeat([]byte{0x80}, 15), 0x00), 0, nil},
+		{ProfileDWARF, []byte{0xac, 0x82, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 300, nil},
+		{ProfileDWARF, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}, 0, ErrOverflow},
+		{ProfileMultiformats, []byte{0xac, 0x02}, 300, nil},
+		{ProfileMultiformats, []byte{0x81, 0x00}, 0, ErrNonCanonical},
+		{ProfileMultiformats, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, 1<<63 - 1, nil},
+		{ProfileMultiformats, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, 0, ErrTooLong},
+		{ProfileProtobuf, []byte{0x96, 0x01}, 150, nil},
+		{ProfileProtobuf, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, 1<<64 - 1, nil},
+		{ProfileProtobuf, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, 0, ErrOverflow},
+		{ProfileProtobuf, nil, 0, io.EOF},
+	} {
+		value, _, err := test.profile.DecodeUint64FromBytes(test.encoded)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected error %v decoding %x but got %v", test.profile.Name, test.err, test.encoded, err)
+		} else if value != test.expected {
+			t.Errorf("%v: expected %x to decode to %v but got %v", test.profile.Name, test.encoded, test.expected, value)
+		}
+	}
+}
+
+func TestProfileSigned(t *testing.T) {
+	for _, test := range []struct {
+		profile  Profile
+		encoded  []byte
+		expected int64
+		err      error
+	}{
+		{ProfileWASM, []byte{0x7f}, -1, nil},
+		{ProfileWASM, []byte{0xff, 0xff, 0xff, 0xff, 0x7f}, -1, nil},
+		{ProfileWASM, []byte{0x80, 0x80, 0x80, 0x80, 0x78}, -1 << 31, nil},
+		{ProfileWASM, []byte{0x80, 0x80, 0x80, 0x80, 0x70}, 0, ErrOverflow},
+		{ProfileWASM, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, 0, ErrTooLong},
+		{ProfileDWARF, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, -1, nil},
+		{ProfileDWARF, []byte{0xc0, 0xbb, 0x78}, -123456, nil},
+		{ProfileDWARF, append(bytes.Repeat([]byte{0xff}, 15), 0x7f), -1, nil},
+		{ProfileProtobuf, []byte{0x03}, -2, nil},
+		{ProfileProtobuf, []byte{0x04}, 2, nil},
+		{ProfileMultiformats, []byte{0x01}, 0, ErrUnsupportedSigned},
+	} {
+		value, _, err := test.profile.DecodeInt64FromBytes(test.encoded)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected error %v decoding %x but got %v", test.profile.Name, test.err, test.encoded, err)
+		} else if value != test.expected {
+			t.Errorf("%v: expected %x to decode to %v but got %v", test.profile.Name, test.encoded, test.expected, value)
+		}
+	}
+}
+
+func TestProfileDWARFBig(t *testing.T) {
+	huge := newBigFromHex("123456789abcdef0123456789abcdef")
+	encoded := append(Append(nil, huge), 0)
+	encoded[len(encoded)-2] |= continuationMask
+	if _, _, err := ProfileDWARF.DecodeUint64FromBytes(encoded); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	_, v, _, err := ProfileDWARF.NewDecoder(bytes.NewReader(encoded)).Decode()
+	if err != nil || v == nil || v.Cmp(huge) != 0 {
+		t.Errorf("Expected %v but got %v (%v)", huge, v, err)
+	}
+}
+
+func TestProfileAppendInt64(t *testing.T) {
+	for _, profile := range []Profile{ProfileWASM, ProfileDWARF, ProfileProtobuf} {
+		for _, value := range []int64{0, 1, -1, 63, -64, 64, -65, 1 << 30, -1 << 31} {
+			encoded, err := profile.AppendInt64(nil, value)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			decoded, byteCount, err := profile.DecodeInt64FromBytes(encoded)
+			if err != nil {
+				t.Error(err)
+			} else if decoded != value || byteCount != len(encoded) {
+				t.Errorf("%v: expected %v (%v bytes) but got %v (%v bytes)", profile.Name, value, len(encoded), decoded, byteCount)
+			}
+		}
+	}
+	if _, err := ProfileMultiformats.AppendInt64(nil, 1); err != ErrUnsupportedSigned {
+		t.Errorf("Expected %v but got %v", ErrUnsupportedSigned, err)
+	}
+}
+
+func TestProfileDetailedErrors(t *testing.T) {
+	profile := ProfileWASM
+	profile.Options.DetailedErrors = true
+	_, _, err := profile.DecodeUint64FromBytes([]byte{0xff, 0xff, 0xff, 0xff, 0x1f})
+	var decodeError *DecodeError
+	if !errors.As(err, &decodeError) || decodeError.Err != ErrOverflow || decodeError.ByteCount != 5 {
+		t.Errorf("Expected a *DecodeError wrapping %v but got %v", ErrOverflow, err)
+	}
+	if ProfileWASM.Options.DetailedErrors {
+		t.Errorf("Expected the copy not to modify ProfileWASM")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrZeroDenominator is returned when a decoded rational has a denominator
+// of 0.
+var ErrZeroDenominator = errors.New("uleb128: rational has a zero denominator")
+
+// A rational is encoded as two consecutive values: the numerator with its
+// sign folded into the low bit (0, -1, 1, -2, 2 ... become 0, 1, 2, 3, 4 ...),
+// followed by the denominator, which is always positive.
+
+// EncodedSizeRat returns the number of bytes required to encode this value.
+func EncodedSizeRat(value *big.Rat) int {
+	return EncodedSize(foldSign(value.Num())) + EncodedSize(value.Denom())
+}
+
+// EncodeRat encodes a math.big.Rat value as a numerator/denominator pair.
+func EncodeRat(value *big.Rat, writer io.Writer) (byteCount int, err error) {
+	return writer.Write(AppendRat(nil, value))
+}
+
+// AppendRat appends the encoding of a math.big.Rat value to buffer,
+// returning the extended buffer.
+func AppendRat(buffer []byte, value *big.Rat) []byte {
+	buffer = Append(buffer, foldSign(value.Num()))
+	return Append(buffer, value.Denom())
+}
+
+// DecodeRat decodes a math.big.Rat value. Returns io.EOF if the stream ends
+// cleanly before the value, io.ErrUnexpectedEOF if it ends partway through,
+// or ErrZeroDenominator if the denominator is 0.
+func DecodeRat(reader io.Reader) (value *big.Rat, byteCount int, err error) {
+	buffer := []byte{0}
+	numUint, numBig, byteCount, err := DecodeWithByteBuffer(reader, buffer)
+	if err != nil {
+		if err == io.EOF && byteCount > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	denomUint, denomBig, denomByteCount, err := DecodeWithByteBuffer(reader, buffer)
+	byteCount += denomByteCount
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	value, err = newRat(numUint, numBig, denomUint, denomBig)
+	return
+}
+
+// DecodeRatFromBytes decodes a math.big.Rat value from the start of buffer.
+// Returns io.EOF if buffer is empty, ErrTruncated if it ends partway through
+// the value, or ErrZeroDenominator if the denominator is 0.
+func DecodeRatFromBytes(buffer []byte) (value *big.Rat, byteCount int, err error) {
+	numUint, numBig, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		return
+	}
+	denomUint, denomBig, denomByteCount, err := DecodeFromBytes(buffer[byteCount:])
+	byteCount += denomByteCount
+	if err != nil {
+		err = truncatedIfEOF(err)
+		return
+	}
+	value, err = newRat(numUint, numBig, denomUint, denomBig)
+	return
+}
+
+func newRat(numUint uint64, numBig *big.Int, denomUint uint64, denomBig *big.Int) (value *big.Rat, err error) {
+	denom := NewValue(denomUint, denomBig).Big()
+	if denom.Sign() == 0 {
+		err = ErrZeroDenominator
+		return
+	}
+	num := unfoldSign(new(big.Int).Set(NewValue(numUint, numBig).Big()))
+	value = new(big.Rat).SetFrac(num, denom)
+	return
+}
+
+// Fold the sign of value into its low bit, returning a new big.Int.
+func foldSign(value *big.Int) *big.Int {
+	folded := new(big.Int).Lsh(value, 1)
+	if value.Sign() < 0 {
+		folded.Neg(folded)
+		folded.Sub(folded, bigOne)
+	}
+	return folded
+}
+
+// Reverse foldSign, modifying value in place.
+func unfoldSign(value *big.Int) *big.Int {
+	if value.Bit(0) == 0 {
+		return value.Rsh(value, 1)
+	}
+	value.Add(value, bigOne)
+	value.Rsh(value, 1)
+	return value.Neg(value)
+}
+
+var bigOne = big.NewInt(1)
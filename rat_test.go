@@ -0,0 +1,101 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertRatEncoding(t *testing.T, value *big.Rat, expected []byte) {
+	encoded := AppendRat(nil, value)
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("Expected %v to encode to %v but got %v", value, describe.D(expected), describe.D(encoded))
+	}
+	if size := EncodedSizeRat(value); size != len(expected) {
+		t.Errorf("Expected %v to have encoded size %v but got %v", value, len(expected), size)
+	}
+}
+
+func TestRatEncoding(t *testing.T) {
+	assertRatEncoding(t, big.NewRat(0, 1), []byte{0x00, 0x01})
+	assertRatEncoding(t, big.NewRat(1, 2), []byte{0x02, 0x02})
+	assertRatEncoding(t, big.NewRat(-1, 2), []byte{0x01, 0x02})
+	assertRatEncoding(t, big.NewRat(-2, 3), []byte{0x03, 0x03})
+	assertRatEncoding(t, big.NewRat(300, 7), []byte{0xd8, 0x04, 0x07})
+}
+
+func TestRatRoundTrip(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 200)
+	values := []*big.Rat{
+		big.NewRat(0, 1),
+		big.NewRat(1, 3),
+		big.NewRat(-1, 3),
+		big.NewRat(-5, 1),
+		new(big.Rat).SetFrac(huge, big.NewInt(3)),
+		new(big.Rat).SetFrac(new(big.Int).Neg(huge), new(big.Int).Add(huge, big.NewInt(1))),
+	}
+	for _, expected := range values {
+		encoded := AppendRat(nil, expected)
+		value, byteCount, err := DecodeRatFromBytes(encoded)
+		if err != nil || value.Cmp(expected) != 0 || byteCount != len(encoded) {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+
+		buff := &bytes.Buffer{}
+		if _, err := EncodeRat(expected, buff); err != nil {
+			t.Error(err)
+			return
+		}
+		value, byteCount, err = DecodeRat(buff)
+		if err != nil || value.Cmp(expected) != 0 || byteCount != len(encoded) {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+	}
+}
+
+func assertDecodeRatFails(t *testing.T, data []byte, expectedFromBytes error, expectedFromReader error) {
+	if _, _, err := DecodeRatFromBytes(data); err != expectedFromBytes {
+		t.Errorf("Expected %v to fail with %v but got %v", data, expectedFromBytes, err)
+	}
+	if _, _, err := DecodeRat(bytes.NewBuffer(data)); err != expectedFromReader {
+		t.Errorf("Expected %v to fail with %v from a reader but got %v", data, expectedFromReader, err)
+	}
+}
+
+func TestDecodeRatErrors(t *testing.T) {
+	assertDecodeRatFails(t, nil, io.EOF, io.EOF)
+	assertDecodeRatFails(t, []byte{0x80}, ErrTruncated, io.ErrUnexpectedEOF)
+	assertDecodeRatFails(t, []byte{0x02}, ErrTruncated, io.ErrUnexpectedEOF)
+	assertDecodeRatFails(t, []byte{0x02, 0x80}, ErrTruncated, io.ErrUnexpectedEOF)
+	assertDecodeRatFails(t, []byte{0x02, 0x00}, ErrZeroDenominator, ErrZeroDenominator)
+}
+
+func TestDecodeRatNormalizes(t *testing.T) {
+	value, _, err := DecodeRatFromBytes([]byte{0x04, 0x04})
+	if err != nil || value.Cmp(big.NewRat(1, 2)) != 0 {
+		t.Errorf("Expected 1/2 but got %v (%v)", value, err)
+	}
+}
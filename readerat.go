@@ -0,0 +1,158 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+)
+
+// The number of bytes ReaderAtArray reads from its source at a time.
+const readerAtChunkSize = 64
+
+// ReaderAtArray gives random access to consecutive ULEB128 values stored in
+// an io.ReaderAt (such as an os.File), in the same way that ULEBArray does
+// for a []byte. Nothing is read up front: each Get reads only the bytes
+// between the nearest sampled offset and the end of the requested value.
+// To serve part of a larger file (such as the values of a block), wrap it in
+// an io.SectionReader. A memory-mapped file is already a []byte, and can be
+// used directly with NewULEBArrayWithIndex.
+//
+// ReaderAtArray is safe for concurrent use if its source is.
+type ReaderAtArray struct {
+	reader io.ReaderAt
+	size   int64
+	index  Index
+}
+
+// NewReaderAtArray returns an array over the first size bytes of reader,
+// using an index that was built earlier (for example by ULEBArrayBuilder or
+// IndexReaderAt). Only the index's shape is checked, since checking its
+// offsets would mean reading the data.
+func NewReaderAtArray(reader io.ReaderAt, size int64, index Index) (array *ReaderAtArray, err error) {
+	if index.Interval < 1 || index.Count < 0 ||
+		len(index.Offsets) != (index.Count+index.Interval-1)/index.Interval {
+		err = ErrInvalidIndex
+		return
+	}
+	for i, offset := range index.Offsets {
+		if int64(offset) >= size || (i > 0 && offset <= index.Offsets[i-1]) {
+			err = ErrInvalidIndex
+			return
+		}
+	}
+	array = &ReaderAtArray{reader: reader, size: size, index: index}
+	return
+}
+
+// IndexReaderAt scans the first size bytes of reader and builds an index as
+// in BuildIndex, reading a chunk at a time rather than all at once.
+func IndexReaderAt(reader io.ReaderAt, size int64, interval int) (index Index, err error) {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+	index.Interval = interval
+	atValueStart := true
+	chunk := make([]byte, DefaultChunkSize)
+	for position := int64(0); position < size; {
+		var n int
+		if n, err = readChunkAt(reader, chunk, position, size); err != nil {
+			return
+		}
+		for i, b := range chunk[:n] {
+			if atValueStart {
+				if index.Count%interval == 0 {
+					index.Offsets = append(index.Offsets, int(position)+i)
+				}
+				index.Count++
+			}
+			atValueStart = b&continuationMask == 0
+		}
+		position += int64(n)
+	}
+	if !atValueStart {
+		err = ErrTruncated
+	}
+	return
+}
+
+// Len returns the number of values in the array.
+func (a *ReaderAtArray) Len() int {
+	return a.index.Count
+}
+
+// Index returns the array's sampled index.
+func (a *ReaderAtArray) Index() Index {
+	return a.index
+}
+
+// Get returns the value at position i. It panics if i is out of range.
+// Errors from the underlying reader are returned as-is, except that io.EOF
+// (the source being shorter than its stated size) becomes
+// io.ErrUnexpectedEOF. If the data ends partway through the value, err will
+// be ErrTruncated.
+func (a *ReaderAtArray) Get(i int) (value Value, err error) {
+	if i < 0 || i >= a.index.Count {
+		panic("uleb128: ReaderAtArray index out of range")
+	}
+	position := int64(a.index.Offsets[i/a.index.Interval])
+	skip := i % a.index.Interval
+	var chunk [readerAtChunkSize]byte
+	var encoded []byte
+	for position < a.size {
+		var n int
+		if n, err = readChunkAt(a.reader, chunk[:], position, a.size); err != nil {
+			return
+		}
+		position += int64(n)
+		j := 0
+		for ; skip > 0 && j < n; j++ {
+			if chunk[j] < continuationMask {
+				skip--
+			}
+		}
+		start := j
+		for ; j < n; j++ {
+			if chunk[j] < continuationMask {
+				encoded = append(encoded, chunk[start:j+1]...)
+				asUint, asBigInt, _, _ := DecodeFromBytes(encoded)
+				value = NewValue(asUint, asBigInt)
+				return
+			}
+		}
+		encoded = append(encoded, chunk[start:n]...)
+	}
+	err = ErrTruncated
+	return
+}
+
+// Read the part of [position, size) that fits into chunk.
+func readChunkAt(reader io.ReaderAt, chunk []byte, position int64, size int64) (n int, err error) {
+	if remaining := size - position; remaining < int64(len(chunk)) {
+		chunk = chunk[:remaining]
+	}
+	n, err = reader.ReadAt(chunk, position)
+	if n == len(chunk) {
+		err = nil
+	} else if err == io.EOF || err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+type countingReaderAt struct {
+	reader    io.ReaderAt
+	byteCount int64
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, offset int64) (n int, err error) {
+	n, err = r.reader.ReadAt(p, offset)
+	r.byteCount += int64(n)
+	return
+}
+
+func TestReaderAtArray(t *testing.T) {
+	var buffer []byte
+	var expected []Value
+	for i := uint64(0); i < 1000; i++ {
+		value := Uint64Value(i * i * i * i * i * i)
+		if i%97 == 0 {
+			value = BigValue(new(big.Int).Lsh(big.NewInt(int64(i)+1), 600))
+		}
+		buffer = value.AppendTo(buffer)
+		expected = append(expected, value)
+	}
+
+	for _, interval := range []int{1, 3, 64, 2000} {
+		reader := &countingReaderAt{reader: bytes.NewReader(buffer)}
+		index, err := IndexReaderAt(reader, int64(len(buffer)), interval)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if expectedIndex, _ := BuildIndex(buffer, interval); !reflect.DeepEqual(index, expectedIndex) {
+			t.Errorf("Expected %+v but got %+v", expectedIndex, index)
+		}
+		reader.byteCount = 0
+		array, err := NewReaderAtArray(reader, int64(len(buffer)), index)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if reader.byteCount != 0 {
+			t.Errorf("Expected no reads up front but got %v bytes", reader.byteCount)
+		}
+		if array.Len() != len(expected) {
+			t.Errorf("Expected %v values but got %v", len(expected), array.Len())
+		}
+		for i, value := range expected {
+			actual, err := array.Get(i)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if actual.Cmp(value) != 0 {
+				t.Errorf("Expected value %v to be %v but got %v (interval %v)", i, value, actual, interval)
+				return
+			}
+		}
+	}
+}
+
+func TestReaderAtArrayReadsLazily(t *testing.T) {
+	builder := NewULEBArrayBuilder(4)
+	for i := uint64(0); i < 10000; i++ {
+		builder.Append(i)
+	}
+	buffer, index := builder.Finalize()
+	reader := &countingReaderAt{reader: bytes.NewReader(buffer)}
+	array, err := NewReaderAtArray(reader, int64(len(buffer)), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := array.Get(9999)
+	if err != nil || value.Cmp(Uint64Value(9999)) != 0 {
+		t.Errorf("Expected 9999 but got %v (%v)", value, err)
+	}
+	if reader.byteCount > readerAtChunkSize {
+		t.Errorf("Expected at most %v bytes to be read but got %v", readerAtChunkSize, reader.byteCount)
+	}
+}
+
+func TestReaderAtArrayErrors(t *testing.T) {
+	buffer := []byte{0x01, 0x02, 0x80, 0x80}
+	index := Index{Interval: 2, Offsets: []int{0, 2}, Count: 3}
+	array, err := NewReaderAtArray(bytes.NewReader(buffer), int64(len(buffer)), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = array.Get(2); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+
+	array, err = NewReaderAtArray(bytes.NewReader(buffer[:3]), int64(len(buffer)), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = array.Get(2); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	for _, bad := range []Index{
+		{Interval: 0, Offsets: []int{0}, Count: 1},
+		{Interval: 2, Offsets: []int{0}, Count: 3},
+		{Interval: 2, Offsets: []int{0, 4}, Count: 3},
+		{Interval: 2, Offsets: []int{2, 1}, Count: 3},
+	} {
+		if _, err = NewReaderAtArray(bytes.NewReader(buffer), int64(len(buffer)), bad); err != ErrInvalidIndex {
+			t.Errorf("Expected %v for %+v but got %v", ErrInvalidIndex, bad, err)
+		}
+	}
+	if _, err = IndexReaderAt(bytes.NewReader(buffer), int64(len(buffer)), 2); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
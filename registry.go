@@ -0,0 +1,73 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var codecRegistry = struct {
+	sync.RWMutex
+	codecs map[string]Codec
+}{
+	codecs: map[string]Codec{
+		"uleb128":    ULEB128Codec,
+		"syncvarint": SyncVarintCodec,
+	},
+}
+
+// RegisterCodec makes a codec available by name to LookupCodec. It's intended
+// to be called from the init function of a package providing an external
+// codec. RegisterCodec panics if codec is nil or name is already registered.
+func RegisterCodec(name string, codec Codec) {
+	if codec == nil {
+		panic("uleb128: RegisterCodec with nil codec")
+	}
+	codecRegistry.Lock()
+	defer codecRegistry.Unlock()
+	if _, exists := codecRegistry.codecs[name]; exists {
+		panic(fmt.Sprintf("uleb128: codec %q is already registered", name))
+	}
+	codecRegistry.codecs[name] = codec
+}
+
+// LookupCodec returns the codec registered under name. The package registers
+// "uleb128" and "syncvarint".
+func LookupCodec(name string) (codec Codec, ok bool) {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+	codec, ok = codecRegistry.codecs[name]
+	return
+}
+
+// CodecNames returns the names of all registered codecs in sorted order.
+func CodecNames() []string {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+	names := make([]string, 0, len(codecRegistry.codecs))
+	for name := range codecRegistry.codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
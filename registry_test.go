@@ -0,0 +1,75 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+type registryTestCodec struct {
+	ulebCodec
+}
+
+func TestLookupCodec(t *testing.T) {
+	if codec, ok := LookupCodec("uleb128"); !ok || codec != ULEB128Codec {
+		t.Errorf("Expected uleb128 to be registered")
+	}
+	if codec, ok := LookupCodec("syncvarint"); !ok || codec != SyncVarintCodec {
+		t.Errorf("Expected syncvarint to be registered")
+	}
+	if _, ok := LookupCodec("no-such-codec"); ok {
+		t.Errorf("Expected no-such-codec to be unregistered")
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	codec := registryTestCodec{}
+	RegisterCodec("test-registered", codec)
+	defer func() {
+		codecRegistry.Lock()
+		delete(codecRegistry.codecs, "test-registered")
+		codecRegistry.Unlock()
+	}()
+
+	if actual, ok := LookupCodec("test-registered"); !ok || actual != Codec(codec) {
+		t.Errorf("Expected test-registered to be registered")
+	}
+	expected := []string{"syncvarint", "test-registered", "uleb128"}
+	if actual := CodecNames(); describe.D(actual) != describe.D(expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+	}
+}
+
+func assertRegisterPanics(t *testing.T, name string, codec Codec) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering %q to panic", name)
+		}
+	}()
+	RegisterCodec(name, codec)
+}
+
+func TestRegisterCodecPanics(t *testing.T) {
+	assertRegisterPanics(t, "uleb128", registryTestCodec{})
+	assertRegisterPanics(t, "test-nil", nil)
+}
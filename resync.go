@@ -0,0 +1,74 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+)
+
+// SkippedRange is a range of bytes that DecodeAllFromBytesLenient couldn't
+// decode, and the reason why.
+type SkippedRange struct {
+	Offset    int
+	ByteCount int
+	Err       error
+}
+
+// DecodeAllFromBytesLenient decodes consecutive ULEB128 values from buffer
+// like DecodeAllFromBytes, but rather than stopping at a bad value it skips
+// past the byte that ends it (the next byte with a clear continuation bit)
+// and resumes from there. Values encoded in more than maxByteCount bytes
+// (MaxBufferWriteBytes if 0 or less) are skipped with ErrTooLong, and an
+// incomplete value at the end of the buffer is skipped with ErrTruncated.
+func DecodeAllFromBytesLenient(buffer []byte, maxByteCount int, onValue func(asUint uint64, asBigInt *big.Int)) (skipped []SkippedRange) {
+	if maxByteCount <= 0 {
+		maxByteCount = MaxBufferWriteBytes
+	}
+	offset := 0
+	for offset < len(buffer) {
+		end := offset
+		for end < len(buffer) && buffer[end]&continuationMask != 0 {
+			end++
+		}
+		if end == len(buffer) {
+			skipped = append(skipped, SkippedRange{
+				Offset:    offset,
+				ByteCount: end - offset,
+				Err:       ErrTruncated,
+			})
+			return
+		}
+		end++
+
+		if end-offset > maxByteCount {
+			skipped = append(skipped, SkippedRange{
+				Offset:    offset,
+				ByteCount: end - offset,
+				Err:       ErrTooLong,
+			})
+		} else {
+			asUint, asBigInt, _, _ := DecodeFromBytes(buffer[offset:end])
+			onValue(asUint, asBigInt)
+		}
+		offset = end
+	}
+	return
+}
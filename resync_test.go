@@ -0,0 +1,70 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertDecodeLenient(t *testing.T, maxByteCount int, data []byte, expectedValues []uint64, expectedSkipped ...SkippedRange) {
+	var actualValues []uint64
+	actualSkipped := DecodeAllFromBytesLenient(data, maxByteCount, func(asUint uint64, asBigInt *big.Int) {
+		if asBigInt != nil {
+			t.Errorf("Expected %v to not contain big ints", describe.D(data))
+		}
+		actualValues = append(actualValues, asUint)
+	})
+	if !reflect.DeepEqual(actualValues, expectedValues) {
+		t.Errorf("Expected %v to decode to %v but got %v", describe.D(data), expectedValues, actualValues)
+	}
+	if !reflect.DeepEqual(actualSkipped, expectedSkipped) {
+		t.Errorf("Expected decoding %v to skip %v but got %v", describe.D(data), describe.D(expectedSkipped), describe.D(actualSkipped))
+	}
+}
+
+func TestDecodeAllFromBytesLenient(t *testing.T) {
+	assertDecodeLenient(t, 2, nil, nil)
+	assertDecodeLenient(t, 2, []byte{0x01, 0x80, 0x01}, []uint64{1, 0x80})
+
+	assertDecodeLenient(t, 2, []byte{0x01, 0x80, 0x80, 0x01, 0x02},
+		[]uint64{1, 2},
+		SkippedRange{Offset: 1, ByteCount: 3, Err: ErrTooLong})
+
+	assertDecodeLenient(t, 2, []byte{0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0x7f, 0x05, 0x80},
+		[]uint64{5},
+		SkippedRange{Offset: 0, ByteCount: 4, Err: ErrTooLong},
+		SkippedRange{Offset: 4, ByteCount: 3, Err: ErrTooLong},
+		SkippedRange{Offset: 8, ByteCount: 1, Err: ErrTruncated})
+
+	// A limit of 0 or less means MaxBufferWriteBytes.
+	for _, maxByteCount := range []int{0, -1} {
+		assertDecodeLenient(t, maxByteCount, append(bytes.Repeat([]byte{0x80}, MaxBufferWriteBytes-1), 0x00, 0x05),
+			[]uint64{0, 5})
+		assertDecodeLenient(t, maxByteCount, append(bytes.Repeat([]byte{0x80}, MaxBufferWriteBytes), 0x01, 0x05),
+			[]uint64{5},
+			SkippedRange{Offset: 0, ByteCount: MaxBufferWriteBytes + 1, Err: ErrTooLong})
+	}
+}
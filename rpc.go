@@ -0,0 +1,150 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+)
+
+// A message is a small building block for RPC-style protocols: a header of
+// the method id, the flags and the payload length (all as ULEB128),
+// followed by the payload. The meaning of the method ids and flags is up to
+// the protocol.
+
+// MessageHeader is the header of a message.
+type MessageHeader struct {
+	Method        uint64
+	Flags         uint64
+	PayloadLength int
+}
+
+// EncodedSizeMessage returns the number of bytes a message with this method,
+// these flags and a payload of payloadLength bytes encodes to.
+func EncodedSizeMessage(method uint64, flags uint64, payloadLength int) int {
+	return EncodedSizeUint64(method) + EncodedSizeUint64(flags) +
+		EncodedSizeUint64(uint64(payloadLength)) + payloadLength
+}
+
+// AppendMessage appends a message to buffer.
+func AppendMessage(buffer []byte, method uint64, flags uint64, payload []byte) []byte {
+	buffer = AppendUint64(buffer, method)
+	buffer = AppendUint64(buffer, flags)
+	buffer = AppendUint64(buffer, uint64(len(payload)))
+	return append(buffer, payload...)
+}
+
+// WriteMessage writes a message to writer in a single Write call.
+func WriteMessage(writer io.Writer, method uint64, flags uint64, payload []byte) (byteCount int, err error) {
+	buffer := make([]byte, 0, EncodedSizeMessage(method, flags, len(payload)))
+	return writer.Write(AppendMessage(buffer, method, flags, payload))
+}
+
+// DecodeMessageFromBytes decodes a message from the start of buffer. The
+// payload refers to buffer rather than being copied. A payload longer than
+// maxPayloadSize returns ErrMessageTooLarge, and a method id or flags too
+// large for a uint64 return ErrOverflow. If buffer is empty, err will be
+// io.EOF, and if it ends partway through the message, err will be
+// ErrTruncated.
+func DecodeMessageFromBytes(buffer []byte, maxPayloadSize int) (header MessageHeader, payload []byte, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	fields := [3]uint64{}
+	for i := range fields {
+		var fieldByteCount int
+		fields[i], fieldByteCount, err = decodeUint64FromBytes(buffer[byteCount:])
+		byteCount += fieldByteCount
+		if err != nil {
+			err = messageFieldError(i, err)
+			return
+		}
+	}
+	if header, err = newMessageHeader(fields, maxPayloadSize); err != nil {
+		return
+	}
+	if header.PayloadLength > len(buffer)-byteCount {
+		err = ErrTruncated
+		return
+	}
+	payload = buffer[byteCount : byteCount+header.PayloadLength]
+	byteCount += header.PayloadLength
+	return
+}
+
+// ReadMessageHeader reads a message header from reader, leaving the payload
+// to be read by the caller. Errors are the same as for ReadMessage.
+func ReadMessageHeader(reader io.Reader, maxPayloadSize int) (header MessageHeader, byteCount int, err error) {
+	buffer := []byte{0}
+	fields := [3]uint64{}
+	for i := range fields {
+		var fieldByteCount int
+		fields[i], fieldByteCount, err = decodeUint64(reader, buffer)
+		byteCount += fieldByteCount
+		if err != nil {
+			if i > 0 {
+				err = unexpectedEOF(err)
+			}
+			err = messageFieldError(i, err)
+			return
+		}
+	}
+	header, err = newMessageHeader(fields, maxPayloadSize)
+	return
+}
+
+// ReadMessage reads a message from reader. Each header field is read as at
+// most MaxBufferWriteBytes bytes, and the payload length is checked against
+// maxPayloadSize before anything is allocated, returning ErrMessageTooLarge
+// if it's over. A method id or flags too large for a uint64 return
+// ErrOverflow. If the stream ends cleanly before the message,
+// err will be io.EOF, and if it ends partway through, err will be
+// io.ErrUnexpectedEOF.
+func ReadMessage(reader io.Reader, maxPayloadSize int) (header MessageHeader, payload []byte, byteCount int, err error) {
+	if header, byteCount, err = ReadMessageHeader(reader, maxPayloadSize); err != nil {
+		return
+	}
+	payload = make([]byte, header.PayloadLength)
+	n, err := io.ReadFull(reader, payload)
+	byteCount += n
+	if err != nil {
+		payload = nil
+		err = unexpectedEOF(err)
+	}
+	return
+}
+
+func newMessageHeader(fields [3]uint64, maxPayloadSize int) (header MessageHeader, err error) {
+	if maxPayloadSize < 0 || fields[2] > uint64(maxPayloadSize) {
+		err = ErrMessageTooLarge
+		return
+	}
+	header = MessageHeader{Method: fields[0], Flags: fields[1], PayloadLength: int(fields[2])}
+	return
+}
+
+// A payload length too large for a uint64 is certainly over the limit.
+func messageFieldError(field int, err error) error {
+	if field == 2 && err == ErrOverflow {
+		return ErrMessageTooLarge
+	}
+	return err
+}
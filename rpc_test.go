@@ -0,0 +1,119 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		method  uint64
+		flags   uint64
+		payload []byte
+	}{
+		{0, 0, nil},
+		{300, 1, []byte("hello")},
+		{0xffffffffffffffff, 0xffffffffffffffff, bytes.Repeat([]byte{0x80}, 200)},
+	} {
+		encoded := AppendMessage(nil, test.method, test.flags, test.payload)
+		if size := EncodedSizeMessage(test.method, test.flags, len(test.payload)); size != len(encoded) {
+			t.Errorf("Expected size %v but got %v", len(encoded), size)
+		}
+		expectedHeader := MessageHeader{Method: test.method, Flags: test.flags, PayloadLength: len(test.payload)}
+
+		header, payload, byteCount, err := DecodeMessageFromBytes(append(encoded, 0x00), 200)
+		if err != nil {
+			t.Error(err)
+		}
+		if header != expectedHeader || !bytes.Equal(payload, test.payload) || byteCount != len(encoded) {
+			t.Errorf("Expected %+v %v (%v bytes) but got %+v %v (%v bytes)",
+				expectedHeader, test.payload, len(encoded), header, payload, byteCount)
+		}
+
+		buffer := &writeCounter{}
+		if _, err = WriteMessage(buffer, test.method, test.flags, test.payload); err != nil {
+			t.Error(err)
+		}
+		if buffer.writeCount != 1 || !bytes.Equal(buffer.Bytes(), encoded) {
+			t.Errorf("Expected %v in one write but got %v in %v", describe.D(encoded), describe.D(buffer.Bytes()), buffer.writeCount)
+		}
+		header, payload, byteCount, err = ReadMessage(buffer, 200)
+		if err != nil {
+			t.Error(err)
+		}
+		if header != expectedHeader || !bytes.Equal(payload, test.payload) || byteCount != len(encoded) {
+			t.Errorf("Expected %+v %v (%v bytes) but got %+v %v (%v bytes)",
+				expectedHeader, test.payload, len(encoded), header, payload, byteCount)
+		}
+	}
+}
+
+func TestMessageEncoding(t *testing.T) {
+	expected := []byte{0xac, 0x02, 0x03, 0x02, 'h', 'i'}
+	actual := AppendMessage(nil, 300, 3, []byte("hi"))
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	tooLong := append([]byte{0x01, 0x00}, bytes.Repeat([]byte{0xff}, 10)...)
+	tooLong = append(tooLong, 0x01)
+	for _, test := range []struct {
+		encoded   []byte
+		bytesErr  error
+		streamErr error
+	}{
+		{[]byte{}, io.EOF, io.EOF},
+		{[]byte{0x80}, ErrTruncated, io.ErrUnexpectedEOF},
+		{[]byte{0x01}, ErrTruncated, io.ErrUnexpectedEOF},
+		{[]byte{0x01, 0x00, 0x03, 'a'}, ErrTruncated, io.ErrUnexpectedEOF},
+		{[]byte{0x01, 0x00, 0x05}, ErrMessageTooLarge, ErrMessageTooLarge},
+		{tooLong, ErrMessageTooLarge, ErrMessageTooLarge},
+		{append(bytes.Repeat([]byte{0xff}, 10), 0x01, 0x00, 0x00), ErrOverflow, ErrOverflow},
+	} {
+		if _, _, _, err := DecodeMessageFromBytes(test.encoded, 4); err != test.bytesErr {
+			t.Errorf("Expected %v to give %v but got %v", describe.D(test.encoded), test.bytesErr, err)
+		}
+		if _, _, _, err := ReadMessage(bytes.NewReader(test.encoded), 4); err != test.streamErr {
+			t.Errorf("Expected reading %v to give %v but got %v", describe.D(test.encoded), test.streamErr, err)
+		}
+	}
+}
+
+func TestReadMessageHostileHeader(t *testing.T) {
+	for field, expectedErr := range []error{ErrOverflow, ErrOverflow, ErrMessageTooLarge} {
+		// Valid fields up to the hostile one, then endless continuation bytes.
+		prefix := bytes.NewReader(make([]byte, field))
+		reader := &continuationReader{}
+		if _, _, _, err := ReadMessage(io.MultiReader(prefix, reader), 1<<20); err != expectedErr {
+			t.Errorf("Field %v: expected %v but got %v", field, expectedErr, err)
+		}
+		if reader.byteCount > MaxBufferWriteBytes {
+			t.Errorf("Field %v: expected to read at most %v bytes but read %v", field, MaxBufferWriteBytes, reader.byteCount)
+		}
+	}
+}
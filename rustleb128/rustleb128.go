@@ -0,0 +1,129 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package rustleb128 reads and writes LEB128 values with the same semantics
+// as the Rust leb128 crate, for services that need to agree with it byte for
+// byte:
+//
+//   - Values are limited to 64 bits, with up to 10 bytes per value.
+//     Redundant (non-canonical) encodings are accepted within that limit.
+//   - A 10th byte with bits beyond the 64th set fails with
+//     uleb128.ErrOverflow, after the rest of the value has been consumed so
+//     that the stream stays aligned on the next value.
+//   - Input that ends before or partway through a value fails with
+//     io.ErrUnexpectedEOF (there is no clean end of stream).
+//
+// Writes always produce the minimal encoding, as the crate does.
+package rustleb128
+
+import (
+	"io"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+const (
+	continuationBit = 0x80
+	signBit         = 0x40
+)
+
+// ReadUnsigned reads an unsigned LEB128 value, mirroring leb128::read::unsigned.
+func ReadUnsigned(reader io.Reader) (value uint64, byteCount int, err error) {
+	buffer := []byte{0}
+	for shift := uint(0); ; shift += 7 {
+		if err = readByte(reader, buffer); err != nil {
+			return
+		}
+		byteCount++
+		if shift == 63 && buffer[0] != 0x00 && buffer[0] != 0x01 {
+			err = skipRest(reader, buffer, &byteCount)
+			return
+		}
+		value |= uint64(buffer[0]&^continuationBit) << shift
+		if buffer[0]&continuationBit == 0 {
+			return
+		}
+	}
+}
+
+// ReadSigned reads a signed LEB128 value, mirroring leb128::read::signed.
+func ReadSigned(reader io.Reader) (value int64, byteCount int, err error) {
+	buffer := []byte{0}
+	shift := uint(0)
+	for {
+		if err = readByte(reader, buffer); err != nil {
+			return
+		}
+		byteCount++
+		if shift == 63 && buffer[0] != 0x00 && buffer[0] != 0x7f {
+			err = skipRest(reader, buffer, &byteCount)
+			return
+		}
+		value |= int64(buffer[0]&^continuationBit) << shift
+		shift += 7
+		if buffer[0]&continuationBit == 0 {
+			break
+		}
+	}
+	if shift < 64 && buffer[0]&signBit != 0 {
+		value |= -1 << shift
+	}
+	return
+}
+
+// WriteUnsigned writes value as unsigned LEB128, mirroring
+// leb128::write::unsigned.
+func WriteUnsigned(writer io.Writer, value uint64) (byteCount int, err error) {
+	return uleb128.EncodeUint64(value, writer)
+}
+
+// WriteSigned writes value as signed LEB128, mirroring leb128::write::signed.
+func WriteSigned(writer io.Writer, value int64) (byteCount int, err error) {
+	buffer := make([]byte, 0, uleb128.MaxBufferWriteBytes)
+	for {
+		b := byte(value) &^ continuationBit
+		value >>= 7
+		if (value == 0 && b&signBit == 0) || (value == -1 && b&signBit != 0) {
+			buffer = append(buffer, b)
+			break
+		}
+		buffer = append(buffer, b|continuationBit)
+	}
+	return writer.Write(buffer)
+}
+
+func readByte(reader io.Reader, buffer []byte) (err error) {
+	if _, err = io.ReadFull(reader, buffer); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// Consume the rest of an overflowing value, whose last byte read is in
+// buffer, returning ErrOverflow unless the stream fails first.
+func skipRest(reader io.Reader, buffer []byte, byteCount *int) error {
+	for buffer[0]&continuationBit != 0 {
+		if err := readByte(reader, buffer); err != nil {
+			return err
+		}
+		*byteCount++
+	}
+	return uleb128.ErrOverflow
+}
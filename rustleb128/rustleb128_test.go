@@ -0,0 +1,125 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package rustleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func repeat(b byte, count int, tail ...byte) []byte {
+	return append(bytes.Repeat([]byte{b}, count), tail...)
+}
+
+func TestReadUnsigned(t *testing.T) {
+	for _, test := range []struct {
+		encoded []byte
+		value   uint64
+		err     error
+	}{
+		{[]byte{0x00}, 0, nil},
+		{[]byte{0xac, 0x02}, 300, nil},
+		{[]byte{0x80, 0x80, 0x00}, 0, nil},
+		{repeat(0x80, 9, 0x01), 1 << 63, nil},
+		{repeat(0xff, 9, 0x01), math.MaxUint64, nil},
+		{repeat(0x80, 9, 0x00), 0, nil},
+		{repeat(0xff, 9, 0x02), 0, uleb128.ErrOverflow},
+		{repeat(0x80, 9, 0x80, 0x00), 0, uleb128.ErrOverflow},
+		{repeat(0x80, 9, 0x81, 0x00), 0, uleb128.ErrOverflow},
+		{nil, 0, io.ErrUnexpectedEOF},
+		{[]byte{0x80}, 0, io.ErrUnexpectedEOF},
+		{repeat(0x80, 9, 0x82), 0, io.ErrUnexpectedEOF},
+	} {
+		value, byteCount, err := ReadUnsigned(bytes.NewReader(test.encoded))
+		if err != test.err || (err == nil && value != test.value) {
+			t.Errorf("Expected [% x] to decode to %v (%v) but got %v (%v)", test.encoded, test.value, test.err, value, err)
+		}
+		if err != io.ErrUnexpectedEOF && byteCount != len(test.encoded) {
+			t.Errorf("Expected [% x] to consume %v bytes but got %v", test.encoded, len(test.encoded), byteCount)
+		}
+	}
+}
+
+func TestReadSigned(t *testing.T) {
+	for _, test := range []struct {
+		encoded []byte
+		value   int64
+		err     error
+	}{
+		{[]byte{0x00}, 0, nil},
+		{[]byte{0x7f}, -1, nil},
+		{[]byte{0x3f}, 63, nil},
+		{[]byte{0xc0, 0x00}, 64, nil},
+		{[]byte{0x40}, -64, nil},
+		{[]byte{0xbf, 0x7f}, -65, nil},
+		{[]byte{0xff, 0x7f}, -1, nil},
+		{repeat(0x80, 9, 0x7f), math.MinInt64, nil},
+		{repeat(0xff, 9, 0x00), math.MaxInt64, nil},
+		{repeat(0x80, 9, 0x01), 0, uleb128.ErrOverflow},
+		{repeat(0x80, 9, 0xff, 0x00), 0, uleb128.ErrOverflow},
+		{nil, 0, io.ErrUnexpectedEOF},
+		{[]byte{0xff}, 0, io.ErrUnexpectedEOF},
+	} {
+		value, byteCount, err := ReadSigned(bytes.NewReader(test.encoded))
+		if err != test.err || (err == nil && value != test.value) {
+			t.Errorf("Expected [% x] to decode to %v (%v) but got %v (%v)", test.encoded, test.value, test.err, value, err)
+		}
+		if err != io.ErrUnexpectedEOF && byteCount != len(test.encoded) {
+			t.Errorf("Expected [% x] to consume %v bytes but got %v", test.encoded, len(test.encoded), byteCount)
+		}
+	}
+}
+
+func TestOverflowKeepsStreamAligned(t *testing.T) {
+	reader := bytes.NewReader(repeat(0x80, 9, 0x82, 0x80, 0x01, 0x05))
+	if _, byteCount, err := ReadUnsigned(reader); err != uleb128.ErrOverflow || byteCount != 12 {
+		t.Errorf("Expected ErrOverflow after 12 bytes but got %v (%v)", byteCount, err)
+	}
+	if value, _, err := ReadUnsigned(reader); err != nil || value != 5 {
+		t.Errorf("Expected 5 but got %v (%v)", value, err)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	for _, value := range []uint64{0, 1, 127, 128, 300, math.MaxUint64} {
+		buffer.Reset()
+		if _, err := WriteUnsigned(buffer, value); err != nil {
+			t.Error(err)
+		}
+		if decoded, byteCount, err := ReadUnsigned(buffer); err != nil || decoded != value || byteCount != uleb128.EncodedSizeUint64(value) {
+			t.Errorf("Expected %v but got %v in %v bytes (%v)", value, decoded, byteCount, err)
+		}
+	}
+	for _, value := range []int64{0, -1, 63, 64, -64, -65, 1000000, math.MinInt64, math.MaxInt64} {
+		buffer.Reset()
+		if _, err := WriteSigned(buffer, value); err != nil {
+			t.Error(err)
+		}
+		if decoded, byteCount, err := ReadSigned(buffer); err != nil || decoded != value || byteCount != uleb128.EncodedSizeInt64SLEB(value) {
+			t.Errorf("Expected %v but got %v in %v bytes (%v)", value, decoded, byteCount, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"math/big"
+)
+
+// ScanOptions controls which byte sequences Scan considers plausible.
+type ScanOptions struct {
+	// Values encoded in more than this many bytes end a run.
+	// If 0, MaxBufferWriteBytes is used.
+	MaxByteCount int
+	// If true, non-canonical (zero padded) encodings end a run.
+	RejectNonCanonical bool
+	// Runs with fewer values than this are not reported.
+	MinValues int
+	// Runs with fewer multi-byte values than this are not reported. Almost
+	// any data decodes as a run of single-byte values, so this is the most
+	// effective way to filter out noise.
+	MinMultiByteValues int
+}
+
+// ScanValue is a single value found by Scan.
+type ScanValue struct {
+	Offset    int
+	ByteCount int
+	// If the value fits into a uint64, AsBigInt will be nil and AsUint will
+	// contain the value.
+	AsUint    uint64
+	AsBigInt  *big.Int
+	Canonical bool
+}
+
+// ScanRun is a sequence of consecutive plausible values found by Scan.
+type ScanRun struct {
+	Offset    int
+	ByteCount int
+	Values    []ScanValue
+}
+
+// Scan walks an arbitrary byte blob and reports the runs of consecutive
+// bytes that plausibly contain ULEB128 values. When a value isn't plausible,
+// the current run ends and scanning resumes at the following byte.
+func Scan(data []byte, options ScanOptions) (runs []ScanRun) {
+	maxByteCount := options.MaxByteCount
+	if maxByteCount <= 0 {
+		maxByteCount = MaxBufferWriteBytes
+	}
+
+	var run ScanRun
+	multiByteCount := 0
+	endRun := func() {
+		if len(run.Values) > 0 && len(run.Values) >= options.MinValues && multiByteCount >= options.MinMultiByteValues {
+			runs = append(runs, run)
+		}
+		run = ScanRun{}
+		multiByteCount = 0
+	}
+
+	for offset := 0; offset < len(data); {
+		window := data[offset:]
+		if len(window) > maxByteCount {
+			window = window[:maxByteCount]
+		}
+		asUint, asBigInt, byteCount, err := DecodeFromBytes(window)
+		canonical := err == nil && isCanonical(window[:byteCount])
+		if err != nil || (options.RejectNonCanonical && !canonical) {
+			endRun()
+			offset++
+			continue
+		}
+
+		if len(run.Values) == 0 {
+			run.Offset = offset
+		}
+		run.Values = append(run.Values, ScanValue{
+			Offset:    offset,
+			ByteCount: byteCount,
+			AsUint:    asUint,
+			AsBigInt:  asBigInt,
+			Canonical: canonical,
+		})
+		run.ByteCount += byteCount
+		if byteCount > 1 {
+			multiByteCount++
+		}
+		offset += byteCount
+	}
+	endRun()
+	return
+}
+
+// NextValueStart returns the offset just past the first byte in buffer that
+// ends a ULEB128 value (or len(buffer) if there isn't one), which is where
+// the next value starts. To find the first value that starts at or after
+// offset i of a larger buffer (for example to split it into shards), call
+// it on buffer[i-1:] and add i-1.
+func NextValueStart(buffer []byte) int {
+	for i, b := range buffer {
+		if b&continuationMask == 0 {
+			return i + 1
+		}
+	}
+	return len(buffer)
+}
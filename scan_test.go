@@ -0,0 +1,109 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertScan(t *testing.T, options ScanOptions, data []byte, expectedRuns ...ScanRun) {
+	actualRuns := Scan(data, options)
+	if !reflect.DeepEqual(actualRuns, expectedRuns) {
+		t.Errorf("Expected scanning %v to find %v but got %v", describe.D(data), describe.D(expectedRuns), describe.D(actualRuns))
+	}
+}
+
+func TestScan(t *testing.T) {
+	assertScan(t, ScanOptions{}, nil)
+	assertScan(t, ScanOptions{}, []byte{0x80})
+
+	assertScan(t, ScanOptions{}, []byte{0x01, 0x80, 0x01},
+		ScanRun{Offset: 0, ByteCount: 3, Values: []ScanValue{
+			{Offset: 0, ByteCount: 1, AsUint: 1, Canonical: true},
+			{Offset: 1, ByteCount: 2, AsUint: 0x80, Canonical: true},
+		}})
+
+	assertScan(t, ScanOptions{}, []byte{0x80, 0x00},
+		ScanRun{Offset: 0, ByteCount: 2, Values: []ScanValue{
+			{Offset: 0, ByteCount: 2, AsUint: 0, Canonical: false},
+		}})
+
+	assertScan(t, ScanOptions{RejectNonCanonical: true}, []byte{0x80, 0x00},
+		ScanRun{Offset: 1, ByteCount: 1, Values: []ScanValue{
+			{Offset: 1, ByteCount: 1, AsUint: 0, Canonical: true},
+		}})
+}
+
+func TestScanFiltering(t *testing.T) {
+	data := []byte{
+		0x41, 0x42,
+		0xff, 0xff, 0xff, 0xff,
+		0xac, 0x02, 0x05, 0x90, 0x4e,
+		0xff, 0xff, 0xff, 0xff,
+		0x43,
+	}
+
+	assertScan(t, ScanOptions{MaxByteCount: 3, MinMultiByteValues: 2},
+		data,
+		ScanRun{Offset: 5, ByteCount: 6, Values: []ScanValue{
+			{Offset: 5, ByteCount: 3, AsUint: 38527, Canonical: true},
+			{Offset: 8, ByteCount: 1, AsUint: 5, Canonical: true},
+			{Offset: 9, ByteCount: 2, AsUint: 10000, Canonical: true},
+		}})
+
+	assertScan(t, ScanOptions{MaxByteCount: 3, MinValues: 2},
+		data,
+		ScanRun{Offset: 0, ByteCount: 2, Values: []ScanValue{
+			{Offset: 0, ByteCount: 1, AsUint: 0x41, Canonical: true},
+			{Offset: 1, ByteCount: 1, AsUint: 0x42, Canonical: true},
+		}},
+		ScanRun{Offset: 5, ByteCount: 6, Values: []ScanValue{
+			{Offset: 5, ByteCount: 3, AsUint: 38527, Canonical: true},
+			{Offset: 8, ByteCount: 1, AsUint: 5, Canonical: true},
+			{Offset: 9, ByteCount: 2, AsUint: 10000, Canonical: true},
+		}})
+}
+
+func TestNextValueStart(t *testing.T) {
+	buffer := []byte{0x01, 0xac, 0x02, 0x80, 0x80, 0x01, 0x05}
+	for _, test := range []struct {
+		offset   int
+		expected int
+	}{
+		{1, 1},
+		{2, 3},
+		{3, 3},
+		{4, 6},
+		{5, 6},
+		{6, 6},
+		{7, 7},
+	} {
+		if actual := NextValueStart(buffer[test.offset-1:]) + test.offset - 1; actual != test.expected {
+			t.Errorf("Expected the first value at or after %v to start at %v but got %v", test.offset, test.expected, actual)
+		}
+	}
+	if actual := NextValueStart([]byte{0x80, 0x80}); actual != 2 {
+		t.Errorf("Expected 2 but got %v", actual)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// ErrFrameAuthentication is returned when a sealed frame can't be opened,
+// because it was corrupted, tampered with, moved, or sealed with another key.
+var ErrFrameAuthentication = errors.New("uleb128: frame failed authentication")
+
+// SealedFrameWriter is like FrameWriter, but encrypts and authenticates each
+// record with an AEAD cipher (such as AES-GCM or ChaCha20-Poly1305). Each
+// record is framed as a ULEB128 length prefix (left in the clear) followed
+// by a fresh random nonce and the sealed bytes. The record's index in the
+// stream is used as additional data, so that records can't be reordered,
+// dropped or repeated without detection. Note that a stream cut short on a
+// record boundary can't be detected this way; end the stream with a record
+// that marks its end if that matters.
+type SealedFrameWriter struct {
+	frames *FrameWriter
+	aead   cipher.AEAD
+	sealed []byte
+	index  uint64
+}
+
+// NewSealedFrameWriter returns a SealedFrameWriter that seals records with
+// aead and writes them to writer.
+func NewSealedFrameWriter(writer io.Writer, aead cipher.AEAD) *SealedFrameWriter {
+	return &SealedFrameWriter{
+		frames: NewFrameWriter(writer),
+		aead:   aead,
+	}
+}
+
+// Write emits p as a single sealed record.
+// n is the number of bytes of p that were written (excluding the prefix,
+// nonce, and authentication overhead).
+func (w *SealedFrameWriter) Write(p []byte) (n int, err error) {
+	nonceSize := w.aead.NonceSize()
+	size := nonceSize + len(p) + w.aead.Overhead()
+	if cap(w.sealed) < size {
+		w.sealed = make([]byte, 0, size)
+	}
+	nonce := w.sealed[:nonceSize]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+	sealed := w.aead.Seal(nonce, nonce, p, AppendUint64(nil, w.index))
+	if _, err = w.frames.Write(sealed); err != nil {
+		return
+	}
+	w.index++
+	n = len(p)
+	return
+}
+
+// SealedFrameReader reads records produced by a SealedFrameWriter, opening
+// each one before returning any of its bytes. Like FrameReader, Read never
+// returns bytes from more than one record.
+type SealedFrameReader struct {
+	frames  *FrameReader
+	aead    cipher.AEAD
+	pending []byte
+	index   uint64
+}
+
+// NewSealedFrameReader returns a SealedFrameReader that reads records from
+// reader and opens them with aead, rejecting any record longer than
+// maxFrameSize bytes (once opened) with ErrFrameTooLarge.
+func NewSealedFrameReader(reader io.Reader, aead cipher.AEAD, maxFrameSize int) *SealedFrameReader {
+	return &SealedFrameReader{
+		frames: NewFrameReader(reader, maxFrameSize+aead.NonceSize()+aead.Overhead()),
+		aead:   aead,
+	}
+}
+
+// NextFrame reads and opens the next complete record, discarding whatever is
+// left of the record currently being read. Returns io.EOF if the stream ends
+// cleanly on a record boundary, io.ErrUnexpectedEOF if it ends partway
+// through one, and ErrFrameAuthentication if the record can't be opened.
+func (r *SealedFrameReader) NextFrame() (frame []byte, err error) {
+	r.pending = nil
+	sealed, err := r.frames.NextFrame()
+	if err != nil {
+		return
+	}
+	nonceSize := r.aead.NonceSize()
+	if len(sealed) < nonceSize+r.aead.Overhead() {
+		err = ErrFrameAuthentication
+		return
+	}
+	frame, err = r.aead.Open(sealed[nonceSize:nonceSize], sealed[:nonceSize], sealed[nonceSize:], AppendUint64(nil, r.index))
+	if err != nil {
+		frame = nil
+		err = ErrFrameAuthentication
+		return
+	}
+	r.index++
+	return
+}
+
+// Read reads from the current record, opening a new one if the previous
+// record has been fully consumed. A zero-length record yields a read of 0
+// bytes.
+func (r *SealedFrameReader) Read(p []byte) (n int, err error) {
+	if len(r.pending) == 0 {
+		if r.pending, err = r.NextFrame(); err != nil {
+			return
+		}
+	}
+	n = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return
+}
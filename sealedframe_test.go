@@ -0,0 +1,138 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func newTestAEAD(t *testing.T, keyByte byte) cipher.AEAD {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{keyByte}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func sealTestRecords(t *testing.T, aead cipher.AEAD, records ...[]byte) []byte {
+	buff := &bytes.Buffer{}
+	writer := NewSealedFrameWriter(buff, aead)
+	for _, record := range records {
+		if n, err := writer.Write(record); err != nil || n != len(record) {
+			t.Fatalf("Expected to write %v bytes but got %v (%v)", len(record), n, err)
+		}
+	}
+	return buff.Bytes()
+}
+
+func TestSealedFrameRoundTrip(t *testing.T) {
+	aead := newTestAEAD(t, 1)
+	records := [][]byte{[]byte("hello"), {}, bytes.Repeat([]byte{0xaa}, 300)}
+	sealed := sealTestRecords(t, aead, records...)
+	if bytes.Contains(sealed, []byte("hello")) {
+		t.Errorf("Expected the records to be encrypted")
+	}
+
+	// Lengths are in the clear, so a plain FrameReader can still walk the
+	// records.
+	frames := NewFrameReader(bytes.NewReader(sealed), 1000)
+	for _, record := range records {
+		frame, err := frames.NextFrame()
+		if expected := len(record) + aead.NonceSize() + aead.Overhead(); err != nil || len(frame) != expected {
+			t.Errorf("Expected a sealed frame of %v bytes but got %v (%v)", expected, len(frame), err)
+		}
+	}
+
+	reader := NewSealedFrameReader(bytes.NewReader(sealed), aead, 300)
+	for _, record := range records {
+		frame, err := reader.NextFrame()
+		if err != nil || !bytes.Equal(frame, record) {
+			t.Errorf("Expected %q but got %q (%v)", record, frame, err)
+		}
+	}
+	if _, err := reader.NextFrame(); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+
+	reader = NewSealedFrameReader(bytes.NewReader(sealed), aead, 300)
+	small := make([]byte, 3)
+	if n, err := reader.Read(small); err != nil || string(small[:n]) != "hel" {
+		t.Errorf("Expected \"hel\" but got %q (%v)", small[:n], err)
+	}
+	if n, err := reader.Read(make([]byte, 100)); err != nil || n != 2 {
+		t.Errorf("Expected the rest of the first record but got %v bytes (%v)", n, err)
+	}
+	if n, err := reader.Read(small); err != nil || n != 0 {
+		t.Errorf("Expected an empty record but got %v bytes (%v)", n, err)
+	}
+	if all, err := ioutil.ReadAll(reader); err != nil || !bytes.Equal(all, records[2]) {
+		t.Errorf("Expected the last record but got %v bytes (%v)", len(all), err)
+	}
+}
+
+func TestSealedFrameTampering(t *testing.T) {
+	aead := newTestAEAD(t, 1)
+	first := sealTestRecords(t, aead, []byte("first"), []byte("second"))
+	firstLength := 1 + int(first[0])
+
+	assertOpenFails := func(name string, data []byte, aead cipher.AEAD, maxFrameSize int, expected error) {
+		reader := NewSealedFrameReader(bytes.NewReader(data), aead, maxFrameSize)
+		var err error
+		for err == nil {
+			_, err = reader.NextFrame()
+		}
+		if err != expected {
+			t.Errorf("%v: Expected %v but got %v", name, expected, err)
+		}
+	}
+
+	flipped := append([]byte(nil), first...)
+	flipped[len(flipped)-1] ^= 1
+	assertOpenFails("flipped bit", flipped, aead, 100, ErrFrameAuthentication)
+
+	swapped := append(append([]byte(nil), first[firstLength:]...), first[:firstLength]...)
+	assertOpenFails("reordered", swapped, aead, 100, ErrFrameAuthentication)
+
+	assertOpenFails("dropped", first[firstLength:], aead, 100, ErrFrameAuthentication)
+	assertOpenFails("wrong key", first, newTestAEAD(t, 2), 100, ErrFrameAuthentication)
+	assertOpenFails("too short", []byte{0x02, 0x00, 0x00}, aead, 100, ErrFrameAuthentication)
+	assertOpenFails("too large", first, aead, 5, ErrFrameTooLarge)
+	assertOpenFails("truncated", first[:len(first)-1], aead, 100, io.ErrUnexpectedEOF)
+}
+
+func TestSealedFrameEndlessLength(t *testing.T) {
+	reader := &continuationReader{}
+	if _, err := NewSealedFrameReader(reader, newTestAEAD(t, 1), 1<<20).NextFrame(); err != ErrFrameTooLarge {
+		t.Errorf("Expected %v but got %v", ErrFrameTooLarge, err)
+	}
+	if reader.byteCount > MaxBufferWriteBytes {
+		t.Errorf("Expected to read at most %v bytes but read %v", MaxBufferWriteBytes, reader.byteCount)
+	}
+}
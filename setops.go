@@ -0,0 +1,128 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+// The set operations work on delta-encoded sorted arrays (see
+// SortedArrayBuilder) and produce their result in the same encoding. Each
+// input is decoded once, front to back, without materializing its values.
+// Inputs may contain duplicate values, but the result never does. If an
+// input ends partway through a value, err will be ErrTruncated, and if a
+// running total doesn't fit into a uint64, err will be ErrOverflow. Both
+// inputs are always read to the end, so these are reported even when the
+// rest of an input couldn't change the result.
+
+// Intersect returns the values that are in both a and b.
+func Intersect(a []byte, b []byte) (result []byte, err error) {
+	return mergeSorted(a, b, false, true, false)
+}
+
+// Union returns the values that are in either a or b.
+func Union(a []byte, b []byte) (result []byte, err error) {
+	return mergeSorted(a, b, true, true, true)
+}
+
+// Difference returns the values that are in a but not in b.
+func Difference(a []byte, b []byte) (result []byte, err error) {
+	return mergeSorted(a, b, true, false, false)
+}
+
+// Merge two sorted arrays, keeping the values that appear only in a, in
+// both, or only in b as requested.
+func mergeSorted(a []byte, b []byte, keepOnlyA, keepBoth, keepOnlyB bool) (result []byte, err error) {
+	readerA := sortedReader{buffer: a}
+	readerB := sortedReader{buffer: b}
+	writer := sortedWriter{}
+	valueA, okA, err := readerA.next()
+	if err != nil {
+		return
+	}
+	valueB, okB, err := readerB.next()
+	if err != nil {
+		return
+	}
+	for okA || okB {
+		switch {
+		case okA && (!okB || valueA < valueB):
+			if keepOnlyA {
+				writer.append(valueA)
+			}
+			valueA, okA, err = readerA.next()
+		case okB && (!okA || valueB < valueA):
+			if keepOnlyB {
+				writer.append(valueB)
+			}
+			valueB, okB, err = readerB.next()
+		default:
+			if keepBoth {
+				writer.append(valueA)
+			}
+			if valueA, okA, err = readerA.next(); err != nil {
+				return
+			}
+			valueB, okB, err = readerB.next()
+		}
+		if err != nil {
+			return
+		}
+	}
+	result = writer.buffer
+	return
+}
+
+// Reads the distinct values of a delta-encoded sorted array in order.
+type sortedReader struct {
+	buffer []byte
+	offset int
+	value  uint64
+}
+
+func (r *sortedReader) next() (value uint64, ok bool, err error) {
+	for r.offset < len(r.buffer) {
+		var delta uint64
+		var byteCount int
+		if delta, byteCount, err = decodeUintFromBytes(r.buffer[r.offset:], 64); err != nil {
+			return
+		}
+		if r.value+delta < r.value {
+			err = ErrOverflow
+			return
+		}
+		first := r.offset == 0
+		r.offset += byteCount
+		r.value += delta
+		if first || delta != 0 {
+			value, ok = r.value, true
+			return
+		}
+	}
+	return
+}
+
+// Writes a delta-encoded sorted array.
+type sortedWriter struct {
+	buffer []byte
+	last   uint64
+}
+
+func (w *sortedWriter) append(value uint64) {
+	w.buffer = AppendUint64(w.buffer, value-w.last)
+	w.last = value
+}
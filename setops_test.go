@@ -0,0 +1,141 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func encodeSorted(values []uint64) []byte {
+	builder := NewSortedArrayBuilder(DefaultIndexInterval)
+	for _, value := range values {
+		builder.Append(value)
+	}
+	buffer, _ := builder.Finalize()
+	return buffer
+}
+
+func decodeSorted(t *testing.T, buffer []byte) (values []uint64) {
+	array, err := NewSortedArray(buffer, DefaultIndexInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < array.Len(); i++ {
+		values = append(values, array.Get(i))
+	}
+	return
+}
+
+func referenceSetOp(a, b []uint64, keep func(inA, inB bool) bool) (result []uint64) {
+	inA := map[uint64]bool{}
+	inB := map[uint64]bool{}
+	all := []uint64{}
+	for _, value := range a {
+		inA[value] = true
+		all = append(all, value)
+	}
+	for _, value := range b {
+		inB[value] = true
+		all = append(all, value)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	for i, value := range all {
+		if (i == 0 || value != all[i-1]) && keep(inA[value], inB[value]) {
+			result = append(result, value)
+		}
+	}
+	return
+}
+
+func assertSetOp(t *testing.T, name string, op func(a, b []byte) ([]byte, error), a, b, expected []uint64) {
+	result, err := op(encodeSorted(a), encodeSorted(b))
+	if err != nil {
+		t.Errorf("%v(%v, %v): %v", name, a, b, err)
+		return
+	}
+	if !bytes.Equal(result, encodeSorted(expected)) {
+		t.Errorf("%v(%v, %v): Expected %v but got %v", name, a, b, expected, decodeSorted(t, result))
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	a := []uint64{1, 3, 3, 5, 300, 1000}
+	b := []uint64{3, 4, 5, 5, 1000, 2000}
+	assertSetOp(t, "Intersect", Intersect, a, b, []uint64{3, 5, 1000})
+	assertSetOp(t, "Union", Union, a, b, []uint64{1, 3, 4, 5, 300, 1000, 2000})
+	assertSetOp(t, "Difference", Difference, a, b, []uint64{1, 300})
+	assertSetOp(t, "Difference", Difference, b, a, []uint64{4, 2000})
+
+	assertSetOp(t, "Intersect", Intersect, a, nil, nil)
+	assertSetOp(t, "Union", Union, nil, b, []uint64{3, 4, 5, 1000, 2000})
+	assertSetOp(t, "Difference", Difference, a, nil, []uint64{1, 3, 5, 300, 1000})
+	assertSetOp(t, "Difference", Difference, nil, b, nil)
+	assertSetOp(t, "Union", Union, []uint64{math.MaxUint64}, []uint64{0}, []uint64{0, math.MaxUint64})
+}
+
+func TestSetOpsRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomSorted := func() []uint64 {
+		values := make([]uint64, random.Intn(200))
+		for i := range values {
+			values[i] = uint64(random.Intn(500))
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		return values
+	}
+	for i := 0; i < 100; i++ {
+		a, b := randomSorted(), randomSorted()
+		assertSetOp(t, "Intersect", Intersect, a, b, referenceSetOp(a, b, func(inA, inB bool) bool { return inA && inB }))
+		assertSetOp(t, "Union", Union, a, b, referenceSetOp(a, b, func(inA, inB bool) bool { return inA || inB }))
+		assertSetOp(t, "Difference", Difference, a, b, referenceSetOp(a, b, func(inA, inB bool) bool { return inA && !inB }))
+	}
+}
+
+func TestSetOpsErrors(t *testing.T) {
+	valid := encodeSorted([]uint64{1, 2, 3})
+	for _, op := range []func(a, b []byte) ([]byte, error){Intersect, Union, Difference} {
+		if _, err := op([]byte{0x01, 0x80}, valid); err != ErrTruncated {
+			t.Errorf("Expected %v but got %v", ErrTruncated, err)
+		}
+		// Malformed tails past the end of the other input.
+		if _, err := op(append(encodeSorted([]uint64{1, 5}), 0x80), []byte{0x01}); err != ErrTruncated {
+			t.Errorf("Expected %v but got %v", ErrTruncated, err)
+		}
+		if _, err := op([]byte{0x01}, append(encodeSorted([]uint64{1, 5}), 0x80)); err != ErrTruncated {
+			t.Errorf("Expected %v but got %v", ErrTruncated, err)
+		}
+		overflow := append(AppendUint64(nil, math.MaxUint64), 0x01)
+		if _, err := op(overflow, []byte{}); err != ErrOverflow {
+			t.Errorf("Expected %v but got %v", ErrOverflow, err)
+		}
+	}
+	if result, err := Intersect(nil, nil); err != nil || len(result) != 0 {
+		t.Errorf("Expected an empty result but got %v, %v", result, err)
+	}
+	if !reflect.DeepEqual(decodeSorted(t, valid), []uint64{1, 2, 3}) {
+		t.Errorf("Sorted encoding did not round trip")
+	}
+}
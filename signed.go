@@ -0,0 +1,164 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/bits"
+)
+
+// The bit in the last byte of an SLEB128 encoding that gives the sign.
+const slebSignBit = 0x40
+
+// EncodedSizeInt64 returns the number of bytes required to encode an int64
+// value as SLEB128.
+func EncodedSizeInt64(value int64) int {
+	return EncodedSizeInt64SLEB(value)
+}
+
+// EncodeInt64 encodes an int64 value as SLEB128 (signed LEB128), returning
+// the number of bytes encoded.
+func EncodeInt64(value int64, writer io.Writer) (byteCount int, err error) {
+	return writer.Write(AppendInt64(make([]byte, 0, MaxBufferWriteBytes), value))
+}
+
+// AppendInt64 appends the SLEB128 encoding of an int64 value to buffer,
+// returning the extended buffer.
+func AppendInt64(buffer []byte, value int64) []byte {
+	for {
+		b := byte(value) &^ continuationMask
+		value >>= 7
+		if (value == 0 && b&slebSignBit == 0) || (value == -1 && b&slebSignBit != 0) {
+			return append(buffer, b)
+		}
+		buffer = append(buffer, b|continuationMask)
+	}
+}
+
+// DecodeInt64 decodes an SLEB128 value. Encodings padded beyond their
+// minimal length are accepted, as long as the padding is a sign extension.
+// Returns io.EOF if the stream ends cleanly before the value,
+// io.ErrUnexpectedEOF if it ends partway through, and ErrOverflow (after
+// consuming the whole value) if the value doesn't fit into an int64. At most
+// MaxBufferWriteBytes bytes are read, so that a hostile stream can't keep it
+// reading forever; a value that continues past them also returns ErrOverflow.
+func DecodeInt64(reader io.Reader) (value int64, byteCount int, err error) {
+	buffer := []byte{0}
+	var state slebState
+	for {
+		if _, err = io.ReadFull(reader, buffer); err != nil {
+			if byteCount > 0 {
+				err = unexpectedEOF(err)
+			}
+			return
+		}
+		byteCount++
+		if state.add(buffer[0]) {
+			value, err = state.result()
+			return
+		}
+		if byteCount == MaxBufferWriteBytes {
+			err = ErrOverflow
+			return
+		}
+	}
+}
+
+// DecodeInt64FromBytes decodes an SLEB128 value from the start of buffer.
+// Returns io.EOF if buffer is empty, ErrTruncated if it ends partway through
+// the value, and otherwise the same errors as DecodeInt64. Since buffer bounds
+// the value, padding isn't limited to MaxBufferWriteBytes bytes.
+func DecodeInt64FromBytes(buffer []byte) (value int64, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	var state slebState
+	for _, b := range buffer {
+		byteCount++
+		if state.add(b) {
+			value, err = state.result()
+			return
+		}
+	}
+	err = ErrTruncated
+	return
+}
+
+// Accumulates the bytes of an SLEB128 value.
+type slebState struct {
+	value     int64
+	byteCount int
+	// The payload of the 10th byte, which holds bit 63 and the start of the
+	// sign extension. Every byte from there on must match it.
+	high     byte
+	overflow bool
+}
+
+// Add the next byte, returning true if it ends the value.
+func (s *slebState) add(b byte) (done bool) {
+	payload := b &^ continuationMask
+	switch {
+	case s.byteCount < 9:
+		s.value |= int64(payload) << (7 * uint(s.byteCount))
+	case s.byteCount == 9:
+		s.value |= int64(payload) << 63
+		s.high = payload
+		s.overflow = payload != 0 && payload != payloadMask
+	default:
+		s.overflow = s.overflow || payload != s.high
+	}
+	s.byteCount++
+	if b&continuationMask != 0 {
+		return false
+	}
+	if s.byteCount < 10 && payload&slebSignBit != 0 {
+		s.value |= -1 << (7 * uint(s.byteCount))
+	}
+	return true
+}
+
+func (s *slebState) result() (value int64, err error) {
+	if s.overflow {
+		err = ErrOverflow
+		return
+	}
+	return s.value, nil
+}
+
+// EncodedSizeInt64SLEB returns the number of bytes required to encode an
+// int64 value as SLEB128 (signed LEB128, where the top payload bit of the
+// last byte is the sign).
+func EncodedSizeInt64SLEB(value int64) int {
+	magnitude := uint64(value)
+	if value < 0 {
+		magnitude = ^magnitude
+	}
+	// One extra bit for the sign.
+	return (bits.Len64(magnitude) + 1 + 6) / 7
+}
+
+// EncodedSizeInt64Zigzag returns the number of bytes required to encode an
+// int64 value as a zigzag sign-folded ULEB128 value (0, -1, 1, -2, ... map
+// to 0, 1, 2, 3, ...).
+func EncodedSizeInt64Zigzag(value int64) int {
+	return EncodedSizeUint64(ZigZag64(value))
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+// Encode an int64 value as SLEB128 the long way, as a reference.
+func referenceSLEB(value int64) (encoded []byte) {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(encoded, b)
+		}
+		encoded = append(encoded, b|0x80)
+	}
+}
+
+func signedTestValues() []int64 {
+	values := []int64{math.MinInt64, math.MaxInt64}
+	for shift := uint(0); shift < 63; shift++ {
+		for _, delta := range []int64{-1, 0, 1} {
+			values = append(values, (int64(1)<<shift)+delta, -(int64(1)<<shift)+delta)
+		}
+	}
+	return values
+}
+
+func TestEncodedSizeInt64SLEB(t *testing.T) {
+	for _, value := range signedTestValues() {
+		if expected, actual := len(referenceSLEB(value)), EncodedSizeInt64SLEB(value); actual != expected {
+			t.Errorf("Expected %v to take %v bytes but got %v", value, expected, actual)
+		}
+	}
+}
+
+func TestEncodedSizeInt64Zigzag(t *testing.T) {
+	for _, value := range signedTestValues() {
+		if expected, actual := len(AppendUint64(nil, uint64(value<<1)^uint64(value>>63))), EncodedSizeInt64Zigzag(value); actual != expected {
+			t.Errorf("Expected %v to take %v bytes but got %v", value, expected, actual)
+		}
+	}
+}
+
+func TestEncodeDecodeInt64(t *testing.T) {
+	for _, value := range signedTestValues() {
+		expected := referenceSLEB(value)
+		encoded := AppendInt64(nil, value)
+		if !bytes.Equal(encoded, expected) {
+			t.Errorf("Expected %v to encode to %v but got %v", value, describe.D(expected), describe.D(encoded))
+		}
+		if size := EncodedSizeInt64(value); size != len(expected) {
+			t.Errorf("Expected %v to take %v bytes but got %v", value, len(expected), size)
+		}
+		buffer := &bytes.Buffer{}
+		if byteCount, err := EncodeInt64(value, buffer); err != nil || !bytes.Equal(buffer.Bytes(), expected) || byteCount != len(expected) {
+			t.Errorf("Expected EncodeInt64 to write %v but got %v (%v)", describe.D(expected), describe.D(buffer.Bytes()), err)
+		}
+		if decoded, byteCount, err := DecodeInt64FromBytes(append(encoded, 0x55)); err != nil || decoded != value || byteCount != len(encoded) {
+			t.Errorf("Expected %v to decode from bytes but got %v in %v bytes (%v)", value, decoded, byteCount, err)
+		}
+		if decoded, byteCount, err := DecodeInt64(buffer); err != nil || decoded != value || byteCount != len(encoded) {
+			t.Errorf("Expected %v to decode from a stream but got %v in %v bytes (%v)", value, decoded, byteCount, err)
+		}
+	}
+}
+
+func TestDecodeInt64Padding(t *testing.T) {
+	for _, test := range []struct {
+		encoded []byte
+		value   int64
+		err     error
+	}{
+		{[]byte{0xff, 0x7f}, -1, nil},
+		{[]byte{0x80, 0x00}, 0, nil},
+		{[]byte{0xbf, 0x80, 0x80, 0x00}, 63, nil},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 0, nil},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, -1, nil},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}, math.MinInt64, nil},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, 0, ErrOverflow},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, 0, ErrOverflow},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}, 0, ErrOverflow},
+	} {
+		value, byteCount, err := DecodeInt64FromBytes(test.encoded)
+		if err != test.err || value != test.value || byteCount != len(test.encoded) {
+			t.Errorf("Expected %v to decode to %v (%v) but got %v in %v bytes (%v)", describe.D(test.encoded), test.value, test.err, value, byteCount, err)
+		}
+		// Streams stop reading after MaxBufferWriteBytes bytes.
+		if len(test.encoded) > MaxBufferWriteBytes {
+			test.encoded, test.value, test.err = test.encoded[:MaxBufferWriteBytes], 0, ErrOverflow
+		}
+		value, byteCount, err = DecodeInt64(bytes.NewReader(test.encoded))
+		if err != test.err || value != test.value || byteCount != len(test.encoded) {
+			t.Errorf("Expected %v to decode to %v (%v) but got %v in %v bytes (%v)", describe.D(test.encoded), test.value, test.err, value, byteCount, err)
+		}
+	}
+}
+
+func TestDecodeInt64Errors(t *testing.T) {
+	if _, _, err := DecodeInt64FromBytes(nil); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if _, _, err := DecodeInt64FromBytes([]byte{0xff}); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+	if _, _, err := DecodeInt64(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if _, _, err := DecodeInt64(bytes.NewReader([]byte{0xff})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF but got %v", err)
+	}
+	counter := &continuationReader{}
+	if _, byteCount, err := DecodeInt64(counter); err != ErrOverflow || byteCount != MaxBufferWriteBytes {
+		t.Errorf("Expected ErrOverflow after %v bytes but got %v after %v", MaxBufferWriteBytes, err, byteCount)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrSimple8bValueTooLarge is returned when a value doesn't fit into the 60
+// payload bits of a simple8b word.
+var ErrSimple8bValueTooLarge = errors.New("uleb128: value too large for simple8b (max 60 bits)")
+
+// MaxSimple8bValue is the largest value that simple8b can encode.
+const MaxSimple8bValue = 1<<60 - 1
+
+// The number of values and the bit width of each value for each of the 16
+// simple8b selectors. Selectors 0 and 1 encode runs of zeroes, and carry no
+// payload.
+var simple8bSelectors = [16]struct {
+	count    int
+	bitWidth uint
+}{
+	{240, 0}, {120, 0}, {60, 1}, {30, 2}, {20, 3}, {15, 4}, {12, 5}, {10, 6},
+	{8, 7}, {7, 8}, {6, 10}, {5, 12}, {4, 15}, {3, 20}, {2, 30}, {1, 60},
+}
+
+// AppendSimple8b appends values to buffer as a simple8b block: the value count
+// as a ULEB128, followed by 64-bit little endian words each packing as many
+// values as will fit at a common bit width. For dense columns of small values
+// this is far smaller and faster than encoding each value as a ULEB128.
+// Values larger than MaxSimple8bValue return ErrSimple8bValueTooLarge, along
+// with buffer as it was passed in.
+func AppendSimple8b(buffer []byte, values []uint64) ([]byte, error) {
+	original := buffer
+	buffer = AppendUint64(buffer, uint64(len(values)))
+	var word [8]byte
+	for len(values) > 0 {
+		selector, count := simple8bSelector(values)
+		if count == 0 {
+			return original, ErrSimple8bValueTooLarge
+		}
+		bitWidth := simple8bSelectors[selector].bitWidth
+		packed := uint64(selector) << 60
+		for i, v := range values[:count] {
+			packed |= v << (uint(i) * bitWidth)
+		}
+		binary.LittleEndian.PutUint64(word[:], packed)
+		buffer = append(buffer, word[:]...)
+		values = values[count:]
+	}
+	return buffer, nil
+}
+
+// DecodeSimple8bFromBytes decodes a simple8b block written by AppendSimple8b
+// from the start of buffer. The count is validated with CheckLength against
+// maxCount before anything is allocated. A buffer that ends before all
+// values are decoded returns ErrTruncated.
+func DecodeSimple8bFromBytes(buffer []byte, maxCount int) (values []uint64, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		err = truncatedIfEOF(err)
+		return
+	}
+	count, err := CheckLength(asUint, asBigInt, maxCount, 8)
+	if err != nil {
+		return
+	}
+	values = make([]uint64, 0, count)
+	for len(values) < count {
+		if len(buffer)-byteCount < 8 {
+			values = nil
+			err = ErrTruncated
+			return
+		}
+		packed := binary.LittleEndian.Uint64(buffer[byteCount:])
+		byteCount += 8
+		selector := simple8bSelectors[packed>>60]
+		n := minInt(selector.count, count-len(values))
+		mask := maskForBitCount(int(selector.bitWidth))
+		for i := 0; i < n; i++ {
+			values = append(values, (packed>>(uint(i)*selector.bitWidth))&mask)
+		}
+	}
+	return
+}
+
+// Find the selector that packs the most of the leading values into one word.
+// If fewer values remain than a selector holds, the rest of the word is
+// padding. Returns a count of 0 if the first value is too large.
+func simple8bSelector(values []uint64) (selector int, count int) {
+	for selector = range simple8bSelectors {
+		count = minInt(simple8bSelectors[selector].count, len(values))
+		limit := maskForBitCount(int(simple8bSelectors[selector].bitWidth))
+		fits := true
+		for _, v := range values[:count] {
+			if v > limit {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return
+		}
+	}
+	return 0, 0
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
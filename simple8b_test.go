@@ -0,0 +1,104 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertSimple8b(t *testing.T, values []uint64, expectedByteCount int) {
+	encoded, err := AppendSimple8b(nil, values)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(encoded) != expectedByteCount {
+		t.Errorf("Expected %v to encode to %v bytes but got %v", describe.D(values), expectedByteCount, len(encoded))
+	}
+	decoded, byteCount, err := DecodeSimple8bFromBytes(encoded, len(values))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if byteCount != len(encoded) {
+		t.Errorf("Expected to decode %v bytes but decoded %v", len(encoded), byteCount)
+	}
+	if len(values) == 0 && len(decoded) == 0 {
+		return
+	}
+	if describe.D(decoded) != describe.D(values) {
+		t.Errorf("Expected %v but got %v", describe.D(values), describe.D(decoded))
+	}
+}
+
+func TestSimple8b(t *testing.T) {
+	assertSimple8b(t, nil, 1)
+	assertSimple8b(t, []uint64{0}, 1+8)
+	assertSimple8b(t, make([]uint64, 240), 2+8)
+	assertSimple8b(t, make([]uint64, 250), 2+16)
+	assertSimple8b(t, []uint64{1, 0, 1, 1}, 1+8)
+	assertSimple8b(t, []uint64{MaxSimple8bValue, 5, 1<<30 - 1}, 1+16)
+
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = uint64(i % 16)
+	}
+	// 1000 4-bit values at 15 per word.
+	assertSimple8b(t, values, 2+67*8)
+}
+
+func TestSimple8bValueTooLarge(t *testing.T) {
+	_, err := AppendSimple8b(nil, []uint64{1, MaxSimple8bValue + 1})
+	if err != ErrSimple8bValueTooLarge {
+		t.Errorf("Expected %v but got %v", ErrSimple8bValueTooLarge, err)
+	}
+
+	// The failed block must not be left in the buffer, even after some of its
+	// words have been packed.
+	values := make([]uint64, 300)
+	values[len(values)-1] = MaxSimple8bValue + 1
+	buffer := make([]byte, 2, 1000)
+	buffer[0], buffer[1] = 0xaa, 0xbb
+	result, err := AppendSimple8b(buffer, values)
+	if err != ErrSimple8bValueTooLarge {
+		t.Errorf("Expected %v but got %v", ErrSimple8bValueTooLarge, err)
+	}
+	if len(result) != 2 || result[0] != 0xaa || result[1] != 0xbb {
+		t.Errorf("Expected the original buffer back but got %v", describe.D(result))
+	}
+}
+
+func TestSimple8bDecodeErrors(t *testing.T) {
+	encoded, _ := AppendSimple8b(nil, []uint64{1, 2, 3})
+	if _, _, err := DecodeSimple8bFromBytes(encoded[:len(encoded)-1], 10); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	if _, _, err := DecodeSimple8bFromBytes(nil, 10); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	if _, _, err := DecodeSimple8bFromBytes(encoded, 2); err == nil {
+		t.Errorf("Expected a count above the maximum to fail")
+	} else if _, ok := err.(*LengthError); !ok {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrNotSorted is returned when values given to a SortedArrayBuilder aren't
+// in non-decreasing order.
+var ErrNotSorted = errors.New("uleb128: values are not sorted")
+
+// A sorted array is stored as the ULEB128 encoding of each value's
+// difference from the one before it (the first value is stored as-is). Its
+// index samples the byte offset and the absolute value of every
+// Interval-th value, so a lookup can start decoding at any sample.
+
+// SortedIndex is a sampled index over a sorted array.
+type SortedIndex struct {
+	Index
+	// Values[k] is the absolute value of value k*Interval.
+	Values []uint64
+}
+
+// SortedArray gives random access and search over a delta-encoded sorted
+// array of uint64 values, such as a posting list, without decoding all of
+// it.
+type SortedArray struct {
+	buffer []byte
+	index  SortedIndex
+}
+
+// NewSortedArray scans a delta-encoded sorted array and indexes every
+// interval-th value. If interval is less than 1, DefaultIndexInterval is
+// used. Returns ErrTruncated if buffer ends partway through a value, or
+// ErrOverflow if a delta or the running total doesn't fit into a uint64.
+func NewSortedArray(buffer []byte, interval int) (array *SortedArray, err error) {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+	// The offsets must point into buffer itself, which may hold non-canonical
+	// deltas, so index it directly rather than re-encoding it.
+	index := SortedIndex{Index: Index{Interval: interval}}
+	var value uint64
+	for offset := 0; offset < len(buffer); {
+		delta, byteCount, decodeErr := decodeUintFromBytes(buffer[offset:], 64)
+		if decodeErr != nil {
+			err = decodeErr
+			return
+		}
+		if value+delta < value {
+			err = ErrOverflow
+			return
+		}
+		value += delta
+		if index.Count%interval == 0 {
+			index.Offsets = append(index.Offsets, offset)
+			index.Values = append(index.Values, value)
+		}
+		index.Count++
+		offset += byteCount
+	}
+	array = &SortedArray{buffer: buffer, index: index}
+	return
+}
+
+// NewSortedArrayWithIndex returns an array over buffer using an index that
+// was built earlier (for example by SortedArrayBuilder). The index is
+// checked as in NewULEBArrayWithIndex, and its sampled values must be in
+// order.
+func NewSortedArrayWithIndex(buffer []byte, index SortedIndex) (array *SortedArray, err error) {
+	if err = checkIndex(buffer, index.Index); err != nil {
+		return
+	}
+	if len(index.Values) != len(index.Offsets) ||
+		!sort.SliceIsSorted(index.Values, func(i, j int) bool { return index.Values[i] < index.Values[j] }) {
+		err = ErrInvalidIndex
+		return
+	}
+	array = &SortedArray{buffer: buffer, index: index}
+	return
+}
+
+// Len returns the number of values in the array.
+func (a *SortedArray) Len() int {
+	return a.index.Count
+}
+
+// Index returns the array's sampled index.
+func (a *SortedArray) Index() SortedIndex {
+	return a.index
+}
+
+// Get returns the value at position i. It panics if i is out of range.
+func (a *SortedArray) Get(i int) uint64 {
+	if i < 0 || i >= a.index.Count {
+		panic("uleb128: SortedArray index out of range")
+	}
+	sample := i / a.index.Interval
+	value := a.index.Values[sample]
+	offset := a.skipSampled(sample)
+	for n := i % a.index.Interval; n > 0; n-- {
+		delta, byteCount, _ := decodeUintFromBytes(a.buffer[offset:], 64)
+		value += delta
+		offset += byteCount
+	}
+	return value
+}
+
+// Search returns the position of the first value that is greater than or
+// equal to target, and whether that value equals target. If every value is
+// less than target, position is Len(). The samples are binary searched, and
+// then at most Interval values are decoded.
+func (a *SortedArray) Search(target uint64) (position int, found bool) {
+	values := a.index.Values
+	// Start from the last sample that's less than target, since an earlier
+	// block could hold copies of target that a later sample also equals.
+	sample := sort.Search(len(values), func(k int) bool { return values[k] >= target }) - 1
+	if sample < 0 {
+		return 0, len(values) > 0 && values[0] == target
+	}
+	position = sample * a.index.Interval
+	value := values[sample]
+	offset := a.skipSampled(sample)
+	end := position + a.index.Interval
+	if end > a.index.Count {
+		end = a.index.Count
+	}
+	for value < target {
+		position++
+		if position == end {
+			// The first value >= target is the next sample, if there is one.
+			found = sample+1 < len(values) && values[sample+1] == target
+			return
+		}
+		delta, byteCount, _ := decodeUintFromBytes(a.buffer[offset:], 64)
+		value += delta
+		offset += byteCount
+	}
+	found = value == target
+	return
+}
+
+// Return the offset just after the delta of sampled value k.
+func (a *SortedArray) skipSampled(k int) int {
+	offset := a.index.Offsets[k]
+	for a.buffer[offset]&continuationMask != 0 {
+		offset++
+	}
+	return offset + 1
+}
+
+// SortedArrayBuilder builds a delta-encoded sorted array along with its
+// sampled index.
+type SortedArrayBuilder struct {
+	buffer []byte
+	index  SortedIndex
+	last   uint64
+}
+
+// NewSortedArrayBuilder returns a builder that samples every interval-th
+// value. If interval is less than 1, DefaultIndexInterval is used.
+func NewSortedArrayBuilder(interval int) *SortedArrayBuilder {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+	return &SortedArrayBuilder{index: SortedIndex{Index: Index{Interval: interval}}}
+}
+
+// Append appends a value, which must not be less than the previous one.
+func (b *SortedArrayBuilder) Append(value uint64) error {
+	if b.index.Count > 0 && value < b.last {
+		return ErrNotSorted
+	}
+	if b.index.Count%b.index.Interval == 0 {
+		b.index.Offsets = append(b.index.Offsets, len(b.buffer))
+		b.index.Values = append(b.index.Values, value)
+	}
+	b.index.Count++
+	b.buffer = AppendUint64(b.buffer, value-b.last)
+	b.last = value
+	return nil
+}
+
+// Len returns the number of values appended so far.
+func (b *SortedArrayBuilder) Len() int {
+	return b.index.Count
+}
+
+// Finalize returns the encoded array and its index, and resets the builder
+// so that it can start a new array with the same interval.
+func (b *SortedArrayBuilder) Finalize() (buffer []byte, index SortedIndex) {
+	buffer, index = b.buffer, b.index
+	*b = SortedArrayBuilder{index: SortedIndex{Index: Index{Interval: index.Interval}}}
+	return
+}
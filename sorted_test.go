@@ -0,0 +1,169 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func buildSortedArray(t *testing.T, values []uint64, interval int) *SortedArray {
+	builder := NewSortedArrayBuilder(interval)
+	for _, value := range values {
+		if err := builder.Append(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buffer, index := builder.Finalize()
+	array, err := NewSortedArrayWithIndex(buffer, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return array
+}
+
+func TestSortedArrayEncoding(t *testing.T) {
+	builder := NewSortedArrayBuilder(2)
+	for _, value := range []uint64{5, 5, 300, 301, 1000} {
+		builder.Append(value)
+	}
+	buffer, index := builder.Finalize()
+	expected := []byte{0x05, 0x00, 0xa7, 0x02, 0x01, 0xbb, 0x05}
+	if !bytes.Equal(buffer, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(buffer))
+	}
+	expectedIndex := SortedIndex{Index: Index{Interval: 2, Offsets: []int{0, 2, 5}, Count: 5}, Values: []uint64{5, 300, 1000}}
+	if !reflect.DeepEqual(index, expectedIndex) {
+		t.Errorf("Expected %+v but got %+v", expectedIndex, index)
+	}
+
+	scanned, err := NewSortedArray(buffer, 2)
+	if err != nil || !reflect.DeepEqual(scanned.Index(), expectedIndex) {
+		t.Errorf("Expected a scanned index of %+v but got %+v (%v)", expectedIndex, scanned.Index(), err)
+	}
+
+	if err := NewSortedArrayBuilder(2).Append(1); err != nil {
+		t.Error(err)
+	}
+	builder = NewSortedArrayBuilder(2)
+	builder.Append(2)
+	if err := builder.Append(1); err != ErrNotSorted {
+		t.Errorf("Expected %v but got %v", ErrNotSorted, err)
+	}
+}
+
+func TestSortedArraySearch(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	values := make([]uint64, 2000)
+	for i := range values {
+		// Plenty of duplicates, including runs longer than an interval
+		values[i] = uint64(random.Intn(500)) * 1000
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	for _, interval := range []int{1, 3, 16, 5000} {
+		array := buildSortedArray(t, values, interval)
+		if array.Len() != len(values) {
+			t.Errorf("Expected %v values but got %v", len(values), array.Len())
+		}
+		for i, value := range values {
+			if actual := array.Get(i); actual != value {
+				t.Errorf("Expected value %v to be %v but got %v", i, value, actual)
+				return
+			}
+		}
+		for target := uint64(0); target <= 500000; target += 250 {
+			expected := sort.Search(len(values), func(i int) bool { return values[i] >= target })
+			expectedFound := expected < len(values) && values[expected] == target
+			position, found := array.Search(target)
+			if position != expected || found != expectedFound {
+				t.Errorf("Expected searching for %v to give %v (%v) but got %v (%v) with interval %v",
+					target, expected, expectedFound, position, found, interval)
+				return
+			}
+		}
+	}
+
+	empty := buildSortedArray(t, nil, 4)
+	if position, found := empty.Search(5); position != 0 || found {
+		t.Errorf("Expected 0 (false) but got %v (%v)", position, found)
+	}
+}
+
+func TestSortedArrayErrors(t *testing.T) {
+	if _, err := NewSortedArray([]byte{0x01, 0x80}, 2); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	overflow := AppendUint64(nil, 0xffffffffffffffff)
+	overflow = AppendUint64(overflow, 1)
+	if _, err := NewSortedArray(overflow, 2); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+
+	buffer := []byte{0x05, 0x01, 0x01}
+	index := SortedIndex{Index: Index{Interval: 2, Offsets: []int{0, 2}, Count: 3}, Values: []uint64{7, 5}}
+	if _, err := NewSortedArrayWithIndex(buffer, index); err != ErrInvalidIndex {
+		t.Errorf("Expected %v but got %v", ErrInvalidIndex, err)
+	}
+	index.Values = []uint64{5}
+	if _, err := NewSortedArrayWithIndex(buffer, index); err != ErrInvalidIndex {
+		t.Errorf("Expected %v but got %v", ErrInvalidIndex, err)
+	}
+}
+
+func BenchmarkSortedArraySearch(b *testing.B) {
+	builder := NewSortedArrayBuilder(DefaultIndexInterval)
+	for i := uint64(0); i < 100000; i++ {
+		builder.Append(i * 7)
+	}
+	buffer, index := builder.Finalize()
+	array, _ := NewSortedArrayWithIndex(buffer, index)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		array.Search(uint64(i*7919) % 700000)
+	}
+}
+
+func TestSortedArrayNonCanonical(t *testing.T) {
+	// The first delta (5) is padded to two bytes.
+	buffer := []byte{0x85, 0x00, 0x01, 0x0a, 0x64}
+	expected := []uint64{5, 6, 16, 116}
+	array, err := NewSortedArray(buffer, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, value := range expected {
+		if actual := array.Get(i); actual != value {
+			t.Errorf("Expected value %v at %v but got %v", value, i, actual)
+		}
+		if position, found := array.Search(value); position != i || !found {
+			t.Errorf("Expected to find %v at %v but got %v (%v)", value, i, position, found)
+		}
+	}
+	if offsets := array.Index().Offsets; !reflect.DeepEqual(offsets, []int{0, 3}) {
+		t.Errorf("Expected offsets into the original buffer but got %v", offsets)
+	}
+}
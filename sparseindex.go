@@ -0,0 +1,168 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bufio"
+	"io"
+	"math/big"
+)
+
+// SparseIndex is an Index over a file of consecutive ULEB128 values that
+// also records the value at each sampled offset. It can be built for
+// existing data and stored alongside it as a sidecar file, giving seekable
+// access (through NewReaderAtArray) without rewriting the data. For sorted
+// data, Values can be binary searched to find where to start looking for a
+// value.
+type SparseIndex struct {
+	Index
+	// Values[k] is the value at position k*Interval.
+	Values []Value
+}
+
+// ScanSparseIndex reads consecutive ULEB128 values from reader until it
+// ends, and builds a sparse index sampling every interval-th value. If
+// interval is less than 1, DefaultIndexInterval is used. If reader ends
+// partway through a value, err will be ErrTruncated.
+func ScanSparseIndex(reader io.Reader, interval int) (index SparseIndex, err error) {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+	index.Interval = interval
+	decoder := NewDecoder(bufio.NewReader(reader), DecoderOptions{})
+	for {
+		offset := decoder.ByteCount()
+		var value Value
+		if value, _, err = decoder.DecodeValue(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		if index.Count%interval == 0 {
+			index.Offsets = append(index.Offsets, offset)
+			index.Values = append(index.Values, value)
+		}
+		index.Count++
+	}
+}
+
+// A sparse index file consists of a header (see Header) with the ULEB128
+// codec id, followed by the interval and value count, and then the byte
+// offset (as a delta from the previous sample's offset) and value of each
+// sample, all as ULEB128 values.
+
+// WriteSparseIndex writes index to writer in the sparse index file format.
+func WriteSparseIndex(writer io.Writer, index SparseIndex) (byteCount int, err error) {
+	buffer := AppendHeader(nil, NewHeader(CodecIDULEB128))
+	buffer = AppendUint64(buffer, uint64(index.Interval))
+	buffer = AppendUint64(buffer, uint64(index.Count))
+	previous := 0
+	for i, offset := range index.Offsets {
+		buffer = AppendUint64(buffer, uint64(offset-previous))
+		buffer = index.Values[i].AppendTo(buffer)
+		previous = offset
+	}
+	return writer.Write(buffer)
+}
+
+// The fewest bytes a sample can occupy in a sparse index file: a one byte
+// offset delta and a one byte value.
+const minSparseIndexSampleSize = 2
+
+// ReadSparseIndex reads a sparse index file written by WriteSparseIndex. An
+// index with more than maxSamples samples returns a *LengthError before
+// anything is allocated, and a sampled value encoded in more than
+// maxValueByteCount bytes (MaxBufferWriteBytes if 0 or less) returns
+// ErrTooLong, so that untrusted files can be read safely. An index that
+// isn't well formed returns ErrInvalidIndex. A stream that ends partway
+// through returns io.ErrUnexpectedEOF.
+func ReadSparseIndex(reader io.Reader, maxSamples int, maxValueByteCount int) (index SparseIndex, byteCount int, err error) {
+	header, byteCount, err := ReadHeader(reader, 0)
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	if header.CodecID != CodecIDULEB128 {
+		err = ErrInvalidIndex
+		return
+	}
+
+	buffer := []byte{0}
+	var fields [2]uint64
+	for i := range fields {
+		var fieldSize int
+		fields[i], fieldSize, err = decodeUint64(reader, buffer)
+		byteCount += fieldSize
+		if err != nil {
+			err = unexpectedEOF(err)
+			return
+		}
+	}
+	interval, count := fields[0], fields[1]
+	if interval < 1 || interval > uint64(maxInt) || count > uint64(maxInt) {
+		err = ErrInvalidIndex
+		return
+	}
+	sampleCount, err := CheckLength((count+interval-1)/interval, nil, maxSamples, minSparseIndexSampleSize)
+	if err != nil {
+		return
+	}
+
+	if maxValueByteCount <= 0 {
+		maxValueByteCount = MaxBufferWriteBytes
+	}
+	values := NewDecoder(reader, DecoderOptions{MaxByteCount: maxValueByteCount, ExpectValue: true})
+
+	index.Interval, index.Count = int(interval), int(count)
+	index.Offsets = make([]int, sampleCount)
+	index.Values = make([]Value, sampleCount)
+	offset := uint64(0)
+	for i := range index.Offsets {
+		var delta uint64
+		var fieldSize int
+		delta, fieldSize, err = decodeUint64(reader, buffer)
+		byteCount += fieldSize
+		if err != nil {
+			err = unexpectedEOF(err)
+			return
+		}
+		if (i > 0 && delta == 0) || delta > uint64(maxInt)-offset {
+			err = ErrInvalidIndex
+			return
+		}
+		offset += delta
+		index.Offsets[i] = int(offset)
+
+		var asUint uint64
+		var asBigInt *big.Int
+		asUint, asBigInt, fieldSize, err = values.Decode()
+		byteCount += fieldSize
+		if err == ErrTruncated {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return
+		}
+		index.Values[i] = NewValue(asUint, asBigInt)
+	}
+	return
+}
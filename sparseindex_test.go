@@ -0,0 +1,163 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sparseIndexTestData() (buffer []byte, values []uint64) {
+	for i := uint64(0); i < 1000; i++ {
+		values = append(values, i*i*i)
+		buffer = AppendUint64(buffer, i*i*i)
+	}
+	return
+}
+
+func TestScanSparseIndex(t *testing.T) {
+	buffer, values := sparseIndexTestData()
+	index, err := ScanSparseIndex(bytes.NewReader(buffer), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := BuildIndex(buffer, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.Index, expected) {
+		t.Errorf("Expected the offsets to match BuildIndex")
+	}
+	for k, value := range index.Values {
+		if value.Cmp(Uint64Value(values[k*10])) != 0 {
+			t.Errorf("Expected sample %v to be %v but got %v", k, values[k*10], value)
+		}
+	}
+
+	// Find the value 125000 by searching the samples and then the array.
+	array, err := NewReaderAtArray(bytes.NewReader(buffer), int64(len(buffer)), index.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := Uint64Value(50 * 50 * 50)
+	sample := sort.Search(len(index.Values), func(k int) bool { return index.Values[k].Cmp(target) > 0 }) - 1
+	for i := sample * index.Interval; i < array.Len(); i++ {
+		value, err := array.Get(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.Cmp(target) == 0 {
+			if i != 50 {
+				t.Errorf("Expected to find %v at 50 but found it at %v", target, i)
+			}
+			break
+		}
+	}
+
+	if _, err = ScanSparseIndex(bytes.NewReader([]byte{0x01, 0x80}), 0); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+}
+
+func TestSparseIndexRoundTrip(t *testing.T) {
+	buffer, _ := sparseIndexTestData()
+	buffer = Append(buffer, new(big.Int).Lsh(big.NewInt(1), 100))
+	for _, interval := range []int{1, 7, 0, 2000} {
+		index, err := ScanSparseIndex(bytes.NewReader(buffer), interval)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sidecar := &bytes.Buffer{}
+		byteCount, err := WriteSparseIndex(sidecar, index)
+		if err != nil || byteCount != sidecar.Len() {
+			t.Fatalf("Expected to write %v bytes but got %v (%v)", sidecar.Len(), byteCount, err)
+		}
+		decoded, byteCount, err := ReadSparseIndex(bytes.NewReader(sidecar.Bytes()), 2000, 20)
+		if err != nil || byteCount != sidecar.Len() {
+			t.Fatalf("Expected to read %v bytes but got %v (%v)", sidecar.Len(), byteCount, err)
+		}
+		if !reflect.DeepEqual(decoded.Index, index.Index) || len(decoded.Values) != len(index.Values) {
+			t.Errorf("Expected interval %v to round trip", interval)
+		}
+		for k := range index.Values {
+			if decoded.Values[k].Cmp(index.Values[k]) != 0 {
+				t.Errorf("Expected sample %v to be %v but got %v", k, index.Values[k], decoded.Values[k])
+			}
+		}
+	}
+}
+
+func TestReadSparseIndexErrors(t *testing.T) {
+	index := SparseIndex{
+		Index:  Index{Interval: 2, Count: 3, Offsets: []int{0, 5}},
+		Values: []Value{Uint64Value(1), Uint64Value(2)},
+	}
+	sidecar := &bytes.Buffer{}
+	WriteSparseIndex(sidecar, index)
+	valid := sidecar.Bytes()
+	header := AppendHeader(nil, NewHeader(CodecIDULEB128))
+
+	for _, test := range []struct {
+		encoded []byte
+		err     error
+	}{
+		{nil, io.ErrUnexpectedEOF},
+		{valid[:len(valid)-1], io.ErrUnexpectedEOF},
+		{append(AppendHeader(nil, NewHeader(CodecIDSyncVarint)), valid[len(header):]...), ErrInvalidIndex},
+		{append(append([]byte(nil), header...), 0x00, 0x00), ErrInvalidIndex},
+		{append(append([]byte(nil), header...), 0x01, 0x02, 0x00, 0x01, 0x00, 0x02), ErrInvalidIndex},
+	} {
+		if _, _, err := ReadSparseIndex(bytes.NewReader(test.encoded), 10, 0); err != test.err {
+			t.Errorf("Expected %v but got %v", test.err, err)
+		}
+	}
+
+	if _, _, err := ReadSparseIndex(bytes.NewReader(valid), 1, 0); err == nil {
+		t.Errorf("Expected too many samples to fail")
+	} else if _, ok := err.(*LengthError); !ok {
+		t.Errorf("Expected a *LengthError but got %v", err)
+	}
+}
+
+func TestReadSparseIndexHostileValue(t *testing.T) {
+	header := AppendHeader(nil, NewHeader(CodecIDULEB128))
+	// Interval 1, count 1, offset 0, then a value that never ends.
+	prefix := append(header, 0x01, 0x01, 0x00)
+	reader := &continuationReader{}
+	if _, _, err := ReadSparseIndex(io.MultiReader(bytes.NewReader(prefix), reader), 10, 0); err != ErrTooLong {
+		t.Errorf("Expected %v but got %v", ErrTooLong, err)
+	}
+	if reader.byteCount > MaxBufferWriteBytes {
+		t.Errorf("Expected to read at most %v bytes but read %v", MaxBufferWriteBytes, reader.byteCount)
+	}
+
+	huge := append(append([]byte(nil), prefix...), Append(nil, new(big.Int).Lsh(big.NewInt(1), 100))...)
+	if _, _, err := ReadSparseIndex(bytes.NewReader(huge), 10, 10); err != ErrTooLong {
+		t.Errorf("Expected %v but got %v", ErrTooLong, err)
+	}
+	if _, _, err := ReadSparseIndex(bytes.NewReader(huge), 10, 15); err != nil {
+		t.Error(err)
+	}
+}
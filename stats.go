@@ -0,0 +1,38 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+// Stats counts the heap allocations that an Encoder or Decoder made itself,
+// so that integrators can verify allocation behaviour in their own
+// environment. Allocations made by a user-supplied Allocator, writer or
+// reader are not included.
+//
+// The counts are estimates: each place that's expected to allocate adds what
+// it normally needs (for example two for a new math.big.Int and its words),
+// rather than measuring what the runtime actually did. Allocations that escape
+// analysis removes, or that the math/big package makes internally, can make
+// the real number differ.
+type Stats struct {
+	// The total number of allocations.
+	Allocations int
+	// How many of those allocations were to grow an internal buffer.
+	BufferGrowths int
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"testing"
+)
+
+func TestEncoderStats(t *testing.T) {
+	encoder := NewEncoder(ioutil.Discard, EncoderOptions{BufferSize: 16, CollectStats: true})
+	encoder.EncodeUint64(300)
+	encoder.Encode(newBigFromHex("123456789abcdef0123456789"))
+	if stats := encoder.Stats(); stats.Allocations != 0 {
+		t.Errorf("Expected no allocations but got %+v", stats)
+	}
+
+	encoder.EncodeBytes(make([]byte, 100))
+	if stats := encoder.Stats(); stats.Allocations != 1 || stats.BufferGrowths != 1 {
+		t.Errorf("Expected 1 buffer growth but got %+v", stats)
+	}
+
+	// Values larger than the engine's scratch space allocate while encoding.
+	encoder = NewEncoder(ioutil.Discard, EncoderOptions{BufferSize: 256, CollectStats: true})
+	encoder.Encode(new(big.Int).Lsh(big.NewInt(1), 1000))
+	if stats := encoder.Stats(); stats.Allocations != 1 || stats.BufferGrowths != 0 {
+		t.Errorf("Expected 1 allocation but got %+v", stats)
+	}
+
+	if stats := NewEncoder(ioutil.Discard, EncoderOptions{}).Stats(); stats != (Stats{}) {
+		t.Errorf("Expected no stats unless collecting but got %+v", stats)
+	}
+}
+
+func TestDecoderStats(t *testing.T) {
+	buff := &bytes.Buffer{}
+	EncodeUint64(300, buff)
+	Encode(newBigFromHex("123456789abcdef0123456789"), buff)
+	buff.Write(append(bytes.Repeat([]byte{0x80}, 20), 0x00))
+	data := buff.Bytes()
+
+	decoder := NewBytesDecoder(data, DecoderOptions{CollectStats: true})
+	decoder.Decode()
+	if stats := decoder.Stats(); stats.Allocations != 0 {
+		t.Errorf("Expected no allocations but got %+v", stats)
+	}
+	decoder.Decode()
+	if stats := decoder.Stats(); stats.Allocations != 2 || stats.BufferGrowths != 0 {
+		t.Errorf("Expected 2 allocations for a big.Int but got %+v", stats)
+	}
+	// Overlong values are decoded through a big.Int.
+	decoder.Decode()
+	if stats := decoder.Stats(); stats.Allocations != 4 {
+		t.Errorf("Expected 2 further allocations but got %+v", stats)
+	}
+
+	decoder = NewDecoder(bytes.NewBuffer(data), DecoderOptions{CollectStats: true, Allocator: &Arena{}})
+	for i := 0; i < 3; i++ {
+		decoder.Decode()
+	}
+	// The 21-byte overlong value outgrows the reader scratch buffer.
+	if stats := decoder.Stats(); stats.Allocations != 1 || stats.BufferGrowths != 1 {
+		t.Errorf("Expected 1 buffer growth but got %+v", stats)
+	}
+}
+
+// Stats must agree with what the runtime measures.
+func TestStatsMatchRuntime(t *testing.T) {
+	const runs = 100
+	huge := newBigFromHex("123456789abcdef0123456789")
+	var data []byte
+	for i := 0; i < (runs+1)*2; i++ {
+		data = Append(data, huge)
+	}
+	decoder := NewBytesDecoder(data, DecoderOptions{CollectStats: true})
+	allocs := testing.AllocsPerRun(runs, func() {
+		decoder.Decode()
+		decoder.DecodeBig(nil)
+	})
+	// AllocsPerRun makes one extra warm-up run.
+	if expected := float64(decoder.Stats().Allocations) / (runs + 1); allocs != expected {
+		t.Errorf("Expected %v allocations per run but the runtime measured %v", expected, allocs)
+	}
+
+	encoder := NewEncoder(ioutil.Discard, EncoderOptions{BufferSize: 256, CollectStats: true})
+	huge = new(big.Int).Lsh(big.NewInt(1), 1000)
+	encode := func() {
+		encoder.EncodeUint64(300)
+		encoder.Encode(huge)
+	}
+	// Let the buffer reach its final size first.
+	for i := 0; i < 3; i++ {
+		encode()
+	}
+	before := encoder.Stats().Allocations
+	allocs = testing.AllocsPerRun(runs, encode)
+	if expected := float64(encoder.Stats().Allocations-before) / (runs + 1); allocs != expected {
+		t.Errorf("Expected %v allocations per run but the runtime measured %v", expected, allocs)
+	}
+}
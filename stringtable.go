@@ -0,0 +1,137 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+)
+
+// ErrInvalidStringOffset is returned when a string table offset doesn't
+// refer to a complete entry inside the table.
+var ErrInvalidStringOffset = errors.New("uleb128: invalid string table offset")
+
+// A string table is encoded as a ULEB128 byte count, followed by that many
+// bytes of entries. Each entry is a ULEB128 length followed by the string's
+// bytes. Strings are referred to by the offset of their entry from the
+// start of the entries, which the referring format stores as ULEB128.
+
+// StringTableBuilder builds a string table, storing each distinct string
+// only once.
+type StringTableBuilder struct {
+	entries []byte
+	offsets map[string]uint64
+}
+
+// NewStringTableBuilder returns an empty StringTableBuilder.
+func NewStringTableBuilder() *StringTableBuilder {
+	return &StringTableBuilder{offsets: make(map[string]uint64)}
+}
+
+// Add adds s to the table if it isn't there already, and returns the offset
+// that refers to it.
+func (b *StringTableBuilder) Add(s string) (offset uint64) {
+	offset, ok := b.offsets[s]
+	if !ok {
+		offset = uint64(len(b.entries))
+		b.entries = AppendUint64(b.entries, uint64(len(s)))
+		b.entries = append(b.entries, s...)
+		b.offsets[s] = offset
+	}
+	return
+}
+
+// Count returns the number of distinct strings in the table.
+func (b *StringTableBuilder) Count() int {
+	return len(b.offsets)
+}
+
+// EncodedSize returns the number of bytes required to encode the table.
+func (b *StringTableBuilder) EncodedSize() int {
+	return EncodedSizeUint64(uint64(len(b.entries))) + len(b.entries)
+}
+
+// AppendTo appends the encoded table to buffer, returning the extended
+// buffer.
+func (b *StringTableBuilder) AppendTo(buffer []byte) []byte {
+	buffer = AppendUint64(buffer, uint64(len(b.entries)))
+	return append(buffer, b.entries...)
+}
+
+// StringTable reads strings out of an encoded string table. It refers to
+// the buffer it was decoded from rather than copying it.
+type StringTable struct {
+	entries []byte
+}
+
+// DecodeStringTableFromBytes decodes a string table from the start of
+// buffer. Returns io.EOF if buffer is empty, or ErrTruncated if it ends
+// before the end of the table.
+func DecodeStringTableFromBytes(buffer []byte) (table StringTable, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		return
+	}
+	if asBigInt != nil || asUint > uint64(len(buffer)-byteCount) {
+		err = ErrTruncated
+		return
+	}
+	table.entries = buffer[byteCount : byteCount+int(asUint)]
+	byteCount += int(asUint)
+	return
+}
+
+// Lookup returns the string whose entry starts at offset. Returns
+// ErrInvalidStringOffset if the offset or the entry's length runs past the
+// end of the table. Offsets aren't otherwise validated, so a corrupt offset
+// that lands inside another string can return garbage.
+func (t StringTable) Lookup(offset uint64) (s string, err error) {
+	if offset >= uint64(len(t.entries)) {
+		err = ErrInvalidStringOffset
+		return
+	}
+	s, _, err = stringTableEntry(t.entries[offset:])
+	return
+}
+
+// Strings returns every string in the table, in order.
+func (t StringTable) Strings() (strings []string, err error) {
+	for remaining := t.entries; len(remaining) > 0; {
+		s, byteCount, entryErr := stringTableEntry(remaining)
+		if entryErr != nil {
+			err = entryErr
+			return
+		}
+		strings = append(strings, s)
+		remaining = remaining[byteCount:]
+	}
+	return
+}
+
+func stringTableEntry(entry []byte) (s string, byteCount int, err error) {
+	length, asBigInt, byteCount, err := DecodeFromBytes(entry)
+	if err != nil || asBigInt != nil || length > uint64(len(entry)-byteCount) {
+		err = ErrInvalidStringOffset
+		return
+	}
+	s = string(entry[byteCount : byteCount+int(length)])
+	byteCount += int(length)
+	return
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestStringTableBuilder(t *testing.T) {
+	b := NewStringTableBuilder()
+	offsets := []uint64{b.Add("main"), b.Add(""), b.Add("x"), b.Add("main"), b.Add("x")}
+	if expected := []uint64{0, 5, 6, 0, 6}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("Expected offsets %v but got %v", expected, offsets)
+	}
+	if b.Count() != 3 {
+		t.Errorf("Expected 3 strings but got %v", b.Count())
+	}
+	expected := []byte{0x08, 0x04, 'm', 'a', 'i', 'n', 0x00, 0x01, 'x'}
+	encoded := b.AppendTo(nil)
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(encoded))
+	}
+	if b.EncodedSize() != len(expected) {
+		t.Errorf("Expected an encoded size of %v but got %v", len(expected), b.EncodedSize())
+	}
+}
+
+func TestStringTableRoundTrip(t *testing.T) {
+	b := NewStringTableBuilder()
+	input := []string{"alpha", "beta", strings.Repeat("long", 100), "alpha", "", "gamma"}
+	var offsets []uint64
+	for _, s := range input {
+		offsets = append(offsets, b.Add(s))
+	}
+	encoded := b.AppendTo([]byte{0xff})
+	encoded = append(encoded, 0xee)
+
+	table, byteCount, err := DecodeStringTableFromBytes(encoded[1:])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if byteCount != len(encoded)-2 {
+		t.Errorf("Expected %v bytes but got %v", len(encoded)-2, byteCount)
+	}
+	for i, offset := range offsets {
+		if s, err := table.Lookup(offset); err != nil || s != input[i] {
+			t.Errorf("Expected offset %v to be %q but got %q (%v)", offset, input[i], s, err)
+		}
+	}
+	all, err := table.Strings()
+	if expected := []string{"alpha", "beta", strings.Repeat("long", 100), "", "gamma"}; err != nil || !reflect.DeepEqual(all, expected) {
+		t.Errorf("Expected %v but got %v (%v)", expected, all, err)
+	}
+}
+
+func TestStringTableErrors(t *testing.T) {
+	if _, _, err := DecodeStringTableFromBytes(nil); err != io.EOF {
+		t.Errorf("Expected %v but got %v", io.EOF, err)
+	}
+	if _, _, err := DecodeStringTableFromBytes([]byte{0x03, 0x01, 'a'}); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+
+	table, _, err := DecodeStringTableFromBytes([]byte{0x04, 0x01, 'a', 0x05, 'b'})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if s, err := table.Lookup(0); err != nil || s != "a" {
+		t.Errorf("Expected \"a\" but got %q (%v)", s, err)
+	}
+	for _, offset := range []uint64{2, 3, 4, 0xffffffffffffffff} {
+		if _, err := table.Lookup(offset); err != ErrInvalidStringOffset {
+			t.Errorf("Expected offset %v to fail with %v but got %v", offset, ErrInvalidStringOffset, err)
+		}
+	}
+	if _, err := table.Strings(); err != ErrInvalidStringOffset {
+		t.Errorf("Expected %v but got %v", ErrInvalidStringOffset, err)
+	}
+}
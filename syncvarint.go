@@ -0,0 +1,188 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"math/bits"
+)
+
+// Maximum number of bytes that a sync varint will ever occupy
+const MaxSyncVarintBytes = 12
+
+// ErrInvalidSyncVarint is returned when a sync varint starts with a
+// continuation byte, or when a continuation byte is missing.
+var ErrInvalidSyncVarint = errors.New("uleb128: invalid sync varint")
+
+// Sync varints are a self-synchronizing encoding modeled after UTF-8. The
+// number of leading 1 bits in the first byte gives the total byte count
+// (except that 0 means a single byte, and 0xff means 12 bytes), and every
+// following byte has the form 10xxxxxx. The value is stored most
+// significant bits first:
+//
+// | First byte | Bytes | Payload bits |
+// | ---------- | ----- | ------------ |
+// | 0xxxxxxx   |    1  |        7     |
+// | 110xxxxx   |    2  |       11     |
+// | 1110xxxx   |    3  |       16     |
+// | 11110xxx   |    4  |       21     |
+// | 111110xx   |    5  |       26     |
+// | 1111110x   |    6  |       31     |
+// | 11111110   |    7  |       36     |
+// | 11111111   |   12  |       66     |
+//
+// Since continuation bytes can never be mistaken for first bytes, a reader
+// that lands in the middle of a stream can always find the next value.
+var syncVarintPayloadBits = []int{7, 11, 16, 21, 26, 31, 36, 66}
+var syncVarintByteCounts = []int{1, 2, 3, 4, 5, 6, 7, 12}
+
+// EncodedSizeSyncVarint returns the number of bytes required to encode this
+// value as a sync varint.
+func EncodedSizeSyncVarint(value uint64) int {
+	bitCount := bits.Len64(value)
+	for i, payloadBits := range syncVarintPayloadBits {
+		if bitCount <= payloadBits {
+			return syncVarintByteCounts[i]
+		}
+	}
+	return MaxSyncVarintBytes
+}
+
+// Encode a uint64 value as a sync varint.
+func EncodeSyncVarint(value uint64, writer io.Writer) (byteCount int, err error) {
+	buffer := make([]byte, MaxSyncVarintBytes)
+	byteCount = EncodeSyncVarintToBytes(value, buffer)
+	return writer.Write(buffer[:byteCount])
+}
+
+// Encode a uint64 value as a sync varint, returning the number of bytes encoded.
+// Assumes that there's enough room in buffer (see MaxSyncVarintBytes).
+func EncodeSyncVarintToBytes(value uint64, buffer []byte) (byteCount int) {
+	byteCount = EncodedSizeSyncVarint(value)
+	if byteCount == 1 {
+		buffer[0] = byte(value)
+		return
+	}
+	for i := byteCount - 1; i > 0; i-- {
+		buffer[i] = 0x80 | byte(value&0x3f)
+		value >>= 6
+	}
+	if byteCount == MaxSyncVarintBytes {
+		buffer[0] = 0xff
+	} else {
+		leadingOnes := byte(0xff) << uint(8-byteCount)
+		buffer[0] = leadingOnes | byte(value)
+	}
+	return
+}
+
+// Decode a sync varint from the start of buffer.
+func DecodeSyncVarintFromBytes(buffer []byte) (value uint64, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	first := buffer[0]
+	leadingOnes := bits.LeadingZeros8(^first)
+	switch leadingOnes {
+	case 0:
+		value = uint64(first)
+		byteCount = 1
+		return
+	case 1:
+		err = ErrInvalidSyncVarint
+		return
+	case 8:
+		byteCount = MaxSyncVarintBytes
+	default:
+		byteCount = leadingOnes
+		value = uint64(first) & maskForBitCount(7-leadingOnes)
+	}
+
+	if len(buffer) < byteCount {
+		err = ErrTruncated
+		return
+	}
+	for _, b := range buffer[1:byteCount] {
+		if b&0xc0 != 0x80 {
+			err = ErrInvalidSyncVarint
+			return
+		}
+		if value>>58 != 0 {
+			err = ErrOverflow
+			return
+		}
+		value = value<<6 | uint64(b&0x3f)
+	}
+	return
+}
+
+// NextSyncVarint returns the offset of the first byte in buffer that can
+// begin a sync varint (or len(buffer) if there isn't one), so that a reader
+// can resynchronize after corruption.
+func NextSyncVarint(buffer []byte) int {
+	for i, b := range buffer {
+		if b&0xc0 != 0x80 {
+			return i
+		}
+	}
+	return len(buffer)
+}
+
+// ConvertSyncVarintsToULEB converts a buffer of consecutive sync varints to
+// consecutive ULEB128 values.
+func ConvertSyncVarintsToULEB(buffer []byte) (converted []byte, err error) {
+	converted = make([]byte, 0, len(buffer))
+	for offset := 0; offset < len(buffer); {
+		value, byteCount, decodeErr := DecodeSyncVarintFromBytes(buffer[offset:])
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		converted = AppendUint64(converted, value)
+		offset += byteCount
+	}
+	return
+}
+
+// ConvertULEBToSyncVarints converts a buffer of consecutive ULEB128 values to
+// consecutive sync varints. Values that don't fit into a uint64 return
+// ErrOverflow.
+func ConvertULEBToSyncVarints(buffer []byte) (converted []byte, err error) {
+	converted = make([]byte, 0, len(buffer))
+	encoded := make([]byte, MaxSyncVarintBytes)
+	overflowed := false
+	_, err = DecodeAllFromBytes(buffer, func(asUint uint64, asBigInt *big.Int) {
+		if asBigInt != nil {
+			overflowed = true
+		}
+		byteCount := EncodeSyncVarintToBytes(asUint, encoded)
+		converted = append(converted, encoded[:byteCount]...)
+	})
+	if err == nil && overflowed {
+		err = ErrOverflow
+	}
+	if err != nil {
+		converted = nil
+	}
+	return
+}
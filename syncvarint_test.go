@@ -0,0 +1,122 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func assertSyncVarint(t *testing.T, value uint64, expectedBytes ...byte) {
+	expectedByteCount := EncodedSizeSyncVarint(value)
+	if expectedByteCount != len(expectedBytes) {
+		t.Errorf("Expected %x to have an encoded size of %v but got %v", value, len(expectedBytes), expectedByteCount)
+		return
+	}
+	buff := &bytes.Buffer{}
+	if _, err := EncodeSyncVarint(value, buff); err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(buff.Bytes(), expectedBytes) {
+		t.Errorf("Expected %x to encode to %v but got %v", value, describe.D(expectedBytes), describe.D(buff.Bytes()))
+		return
+	}
+	actual, actualByteCount, err := DecodeSyncVarintFromBytes(expectedBytes)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("Expected decoding %v to have a byte count of %v but got %v", describe.D(expectedBytes), expectedByteCount, actualByteCount)
+		return
+	}
+	if actual != value {
+		t.Errorf("Expected %v to decode to %x but got %x", describe.D(expectedBytes), value, actual)
+	}
+}
+
+func assertSyncVarintFails(t *testing.T, expectedErr error, b ...byte) {
+	_, _, err := DecodeSyncVarintFromBytes(b)
+	if err != expectedErr {
+		t.Errorf("Expected decoding %v to fail with %v but got %v", describe.D(b), expectedErr, err)
+	}
+}
+
+func TestSyncVarint(t *testing.T) {
+	assertSyncVarint(t, 0, 0x00)
+	assertSyncVarint(t, 0x7f, 0x7f)
+	assertSyncVarint(t, 0x80, 0xc2, 0x80)
+	assertSyncVarint(t, 0x7ff, 0xdf, 0xbf)
+	assertSyncVarint(t, 0x800, 0xe0, 0xa0, 0x80)
+	assertSyncVarint(t, 0xffff, 0xef, 0xbf, 0xbf)
+	assertSyncVarint(t, 0x10000, 0xf0, 0x90, 0x80, 0x80)
+	assertSyncVarint(t, 0xfffffffff, 0xfe, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf)
+	assertSyncVarint(t, 0x1000000000, 0xff, 0x80, 0x80, 0x80, 0x80, 0x81, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80)
+	assertSyncVarint(t, 0xffffffffffffffff, 0xff, 0x8f, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf)
+}
+
+func TestSyncVarintErrors(t *testing.T) {
+	assertSyncVarintFails(t, io.EOF)
+	assertSyncVarintFails(t, ErrInvalidSyncVarint, 0x80)
+	assertSyncVarintFails(t, ErrInvalidSyncVarint, 0xc2, 0x00)
+	assertSyncVarintFails(t, ErrTruncated, 0xe0, 0x80)
+	assertSyncVarintFails(t, ErrOverflow, 0xff, 0x90, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80)
+}
+
+func TestNextSyncVarint(t *testing.T) {
+	if offset := NextSyncVarint([]byte{0x80, 0xbf, 0xc2, 0x80}); offset != 2 {
+		t.Errorf("Expected offset 2 but got %v", offset)
+	}
+	if offset := NextSyncVarint([]byte{0x80, 0xbf}); offset != 2 {
+		t.Errorf("Expected offset 2 but got %v", offset)
+	}
+}
+
+func TestSyncVarintConversion(t *testing.T) {
+	uleb := []byte{0x00, 0x80, 0x01, 0xff, 0xff, 0x03}
+	sync := []byte{0x00, 0xc2, 0x80, 0xef, 0xbf, 0xbf}
+
+	converted, err := ConvertULEBToSyncVarints(uleb)
+	if err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(converted, sync) {
+		t.Errorf("Expected %v but got %v", describe.D(sync), describe.D(converted))
+	}
+
+	converted, err = ConvertSyncVarintsToULEB(sync)
+	if err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(converted, uleb) {
+		t.Errorf("Expected %v but got %v", describe.D(uleb), describe.D(converted))
+	}
+
+	if _, err = ConvertULEBToSyncVarints([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+	if _, err = ConvertSyncVarintsToULEB([]byte{0x01, 0x80}); err != ErrInvalidSyncVarint {
+		t.Errorf("Expected ErrInvalidSyncVarint but got %v", err)
+	}
+}
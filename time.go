@@ -0,0 +1,131 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrInvalidNanoseconds is returned when a decoded time has a nanosecond
+// field outside of the range [0, 999999999].
+var ErrInvalidNanoseconds = errors.New("uleb128: nanoseconds out of range")
+
+// A time is encoded as two consecutive values: the seconds since the Unix
+// epoch with the sign folded into the low bit, followed by the nanoseconds
+// within that second. The location isn't encoded, so decoded times are in
+// UTC. A duration is encoded as a single sign-folded count of nanoseconds.
+
+// EncodeTime encodes a time.Time value as Unix seconds and nanoseconds.
+func EncodeTime(value time.Time, writer io.Writer) (byteCount int, err error) {
+	var buffer [MaxBufferWriteBytes * 2]byte
+	return writer.Write(AppendTime(buffer[:0], value))
+}
+
+// AppendTime appends the encoding of a time.Time value to buffer,
+// returning the extended buffer.
+func AppendTime(buffer []byte, value time.Time) []byte {
+	buffer = AppendUint64(buffer, ZigZag64(value.Unix()))
+	return AppendUint64(buffer, uint64(value.Nanosecond()))
+}
+
+// DecodeTime decodes a time.Time value. Returns io.EOF if the stream ends
+// cleanly before the value, io.ErrUnexpectedEOF if it ends partway through,
+// ErrOverflow if a field doesn't fit into 64 bits, or ErrInvalidNanoseconds
+// if the nanoseconds are out of range.
+func DecodeTime(reader io.Reader) (value time.Time, byteCount int, err error) {
+	buffer := []byte{0}
+	seconds, byteCount, err := decodeUint64(reader, buffer)
+	if err != nil {
+		return
+	}
+	nanoseconds, nanosecondsByteCount, err := decodeUint64(reader, buffer)
+	byteCount += nanosecondsByteCount
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	value, err = newTime(seconds, nanoseconds)
+	return
+}
+
+// DecodeTimeFromBytes decodes a time.Time value from the start of buffer.
+// Returns io.EOF if buffer is empty, ErrTruncated if it ends partway through
+// the value, and otherwise the same errors as DecodeTime.
+func DecodeTimeFromBytes(buffer []byte) (value time.Time, byteCount int, err error) {
+	seconds, byteCount, err := decodeUintFromBytes(buffer, 64)
+	if err != nil {
+		return
+	}
+	nanoseconds, nanosecondsByteCount, err := decodeUintFromBytes(buffer[byteCount:], 64)
+	byteCount += nanosecondsByteCount
+	if err != nil {
+		err = truncatedIfEOF(err)
+		return
+	}
+	value, err = newTime(seconds, nanoseconds)
+	return
+}
+
+func newTime(seconds uint64, nanoseconds uint64) (value time.Time, err error) {
+	if nanoseconds >= uint64(time.Second) {
+		err = ErrInvalidNanoseconds
+		return
+	}
+	value = time.Unix(UnZigZag64(seconds), int64(nanoseconds)).UTC()
+	return
+}
+
+// EncodeDuration encodes a time.Duration value as sign-folded nanoseconds.
+func EncodeDuration(value time.Duration, writer io.Writer) (byteCount int, err error) {
+	return EncodeUint64(ZigZag64(int64(value)), writer)
+}
+
+// AppendDuration appends the encoding of a time.Duration value to buffer,
+// returning the extended buffer.
+func AppendDuration(buffer []byte, value time.Duration) []byte {
+	return AppendUint64(buffer, ZigZag64(int64(value)))
+}
+
+// DecodeDuration decodes a time.Duration value. Returns io.EOF if the stream
+// ends cleanly before the value, io.ErrUnexpectedEOF if it ends partway
+// through, or ErrOverflow if the value doesn't fit into 64 bits.
+func DecodeDuration(reader io.Reader) (value time.Duration, byteCount int, err error) {
+	asUint, byteCount, err := decodeUint64(reader, []byte{0})
+	if err != nil {
+		return
+	}
+	value = time.Duration(UnZigZag64(asUint))
+	return
+}
+
+// DecodeDurationFromBytes decodes a time.Duration value from the start of
+// buffer. Errors are the same as for DecodeFromBytes, plus ErrOverflow if
+// the value doesn't fit into 64 bits.
+func DecodeDurationFromBytes(buffer []byte) (value time.Duration, byteCount int, err error) {
+	asUint, byteCount, err := decodeUintFromBytes(buffer, 64)
+	if err != nil {
+		return
+	}
+	value = time.Duration(UnZigZag64(asUint))
+	return
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestTimeEncoding(t *testing.T) {
+	value := time.Unix(-1, 300).UTC()
+	expected := []byte{0x01, 0xac, 0x02}
+	if encoded := AppendTime(nil, value); !bytes.Equal(encoded, expected) {
+		t.Errorf("Expected %v to encode to %v but got %v", value, describe.D(expected), describe.D(encoded))
+	}
+}
+
+func TestTimeRoundTrip(t *testing.T) {
+	values := []time.Time{
+		time.Unix(0, 0),
+		time.Unix(-1, 999999999),
+		time.Date(2020, 2, 29, 12, 34, 56, 789, time.UTC),
+		time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.FixedZone("X", 3600)),
+	}
+	for _, expected := range values {
+		encoded := AppendTime(nil, expected)
+		value, byteCount, err := DecodeTimeFromBytes(encoded)
+		if err != nil || !value.Equal(expected) || value.Location() != time.UTC || byteCount != len(encoded) {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+
+		buff := &bytes.Buffer{}
+		if _, err := EncodeTime(expected, buff); err != nil {
+			t.Error(err)
+			return
+		}
+		value, byteCount, err = DecodeTime(buff)
+		if err != nil || !value.Equal(expected) || byteCount != len(encoded) {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+	}
+}
+
+func assertDecodeTimeFails(t *testing.T, data []byte, expectedFromBytes error, expectedFromReader error) {
+	if _, _, err := DecodeTimeFromBytes(data); err != expectedFromBytes {
+		t.Errorf("Expected %v to fail with %v but got %v", data, expectedFromBytes, err)
+	}
+	if _, _, err := DecodeTime(bytes.NewBuffer(data)); err != expectedFromReader {
+		t.Errorf("Expected %v to fail with %v from a reader but got %v", data, expectedFromReader, err)
+	}
+}
+
+func TestDecodeTimeErrors(t *testing.T) {
+	assertDecodeTimeFails(t, nil, io.EOF, io.EOF)
+	assertDecodeTimeFails(t, []byte{0x80}, ErrTruncated, io.ErrUnexpectedEOF)
+	assertDecodeTimeFails(t, []byte{0x00}, ErrTruncated, io.ErrUnexpectedEOF)
+	assertDecodeTimeFails(t, []byte{0x00, 0x80, 0x94, 0xeb, 0xdc, 0x03}, ErrInvalidNanoseconds, ErrInvalidNanoseconds)
+	assertDecodeTimeFails(t, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02, 0x00}, ErrOverflow, ErrOverflow)
+}
+
+func TestDurationEncoding(t *testing.T) {
+	for _, test := range []struct {
+		value    time.Duration
+		expected []byte
+	}{
+		{0, []byte{0x00}},
+		{-1, []byte{0x01}},
+		{1, []byte{0x02}},
+		{time.Second, []byte{0x80, 0xa8, 0xd6, 0xb9, 0x07}},
+		{math.MinInt64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	} {
+		if encoded := AppendDuration(nil, test.value); !bytes.Equal(encoded, test.expected) {
+			t.Errorf("Expected %v to encode to %v but got %v", test.value, describe.D(test.expected), describe.D(encoded))
+		}
+	}
+}
+
+func TestDurationRoundTrip(t *testing.T) {
+	for _, expected := range []time.Duration{0, 1, -1, time.Hour, -time.Millisecond, math.MaxInt64, math.MinInt64} {
+		encoded := AppendDuration(nil, expected)
+		value, byteCount, err := DecodeDurationFromBytes(encoded)
+		if err != nil || value != expected || byteCount != len(encoded) {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+
+		buff := &bytes.Buffer{}
+		if _, err := EncodeDuration(expected, buff); err != nil {
+			t.Error(err)
+			return
+		}
+		value, byteCount, err = DecodeDuration(buff)
+		if err != nil || value != expected || byteCount != len(encoded) {
+			t.Errorf("Expected %v (%v bytes) but got %v (%v bytes, %v)", expected, len(encoded), value, byteCount, err)
+		}
+	}
+
+	if _, _, err := DecodeDuration(bytes.NewBuffer([]byte{0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
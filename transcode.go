@@ -0,0 +1,97 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bufio"
+	"io"
+)
+
+// TranscodeStats reports how much a transcoding call processed.
+type TranscodeStats struct {
+	// The number of values converted.
+	Values int64
+	// The number of bytes of complete values read from the source.
+	BytesRead int64
+	// The number of bytes written to the destination.
+	BytesWritten int64
+}
+
+// Transcode reads a stream of values packed with codec src until it ends,
+// and writes them packed with codec dst. Values are preserved exactly, so
+// any codec can convert to any other as long as the values fit into a
+// uint64. Output is written in chunks of about DefaultChunkSize bytes. If a
+// value can't be decoded (such as ErrTruncated when the source ends partway
+// through one), the values before it are still written, and
+// stats.BytesRead will be its offset.
+func Transcode(dst Codec, src Codec, writer io.Writer, reader io.Reader) (stats TranscodeStats, err error) {
+	bufferedReader := bufio.NewReaderSize(reader, DefaultChunkSize)
+	buffer := make([]byte, 0, DefaultChunkSize+dst.MaxEncodedSize())
+	flush := func() error {
+		n, err := writer.Write(buffer)
+		stats.BytesWritten += int64(n)
+		buffer = buffer[:0]
+		return err
+	}
+	for {
+		value, byteCount, decodeErr := src.Decode(bufferedReader)
+		if decodeErr != nil {
+			if decodeErr != io.EOF || byteCount > 0 {
+				err = decodeErr
+			}
+			break
+		}
+		stats.Values++
+		stats.BytesRead += int64(byteCount)
+		buffer = dst.AppendTo(buffer, value)
+		if len(buffer) >= DefaultChunkSize {
+			if err = flush(); err != nil {
+				return
+			}
+		}
+	}
+	if len(buffer) > 0 {
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return
+}
+
+// TranscodeBytes converts the values packed with codec src in input to codec
+// dst, appending them to output. Errors are the same as for Transcode, and
+// on error, result holds the values converted before the one that failed.
+func TranscodeBytes(dst Codec, src Codec, output []byte, input []byte) (result []byte, stats TranscodeStats, err error) {
+	result = output
+	for int(stats.BytesRead) < len(input) {
+		value, byteCount, decodeErr := src.DecodeFromBytes(input[stats.BytesRead:])
+		if decodeErr != nil {
+			err = truncatedIfEOF(decodeErr)
+			break
+		}
+		start := len(result)
+		result = dst.AppendTo(result, value)
+		stats.Values++
+		stats.BytesRead += int64(byteCount)
+		stats.BytesWritten += int64(len(result) - start)
+	}
+	return
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"testing"
+)
+
+func transcodeTestValues() (values []uint64) {
+	for shift := uint(0); shift < 64; shift++ {
+		values = append(values, 1<<shift, 1<<shift-1)
+	}
+	for i := uint64(0); i < 5000; i++ {
+		values = append(values, i*i*i)
+	}
+	return
+}
+
+func packWith(codec Codec, values []uint64) (packed []byte) {
+	for _, value := range values {
+		packed = codec.AppendTo(packed, value)
+	}
+	return
+}
+
+func TestTranscode(t *testing.T) {
+	values := transcodeTestValues()
+	uleb := packWith(ULEB128Codec, values)
+	sync := packWith(SyncVarintCodec, values)
+
+	for _, test := range []struct {
+		dst, src Codec
+		from, to []byte
+	}{
+		{SyncVarintCodec, ULEB128Codec, uleb, sync},
+		{ULEB128Codec, SyncVarintCodec, sync, uleb},
+		{ULEB128Codec, ULEB128Codec, uleb, uleb},
+	} {
+		output := &writeCounter{}
+		stats, err := Transcode(test.dst, test.src, output, bytes.NewReader(test.from))
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(output.Bytes(), test.to) {
+			t.Errorf("Transcoded stream doesn't match")
+		}
+		expected := TranscodeStats{Values: int64(len(values)), BytesRead: int64(len(test.from)), BytesWritten: int64(len(test.to))}
+		if stats != expected {
+			t.Errorf("Expected %+v but got %+v", expected, stats)
+		}
+		if maxWrites := len(test.to)/DefaultChunkSize + 1; output.writeCount > maxWrites {
+			t.Errorf("Expected at most %v writes but got %v", maxWrites, output.writeCount)
+		}
+
+		result, stats, err := TranscodeBytes(test.dst, test.src, nil, test.from)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(result, test.to) || stats != expected {
+			t.Errorf("Expected %+v but got %+v", expected, stats)
+		}
+	}
+}
+
+func TestTranscodeTruncated(t *testing.T) {
+	input := []byte{0x01, 0xac, 0x02, 0x80}
+	output := &bytes.Buffer{}
+	stats, err := Transcode(SyncVarintCodec, ULEB128Codec, output, bytes.NewReader(input))
+	if err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+	expected := packWith(SyncVarintCodec, []uint64{1, 300})
+	if stats.Values != 2 || stats.BytesRead != 3 || !bytes.Equal(output.Bytes(), expected) {
+		t.Errorf("Expected the two complete values to be written but got %+v %v", stats, output.Bytes())
+	}
+
+	result, stats, err := TranscodeBytes(SyncVarintCodec, ULEB128Codec, nil, input)
+	if err != ErrTruncated || stats.Values != 2 || stats.BytesRead != 3 || !bytes.Equal(result, expected) {
+		t.Errorf("Expected the two complete values and %v but got %+v %v (%v)", ErrTruncated, stats, result, err)
+	}
+
+	big := append(bytes.Repeat([]byte{0xff}, 10), 0x01)
+	if _, err = Transcode(SyncVarintCodec, ULEB128Codec, output, bytes.NewReader(big)); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	if _, err = Transcode(SyncVarintCodec, ULEB128Codec, failingWriter{}, bytes.NewReader(input[:1])); err != errWriteFailed {
+		t.Errorf("Expected %v but got %v", errWriteFailed, err)
+	}
+}
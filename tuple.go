@@ -0,0 +1,193 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// ErrUnsupportedTupleType is returned when a tuple field isn't one of the
+// supported types.
+var ErrUnsupportedTupleType = errors.New("uleb128: unsupported tuple field type")
+
+// WriteTuple encodes fields consecutively and writes them in a single Write
+// call. Each field must be a uint64 (encoded as a ULEB128), an int64
+// (zigzag-encoded), or a string or []byte (a ULEB128 length followed by
+// the bytes). If any field has another type, nothing is written and err
+// will be ErrUnsupportedTupleType.
+func WriteTuple(writer io.Writer, fields ...interface{}) (byteCount int, err error) {
+	buffer, err := AppendTuple(nil, fields...)
+	if err != nil {
+		return
+	}
+	return writer.Write(buffer)
+}
+
+// AppendTuple appends fields to buffer as in WriteTuple. If any field has an
+// unsupported type, buffer is returned unchanged along with
+// ErrUnsupportedTupleType.
+func AppendTuple(buffer []byte, fields ...interface{}) (result []byte, err error) {
+	for _, field := range fields {
+		switch field.(type) {
+		case uint64, int64, string, []byte:
+		default:
+			return buffer, ErrUnsupportedTupleType
+		}
+	}
+	result = buffer
+	for _, field := range fields {
+		switch field := field.(type) {
+		case uint64:
+			result = AppendUint64(result, field)
+		case int64:
+			result = AppendUint64(result, ZigZag64(field))
+		case string:
+			result = AppendUint64(result, uint64(len(field)))
+			result = append(result, field...)
+		case []byte:
+			result = AppendUint64(result, uint64(len(field)))
+			result = append(result, field...)
+		}
+	}
+	return
+}
+
+// EncodedSizeTuple returns the number of bytes that fields encode to as in
+// WriteTuple, without encoding them. Besides the types WriteTuple accepts,
+// fields may be of any integer, string or byte slice type (including named
+// types such as time.Duration), and are sized as if converted to a uint64,
+// int64, string or []byte. If any field has another type, err will be
+// ErrUnsupportedTupleType.
+func EncodedSizeTuple(fields ...interface{}) (size int, err error) {
+	for _, field := range fields {
+		fieldSize, ok := encodedSizeTupleField(reflect.ValueOf(field))
+		if !ok {
+			return 0, ErrUnsupportedTupleType
+		}
+		size += fieldSize
+	}
+	return
+}
+
+// EncodedSizeOf returns the number of bytes that v encodes to as a tuple,
+// for computing record sizes (for index building or quota checks) without
+// encoding anything. v may be a single field (see EncodedSizeTuple), or a
+// struct or pointer to a struct whose exported fields are all such fields,
+// in which case the size is that of a tuple of those fields in declaration
+// order. Unexported fields are ignored. If v or any of its exported fields
+// has an unsupported type, err will be ErrUnsupportedTupleType.
+func EncodedSizeOf(v interface{}) (size int, err error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Struct {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return EncodedSizeTuple(v)
+	}
+	valueType := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		if valueType.Field(i).PkgPath != "" {
+			continue
+		}
+		fieldSize, ok := encodedSizeTupleField(value.Field(i))
+		if !ok {
+			return 0, ErrUnsupportedTupleType
+		}
+		size += fieldSize
+	}
+	return
+}
+
+func encodedSizeTupleField(field reflect.Value) (size int, ok bool) {
+	switch field.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return EncodedSizeUint64(field.Uint()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return EncodedSizeUint64(ZigZag64(field.Int())), true
+	case reflect.String:
+		return EncodedSizeUint64(uint64(field.Len())) + field.Len(), true
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			return EncodedSizeUint64(uint64(field.Len())) + field.Len(), true
+		}
+	}
+	return
+}
+
+// ReadTuple reads fields written by WriteTuple into fields, each of which
+// must be a *uint64, *int64, *string or *[]byte matching the type that was
+// written. Strings and byte slices longer than maxLength return a
+// *LengthError before anything is allocated, and values too large for
+// their field return ErrOverflow. If the stream ends cleanly before the
+// tuple, err will be io.EOF, and if it ends partway through, err will be
+// io.ErrUnexpectedEOF.
+func ReadTuple(reader io.Reader, maxLength int, fields ...interface{}) (byteCount int, err error) {
+	for _, field := range fields {
+		switch field.(type) {
+		case *uint64, *int64, *string, *[]byte:
+		default:
+			return 0, ErrUnsupportedTupleType
+		}
+	}
+
+	buffer := []byte{0}
+	for i, field := range fields {
+		var value uint64
+		var fieldByteCount int
+		value, fieldByteCount, err = decodeUint64(reader, buffer)
+		byteCount += fieldByteCount
+		if err == nil {
+			switch field := field.(type) {
+			case *uint64:
+				*field = value
+			case *int64:
+				*field = UnZigZag64(value)
+			case *string:
+				var bytes []byte
+				bytes, fieldByteCount, err = readTupleBytes(reader, value, maxLength)
+				byteCount += fieldByteCount
+				*field = string(bytes)
+			case *[]byte:
+				*field, fieldByteCount, err = readTupleBytes(reader, value, maxLength)
+				byteCount += fieldByteCount
+			}
+		}
+		if err != nil {
+			if i > 0 || byteCount > 0 {
+				err = unexpectedEOF(err)
+			}
+			return
+		}
+	}
+	return
+}
+
+func readTupleBytes(reader io.Reader, length uint64, maxLength int) (bytes []byte, byteCount int, err error) {
+	count, err := CheckLength(length, nil, maxLength, 1)
+	if err != nil {
+		return
+	}
+	bytes = make([]byte, count)
+	byteCount, err = io.ReadFull(reader, bytes)
+	return
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func TestTupleRoundTrip(t *testing.T) {
+	buffer := &writeCounter{}
+	byteCount, err := WriteTuple(buffer, uint64(300), int64(-3), "hello", []byte{1, 2}, int64(math.MinInt64), uint64(math.MaxUint64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buffer.writeCount != 1 || byteCount != buffer.Len() {
+		t.Errorf("Expected %v bytes in one write but got %v in %v", buffer.Len(), byteCount, buffer.writeCount)
+	}
+
+	var a, f uint64
+	var b, e int64
+	var c string
+	var d []byte
+	readByteCount, err := ReadTuple(buffer, 10, &a, &b, &c, &d, &e, &f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readByteCount != byteCount {
+		t.Errorf("Expected to read %v bytes but read %v", byteCount, readByteCount)
+	}
+	if a != 300 || b != -3 || c != "hello" || !bytes.Equal(d, []byte{1, 2}) || e != math.MinInt64 || f != math.MaxUint64 {
+		t.Errorf("Unexpected fields %v %v %q %v %v %v", a, b, c, d, e, f)
+	}
+}
+
+func TestTupleEncoding(t *testing.T) {
+	expected := []byte{0xac, 0x02, 0x05, 0x02, 'h', 'i', 0x00}
+	actual, err := AppendTuple(nil, uint64(300), int64(-3), "hi", []byte{})
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Expected %v but got %v", describe.D(expected), describe.D(actual))
+	}
+}
+
+func TestTupleErrors(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	if _, err := WriteTuple(buffer, uint64(1), 5); err != ErrUnsupportedTupleType {
+		t.Errorf("Expected %v but got %v", ErrUnsupportedTupleType, err)
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Expected nothing to be written")
+	}
+	if result, err := AppendTuple([]byte{0x01}, uint64(1), 1.5); err != ErrUnsupportedTupleType || !bytes.Equal(result, []byte{0x01}) {
+		t.Errorf("Expected the buffer to be unchanged but got %v (%v)", result, err)
+	}
+
+	var value uint64
+	var text string
+	if _, err := ReadTuple(bytes.NewReader([]byte{0x01}), 10, value); err != ErrUnsupportedTupleType {
+		t.Errorf("Expected %v but got %v", ErrUnsupportedTupleType, err)
+	}
+	for _, test := range []struct {
+		encoded []byte
+		err     error
+	}{
+		{[]byte{}, io.EOF},
+		{[]byte{0x80}, io.ErrUnexpectedEOF},
+		{[]byte{0x01}, io.ErrUnexpectedEOF},
+		{[]byte{0x01, 0x03, 'a'}, io.ErrUnexpectedEOF},
+		{append(bytes.Repeat([]byte{0xff}, 10), 0x01), ErrOverflow},
+	} {
+		if _, err := ReadTuple(bytes.NewReader(test.encoded), 10, &value, &text); err != test.err {
+			t.Errorf("Expected %v to give %v but got %v", describe.D(test.encoded), test.err, err)
+		}
+	}
+	if _, err := ReadTuple(bytes.NewReader([]byte{0x01, 0x0b}), 10, &value, &text); err == nil {
+		t.Errorf("Expected a string over the maximum length to fail")
+	}
+}
+
+func TestEncodedSizeOf(t *testing.T) {
+	type ID uint32
+	type record struct {
+		ID      ID
+		Delta   int64
+		Timeout time.Duration
+		Name    string
+		Payload []byte
+		cache   map[string]int
+	}
+	value := record{ID: 300, Delta: -70, Timeout: time.Second, Name: "name", Payload: make([]byte, 200)}
+	encoded, err := AppendTuple(nil, uint64(value.ID), value.Delta, int64(value.Timeout), value.Name, value.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{value, &value} {
+		if size, err := EncodedSizeOf(v); err != nil || size != len(encoded) {
+			t.Errorf("Expected size %v but got %v (%v)", len(encoded), size, err)
+		}
+	}
+	if size, err := EncodedSizeTuple(value.ID, value.Delta, value.Timeout, value.Name, value.Payload); err != nil || size != len(encoded) {
+		t.Errorf("Expected size %v but got %v (%v)", len(encoded), size, err)
+	}
+	if size, err := EncodedSizeOf(ID(300)); err != nil || size != 2 {
+		t.Errorf("Expected size 2 but got %v (%v)", size, err)
+	}
+
+	type unsupported struct {
+		Ratio float64
+	}
+	for _, v := range []interface{}{unsupported{}, 1.5, nil, (*record)(nil), []int{1}} {
+		if _, err := EncodedSizeOf(v); err != ErrUnsupportedTupleType {
+			t.Errorf("Expected %T to give %v but got %v", v, ErrUnsupportedTupleType, err)
+		}
+	}
+}
@@ -23,6 +23,7 @@
 package uleb128
 
 import (
+	"errors"
 	"io"
 	"math/big"
 )
@@ -30,6 +31,18 @@ import (
 // Maximum number of bytes that will ever be written to a buffer
 const MaxBufferWriteBytes = 10
 
+// ErrTruncated is returned when the input ends partway through a value.
+var ErrTruncated = errors.New("uleb128: truncated value")
+
+// ErrTooLong is returned when a value's encoding is longer than allowed.
+var ErrTooLong = errors.New("uleb128: value encoding too long")
+
+// ErrNonCanonical is returned when a value isn't encoded in its minimal form.
+var ErrNonCanonical = errors.New("uleb128: non-canonical encoding")
+
+// ErrOverflow is returned when a value that must fit into a uint64 doesn't.
+var ErrOverflow = errors.New("uleb128: value overflows uint64")
+
 // EncodedSize returns the number of bytes required to encode this value.
 func EncodedSize(value *big.Int) int {
 	if isZero(value) {
@@ -70,12 +83,64 @@ func Encode(value *big.Int, writer io.Writer) (byteCount int, err error) {
 // Encode a math.big.Int value (the sign of the value will be ignored).
 // Assumes that there's enough room in buffer (see MaxBufferWriteBytes).
 func EncodeToBytes(value *big.Int, buffer []byte) (byteCount int) {
-	// Manually dispatch based on architecture because +build can't select on
-	// word size. This is awkward, but should in theory be optimized away.
-	if is32Bit() {
-		return encode32(value, buffer)
+	return EncodeToBytesWithEngine(value, buffer, NativeEngine)
+}
+
+// Engine selects the word-size specific algorithm used to encode math.big.Int
+// values. Both engines produce identical output on any architecture; the
+// choice exists so that tests on one architecture can cross-check the other.
+type Engine int
+
+const (
+	// Use the engine matching the machine word size.
+	NativeEngine Engine = iota
+	// Process values in 32-bit words, split into 16-bit groups.
+	Engine32
+	// Process values in 64-bit words, split into 32-bit groups.
+	Engine64
+)
+
+// Encode a math.big.Int value (the sign of the value will be ignored) using
+// the specified engine.
+// Assumes that there's enough room in buffer (see MaxBufferWriteBytes).
+func EncodeToBytesWithEngine(value *big.Int, buffer []byte, engine Engine) (byteCount int) {
+	return encodeToBytes(value, buffer, engine, false)
+}
+
+// Encode as in EncodeToBytesWithEngine. If zeroize is true, the words the
+// value was copied into while encoding are wiped afterwards.
+func encodeToBytes(value *big.Int, buffer []byte, engine Engine, zeroize bool) (byteCount int) {
+	if engine == NativeEngine {
+		engine = Engine64
+		if is32Bit() {
+			engine = Engine32
+		}
+	}
+	if engine == Engine32 {
+		var scratch [encodeScratchWords * 2]uint32
+		words := wordsAsUint32(value.Bits(), scratch[:0])
+		byteCount = encode32(words, buffer)
+		if zeroize {
+			wipeUint32s(words[:cap(words)])
+		}
+		return
+	}
+	var scratch [encodeScratchWords]uint64
+	words := wordsAsUint64(value.Bits(), scratch[:0])
+	byteCount = encode64(words, buffer)
+	if zeroize {
+		wipeUint64s(words[:cap(words)])
 	}
-	return encode64(value, buffer)
+	return
+}
+
+// The number of 64-bit words that EncodeToBytes can handle without
+// allocating.
+const encodeScratchWords = 8
+
+// Returns true if EncodeToBytes will make an allocation for value.
+func encodeAllocates(value *big.Int) bool {
+	return value.BitLen() > encodeScratchWords*64
 }
 
 // Encode a uint64 value, returning the number of bytes encoded.
@@ -88,26 +153,60 @@ func EncodeUint64(value uint64, writer io.Writer) (byteCount int, err error) {
 // Encode a uint64 value, returning the number of bytes encoded.
 // Assumes that there's enough room in buffer (see MaxBufferWriteBytes).
 func EncodeUint64ToBytes(value uint64, buffer []byte) (byteCount int) {
-	const lastByteMask = ^uint64(0x7f)
-
-	if (value & lastByteMask) == 0 {
-		buffer[0] = byte(value)
-		byteCount = 1
-		return
-	}
-
-	continueFlag := uint64(continuationMask)
-	for value != 0 {
-		buffer[byteCount] = byte((value & payloadMask) | continueFlag)
+	// Bounding the loop by len(buffer) lets the compiler drop the per-byte
+	// bounds checks. A buffer that's too small still panics on the final
+	// store.
+	for byteCount < len(buffer) && value > payloadMask {
+		buffer[byteCount] = byte(value) | continuationMask
 		byteCount++
 		value >>= 7
-		if (value & lastByteMask) == 0 {
-			continueFlag = 0
-		}
 	}
+	buffer[byteCount] = byte(value)
+	byteCount++
 	return
 }
 
+// Encode several math.big.Int values (their signs will be ignored) using a
+// single internal buffer and write.
+func EncodeMany(writer io.Writer, values ...*big.Int) (byteCount int, err error) {
+	size := 0
+	for _, value := range values {
+		size += EncodedSize(value)
+	}
+	buffer := make([]byte, 0, size)
+	for _, value := range values {
+		buffer = Append(buffer, value)
+	}
+	return writer.Write(buffer)
+}
+
+// Encode several uint64 values using a single internal buffer and write.
+func EncodeManyUint64(writer io.Writer, values ...uint64) (byteCount int, err error) {
+	buffer := make([]byte, 0, len(values)*MaxBufferWriteBytes)
+	for _, value := range values {
+		buffer = AppendUint64(buffer, value)
+	}
+	return writer.Write(buffer)
+}
+
+// Append the encoding of a math.big.Int value (the sign of the value will be
+// ignored) to buffer, returning the extended buffer.
+func Append(buffer []byte, value *big.Int) []byte {
+	start := len(buffer)
+	buffer = growBuffer(buffer, EncodedSize(value))
+	byteCount := EncodeToBytes(value, buffer[start:])
+	return buffer[:start+byteCount]
+}
+
+// Append the encoding of a uint64 value to buffer, returning the extended
+// buffer.
+func AppendUint64(buffer []byte, value uint64) []byte {
+	start := len(buffer)
+	buffer = growBuffer(buffer, MaxBufferWriteBytes)
+	byteCount := EncodeUint64ToBytes(value, buffer[start:])
+	return buffer[:start+byteCount]
+}
+
 // Decode a ULEB128 value.
 // If the result is small enough to fit into type uint64, asBigInt will be nil
 // and asUint will contain the result.
@@ -119,9 +218,16 @@ func Decode(reader io.Reader) (asUint uint64, asBigInt *big.Int, byteCount int,
 // Decode a ULEB128 value using the supplied 1-byte buffer (to avoid extra allocations).
 // If the result is small enough to fit into type uint64, asBigInt will be nil
 // and asUint will contain the result.
+//
+// Deprecated: Use a Decoder, which reuses its buffers across calls.
 func DecodeWithByteBuffer(reader io.Reader, buffer []byte) (asUint uint64, asBigInt *big.Int, byteCount int, err error) {
+	var peekBuffer [MaxBufferWriteBytes]byte
+	if encoded := readBufferedValue(reader, peekBuffer[:], 0); encoded != nil {
+		return DecodeFromBytes(encoded)
+	}
+
 	buffer = buffer[:1]
-	if _, err = reader.Read(buffer); err != nil {
+	if _, err = io.ReadFull(reader, buffer); err != nil {
 		return
 	}
 	byteCount = 1
@@ -134,10 +240,8 @@ func DecodeWithByteBuffer(reader io.Reader, buffer []byte) (asUint uint64, asBig
 
 	word := big.Word(buffer[0] & payloadMask)
 	bitIndex := uint(7)
-	bytesRead := 0
 	for {
-		bytesRead, err = reader.Read(buffer[:])
-		if bytesRead == 0 {
+		if _, err = io.ReadFull(reader, buffer); err != nil {
 			return
 		}
 		byteCount++
@@ -151,86 +255,318 @@ func DecodeWithByteBuffer(reader io.Reader, buffer []byte) (asUint uint64, asBig
 		}
 
 		if buffer[0]&continuationMask != continuationMask {
-			if len(words) == 0 {
-				asUint = uint64(word)
-				return
-			}
-			if word != 0 {
-				words = append(words, big.Word(word))
-			}
-			if is32Bit() {
-				if len(words) == 1 {
-					asUint = uint64(words[0])
-					return
-				} else if len(words) == 2 {
-					asUint = (uint64(words[1]) << 32) | uint64(words[0])
-					return
-				}
-			} else {
-				if len(words) == 1 {
-					asUint = uint64(words[0])
-					return
-				}
+			asUint, asBigInt = decodedValue(words, word)
+			return
+		}
+	}
+}
+
+// Decode a ULEB128 value from the start of buffer.
+// If the result is small enough to fit into type uint64, asBigInt will be nil
+// and asUint will contain the result. If buffer is empty, err will be io.EOF.
+// If buffer ends partway through the value, err will be ErrTruncated.
+func DecodeFromBytes(buffer []byte) (asUint uint64, asBigInt *big.Int, byteCount int, err error) {
+	// Small values dominate real streams, so handle them in a form that the
+	// compiler can inline into callers.
+	if asUint, byteCount = decodeSmall(buffer); byteCount != 0 {
+		return
+	}
+	return decodeFromBytes(buffer)
+}
+
+// Decode a 1 or 2 byte value from the start of buffer. This must stay within
+// the compiler's inlining budget. Returns a byteCount of 0 if the value is
+// longer, or buffer is too short.
+func decodeSmall(buffer []byte) (value uint64, byteCount int) {
+	if len(buffer) > 0 && buffer[0] < 0x80 {
+		return uint64(buffer[0]), 1
+	}
+	if len(buffer) > 1 && buffer[1] < 0x80 {
+		return uint64(buffer[0]&payloadMask) | uint64(buffer[1])<<7, 2
+	}
+	return 0, 0
+}
+
+func decodeFromBytes(buffer []byte) (asUint uint64, asBigInt *big.Int, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	byteCount = 1
+	if buffer[0] < 0x80 {
+		asUint = uint64(buffer[0])
+		return
+	}
+
+	var words []big.Word
+
+	word := big.Word(buffer[0] & payloadMask)
+	bitIndex := uint(7)
+	for byteCount < len(buffer) {
+		b := buffer[byteCount]
+		byteCount++
+		word |= big.Word(b&payloadMask) << bitIndex
+
+		bitIndex += 7
+		if int(bitIndex) >= wordSize() {
+			words = append(words, word)
+			bitIndex &= wordMask()
+			word = big.Word(b&payloadMask) >> (7 - bitIndex)
+		}
+
+		if b&continuationMask != continuationMask {
+			asUint, asBigInt = decodedValue(words, word)
+			return
+		}
+	}
+	err = ErrTruncated
+	return
+}
+
+// DecodeTail decodes the ULEB128 value at the end of buffer, scanning
+// backwards from the last byte rather than forwards from the start. The
+// value begins at buffer[len(buffer)-byteCount], so whatever precedes it must
+// either be empty or end with a byte whose continuation bit is clear (such
+// as another complete value).
+// If buffer is empty, err will be io.EOF. If its last byte has the
+// continuation bit set, err will be ErrTruncated.
+func DecodeTail(buffer []byte) (value Value, byteCount int, err error) {
+	end := len(buffer)
+	if end == 0 {
+		err = io.EOF
+		return
+	}
+	if buffer[end-1]&continuationMask != 0 {
+		err = ErrTruncated
+		return
+	}
+	start := end - 1
+	for start > 0 && buffer[start-1]&continuationMask != 0 {
+		start--
+	}
+	return DecodeValueFromBytes(buffer[start:])
+}
+
+// DecodeAllFromBytes decodes consecutive ULEB128 values from buffer until it
+// is exhausted, passing each one to onValue. byteCount is the number of bytes
+// occupied by complete values. If buffer ends partway through a value, err
+// will be ErrTruncated and the leftover bytes start at buffer[byteCount].
+func DecodeAllFromBytes(buffer []byte, onValue func(asUint uint64, asBigInt *big.Int)) (byteCount int, err error) {
+	for byteCount < len(buffer) {
+		asUint, asBigInt, valueByteCount, decodeErr := DecodeFromBytes(buffer[byteCount:])
+		if decodeErr != nil {
+			err = decodeErr
+			return
+		}
+		onValue(asUint, asBigInt)
+		byteCount += valueByteCount
+	}
+	return
+}
+
+// Check if a complete encoded value is in its minimal form. The only way to
+// pad an encoding is with trailing zero groups, so a multi-byte encoding is
+// canonical as long as its last byte isn't zero.
+func isCanonical(encoded []byte) bool {
+	return len(encoded) == 1 || encoded[len(encoded)-1] != 0
+}
+
+// Extend buffer by byteCount bytes, reallocating if necessary.
+func growBuffer(buffer []byte, byteCount int) []byte {
+	return growBufferZeroizing(buffer, byteCount, false)
+}
+
+// Extend buffer as in growBuffer. If zeroize is true and buffer has to be
+// reallocated, its old backing array is wiped.
+func growBufferZeroizing(buffer []byte, byteCount int, zeroize bool) []byte {
+	if cap(buffer)-len(buffer) < byteCount {
+		grown := make([]byte, len(buffer), 2*cap(buffer)+byteCount)
+		copy(grown, buffer)
+		if zeroize {
+			wipeBytes(buffer[:cap(buffer)])
+		}
+		buffer = grown
+	}
+	return buffer[:len(buffer)+byteCount]
+}
+
+// Decode a value that must fit into a uint64, reading at most
+// MaxBufferWriteBytes bytes so that a hostile stream can't make it allocate.
+// A value that doesn't fit (including one padded past MaxBufferWriteBytes
+// bytes) returns ErrOverflow, and a stream that ends partway through the
+// value returns io.ErrUnexpectedEOF. buffer is only used if reader isn't an
+// io.ByteReader, and may be nil, in which case one is allocated if needed.
+func decodeUint64(reader io.Reader, buffer []byte) (value uint64, byteCount int, err error) {
+	var peekBuffer [MaxBufferWriteBytes]byte
+	if encoded := readBufferedValue(reader, peekBuffer[:], 0); encoded != nil {
+		return decodeUintFromBytes(encoded, 64)
+	}
+
+	byteReader, isByteReader := reader.(io.ByteReader)
+	if !isByteReader {
+		if cap(buffer) == 0 {
+			buffer = make([]byte, 1)
+		}
+		buffer = buffer[:1]
+	}
+	for shift := uint(0); ; shift += 7 {
+		var b byte
+		if isByteReader {
+			b, err = byteReader.ReadByte()
+		} else {
+			_, err = io.ReadFull(reader, buffer)
+			b = buffer[0]
+		}
+		if err != nil {
+			if byteCount > 0 {
+				err = unexpectedEOF(err)
 			}
-			asBigInt = big.NewInt(0)
-			asBigInt.SetBits(words)
+			value = 0
+			return
+		}
+		byteCount++
+		// The last byte that fits holds only bit 63, and must end the value.
+		if byteCount == MaxBufferWriteBytes && b > 1 {
+			value = 0
+			err = ErrOverflow
+			return
+		}
+		value |= uint64(b&payloadMask) << shift
+		if b&continuationMask == 0 {
 			return
 		}
 	}
+}
 
+// Decode a value from the start of buffer, checking that it fits into
+// bitWidth bits.
+func decodeUintFromBytes(buffer []byte, bitWidth int) (value uint64, byteCount int, err error) {
+	value, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		return
+	}
+	if asBigInt != nil || value&^maskForBitCount(bitWidth) != 0 {
+		value = 0
+		err = ErrOverflow
+	}
 	return
 }
 
-func maskForBitCount(bitCount int) uint64 {
-	return ^(^uint64(0) << uint(bitCount))
+// DecodeBigFromBytes decodes a ULEB128 value from the start of buffer as a
+// math.big.Int regardless of its magnitude. The result is stored in result
+// (reusing its storage) if it's not nil, or in a new big.Int otherwise.
+// Errors are the same as for DecodeFromBytes.
+func DecodeBigFromBytes(buffer []byte, result *big.Int) (value *big.Int, byteCount int, err error) {
+	if len(buffer) == 0 {
+		err = io.EOF
+		return
+	}
+	for byteCount < len(buffer) {
+		byteCount++
+		if buffer[byteCount-1]&continuationMask == 0 {
+			value = setBigFromEncoded(result, buffer[:byteCount])
+			return
+		}
+	}
+	err = ErrTruncated
+	return
 }
 
-func encode32(value *big.Int, buffer []byte) (byteCount int) {
-	// Prevent compilation on 64-bit arch
-	if !is32Bit() {
+// Set result to the value of a complete encoding, reusing result's storage.
+func setBigFromEncoded(result *big.Int, encoded []byte) *big.Int {
+	if result == nil {
+		result = new(big.Int)
+	}
+	words := result.Bits()[:0]
+	word := big.Word(0)
+	bitIndex := uint(0)
+	for _, b := range encoded {
+		payload := big.Word(b & payloadMask)
+		word |= payload << bitIndex
+		bitIndex += 7
+		if int(bitIndex) >= wordSize() {
+			words = append(words, word)
+			bitIndex &= wordMask()
+			word = payload >> (7 - bitIndex)
+		}
+	}
+	words = append(words, word)
+	return result.SetBits(words)
+}
+
+// Build the result of a decode from the completed words and the final
+// partial word.
+func decodedValue(words []big.Word, word big.Word) (asUint uint64, asBigInt *big.Int) {
+	if len(words) == 0 {
+		asUint = uint64(word)
 		return
 	}
+	if word != 0 {
+		words = append(words, word)
+	}
+	if is32Bit() {
+		if len(words) == 1 {
+			asUint = uint64(words[0])
+			return
+		} else if len(words) == 2 {
+			asUint = (uint64(words[1]) << 32) | uint64(words[0])
+			return
+		}
+	} else {
+		if len(words) == 1 {
+			asUint = uint64(words[0])
+			return
+		}
+	}
+	asBigInt = big.NewInt(0)
+	asBigInt.SetBits(words)
+	return
+}
 
-	if isZero(value) {
+func maskForBitCount(bitCount int) uint64 {
+	return ^(^uint64(0) << uint(bitCount))
+}
+
+func encode32(words []uint32, buffer []byte) (byteCount int) {
+	if len(words) == 0 {
 		buffer[0] = 0
 		byteCount = 1
 		return
 	}
 
+	// Each full half-word's groups are written through a window re-sliced
+	// once from buffer, so that the inner loops run without bounds checks.
 	const lowMask = 0xffff
 	const highMask = 0xffff0000
-	words := value.Bits()
-	accum := big.Word(0)
+	accum := uint32(0)
 	end := len(words) - 1
-	shiftIndex := 0
+	shiftIndex := uint(0)
 	shift := uint(0)
 
-	for i := 0; i < end; i++ {
-		srcWord := words[i]
-
+	for _, srcWord := range words[:end] {
 		// Low 16 bits
 		shift = uint(leftShifts32[shiftIndex])
 		accum |= (srcWord & lowMask) << shift
-		groupCount := int(groupCounts32[shiftIndex])
-		for j := 0; j < groupCount; j++ {
-			buffer[byteCount] = byte(accum&payloadMask) | continuationMask
-			byteCount++
+		groups := buffer[byteCount : byteCount+int(groupCounts32[shiftIndex])]
+		for j := range groups {
+			groups[j] = byte(accum&payloadMask) | continuationMask
 			accum >>= 7
 		}
+		byteCount += len(groups)
 
-		shiftIndex = (shiftIndex + 1) % 15
+		shiftIndex = (shiftIndex + 1) % 14
 
 		// High 16 bits
 		shift = uint(rightShifts32[shiftIndex])
 		accum |= (srcWord & highMask) >> shift
-		groupCount = int(groupCounts32[shiftIndex])
-		for j := 0; j < groupCount; j++ {
-			buffer[byteCount] = byte(accum&payloadMask) | continuationMask
-			byteCount++
+		groups = buffer[byteCount : byteCount+int(groupCounts32[shiftIndex])]
+		for j := range groups {
+			groups[j] = byte(accum&payloadMask) | continuationMask
 			accum >>= 7
 		}
+		byteCount += len(groups)
 
-		shiftIndex = (shiftIndex + 1) % 15
+		shiftIndex = (shiftIndex + 1) % 14
 	}
 
 	srcWord := words[end]
@@ -250,12 +586,11 @@ func encode32(value *big.Int, buffer []byte) (byteCount int) {
 		buffer[byteCount-1] |= continuationMask
 	}
 
-	shiftIndex = (shiftIndex + 1) % 15
+	shiftIndex = (shiftIndex + 1) % 14
 
 	// High 16 bits
 	shift = uint(rightShifts32[shiftIndex])
 	accum |= (srcWord & highMask) >> shift
-	groupCount = int(groupCounts32[shiftIndex])
 	for {
 		buffer[byteCount] = byte(accum & payloadMask)
 		byteCount++
@@ -265,56 +600,48 @@ func encode32(value *big.Int, buffer []byte) (byteCount int) {
 		}
 		buffer[byteCount-1] |= continuationMask
 	}
-	return
 }
 
-func encode64(value *big.Int, buffer []byte) (byteCount int) {
-	// Prevent compilation on 32-bit arch
-	if is32Bit() {
-		return
-	}
-
-	if isZero(value) {
+func encode64(words []uint64, buffer []byte) (byteCount int) {
+	if len(words) == 0 {
 		buffer[0] = 0
 		byteCount = 1
 		return
 	}
 
+	// Each full half-word's groups are written through a window re-sliced
+	// once from buffer, so that the inner loops run without bounds checks.
 	const lowMask = 0xffffffff
-	highMask := big.Word(0xffffffff)
-	highMask <<= 32
-	words := value.Bits()
-	accum := big.Word(0)
+	const highMask = 0xffffffff00000000
+	accum := uint64(0)
 	end := len(words) - 1
 	shiftIndex := uint(0)
 	shift := uint(0)
 
-	for i := 0; i < end; i++ {
-		srcWord := words[i]
-
+	for _, srcWord := range words[:end] {
 		// Low 32 bits
 		shift = shiftIndex / 2
 		accum |= (srcWord & lowMask) << shift
-		groupCount := int(groupCounts64[shiftIndex])
-		for j := 0; j < groupCount; j++ {
-			buffer[byteCount] = byte(accum&payloadMask) | continuationMask
-			byteCount++
+		groups := buffer[byteCount : byteCount+int(groupCounts64[shiftIndex])]
+		for j := range groups {
+			groups[j] = byte(accum&payloadMask) | continuationMask
 			accum >>= 7
 		}
+		byteCount += len(groups)
 
-		shiftIndex = (shiftIndex + 1) % 15
+		shiftIndex = (shiftIndex + 1) % 14
 
 		// High 32 bits
 		shift = uint(rightShifts64[shiftIndex])
 		accum |= (srcWord & highMask) >> shift
-		groupCount = int(groupCounts64[shiftIndex])
-		for j := 0; j < groupCount; j++ {
-			buffer[byteCount] = byte(accum&payloadMask) | continuationMask
-			byteCount++
+		groups = buffer[byteCount : byteCount+int(groupCounts64[shiftIndex])]
+		for j := range groups {
+			groups[j] = byte(accum&payloadMask) | continuationMask
 			accum >>= 7
 		}
+		byteCount += len(groups)
 
-		shiftIndex = (shiftIndex + 1) % 15
+		shiftIndex = (shiftIndex + 1) % 14
 	}
 
 	srcWord := words[end]
@@ -334,12 +661,11 @@ func encode64(value *big.Int, buffer []byte) (byteCount int) {
 		buffer[byteCount-1] |= continuationMask
 	}
 
-	shiftIndex = (shiftIndex + 1) % 15
+	shiftIndex = (shiftIndex + 1) % 14
 
 	// High 32 bits
 	shift = uint(rightShifts64[shiftIndex])
 	accum |= srcWordHigh >> shift
-	groupCount = int(groupCounts64[shiftIndex])
 	for {
 		buffer[byteCount] = byte(accum & payloadMask)
 		byteCount++
@@ -351,6 +677,47 @@ func encode64(value *big.Int, buffer []byte) (byteCount int) {
 	}
 }
 
+// Convert big.Int words to 32-bit words, dropping any high zero words.
+func wordsAsUint32(words []big.Word, result []uint32) []uint32 {
+	if needed := len(words) * wordSize() / 32; cap(result) < needed {
+		result = make([]uint32, 0, needed)
+	}
+	for _, word := range words {
+		result = append(result, uint32(word))
+		if !is32Bit() {
+			result = append(result, uint32(uint64(word)>>32))
+		}
+	}
+	for len(result) > 0 && result[len(result)-1] == 0 {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+// Convert big.Int words to 64-bit words, dropping any high zero words.
+func wordsAsUint64(words []big.Word, result []uint64) []uint64 {
+	if needed := (len(words)*wordSize() + 63) / 64; cap(result) < needed {
+		result = make([]uint64, 0, needed)
+	}
+	if is32Bit() {
+		for i := 0; i < len(words); i += 2 {
+			word := uint64(words[i])
+			if i+1 < len(words) {
+				word |= uint64(words[i+1]) << 32
+			}
+			result = append(result, word)
+		}
+	} else {
+		for _, word := range words {
+			result = append(result, uint64(word))
+		}
+	}
+	for len(result) > 0 && result[len(result)-1] == 0 {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
 func is32Bit() bool {
 	return ^uint(0) == 0xffffffff
 }
@@ -391,8 +758,8 @@ const continuationMask = 0x80
 // |   11 |  H  |  -30  |    4   |    6   |
 // |   12 |  L  |    6  |    5   |    3   |
 // |   13 |  H  |  -29  |    5   |    0   |
-var groupCounts64 = []uint8{4, 5, 4, 5, 4, 5, 5, 4, 5, 4, 5, 4, 5, 5}
-var rightShifts64 = []uint8{0, 28, 0, 27, 0, 26, 0, 32, 0, 31, 0, 30, 0, 29}
+var groupCounts64 = [14]uint8{4, 5, 4, 5, 4, 5, 5, 4, 5, 4, 5, 4, 5, 5}
+var rightShifts64 = [14]uint8{0, 28, 0, 27, 0, 26, 0, 32, 0, 31, 0, 30, 0, 29}
 
 // 32-bit words, split into upper and lower 16-bit groups:
 //
@@ -412,6 +779,6 @@ var rightShifts64 = []uint8{0, 28, 0, 27, 0, 26, 0, 32, 0, 31, 0, 30, 0, 29}
 // |   11 |  H  |  -15  |    2   |    3   |
 // |   12 |  L  |    3  |    2   |    5   |
 // |   13 |  H  |  -11  |    3   |    0   |
-var groupCounts32 = []uint8{2, 2, 2, 3, 2, 2, 3, 2, 2, 2, 3, 2, 2, 3}
-var leftShifts32 = []uint8{0, 0, 4, 0, 1, 0, 5, 0, 2, 0, 6, 0, 3, 0}
-var rightShifts32 = []uint8{0, 14, 0, 10, 0, 13, 0, 16, 0, 12, 0, 15, 0, 11}
+var groupCounts32 = [14]uint8{2, 2, 2, 3, 2, 2, 3, 2, 2, 2, 3, 2, 2, 3}
+var leftShifts32 = [14]uint8{0, 0, 4, 0, 1, 0, 5, 0, 2, 0, 6, 0, 3, 0}
+var rightShifts32 = [14]uint8{0, 14, 0, 10, 0, 13, 0, 16, 0, 12, 0, 15, 0, 11}
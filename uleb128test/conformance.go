@@ -0,0 +1,194 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// Vector is a known-good encoding of a value in a particular format.
+type Vector struct {
+	Value   uint64
+	Encoded []byte
+}
+
+// ULEB128Vectors are the expected ULEB128 encodings of boundary values.
+var ULEB128Vectors = []Vector{
+	{0, []byte{0x00}},
+	{1, []byte{0x01}},
+	{0x7f, []byte{0x7f}},
+	{0x80, []byte{0x80, 0x01}},
+	{300, []byte{0xac, 0x02}},
+	{0x3fff, []byte{0xff, 0x7f}},
+	{0x4000, []byte{0x80, 0x80, 0x01}},
+	{104543565, []byte{0xcd, 0xea, 0xec, 0x31}},
+	{0xffffffff, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+	{0x8000000000000000, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}},
+	{0xffffffffffffffff, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+}
+
+// SyncVarintVectors are the expected sync varint encodings of boundary
+// values.
+var SyncVarintVectors = []Vector{
+	{0, []byte{0x00}},
+	{0x7f, []byte{0x7f}},
+	{0x80, []byte{0xc2, 0x80}},
+	{0x7ff, []byte{0xdf, 0xbf}},
+	{0x800, []byte{0xe0, 0xa0, 0x80}},
+	{0xffff, []byte{0xef, 0xbf, 0xbf}},
+	{0x10000, []byte{0xf0, 0x90, 0x80, 0x80}},
+	{0xffffffffffffffff, []byte{0xff, 0x8f, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf, 0xbf}},
+}
+
+// ConformanceValues returns the values that RunConformance checks: 0, every
+// power of 2 along with its neighbours, and the maximum uint64.
+func ConformanceValues() []uint64 {
+	values := []uint64{0}
+	for bit := uint(0); bit < 64; bit++ {
+		power := uint64(1) << bit
+		values = append(values, power-1, power, power+1)
+	}
+	return append(values, 0xffffffffffffffff)
+}
+
+// RunConformance checks that codec behaves as the Codec interface requires:
+// that every encoding path agrees with EncodedSize and MaxEncodedSize, that
+// every value round-trips through both decoding paths, that a stream decodes
+// one value at a time without reading ahead, and that missing or truncated
+// input is reported with io.EOF or ErrTruncated.
+func RunConformance(t *testing.T, codec uleb128.Codec) {
+	values := ConformanceValues()
+
+	t.Run("Encode", func(t *testing.T) {
+		for _, value := range values {
+			if err := checkEncode(codec, value); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("Decode", func(t *testing.T) {
+		for _, value := range values {
+			if err := checkDecode(codec, value); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		var stream []byte
+		for _, value := range values {
+			stream = codec.AppendTo(stream, value)
+		}
+		reader := bytes.NewBuffer(stream)
+		for _, expected := range values {
+			value, _, err := codec.Decode(reader)
+			if err != nil || value != expected {
+				t.Errorf("Expected %x from stream but got %x (%v)", expected, value, err)
+				return
+			}
+		}
+		if _, _, err := codec.Decode(reader); err != io.EOF {
+			t.Errorf("Expected io.EOF at the end of the stream but got %v", err)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		if _, _, err := codec.DecodeFromBytes(nil); err != io.EOF {
+			t.Errorf("Expected io.EOF from empty input but got %v", err)
+		}
+		for _, value := range values {
+			encoded := codec.AppendTo(nil, value)
+			for i := 1; i < len(encoded); i++ {
+				if _, _, err := codec.DecodeFromBytes(encoded[:i]); err != uleb128.ErrTruncated {
+					t.Errorf("Expected %x truncated to %v bytes to fail with ErrTruncated but got %v", value, i, err)
+				}
+				if _, _, err := codec.Decode(bytes.NewBuffer(encoded[:i])); err != uleb128.ErrTruncated {
+					t.Errorf("Expected %x truncated to %v bytes to fail with ErrTruncated from a reader but got %v", value, i, err)
+				}
+			}
+		}
+	})
+}
+
+// RunVectors checks that codec encodes and decodes each vector exactly.
+func RunVectors(t *testing.T, codec uleb128.Codec, vectors []Vector) {
+	for _, vector := range vectors {
+		if encoded := codec.AppendTo(nil, vector.Value); !bytes.Equal(encoded, vector.Encoded) {
+			t.Errorf("Expected %x to encode to [% x] but got [% x]", vector.Value, vector.Encoded, encoded)
+		}
+		value, byteCount, err := codec.DecodeFromBytes(vector.Encoded)
+		if err != nil || value != vector.Value || byteCount != len(vector.Encoded) {
+			t.Errorf("Expected [% x] to decode to %x (%v bytes) but got %x (%v bytes, %v)",
+				vector.Encoded, vector.Value, len(vector.Encoded), value, byteCount, err)
+		}
+	}
+}
+
+func checkEncode(codec uleb128.Codec, value uint64) error {
+	prefix := []byte{0xaa, 0xbb}
+	appended := codec.AppendTo(append([]byte(nil), prefix...), value)
+	if !bytes.Equal(appended[:len(prefix)], prefix) {
+		return fmt.Errorf("AppendTo of %x overwrote the existing buffer contents", value)
+	}
+	encoded := appended[len(prefix):]
+	size := codec.EncodedSize(value)
+	if len(encoded) != size {
+		return fmt.Errorf("AppendTo of %x produced %v bytes but EncodedSize is %v", value, len(encoded), size)
+	}
+	if size < 1 || size > codec.MaxEncodedSize() {
+		return fmt.Errorf("EncodedSize of %x is %v, outside of 1-%v", value, size, codec.MaxEncodedSize())
+	}
+	buff := &bytes.Buffer{}
+	byteCount, err := codec.Encode(value, buff)
+	if err != nil {
+		return fmt.Errorf("Encode of %x: %v", value, err)
+	}
+	if byteCount != size || !bytes.Equal(buff.Bytes(), encoded) {
+		return fmt.Errorf("Encode of %x produced [% x] (reported %v bytes) but AppendTo produced [% x]", value, buff.Bytes(), byteCount, encoded)
+	}
+	return nil
+}
+
+func checkDecode(codec uleb128.Codec, expected uint64) error {
+	encoded := codec.AppendTo(nil, expected)
+	withTrailing := append(append([]byte(nil), encoded...), 0xff, 0xff)
+	value, byteCount, err := codec.DecodeFromBytes(withTrailing)
+	if err != nil || value != expected || byteCount != len(encoded) {
+		return fmt.Errorf("DecodeFromBytes of [% x]: expected %x (%v bytes) but got %x (%v bytes, %v)",
+			encoded, expected, len(encoded), value, byteCount, err)
+	}
+	reader := bytes.NewBuffer(withTrailing)
+	value, byteCount, err = codec.Decode(reader)
+	if err != nil || value != expected || byteCount != len(encoded) {
+		return fmt.Errorf("Decode of [% x]: expected %x (%v bytes) but got %x (%v bytes, %v)",
+			encoded, expected, len(encoded), value, byteCount, err)
+	}
+	if reader.Len() != 2 {
+		return fmt.Errorf("Decode of [% x] read %v bytes past the end of the value", encoded, 2-reader.Len())
+	}
+	return nil
+}
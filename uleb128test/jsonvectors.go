@@ -0,0 +1,166 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// JSONVectorFormat identifies a JSON test vector file holding ULEB128
+// vectors.
+const JSONVectorFormat = "uleb128"
+
+// JSONVector is a ULEB128 test vector in a language-neutral form, for
+// checking other implementations against this one byte for byte.
+type JSONVector struct {
+	// The value in base 10 (of any size). Empty if decoding must fail.
+	Value string `json:"value,omitempty"`
+	// The encoding in lowercase hex.
+	Encoded string `json:"encoded"`
+	// True if Encoded is the minimal encoding of Value. Vectors with valid
+	// but redundant encodings leave it false (and omit it from the JSON),
+	// since an encoder must never produce them.
+	Canonical bool `json:"canonical,omitempty"`
+	// If decoding must fail, the kind of failure: "empty" for no input, or
+	// "truncated" for input that ends partway through a value.
+	Error string `json:"error,omitempty"`
+}
+
+type jsonVectorFile struct {
+	Format  string       `json:"format"`
+	Vectors []JSONVector `json:"vectors"`
+}
+
+var jsonVectorErrors = map[error]string{
+	io.EOF:               "empty",
+	uleb128.ErrTruncated: "truncated",
+}
+
+// DefaultJSONVectors returns this library's reference set of vectors:
+// boundary values, values beyond 64 bits, redundant encodings, and inputs
+// that must fail to decode.
+func DefaultJSONVectors() (vectors []JSONVector) {
+	for _, v := range ULEB128Vectors {
+		vectors = append(vectors, JSONVector{
+			Value:     new(big.Int).SetUint64(v.Value).String(),
+			Encoded:   hex.EncodeToString(v.Encoded),
+			Canonical: true,
+		})
+	}
+	for _, decimal := range []string{
+		"18446744073709551616",
+		"340282366920938463463374607431768211455",
+		"10000000000000000000000000000",
+	} {
+		value, _ := new(big.Int).SetString(decimal, 10)
+		vectors = append(vectors, JSONVector{
+			Value:     decimal,
+			Encoded:   uleb128.EncodeToHex(value),
+			Canonical: true,
+		})
+	}
+	vectors = append(vectors,
+		JSONVector{Value: "0", Encoded: "8000"},
+		JSONVector{Value: "1", Encoded: "818000"},
+		JSONVector{Value: "127", Encoded: "ff00"},
+		JSONVector{Encoded: "", Error: "empty"},
+		JSONVector{Encoded: "80", Error: "truncated"},
+		JSONVector{Encoded: "ffffffffffffffffffff", Error: "truncated"},
+	)
+	return
+}
+
+// WriteJSONVectors writes vectors to writer as an indented JSON document.
+func WriteJSONVectors(writer io.Writer, vectors []JSONVector) error {
+	encoded, err := json.MarshalIndent(jsonVectorFile{
+		Format:  JSONVectorFormat,
+		Vectors: vectors,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(encoded, '\n'))
+	return err
+}
+
+// ReadJSONVectors reads a JSON document written by WriteJSONVectors.
+func ReadJSONVectors(reader io.Reader) (vectors []JSONVector, err error) {
+	var file jsonVectorFile
+	if err = json.NewDecoder(reader).Decode(&file); err != nil {
+		return
+	}
+	if file.Format != JSONVectorFormat {
+		err = fmt.Errorf("uleb128test: expected vector format %q but got %q", JSONVectorFormat, file.Format)
+		return
+	}
+	vectors = file.Vectors
+	return
+}
+
+// CheckJSONVector checks that this library decodes the vector as described,
+// and for canonical vectors that it encodes the value identically.
+func CheckJSONVector(vector JSONVector) error {
+	encoded, err := hex.DecodeString(vector.Encoded)
+	if err != nil {
+		return fmt.Errorf("vector %q: %v", vector.Encoded, err)
+	}
+	asUint, asBigInt, byteCount, err := uleb128.DecodeFromBytes(encoded)
+	if vector.Error != "" {
+		if jsonVectorErrors[err] != vector.Error {
+			return fmt.Errorf("vector %q: expected error %q but got %v", vector.Encoded, vector.Error, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("vector %q: %v", vector.Encoded, err)
+	}
+	if asBigInt == nil {
+		asBigInt = new(big.Int).SetUint64(asUint)
+	}
+	if asBigInt.String() != vector.Value || byteCount != len(encoded) {
+		return fmt.Errorf("vector %q: expected %v (%v bytes) but got %v (%v bytes)",
+			vector.Encoded, vector.Value, len(encoded), asBigInt, byteCount)
+	}
+	reencoded := uleb128.Append(nil, asBigInt)
+	if vector.Canonical != bytes.Equal(reencoded, encoded) {
+		return fmt.Errorf("vector %q: expected canonical to be %v, but %v encodes to %x",
+			vector.Encoded, vector.Canonical, vector.Value, reencoded)
+	}
+	return nil
+}
+
+// RunJSONVectors calls CheckJSONVector on each vector and reports any
+// failures to t.
+func RunJSONVectors(t *testing.T, vectors []JSONVector) {
+	for _, vector := range vectors {
+		if err := CheckJSONVector(vector); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden test vector file")
+
+var vectorFilePath = filepath.Join("testdata", "vectors.json")
+
+func TestDefaultJSONVectors(t *testing.T) {
+	RunJSONVectors(t, DefaultJSONVectors())
+}
+
+func TestJSONVectorsRoundTrip(t *testing.T) {
+	buff := &bytes.Buffer{}
+	if err := WriteJSONVectors(buff, DefaultJSONVectors()); err != nil {
+		t.Error(err)
+		return
+	}
+	vectors, err := ReadJSONVectors(buff)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(vectors) != len(DefaultJSONVectors()) {
+		t.Errorf("Expected %v vectors but got %v", len(DefaultJSONVectors()), len(vectors))
+	}
+	RunJSONVectors(t, vectors)
+}
+
+// The checked-in vector file is what other implementations test against, so
+// it must always match DefaultJSONVectors. Run with -update to regenerate it.
+func TestJSONVectorFile(t *testing.T) {
+	expected := &bytes.Buffer{}
+	if err := WriteJSONVectors(expected, DefaultJSONVectors()); err != nil {
+		t.Error(err)
+		return
+	}
+	if *update {
+		os.MkdirAll(filepath.Dir(vectorFilePath), 0755)
+		if err := ioutil.WriteFile(vectorFilePath, expected.Bytes(), 0644); err != nil {
+			t.Error(err)
+		}
+		return
+	}
+	actual, err := ioutil.ReadFile(vectorFilePath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(actual, expected.Bytes()) {
+		t.Errorf("%v is out of date; run go test -update", vectorFilePath)
+	}
+}
+
+func TestCheckJSONVectorFailures(t *testing.T) {
+	for _, vector := range []JSONVector{
+		{Value: "1", Encoded: "02", Canonical: true},
+		{Value: "0", Encoded: "8000", Canonical: true},
+		{Value: "0", Encoded: "00", Canonical: false},
+		{Encoded: "00", Error: "truncated"},
+		{Encoded: "80", Error: "empty"},
+		{Value: "1", Encoded: "zz"},
+	} {
+		if err := CheckJSONVector(vector); err == nil {
+			t.Errorf("Expected vector %+v to fail", vector)
+		}
+	}
+
+	if _, err := ReadJSONVectors(bytes.NewBufferString(`{"format": "vlq", "vectors": []}`)); err == nil {
+		t.Errorf("Expected an unknown format to fail")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package uleb128test provides helpers for testing code built on the uleb128
+// package, and for validating alternative implementations of it.
+package uleb128test
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// RoundTrip encodes value (the sign of the value will be ignored) through
+// every encoding path, checks that they agree with each other and with
+// EncodedSize, and then decodes the result through every decoding path. It
+// returns the encoded bytes, or an error describing the first mismatch.
+// Since it doesn't need a *testing.T, fuzzers can call it directly.
+func RoundTrip(value *big.Int) (encoded []byte, err error) {
+	expected := new(big.Int).Abs(value)
+	encoded = uleb128.Append(nil, value)
+	if err = checkEncodings(encoded, uleb128.EncodedSize(value),
+		func(b *bytes.Buffer) (int, error) { return uleb128.Encode(value, b) },
+		func(b []byte) int { return uleb128.EncodeToBytes(value, b) }); err != nil {
+		return
+	}
+
+	asUint, asBigInt, byteCount, err := uleb128.DecodeFromBytes(encoded)
+	if err = checkDecoded("DecodeFromBytes", expected, asUint, asBigInt, byteCount, len(encoded), err); err != nil {
+		return
+	}
+	asUint, asBigInt, byteCount, err = uleb128.Decode(bytes.NewBuffer(encoded))
+	if err = checkDecoded("Decode", expected, asUint, asBigInt, byteCount, len(encoded), err); err != nil {
+		return
+	}
+	decoded, byteCount, err := uleb128.DecodeBigFromBytes(encoded, nil)
+	if err != nil {
+		err = fmt.Errorf("DecodeBigFromBytes of %v: %v", expected, err)
+		return
+	}
+	if decoded.Cmp(expected) != 0 || byteCount != len(encoded) {
+		err = fmt.Errorf("DecodeBigFromBytes: expected %v (%v bytes) but got %v (%v bytes)", expected, len(encoded), decoded, byteCount)
+	}
+	return
+}
+
+// RoundTripUint64 is like RoundTrip, but also checks that the uint64 paths
+// produce the same encoding as the math.big.Int paths.
+func RoundTripUint64(value uint64) (encoded []byte, err error) {
+	encoded = uleb128.AppendUint64(nil, value)
+	if err = checkEncodings(encoded, uleb128.EncodedSizeUint64(value),
+		func(b *bytes.Buffer) (int, error) { return uleb128.EncodeUint64(value, b) },
+		func(b []byte) int { return uleb128.EncodeUint64ToBytes(value, b) }); err != nil {
+		return
+	}
+	bigEncoded, err := RoundTrip(new(big.Int).SetUint64(value))
+	if err != nil {
+		return
+	}
+	if !bytes.Equal(encoded, bigEncoded) {
+		err = fmt.Errorf("%v: uint64 encoding %x differs from big.Int encoding %x", value, encoded, bigEncoded)
+	}
+	return
+}
+
+// AssertRoundTrip calls RoundTrip and reports any failure to t.
+func AssertRoundTrip(t testing.TB, value *big.Int) []byte {
+	t.Helper()
+	encoded, err := RoundTrip(value)
+	if err != nil {
+		t.Error(err)
+	}
+	return encoded
+}
+
+// AssertRoundTripUint64 calls RoundTripUint64 and reports any failure to t.
+func AssertRoundTripUint64(t testing.TB, value uint64) []byte {
+	t.Helper()
+	encoded, err := RoundTripUint64(value)
+	if err != nil {
+		t.Error(err)
+	}
+	return encoded
+}
+
+func checkEncodings(appended []byte, expectedSize int,
+	encode func(*bytes.Buffer) (int, error), encodeToBytes func([]byte) int) error {
+
+	if len(appended) != expectedSize {
+		return fmt.Errorf("Append produced %v bytes (%x) but EncodedSize is %v", len(appended), appended, expectedSize)
+	}
+	buff := &bytes.Buffer{}
+	byteCount, err := encode(buff)
+	if err != nil {
+		return fmt.Errorf("Encode: %v", err)
+	}
+	if byteCount != expectedSize || !bytes.Equal(buff.Bytes(), appended) {
+		return fmt.Errorf("Encode produced %x (reported %v bytes) but Append produced %x", buff.Bytes(), byteCount, appended)
+	}
+	buffer := make([]byte, expectedSize)
+	byteCount = encodeToBytes(buffer)
+	if byteCount != expectedSize || !bytes.Equal(buffer, appended) {
+		return fmt.Errorf("EncodeToBytes produced %x (reported %v bytes) but Append produced %x", buffer, byteCount, appended)
+	}
+	return nil
+}
+
+func checkDecoded(name string, expected *big.Int, asUint uint64, asBigInt *big.Int,
+	byteCount int, expectedByteCount int, err error) error {
+
+	if err != nil {
+		return fmt.Errorf("%v of %v: %v", name, expected, err)
+	}
+	if byteCount != expectedByteCount {
+		return fmt.Errorf("%v of %v: expected %v bytes but decoded %v", name, expected, expectedByteCount, byteCount)
+	}
+	if expected.BitLen() <= 64 {
+		if asBigInt != nil || asUint != expected.Uint64() {
+			return fmt.Errorf("%v: expected uint64 %v but got %v / %v", name, expected, asUint, asBigInt)
+		}
+		return nil
+	}
+	if asBigInt == nil || asBigInt.Cmp(expected) != 0 {
+		return fmt.Errorf("%v: expected big.Int %v but got %v / %v", name, expected, asUint, asBigInt)
+	}
+	return nil
+}
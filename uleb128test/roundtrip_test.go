@@ -0,0 +1,48 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, value := range []uint64{0, 1, 0x7f, 0x80, 300, 0xffffffffffffffff} {
+		AssertRoundTripUint64(t, value)
+	}
+	value := big.NewInt(1)
+	for i := 0; i < 300; i++ {
+		AssertRoundTrip(t, value)
+		value.Lsh(value, 1)
+	}
+	AssertRoundTrip(t, big.NewInt(-300))
+}
+
+func TestRoundTripReportsSizes(t *testing.T) {
+	encoded, err := RoundTripUint64(300)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(encoded) != 2 {
+		t.Errorf("Expected 2 encoded bytes but got %v", len(encoded))
+	}
+}
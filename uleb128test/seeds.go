@@ -0,0 +1,84 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// SeedCorpus returns a deterministic set of interesting encoded inputs for
+// seeding fuzzers: boundary values at every 7-bit group and machine word
+// edge, maximal-length values, redundant (overlong) encodings, truncations,
+// and empty input. Each seed appears only once.
+func SeedCorpus() (seeds [][]byte) {
+	seen := map[string]bool{}
+	add := func(seed []byte) {
+		if !seen[string(seed)] {
+			seen[string(seed)] = true
+			seeds = append(seeds, seed)
+		}
+	}
+
+	add([]byte{})
+	var values []*big.Int
+	for groups := uint(1); groups <= 20; groups++ {
+		power := new(big.Int).Lsh(big.NewInt(1), groups*7)
+		values = append(values, new(big.Int).Sub(power, big.NewInt(1)), power)
+	}
+	for _, bitLength := range []uint{32, 63, 64, 128, 448} {
+		power := new(big.Int).Lsh(big.NewInt(1), bitLength)
+		values = append(values,
+			new(big.Int).Sub(power, big.NewInt(1)),
+			power,
+			new(big.Int).Add(power, big.NewInt(1)))
+	}
+	values = append([]*big.Int{big.NewInt(0), big.NewInt(1)}, values...)
+
+	for _, value := range values {
+		encoded := uleb128.Append(nil, value)
+		add(encoded)
+		// A truncation: the value without its final byte.
+		if len(encoded) > 1 {
+			add(encoded[:len(encoded)-1])
+		}
+		// An overlong form: the value with a redundant zero group.
+		overlong := append([]byte(nil), encoded...)
+		overlong[len(overlong)-1] |= 0x80
+		add(append(overlong, 0x00))
+	}
+
+	// Zero in every length up to just beyond the longest uint64 encoding.
+	for length := 2; length <= uleb128.MaxBufferWriteBytes+1; length++ {
+		zero := bytes.Repeat([]byte{0x80}, length)
+		zero[length-1] = 0x00
+		add(zero)
+	}
+	// Runs of continuation bytes, with and without a terminator.
+	for _, length := range []int{9, 10, 11, 64} {
+		run := bytes.Repeat([]byte{0xff}, length)
+		add(run)
+		add(append(run, 0x01))
+	}
+	return
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func TestSeedCorpus(t *testing.T) {
+	seeds := SeedCorpus()
+	seen := map[string]bool{}
+	valid, invalid := 0, 0
+	for _, seed := range seeds {
+		if seen[string(seed)] {
+			t.Errorf("Duplicate seed %x", seed)
+		}
+		seen[string(seed)] = true
+		if _, _, byteCount, err := uleb128.DecodeFromBytes(seed); err == nil && byteCount == len(seed) {
+			valid++
+		} else {
+			invalid++
+		}
+	}
+	if valid == 0 || invalid == 0 {
+		t.Errorf("Expected both valid and invalid seeds but got %v valid and %v invalid", valid, invalid)
+	}
+	if !seen[""] || !seen["\x80\x00"] || !seen["\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"] {
+		t.Errorf("Expected the corpus to contain the empty, overlong zero and maximum uint64 seeds")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"math/rand"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+// TortureOptions configures WriteTortureStream. Zero fields take their
+// defaults.
+type TortureOptions struct {
+	// Seeds the random choices, so that a stream can be reproduced.
+	Seed int64
+	// The number of values to write (default 1000).
+	ValueCount int
+	// The bit length of the largest random giant values (default 4096).
+	MaxGiantBits int
+	// The most redundant continuation bytes in an overlong value (default
+	// 65536).
+	MaxContinuationRun int
+	// If true, the stream ends partway through an extra final value.
+	TruncateEnd bool
+}
+
+// Word-size and group boundary values that have historically broken
+// decoders.
+var pathologicalValues = func() (values []*big.Int) {
+	for _, bitLength := range []uint{7, 32, 56, 63, 64, 70, 128, 448} {
+		power := new(big.Int).Lsh(big.NewInt(1), bitLength)
+		values = append(values,
+			new(big.Int).Sub(power, big.NewInt(1)),
+			power,
+			new(big.Int).Add(power, big.NewInt(1)))
+	}
+	return
+}()
+
+// WriteTortureStream writes an adversarial stream of ULEB128 values for
+// robustness and limit testing of decoders. It randomly interleaves tiny
+// values, pathological word-boundary values (2^63, 2^64±1, 2^448 and so on),
+// giant values, and overlong encodings with long runs of continuation bytes.
+// It returns the values in the order written, for comparison with what a
+// decoder produces.
+func WriteTortureStream(writer io.Writer, options TortureOptions) (expected []*big.Int, err error) {
+	if options.ValueCount <= 0 {
+		options.ValueCount = 1000
+	}
+	if options.MaxGiantBits <= 0 {
+		options.MaxGiantBits = 4096
+	}
+	if options.MaxContinuationRun <= 0 {
+		options.MaxContinuationRun = 65536
+	}
+	random := rand.New(rand.NewSource(options.Seed))
+
+	var encoded []byte
+	for i := 0; i < options.ValueCount; i++ {
+		var value *big.Int
+		encoded = encoded[:0]
+		switch random.Intn(4) {
+		case 0:
+			value = big.NewInt(random.Int63n(0x80))
+			encoded = uleb128.Append(encoded, value)
+		case 1:
+			value = pathologicalValues[random.Intn(len(pathologicalValues))]
+			encoded = uleb128.Append(encoded, value)
+		case 2:
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(1+random.Intn(options.MaxGiantBits)))
+			value = new(big.Int).Rand(random, limit)
+			encoded = uleb128.Append(encoded, value)
+		case 3:
+			value = big.NewInt(random.Int63n(0x4000))
+			encoded = appendOverlong(encoded, value, 1+random.Intn(options.MaxContinuationRun))
+		}
+		if _, err = writer.Write(encoded); err != nil {
+			return
+		}
+		expected = append(expected, value)
+	}
+	if options.TruncateEnd {
+		giant := new(big.Int).Lsh(big.NewInt(1), uint(options.MaxGiantBits))
+		encoded = uleb128.Append(encoded[:0], giant)
+		_, err = writer.Write(encoded[:len(encoded)/2])
+	}
+	return
+}
+
+// Append value followed by extraCount redundant groups.
+func appendOverlong(buffer []byte, value *big.Int, extraCount int) []byte {
+	buffer = uleb128.Append(buffer, value)
+	buffer[len(buffer)-1] |= 0x80
+	buffer = append(buffer, bytes.Repeat([]byte{0x80}, extraCount-1)...)
+	return append(buffer, 0x00)
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-uleb128"
+)
+
+func TestTortureStream(t *testing.T) {
+	buff := &bytes.Buffer{}
+	expected, err := WriteTortureStream(buff, TortureOptions{Seed: 1, ValueCount: 200, MaxContinuationRun: 100})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(expected) != 200 {
+		t.Errorf("Expected 200 values but got %v", len(expected))
+	}
+
+	decoder := uleb128.NewBytesDecoder(buff.Bytes(), uleb128.DecoderOptions{})
+	for i, value := range expected {
+		actual, _, err := decoder.DecodeBig(nil)
+		if err != nil {
+			t.Errorf("Value %v: %v", i, err)
+			return
+		}
+		if actual.Cmp(value) != 0 {
+			t.Errorf("Value %v: Expected %v but got %v", i, value, actual)
+		}
+	}
+	if _, _, err := decoder.DecodeBig(nil); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
+
+func TestTortureStreamIsReproducible(t *testing.T) {
+	a := &bytes.Buffer{}
+	b := &bytes.Buffer{}
+	WriteTortureStream(a, TortureOptions{Seed: 5, ValueCount: 50})
+	WriteTortureStream(b, TortureOptions{Seed: 5, ValueCount: 50})
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("Expected the same seed to produce the same stream")
+	}
+}
+
+func TestTortureStreamLimits(t *testing.T) {
+	buff := &bytes.Buffer{}
+	expected, _ := WriteTortureStream(buff, TortureOptions{Seed: 2, ValueCount: 100, TruncateEnd: true})
+
+	decoder := uleb128.NewBytesDecoder(buff.Bytes(), uleb128.DecoderOptions{})
+	for range expected {
+		decoder.DecodeBig(nil)
+	}
+	if _, _, err := decoder.DecodeBig(nil); err != uleb128.ErrTruncated {
+		t.Errorf("Expected ErrTruncated at the end but got %v", err)
+	}
+
+	limited := uleb128.NewBytesDecoder(buff.Bytes(), uleb128.DecoderOptions{MaxByteCount: uleb128.MaxBufferWriteBytes})
+	for {
+		_, _, err := limited.DecodeBig(nil)
+		if err == uleb128.ErrTooLong {
+			return
+		}
+		if err != nil {
+			t.Errorf("Expected ErrTooLong but got %v", err)
+			return
+		}
+	}
+}
@@ -23,6 +23,7 @@ package uleb128
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"math/big"
 	"reflect"
 	"testing"
@@ -95,6 +96,42 @@ func assertEncodeDecode(t *testing.T, words []uint64, expectedBytes ...byte) {
 		}
 	}
 
+	actualUint, actualBigInt, actualByteCount, err = DecodeFromBytes(expectedBytes)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("DecodeFromBytes %v: Expected byte count of %v but got %v", describe.D(expectedBytes), expectedByteCount, actualByteCount)
+		return
+	}
+	if actualBigInt != nil {
+		if expectedBigInt.Cmp(actualBigInt) != 0 {
+			t.Errorf("DecodeFromBytes: Expected %v to decode to big %x but got %x", describe.D(expectedBytes), expectedBigInt, actualBigInt)
+			return
+		}
+	} else {
+		if expectedBigInt.Uint64() != actualUint {
+			t.Errorf("DecodeFromBytes: Expected %v to decode to %x but got %x", describe.D(expectedBytes), expectedBigInt.Uint64(), actualUint)
+			return
+		}
+	}
+
+	reusedBigInt := big.NewInt(12345)
+	actualBigInt, actualByteCount, err = DecodeBigFromBytes(expectedBytes, reusedBigInt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if actualByteCount != expectedByteCount {
+		t.Errorf("DecodeBigFromBytes %v: Expected byte count of %v but got %v", describe.D(expectedBytes), expectedByteCount, actualByteCount)
+		return
+	}
+	if actualBigInt != reusedBigInt || expectedBigInt.Cmp(actualBigInt) != 0 {
+		t.Errorf("DecodeBigFromBytes: Expected %v to decode into the supplied big %x but got %x", describe.D(expectedBytes), expectedBigInt, actualBigInt)
+		return
+	}
+
 	if len(words) > 1 {
 		return
 	}
@@ -284,6 +321,36 @@ func TestEncodeDecode(t *testing.T) {
 		0xff, 0xff, 0xff, 0x07)
 }
 
+func TestEncodeDecodeLarge(t *testing.T) {
+	for bitCount := uint(1); bitCount < 1500; bitCount++ {
+		value := big.NewInt(1)
+		value.Lsh(value, bitCount)
+		value.Sub(value, big.NewInt(12345))
+		if value.Sign() <= 0 {
+			continue
+		}
+		buff := &bytes.Buffer{}
+		byteCount, err := Encode(value, buff)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if byteCount != EncodedSize(value) {
+			t.Errorf("Expected 2^%v-12345 to encode to %v bytes but got %v", bitCount, EncodedSize(value), byteCount)
+			return
+		}
+		decoded, _, err := DecodeBigFromBytes(buff.Bytes(), nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if decoded.Cmp(value) != 0 {
+			t.Errorf("Expected 2^%v-12345 to survive a round trip but got %x", bitCount, decoded)
+			return
+		}
+	}
+}
+
 func TestExtraData(t *testing.T) {
 	var assertExtraData = func(value uint64, expectedByteCount int, b ...byte) {
 		buff := bytes.NewBuffer(b)
@@ -309,6 +376,141 @@ func TestExtraData(t *testing.T) {
 	assertExtraData(0x80, 2, 0x80, 0x01, 0x01, 0x00)
 }
 
+func TestDecodeFromBytesErrors(t *testing.T) {
+	assertDecodeFromBytesError := func(expectedErr error, b ...byte) {
+		_, _, _, err := DecodeFromBytes(b)
+		if err != expectedErr {
+			t.Errorf("Expected decoding %v to fail with %v but got %v", describe.D(b), expectedErr, err)
+		}
+	}
+
+	assertDecodeFromBytesError(io.EOF)
+	assertDecodeFromBytesError(ErrTruncated, 0x80)
+	assertDecodeFromBytesError(ErrTruncated, 0xff, 0xff)
+	assertDecodeFromBytesError(ErrTruncated, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80)
+}
+
+func TestDecodeAllFromBytes(t *testing.T) {
+	assertDecodeAll := func(expectedValues []uint64, expectedByteCount int, expectedErr error, b ...byte) {
+		var actualValues []uint64
+		actualByteCount, err := DecodeAllFromBytes(b, func(asUint uint64, asBigInt *big.Int) {
+			if asBigInt != nil {
+				t.Errorf("Expected %v to not contain big ints", describe.D(b))
+			}
+			actualValues = append(actualValues, asUint)
+		})
+		if err != expectedErr {
+			t.Errorf("Expected decoding %v to return error %v but got %v", describe.D(b), expectedErr, err)
+		}
+		if actualByteCount != expectedByteCount {
+			t.Errorf("Expected decoding %v to consume %v bytes but got %v", describe.D(b), expectedByteCount, actualByteCount)
+		}
+		if !reflect.DeepEqual(actualValues, expectedValues) {
+			t.Errorf("Expected %v to decode to %v but got %v", describe.D(b), expectedValues, actualValues)
+		}
+	}
+
+	assertDecodeAll(nil, 0, nil)
+	assertDecodeAll([]uint64{0}, 1, nil, 0x00)
+	assertDecodeAll([]uint64{1, 0x80, 0x7f}, 4, nil, 0x01, 0x80, 0x01, 0x7f)
+	assertDecodeAll([]uint64{1}, 1, ErrTruncated, 0x01, 0x80)
+	assertDecodeAll([]uint64{1, 0x80}, 3, ErrTruncated, 0x01, 0x80, 0x01, 0xff, 0xff)
+
+	var bigCount int
+	byteCount, err := DecodeAllFromBytes([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02, 0x05},
+		func(asUint uint64, asBigInt *big.Int) {
+			if asBigInt != nil {
+				bigCount++
+			}
+		})
+	if err != nil {
+		t.Error(err)
+	}
+	if byteCount != 11 {
+		t.Errorf("Expected byte count of 11 but got %v", byteCount)
+	}
+	if bigCount != 1 {
+		t.Errorf("Expected 1 big int but got %v", bigCount)
+	}
+}
+
+func TestDecodeTail(t *testing.T) {
+	assertDecodeTail := func(expected uint64, expectedByteCount int, b ...byte) {
+		value, byteCount, err := DecodeTail(b)
+		if err != nil || value != Uint64Value(expected) || byteCount != expectedByteCount {
+			t.Errorf("Expected the tail of %v to decode to %v (%v bytes) but got %v (%v bytes, %v)",
+				describe.D(b), expected, expectedByteCount, value, byteCount, err)
+		}
+	}
+	assertDecodeTail(0, 1, 0x00)
+	assertDecodeTail(1, 1, 0xac, 0x02, 0x01)
+	assertDecodeTail(300, 2, 0x01, 0xac, 0x02)
+	assertDecodeTail(300, 2, 0xac, 0x02)
+	assertDecodeTail(0xffffffffffffffff, 10, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)
+
+	value, byteCount, err := DecodeTail([]byte{0x05, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02})
+	if err != nil || byteCount != 10 || value.Cmp(BigValue(new(big.Int).Lsh(big.NewInt(1), 64))) != 0 {
+		t.Errorf("Expected 2^64 (10 bytes) but got %v (%v bytes, %v)", value, byteCount, err)
+	}
+
+	if _, _, err := DecodeTail(nil); err != io.EOF {
+		t.Errorf("Expected %v but got %v", io.EOF, err)
+	}
+	if _, _, err := DecodeTail([]byte{0x01, 0x80}); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}
+
+func TestDecodeSmall(t *testing.T) {
+	// Every 1 and 2 byte input must agree with the general decoder.
+	for b0 := 0; b0 < 0x100; b0++ {
+		for b1 := 0; b1 < 0x100; b1++ {
+			buffer := []byte{byte(b0), byte(b1)}
+			value, byteCount := decodeSmall(buffer)
+			expectedValue, _, expectedByteCount, err := decodeFromBytes(buffer)
+			if err != nil {
+				expectedValue, expectedByteCount = 0, 0
+			}
+			if value != expectedValue || byteCount != expectedByteCount {
+				t.Errorf("%v: Expected %v (%v bytes) but got %v (%v bytes)", describe.D(buffer), expectedValue, expectedByteCount, value, byteCount)
+				return
+			}
+		}
+	}
+	if _, byteCount := decodeSmall([]byte{0x80, 0x80, 0x01}); byteCount != 0 {
+		t.Errorf("Expected a 3-byte value to be left to the general decoder")
+	}
+	if _, byteCount := decodeSmall(nil); byteCount != 0 {
+		t.Errorf("Expected an empty buffer to be left to the general decoder")
+	}
+}
+
+func TestEncodeMany(t *testing.T) {
+	buff := &bytes.Buffer{}
+	byteCount, err := EncodeManyUint64(buff, 1, 0x80, 0xffffffffffffffff)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []byte{0x01, 0x80, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	if byteCount != len(expected) || !reflect.DeepEqual(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v (%v bytes)", describe.D(expected), describe.D(buff.Bytes()), byteCount)
+	}
+
+	buff.Reset()
+	big64 := big.NewInt(0)
+	big64.SetBits(toBigWords([]uint64{0, 1}))
+	byteCount, err = EncodeMany(buff, big.NewInt(1), big64)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected = []byte{0x01, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+	if byteCount != len(expected) || !reflect.DeepEqual(buff.Bytes(), expected) {
+		t.Errorf("Expected %v but got %v (%v bytes)", describe.D(expected), describe.D(buff.Bytes()), byteCount)
+	}
+}
+
 // func TestBadData(t *testing.T) {
 // 	for i := 0; i < 0x80; i++ {
 // 		assertEncodeFails(t, []uint64{uint64(i)}, 0)
@@ -369,3 +571,111 @@ func TestDemonstrate(t *testing.T) {
 	demonstrateUint()
 	demonstrateBigInt()
 }
+
+func BenchmarkDecodeFromBytesSmall(b *testing.B) {
+	data := []byte{0x05, 0xac, 0x02, 0x7f, 0x80, 0x01}
+	for i := 0; i < b.N; i++ {
+		for offset := 0; offset < len(data); {
+			_, _, byteCount, _ := DecodeFromBytes(data[offset:])
+			offset += byteCount
+		}
+	}
+}
+
+func BenchmarkEncodeUint64ToBytes(b *testing.B) {
+	buffer := make([]byte, MaxBufferWriteBytes)
+	for i := 0; i < b.N; i++ {
+		EncodeUint64ToBytes(0xffffffffffffffff, buffer)
+	}
+}
+
+func BenchmarkEncodeToBytes(b *testing.B) {
+	value := new(big.Int).Lsh(big.NewInt(1), 500)
+	value.Sub(value, big.NewInt(1))
+	buffer := make([]byte, EncodedSize(value))
+	for i := 0; i < b.N; i++ {
+		EncodeToBytes(value, buffer)
+	}
+}
+
+func BenchmarkDecodeFromBytesUint64(b *testing.B) {
+	data := AppendUint64(nil, 0xffffffffffffffff)
+	for i := 0; i < b.N; i++ {
+		DecodeFromBytes(data)
+	}
+}
+
+// An endless stream of continuation bytes, counting how many were read.
+type continuationReader struct {
+	byteCount int
+}
+
+func (r *continuationReader) Read(p []byte) (n int, err error) {
+	for i := range p {
+		p[i] = 0x80
+	}
+	r.byteCount += len(p)
+	return len(p), nil
+}
+
+func TestDecodeUint64Bounded(t *testing.T) {
+	maxValue := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	for _, test := range []struct {
+		encoded  []byte
+		expected uint64
+		err      error
+	}{
+		{maxValue, 0xffffffffffffffff, nil},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 0, nil},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, 0, ErrOverflow},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 0, ErrOverflow},
+		{[]byte{0x80}, 0, io.ErrUnexpectedEOF},
+		{[]byte{}, 0, io.EOF},
+	} {
+		for name, reader := range map[string]io.Reader{
+			"bytes":  bytes.NewReader(test.encoded),
+			"buffer": bytes.NewBuffer(test.encoded),
+		} {
+			value, _, err := decodeUint64(reader, []byte{0})
+			if err != test.err || value != test.expected {
+				t.Errorf("%v: expected %v to give %v (%v) but got %v (%v)", name, describe.D(test.encoded), test.expected, test.err, value, err)
+			}
+		}
+	}
+
+	// Each decoder must give up with ErrOverflow, or a *LengthError if the
+	// value is a length, after at most MaxBufferWriteBytes bytes.
+	for name, decode := range map[string]func(io.Reader) error{
+		"decodeUint64": func(r io.Reader) (err error) {
+			_, _, err = decodeUint64(r, []byte{0})
+			return
+		},
+		"DecodeBitmap": func(r io.Reader) (err error) {
+			_, _, err = DecodeBitmap(r, 1)
+			return
+		},
+		"DecodeDeltaInstruction": func(r io.Reader) (err error) {
+			// A valid op byte, so that the length is what gets decoded.
+			_, _, err = DecodeDeltaInstruction(io.MultiReader(bytes.NewReader([]byte{byte(DeltaAdd)}), r), 1)
+			return
+		},
+		"DecodeUleb128p1": func(r io.Reader) (err error) {
+			_, _, err = DecodeUleb128p1(r)
+			return
+		},
+		"ReadTuple": func(r io.Reader) (err error) {
+			var value uint64
+			_, err = ReadTuple(r, 1, &value)
+			return
+		},
+	} {
+		reader := &continuationReader{}
+		err := decode(reader)
+		if _, isLengthError := err.(*LengthError); err != ErrOverflow && !isLengthError {
+			t.Errorf("%v: expected an overflow decoding an endless value but got %v", name, err)
+		}
+		if reader.byteCount > MaxBufferWriteBytes {
+			t.Errorf("%v: expected to read at most %v bytes but read %v", name, MaxBufferWriteBytes, reader.byteCount)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+const maxInt = int(^uint(0) >> 1)
+
+// Value is a decoded ULEB128 value. It stores values that fit into a uint64
+// directly, and only falls back to a *big.Int for values that don't.
+type Value struct {
+	small uint64
+	big   *big.Int
+}
+
+// NewValue builds a Value from the (asUint, asBigInt) pair returned by the
+// decode functions.
+func NewValue(asUint uint64, asBigInt *big.Int) Value {
+	if asBigInt != nil {
+		return BigValue(asBigInt)
+	}
+	return Value{small: asUint}
+}
+
+// Uint64Value returns a Value holding value.
+func Uint64Value(value uint64) Value {
+	return Value{small: value}
+}
+
+// BigValue returns a Value holding the magnitude of value (the sign of the
+// value will be ignored). The Value takes ownership of value, which must not
+// be modified afterwards.
+func BigValue(value *big.Int) Value {
+	if value.Sign() < 0 {
+		value = new(big.Int).Abs(value)
+	}
+	if value.IsUint64() {
+		return Value{small: value.Uint64()}
+	}
+	return Value{big: value}
+}
+
+// IsBig returns true if the value doesn't fit into a uint64.
+func (v Value) IsBig() bool {
+	return v.big != nil
+}
+
+// Uint64 returns the value as a uint64. ok will be false if it doesn't fit.
+func (v Value) Uint64() (value uint64, ok bool) {
+	return v.small, v.big == nil
+}
+
+// AsInt64 returns the value as an int64. ok will be false if it doesn't fit.
+func (v Value) AsInt64() (value int64, ok bool) {
+	if v.big != nil || v.small > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(v.small), true
+}
+
+// AsInt returns the value as an int. ok will be false if it doesn't fit.
+func (v Value) AsInt() (value int, ok bool) {
+	if v.big != nil || v.small > uint64(maxInt) {
+		return 0, false
+	}
+	return int(v.small), true
+}
+
+// Big returns the value as a *big.Int. If the value is big, the returned
+// pointer is shared with the Value and must be treated as read-only.
+func (v Value) Big() *big.Int {
+	if v.big != nil {
+		return v.big
+	}
+	return new(big.Int).SetUint64(v.small)
+}
+
+// Cmp compares v and other, returning -1 if v < other, 0 if v == other, and
+// +1 if v > other.
+func (v Value) Cmp(other Value) int {
+	switch {
+	case v.big == nil && other.big == nil:
+		if v.small < other.small {
+			return -1
+		} else if v.small > other.small {
+			return 1
+		}
+		return 0
+	case v.big == nil:
+		return -1
+	case other.big == nil:
+		return 1
+	default:
+		return v.big.Cmp(other.big)
+	}
+}
+
+// Add returns v + other, falling back to a big.Int only if the sum doesn't
+// fit into a uint64.
+func (v Value) Add(other Value) Value {
+	if v.big == nil && other.big == nil {
+		sum, carry := bits.Add64(v.small, other.small, 0)
+		if carry == 0 {
+			return Value{small: sum}
+		}
+	}
+	return BigValue(new(big.Int).Add(v.Big(), other.Big()))
+}
+
+// Sub returns v - other. ok will be false if other is greater than v, since
+// a Value can't be negative.
+func (v Value) Sub(other Value) (difference Value, ok bool) {
+	if v.Cmp(other) < 0 {
+		return
+	}
+	if v.big == nil {
+		return Value{small: v.small - other.small}, true
+	}
+	return BigValue(new(big.Int).Sub(v.big, other.Big())), true
+}
+
+// EncodedSize returns the number of bytes required to encode this value.
+func (v Value) EncodedSize() int {
+	if v.big != nil {
+		return EncodedSize(v.big)
+	}
+	return EncodedSizeUint64(v.small)
+}
+
+// AppendTo appends the encoding of this value to buffer, returning the
+// extended buffer.
+func (v Value) AppendTo(buffer []byte) []byte {
+	if v.big != nil {
+		return Append(buffer, v.big)
+	}
+	return AppendUint64(buffer, v.small)
+}
+
+func (v Value) String() string {
+	if v.big != nil {
+		return v.big.String()
+	}
+	return fmt.Sprintf("%d", v.small)
+}
+
+// DecodeValueFromBytes decodes a ULEB128 value from the start of buffer.
+// See DecodeFromBytes.
+func DecodeValueFromBytes(buffer []byte) (value Value, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err == nil {
+		value = NewValue(asUint, asBigInt)
+	}
+	return
+}
+
+// DecodeValue decodes the next value.
+func (d *Decoder) DecodeValue() (value Value, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := d.Decode()
+	if err == nil {
+		value = NewValue(asUint, asBigInt)
+	}
+	return
+}
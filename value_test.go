@@ -0,0 +1,185 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/kstenerud/go-describe"
+)
+
+func newBigFromHex(hex string) *big.Int {
+	value, ok := new(big.Int).SetString(hex, 16)
+	if !ok {
+		panic("bad hex " + hex)
+	}
+	return value
+}
+
+func TestValueNormalization(t *testing.T) {
+	if BigValue(big.NewInt(5)).IsBig() {
+		t.Errorf("Expected small big.Int to be stored as a uint64")
+	}
+	if BigValue(big.NewInt(-5)).Cmp(Uint64Value(5)) != 0 {
+		t.Errorf("Expected sign to be ignored")
+	}
+	if !BigValue(newBigFromHex("10000000000000000")).IsBig() {
+		t.Errorf("Expected 2^64 to be big")
+	}
+	if NewValue(0, newBigFromHex("ffffffffffffffff")).IsBig() {
+		t.Errorf("Expected 2^64-1 to not be big")
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	small := Uint64Value(300)
+	if v, ok := small.Uint64(); !ok || v != 300 {
+		t.Errorf("Expected (300, true) but got (%v, %v)", v, ok)
+	}
+	if small.Big().Cmp(big.NewInt(300)) != 0 {
+		t.Errorf("Expected big 300 but got %v", small.Big())
+	}
+	if small.String() != "300" {
+		t.Errorf("Expected \"300\" but got %q", small.String())
+	}
+
+	huge := BigValue(newBigFromHex("100000000000000000000"))
+	if _, ok := huge.Uint64(); ok {
+		t.Errorf("Expected %v to not fit into a uint64", huge)
+	}
+	if huge.String() != "1208925819614629174706176" {
+		t.Errorf("Expected \"1208925819614629174706176\" but got %q", huge.String())
+	}
+}
+
+func TestValueCmp(t *testing.T) {
+	values := []Value{
+		Uint64Value(0),
+		Uint64Value(1),
+		Uint64Value(0xffffffffffffffff),
+		BigValue(newBigFromHex("10000000000000000")),
+		BigValue(newBigFromHex("10000000000000001")),
+	}
+	for i, a := range values {
+		for j, b := range values {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if actual := a.Cmp(b); actual != expected {
+				t.Errorf("Expected %v.Cmp(%v) to be %v but got %v", a, b, expected, actual)
+			}
+		}
+	}
+}
+
+func TestValueAddSub(t *testing.T) {
+	values := []Value{
+		Uint64Value(0),
+		Uint64Value(1),
+		Uint64Value(0xfffffffffffffffe),
+		Uint64Value(0xffffffffffffffff),
+		BigValue(newBigFromHex("10000000000000000")),
+		BigValue(newBigFromHex("123456789abcdef0123456789")),
+	}
+	for _, a := range values {
+		for _, b := range values {
+			sum := a.Add(b)
+			expectedSum := new(big.Int).Add(a.Big(), b.Big())
+			if sum.Big().Cmp(expectedSum) != 0 || sum.IsBig() != !expectedSum.IsUint64() {
+				t.Errorf("Expected %v + %v to be %v but got %v", a, b, expectedSum, sum)
+			}
+
+			difference, ok := a.Sub(b)
+			if a.Cmp(b) < 0 {
+				if ok {
+					t.Errorf("Expected %v - %v to fail but got %v", a, b, difference)
+				}
+				continue
+			}
+			expectedDifference := new(big.Int).Sub(a.Big(), b.Big())
+			if !ok || difference.Big().Cmp(expectedDifference) != 0 || difference.IsBig() != !expectedDifference.IsUint64() {
+				t.Errorf("Expected %v - %v to be %v but got %v (%v)", a, b, expectedDifference, difference, ok)
+			}
+		}
+	}
+}
+
+func TestValueEncodeDecode(t *testing.T) {
+	values := []Value{Uint64Value(0), Uint64Value(300), BigValue(newBigFromHex("123456789abcdef0123456789"))}
+	var buffer []byte
+	for _, value := range values {
+		start := len(buffer)
+		buffer = value.AppendTo(buffer)
+		if len(buffer)-start != value.EncodedSize() {
+			t.Errorf("Expected %v to encode to %v bytes but got %v", value, value.EncodedSize(), len(buffer)-start)
+		}
+	}
+
+	decoder := NewDecoder(bytes.NewBuffer(buffer), DecoderOptions{})
+	offset := 0
+	for _, expected := range values {
+		fromBytes, byteCount, err := DecodeValueFromBytes(buffer[offset:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		offset += byteCount
+		fromDecoder, _, err := decoder.DecodeValue()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if fromBytes.Cmp(expected) != 0 || fromDecoder.Cmp(expected) != 0 {
+			t.Errorf("Expected %v but got %v and %v (from %v)", expected, fromBytes, fromDecoder, describe.D(buffer))
+		}
+	}
+}
+
+func TestValueCheckedAccessors(t *testing.T) {
+	assertInt64 := func(value Value, expected int64, expectedOk bool) {
+		actual, ok := value.AsInt64()
+		if actual != expected || ok != expectedOk {
+			t.Errorf("Expected %v.AsInt64() to be (%v, %v) but got (%v, %v)", value, expected, expectedOk, actual, ok)
+		}
+	}
+	assertInt := func(value Value, expected int, expectedOk bool) {
+		actual, ok := value.AsInt()
+		if actual != expected || ok != expectedOk {
+			t.Errorf("Expected %v.AsInt() to be (%v, %v) but got (%v, %v)", value, expected, expectedOk, actual, ok)
+		}
+	}
+
+	assertInt64(Uint64Value(0), 0, true)
+	assertInt64(Uint64Value(0x7fffffffffffffff), 0x7fffffffffffffff, true)
+	assertInt64(Uint64Value(0x8000000000000000), 0, false)
+	assertInt64(BigValue(newBigFromHex("10000000000000000")), 0, false)
+
+	assertInt(Uint64Value(0), 0, true)
+	assertInt(Uint64Value(0x7fffffff), 0x7fffffff, true)
+	assertInt(Uint64Value(uint64(maxInt)), maxInt, true)
+	assertInt(Uint64Value(uint64(maxInt)+1), 0, false)
+	assertInt(BigValue(newBigFromHex("10000000000000000")), 0, false)
+}
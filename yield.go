@@ -0,0 +1,76 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"context"
+	"runtime"
+)
+
+// DefaultYieldInterval is the number of bytes that the yielding decoders
+// decode between yields when no interval is given.
+const DefaultYieldInterval = 64 * 1024
+
+// DecodeAllFromBytesYielding behaves like DecodeAllFromBytes (passing each
+// value to onValue as a Value), but calls yield after every interval bytes
+// (DefaultYieldInterval if 0), so that decoding a large buffer can be paced
+// or abandoned. If yield returns an
+// error, decoding stops and that error is returned, with byteCount being
+// the number of bytes decoded so far.
+func DecodeAllFromBytesYielding(buffer []byte, interval int, yield func() error, onValue func(value Value)) (byteCount int, err error) {
+	if interval <= 0 {
+		interval = DefaultYieldInterval
+	}
+	for byteCount < len(buffer) {
+		end := byteCount + interval
+		if end > len(buffer) {
+			end = len(buffer)
+		}
+		for byteCount < end {
+			value, valueByteCount, decodeErr := DecodeValueFromBytes(buffer[byteCount:])
+			if decodeErr != nil {
+				err = decodeErr
+				return
+			}
+			onValue(value)
+			byteCount += valueByteCount
+		}
+		if byteCount < len(buffer) {
+			if err = yield(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// DecodeAllFromBytesContext behaves like DecodeAllFromBytes, but after every
+// interval bytes (DefaultYieldInterval if 0) it lets other goroutines run
+// and checks ctx, returning ctx.Err() if it's done.
+func DecodeAllFromBytesContext(ctx context.Context, buffer []byte, interval int, onValue func(value Value)) (byteCount int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return DecodeAllFromBytesYielding(buffer, interval, func() error {
+		runtime.Gosched()
+		return ctx.Err()
+	}, onValue)
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDecodeAllFromBytesYielding(t *testing.T) {
+	var buffer []byte
+	for i := uint64(0); i < 100; i++ {
+		buffer = AppendUint64(buffer, i*10)
+	}
+	var values []Value
+	yields := 0
+	byteCount, err := DecodeAllFromBytesYielding(buffer, 16, func() error {
+		yields++
+		return nil
+	}, func(value Value) {
+		values = append(values, value)
+	})
+	if err != nil || byteCount != len(buffer) || len(values) != 100 {
+		t.Errorf("Expected 100 values in %v bytes but got %v in %v (%v)", len(buffer), len(values), byteCount, err)
+	}
+	if expected := (len(buffer) - 1) / 16; yields != expected {
+		t.Errorf("Expected %v yields but got %v", expected, yields)
+	}
+
+	stop := errors.New("stop")
+	values = values[:0]
+	byteCount, err = DecodeAllFromBytesYielding(buffer, 16, func() error {
+		return stop
+	}, func(value Value) {
+		values = append(values, value)
+	})
+	if err != stop || byteCount < 16 || byteCount > 16+MaxBufferWriteBytes {
+		t.Errorf("Expected to stop after about 16 bytes but got %v (%v)", byteCount, err)
+	}
+	if len(values) == 0 {
+		t.Errorf("Expected some values before stopping")
+	}
+
+	if _, err = DecodeAllFromBytesYielding([]byte{0x01, 0x80}, 0, func() error { return nil }, func(Value) {}); err != ErrTruncated {
+		t.Errorf("Expected ErrTruncated but got %v", err)
+	}
+}
+
+func TestDecodeAllFromBytesContext(t *testing.T) {
+	buffer := make([]byte, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	byteCount, err := DecodeAllFromBytesContext(ctx, buffer, 100, func(Value) {
+		count++
+		if count == 150 {
+			cancel()
+		}
+	})
+	if err != context.Canceled || byteCount != 200 {
+		t.Errorf("Expected cancellation after 200 bytes but got %v (%v)", byteCount, err)
+	}
+
+	if byteCount, err = DecodeAllFromBytesContext(ctx, buffer, 0, func(Value) {}); err != context.Canceled || byteCount != 0 {
+		t.Errorf("Expected an already cancelled context to decode nothing but got %v (%v)", byteCount, err)
+	}
+}
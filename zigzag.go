@@ -0,0 +1,130 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"io"
+	"math/big"
+)
+
+// ZigZag64 maps a signed value to an unsigned one so that small magnitudes
+// of either sign encode in few bytes: 0, -1, 1, -2, 2 ... map to
+// 0, 1, 2, 3, 4 .... This is the same mapping as protobuf's sint64.
+func ZigZag64(value int64) uint64 {
+	return uint64(value<<1) ^ uint64(value>>63)
+}
+
+// UnZigZag64 reverses ZigZag64.
+func UnZigZag64(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+// ZigZagBig is ZigZag64 for math.big.Int values of any magnitude, returning
+// a new big.Int.
+func ZigZagBig(value *big.Int) *big.Int {
+	return foldSign(value)
+}
+
+// UnZigZagBig reverses ZigZagBig, returning a new big.Int. The sign of value
+// will be ignored.
+func UnZigZagBig(value *big.Int) *big.Int {
+	return unfoldSign(new(big.Int).Abs(value))
+}
+
+// EncodeZigZag64 zigzag maps an int64 value and encodes it as ULEB128,
+// returning the number of bytes encoded.
+func EncodeZigZag64(value int64, writer io.Writer) (byteCount int, err error) {
+	return EncodeUint64(ZigZag64(value), writer)
+}
+
+// AppendZigZag64 appends the zigzag mapped ULEB128 encoding of an int64
+// value to buffer, returning the extended buffer.
+func AppendZigZag64(buffer []byte, value int64) []byte {
+	return AppendUint64(buffer, ZigZag64(value))
+}
+
+// DecodeZigZag64 decodes a value encoded by EncodeZigZag64. A stream that
+// ends partway through the value returns io.ErrUnexpectedEOF, and a value
+// too large for an int64 returns ErrOverflow.
+func DecodeZigZag64(reader io.Reader) (value int64, byteCount int, err error) {
+	asUint, byteCount, err := decodeUint64(reader, []byte{0})
+	if err != nil {
+		return
+	}
+	value = UnZigZag64(asUint)
+	return
+}
+
+// DecodeZigZag64FromBytes decodes a value encoded by EncodeZigZag64 from the
+// start of buffer. Errors are the same as for DecodeFromBytes, plus
+// ErrOverflow if the value is too large for an int64.
+func DecodeZigZag64FromBytes(buffer []byte) (value int64, byteCount int, err error) {
+	asUint, byteCount, err := decodeUintFromBytes(buffer, 64)
+	if err != nil {
+		return
+	}
+	value = UnZigZag64(asUint)
+	return
+}
+
+// EncodeZigZagBig zigzag maps a math.big.Int value and encodes it as
+// ULEB128, returning the number of bytes encoded.
+func EncodeZigZagBig(value *big.Int, writer io.Writer) (byteCount int, err error) {
+	return Encode(ZigZagBig(value), writer)
+}
+
+// AppendZigZagBig appends the zigzag mapped ULEB128 encoding of a
+// math.big.Int value to buffer, returning the extended buffer.
+func AppendZigZagBig(buffer []byte, value *big.Int) []byte {
+	return Append(buffer, ZigZagBig(value))
+}
+
+// DecodeZigZagBig decodes a value encoded by EncodeZigZagBig. A stream that
+// ends partway through the value returns io.ErrUnexpectedEOF.
+func DecodeZigZagBig(reader io.Reader) (value *big.Int, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := Decode(reader)
+	if err != nil {
+		if byteCount > 0 {
+			err = unexpectedEOF(err)
+		}
+		return
+	}
+	value = unzigzagDecoded(asUint, asBigInt)
+	return
+}
+
+// DecodeZigZagBigFromBytes decodes a value encoded by EncodeZigZagBig from
+// the start of buffer. Errors are the same as for DecodeFromBytes.
+func DecodeZigZagBigFromBytes(buffer []byte) (value *big.Int, byteCount int, err error) {
+	asUint, asBigInt, byteCount, err := DecodeFromBytes(buffer)
+	if err != nil {
+		return
+	}
+	value = unzigzagDecoded(asUint, asBigInt)
+	return
+}
+
+func unzigzagDecoded(asUint uint64, asBigInt *big.Int) *big.Int {
+	if asBigInt == nil {
+		asBigInt = new(big.Int).SetUint64(asUint)
+	}
+	return unfoldSign(asBigInt)
+}
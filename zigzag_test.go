@@ -0,0 +1,144 @@
+// Copyright 2020 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package uleb128
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestZigZag64Mapping(t *testing.T) {
+	for _, test := range []struct {
+		signed   int64
+		unsigned uint64
+	}{
+		{0, 0},
+		{-1, 1},
+		{1, 2},
+		{-2, 3},
+		{2, 4},
+		{math.MaxInt64, math.MaxUint64 - 1},
+		{math.MinInt64, math.MaxUint64},
+	} {
+		if actual := ZigZag64(test.signed); actual != test.unsigned {
+			t.Errorf("Expected %v to map to %v but got %v", test.signed, test.unsigned, actual)
+		}
+		if actual := UnZigZag64(test.unsigned); actual != test.signed {
+			t.Errorf("Expected %v to map back to %v but got %v", test.unsigned, test.signed, actual)
+		}
+	}
+}
+
+func TestZigZagBigMapping(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	for _, value := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(-1),
+		big.NewInt(1),
+		big.NewInt(math.MinInt64),
+		big.NewInt(math.MaxInt64),
+		huge,
+		new(big.Int).Neg(huge),
+	} {
+		encoded := ZigZagBig(value)
+		if encoded.Sign() < 0 {
+			t.Errorf("Expected %v to map to a non-negative value but got %v", value, encoded)
+		}
+		if value.IsInt64() && (!encoded.IsUint64() || encoded.Uint64() != ZigZag64(value.Int64())) {
+			t.Errorf("Expected %v to map to %v but got %v", value, ZigZag64(value.Int64()), encoded)
+		}
+		original := new(big.Int).Set(encoded)
+		if decoded := UnZigZagBig(encoded); decoded.Cmp(value) != 0 {
+			t.Errorf("Expected %v to map back to %v but got %v", encoded, value, decoded)
+		}
+		if encoded.Cmp(original) != 0 {
+			t.Errorf("Expected UnZigZagBig not to modify its argument")
+		}
+	}
+}
+
+func TestZigZag64Encoding(t *testing.T) {
+	for _, test := range []struct {
+		value   int64
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{-1, []byte{0x01}},
+		{1, []byte{0x02}},
+		{-64, []byte{0x7f}},
+		{64, []byte{0x80, 0x01}},
+		{math.MinInt64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	} {
+		if actual := AppendZigZag64(nil, test.value); !bytes.Equal(actual, test.encoded) {
+			t.Errorf("Expected %v to encode to %x but got %x", test.value, test.encoded, actual)
+		}
+		var buffer bytes.Buffer
+		if byteCount, err := EncodeZigZag64(test.value, &buffer); err != nil || byteCount != len(test.encoded) || !bytes.Equal(buffer.Bytes(), test.encoded) {
+			t.Errorf("Expected %v to encode to %x but got %x (%v)", test.value, test.encoded, buffer.Bytes(), err)
+		}
+		if value, byteCount, err := DecodeZigZag64FromBytes(test.encoded); err != nil || value != test.value || byteCount != len(test.encoded) {
+			t.Errorf("Expected %x to decode to %v but got %v (%v)", test.encoded, test.value, value, err)
+		}
+		if value, byteCount, err := DecodeZigZag64(bytes.NewReader(test.encoded)); err != nil || value != test.value || byteCount != len(test.encoded) {
+			t.Errorf("Expected %x to decode to %v but got %v (%v)", test.encoded, test.value, value, err)
+		}
+	}
+
+	tooLarge := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}
+	if _, _, err := DecodeZigZag64FromBytes(tooLarge); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	if _, _, err := DecodeZigZag64(bytes.NewReader(tooLarge)); err != ErrOverflow {
+		t.Errorf("Expected %v but got %v", ErrOverflow, err)
+	}
+	if _, _, err := DecodeZigZag64(bytes.NewReader([]byte{0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestZigZagBigEncoding(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	for _, value := range []*big.Int{big.NewInt(0), big.NewInt(-1), big.NewInt(64), huge, new(big.Int).Neg(huge)} {
+		encoded := AppendZigZagBig(nil, value)
+		if expected := Append(nil, ZigZagBig(value)); !bytes.Equal(encoded, expected) {
+			t.Errorf("Expected %v to encode to %x but got %x", value, expected, encoded)
+		}
+		var buffer bytes.Buffer
+		if byteCount, err := EncodeZigZagBig(value, &buffer); err != nil || byteCount != len(encoded) || !bytes.Equal(buffer.Bytes(), encoded) {
+			t.Errorf("Expected %v to encode to %x but got %x (%v)", value, encoded, buffer.Bytes(), err)
+		}
+		if decoded, byteCount, err := DecodeZigZagBigFromBytes(encoded); err != nil || decoded.Cmp(value) != 0 || byteCount != len(encoded) {
+			t.Errorf("Expected %x to decode to %v but got %v (%v)", encoded, value, decoded, err)
+		}
+		if decoded, byteCount, err := DecodeZigZagBig(bytes.NewReader(encoded)); err != nil || decoded.Cmp(value) != 0 || byteCount != len(encoded) {
+			t.Errorf("Expected %x to decode to %v but got %v (%v)", encoded, value, decoded, err)
+		}
+	}
+	if _, _, err := DecodeZigZagBig(bytes.NewReader([]byte{0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+	if _, _, err := DecodeZigZagBigFromBytes([]byte{0x80}); err != ErrTruncated {
+		t.Errorf("Expected %v but got %v", ErrTruncated, err)
+	}
+}